@@ -35,12 +35,14 @@ var (
 				{
 					Name:        "create",
 					Description: "Creates a new task in the snap scheduler",
-					Usage:       "There are two ways to create a task.\n\t1) Use a task manifest with [--task-manifest]\n\t2) Provide a workflow manifest and schedule details.\n\n\t* Note: Start and stop date/time are optional.\n",
+					Usage:       "There are two ways to create a task.\n\t1) Use a task manifest with [--task-manifest]\n\t2) Provide a workflow manifest and schedule details.\n\n\t* Note: Start and stop date/time are optional.\n\t* Note: --cron can be used instead of --interval to fire at wall clock times.\n",
 					Action:      createTask,
 					Flags: []cli.Flag{
 						flTaskManifest,
 						flWorkfowManifest,
 						flTaskSchedInterval,
+						flTaskSchedCron,
+						flTaskSchedTimeZone,
 						flTaskSchedStartDate,
 						flTaskSchedStartTime,
 						flTaskSchedStopDate,
@@ -86,6 +88,21 @@ var (
 					Usage:  "enable <task_id>",
 					Action: enableTask,
 				},
+				{
+					Name:        "update",
+					Description: "Updates an existing task in place keeping its id",
+					Usage:       "update <task_id>\n\n\t* Note: Only the given schedule, workflow, name or deadline are changed.\n\t* Note: A task manifest replaces the schedule, workflow and deadline it contains.\n",
+					Action:      updateTask,
+					Flags: []cli.Flag{
+						flTaskManifest,
+						flWorkfowManifest,
+						flTaskSchedInterval,
+						flTaskSchedCron,
+						flTaskSchedTimeZone,
+						flTaskName,
+						flTaskDeadline,
+					},
+				},
 			},
 		},
 		{
@@ -117,6 +134,18 @@ var (
 						flRunning,
 					},
 				},
+				{
+					Name:   "logs",
+					Usage:  "logs <plugin_type>:<plugin_name>:<plugin_version> or logs -t <plugin_type> -n <plugin_name> -v <plugin_version>",
+					Action: pluginLogs,
+					Flags: []cli.Flag{
+						flPluginType,
+						flPluginName,
+						flPluginVersion,
+						flPluginLogLines,
+						flPluginLogFollow,
+					},
+				},
 			},
 		},
 		{
@@ -142,6 +171,16 @@ var (
 				},
 			},
 		},
+		{
+			Name:   "events",
+			Usage:  "Streams events such as plugins loading and tasks starting until interrupted",
+			Action: watchEvents,
+			Flags: []cli.Flag{
+				flEventNamespace,
+				flEventPlugin,
+				flEventTask,
+			},
+		},
 	}
 
 	tribeCommands = []cli.Command{
@@ -0,0 +1,96 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/intelsdi-x/snap/mgmt/rest/client"
+)
+
+func watchEvents(ctx *cli.Context) {
+	f := client.EventFilter{
+		Namespaces: splitList(ctx.String("namespace")),
+		Plugins:    splitList(ctx.String("plugin")),
+		Tasks:      splitList(ctx.String("task")),
+	}
+	r := pClient.WatchEvents(f)
+	if r.Err != nil {
+		fmt.Printf("Error watching events:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Watching events:")
+
+	// catch interrupt so we signal the server we are done before exiting
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		<-c
+		fmt.Println("Stopping event watch")
+		r.Close()
+	}()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "TIMESTAMP", "EVENT", "PLUGIN", "TASK", "DETAILS")
+	w.Flush()
+	for {
+		select {
+		case e := <-r.EventChan:
+			plugin := e.PluginName
+			if plugin != "" && e.PluginVersion > 0 {
+				plugin = fmt.Sprintf("%s:%d", plugin, e.PluginVersion)
+			}
+			details, _ := json.Marshal(e.Event)
+			printFields(w, false, 0,
+				e.Timestamp.Format(timeFormat),
+				e.Namespace,
+				plugin,
+				e.TaskID,
+				string(details),
+			)
+			w.Flush()
+		case <-r.DoneChan:
+			if r.Err != nil {
+				fmt.Printf("Error watching events:\n%v\n", r.Err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
+
+// splitList returns the values of a comma separated flag
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
@@ -39,6 +39,29 @@ var (
 		Name:  "insecure",
 		Usage: "Ignore certificate errors when snap's API is running HTTPS",
 	}
+	flUser = cli.StringFlag{
+		Name:   "user",
+		Usage:  "User for HTTP basic auth against snap's API",
+		EnvVar: "SNAP_USER",
+	}
+	flPassword = cli.StringFlag{
+		Name:   "password",
+		Usage:  "Password for HTTP basic auth against snap's API",
+		EnvVar: "SNAP_PASSWORD",
+	}
+	flToken = cli.StringFlag{
+		Name:   "token",
+		Usage:  "Bearer token to authenticate with snap's API",
+		EnvVar: "SNAP_TOKEN",
+	}
+	flClientCert = cli.StringFlag{
+		Name:  "client-cert",
+		Usage: "A path to a client certificate to present when snap's API is running HTTPS",
+	}
+	flClientKey = cli.StringFlag{
+		Name:  "client-key",
+		Usage: "A path to the key of the client certificate",
+	}
 	flRunning = cli.BoolFlag{
 		Name:  "running",
 		Usage: "Shows running plugins",
@@ -61,6 +84,15 @@ var (
 		Name:  "plugin-version, v",
 		Usage: "The plugin version",
 	}
+	flPluginLogLines = cli.IntFlag{
+		Name:  "lines, l",
+		Usage: "The number of most recent lines to show, all that are kept when 0",
+		Value: 100,
+	}
+	flPluginLogFollow = cli.BoolFlag{
+		Name:  "follow, f",
+		Usage: "Keep streaming lines as the plugin writes them until interrupted",
+	}
 
 	// Task flags
 	flTaskName = cli.StringFlag{
@@ -83,6 +115,15 @@ var (
 		Usage: "Interval for the task schedule [ex: 250ms, 1s, 30m]",
 	}
 
+	flTaskSchedCron = cli.StringFlag{
+		Name:  "cron",
+		Usage: "Cron entry for the task schedule, used instead of an interval [ex: \"0 * * * *\", \"*/30 * * * * *\", @hourly]",
+	}
+	flTaskSchedTimeZone = cli.StringFlag{
+		Name:  "timezone",
+		Usage: "IANA time zone the cron entry is evaluated in [defaults to local time]",
+	}
+
 	flTaskSchedStartTime = cli.StringFlag{
 		Name:  "start-time",
 		Usage: "Start time for the task schedule [defaults to now]",
@@ -128,4 +169,18 @@ var (
 		Name:  "verbose, v",
 		Usage: "Verbose output",
 	}
+
+	// Event flags
+	flEventNamespace = cli.StringFlag{
+		Name:  "namespace, n",
+		Usage: "Only stream events in these comma separated namespaces [ex: Control, Scheduler.TaskStarted]",
+	}
+	flEventPlugin = cli.StringFlag{
+		Name:  "plugin, p",
+		Usage: "Only stream events about these comma separated plugin names",
+	}
+	flEventTask = cli.StringFlag{
+		Name:  "task, t",
+		Usage: "Only stream events about these comma separated task ids",
+	}
 )
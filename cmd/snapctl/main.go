@@ -21,6 +21,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"sort"
 	"time"
@@ -40,7 +41,7 @@ func main() {
 	app.Name = "snapctl"
 	app.Version = gitversion
 	app.Usage = "A powerful telemetry framework"
-	app.Flags = []cli.Flag{flURL, flSecure, flAPIVer}
+	app.Flags = []cli.Flag{flURL, flSecure, flAPIVer, flUser, flPassword, flToken, flClientCert, flClientKey}
 	app.Commands = commands
 	sort.Sort(ByCommand(app.Commands))
 	app.Run(os.Args)
@@ -53,10 +54,20 @@ func init() {
 	prtAv := f1.String("api-version", flAPIVer.Value, flAPIVer.Usage)
 	prtA := f1.String("a", flAPIVer.Value, flAPIVer.Usage)
 	prti := f1.Bool("insecure", false, flSecure.Usage)
+	prtUser := f1.String("user", "", flUser.Usage)
+	prtPassword := f1.String("password", "", flPassword.Usage)
+	prtToken := f1.String("token", "", flToken.Usage)
+	prtCert := f1.String("client-cert", "", flClientCert.Usage)
+	prtKey := f1.String("client-key", "", flClientKey.Usage)
 
 	url := flURL.Value
 	ver := flAPIVer.Value
 	secure := false
+	user := os.Getenv(flUser.EnvVar)
+	password := os.Getenv(flPassword.EnvVar)
+	token := os.Getenv(flToken.EnvVar)
+	cert := ""
+	key := ""
 
 	for idx, a := range os.Args {
 		switch a {
@@ -80,9 +91,41 @@ func init() {
 			if err := f1.Parse(os.Args[idx:]); err == nil {
 				secure = *prti
 			}
+		case "--user":
+			if err := f1.Parse(os.Args[idx : idx+2]); err == nil {
+				user = *prtUser
+			}
+		case "--password":
+			if err := f1.Parse(os.Args[idx : idx+2]); err == nil {
+				password = *prtPassword
+			}
+		case "--token":
+			if err := f1.Parse(os.Args[idx : idx+2]); err == nil {
+				token = *prtToken
+			}
+		case "--client-cert":
+			if err := f1.Parse(os.Args[idx : idx+2]); err == nil {
+				cert = *prtCert
+			}
+		case "--client-key":
+			if err := f1.Parse(os.Args[idx : idx+2]); err == nil {
+				key = *prtKey
+			}
 		}
 	}
 	pClient = client.New(url, ver, secure)
+	if user != "" {
+		pClient.SetBasicAuth(user, password)
+	}
+	if token != "" {
+		pClient.SetToken(token)
+	}
+	if cert != "" {
+		if err := pClient.SetClientCert(cert, key); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading client certificate: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	resp := pClient.ListAgreements()
 	if resp.Err == nil {
 		commands = append(commands, tribeCommands...)
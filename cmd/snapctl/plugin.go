@@ -22,13 +22,16 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/codegangsta/cli"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 )
 
 func loadPlugin(ctx *cli.Context) {
@@ -68,6 +71,23 @@ func loadPlugin(ctx *cli.Context) {
 }
 
 func unloadPlugin(ctx *cli.Context) {
+	pType, pName, pVer := pluginArgs(ctx)
+
+	r := pClient.UnloadPlugin(pType, pName, pVer)
+	if r.Err != nil {
+		fmt.Printf("Error unloading plugin:\n%v\n", r.Err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("Plugin unloaded")
+	fmt.Printf("Name: %s\n", r.Name)
+	fmt.Printf("Version: %d\n", r.Version)
+	fmt.Printf("Type: %s\n", r.Type)
+}
+
+// pluginArgs returns the plugin given as <plugin_type>:<plugin_name>:<plugin_version>
+// or by the plugin flags exiting when any of them is missing
+func pluginArgs(ctx *cli.Context) (string, string, int) {
 	pDetails := filepath.SplitList(ctx.Args().First())
 	var pType string
 	var pName string
@@ -103,17 +123,55 @@ func unloadPlugin(ctx *cli.Context) {
 		cli.ShowCommandHelp(ctx, ctx.Command.Name)
 		os.Exit(1)
 	}
+	return pType, pName, pVer
+}
 
-	r := pClient.UnloadPlugin(pType, pName, pVer)
+func pluginLogs(ctx *cli.Context) {
+	pType, pName, pVer := pluginArgs(ctx)
+
+	if !ctx.Bool("follow") {
+		r := pClient.GetPluginLogs(pType, pName, pVer, ctx.Int("lines"))
+		if r.Err != nil {
+			fmt.Printf("Error getting plugin logs:\n%v\n", r.Err.Error())
+			os.Exit(1)
+		}
+		for _, l := range r.Lines {
+			printPluginLogLine(l)
+		}
+		return
+	}
+
+	r := pClient.FollowPluginLogs(pType, pName, pVer, ctx.Int("lines"))
 	if r.Err != nil {
-		fmt.Printf("Error unloading plugin:\n%v\n", r.Err.Error())
+		fmt.Printf("Error following plugin logs:\n%v\n", r.Err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Plugin unloaded")
-	fmt.Printf("Name: %s\n", r.Name)
-	fmt.Printf("Version: %d\n", r.Version)
-	fmt.Printf("Type: %s\n", r.Type)
+	// catch interrupt so we signal the server we are done before exiting
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		<-c
+		r.Close()
+	}()
+
+	for {
+		select {
+		case l := <-r.LineChan:
+			printPluginLogLine(l)
+		case <-r.DoneChan:
+			if r.Err != nil {
+				fmt.Printf("Error following plugin logs:\n%v\n", r.Err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
+
+func printPluginLogLine(l rbody.PluginLogLine) {
+	fmt.Printf("%s %s: %s\n", l.Timestamp.Format(timeFormat), l.Stream, l.Line)
 }
 
 func listPlugins(ctx *cli.Context) {
@@ -109,7 +109,41 @@ func createTask(ctx *cli.Context) {
 }
 
 func createTaskUsingTaskManifest(ctx *cli.Context) {
-	path := ctx.String("task-manifest")
+	t := readTaskManifest(ctx.String("task-manifest"))
+	t.Name = ctx.String("name")
+	checkWorkflow(t.Workflow)
+	r := pClient.CreateTask(t.Schedule, t.Workflow, t.Name, t.Deadline, !ctx.IsSet("no-start"))
+
+	if r.Err != nil {
+		errors := strings.Split(r.Err.Error(), " -- ")
+		fmt.Println("Error creating task:")
+		for _, err := range errors {
+			fmt.Printf("%v\n", err)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("Task created")
+	fmt.Printf("ID: %s\n", r.ID)
+	fmt.Printf("Name: %s\n", r.Name)
+	fmt.Printf("State: %s\n", r.State)
+}
+
+func createTaskUsingWFManifest(ctx *cli.Context) {
+	// Get the workflow
+	wf := readWorkflowManifest(ctx.String("workflow-manifest"))
+	checkWorkflow(wf)
+	// Get the task name
+	name := ctx.String("name")
+
+	// Deadline for a task
+	dl := ctx.String("deadline")
+
+	createTaskWithSchedule(ctx, scheduleFromFlags(ctx), wf, name, dl)
+}
+
+// readTaskManifest reads the YAML or JSON task manifest at path exiting on
+// any error
+func readTaskManifest(path string) *task {
 	ext := filepath.Ext(path)
 	file, e := ioutil.ReadFile(path)
 	if e != nil {
@@ -117,16 +151,16 @@ func createTaskUsingTaskManifest(ctx *cli.Context) {
 		os.Exit(1)
 	}
 
-	t := task{}
+	t := &task{}
 	switch ext {
 	case ".yaml", ".yml":
-		e = yaml.Unmarshal(file, &t)
+		e = yaml.Unmarshal(file, t)
 		if e != nil {
 			fmt.Printf("Error parsing YAML file input - %v\n", e)
 			os.Exit(1)
 		}
 	case ".json":
-		e = json.Unmarshal(file, &t)
+		e = json.Unmarshal(file, t)
 		if e != nil {
 			fmt.Printf("Error parsing JSON file input - %v\n", e)
 			os.Exit(1)
@@ -136,30 +170,16 @@ func createTaskUsingTaskManifest(ctx *cli.Context) {
 		os.Exit(1)
 	}
 
-	t.Name = ctx.String("name")
 	if t.Version != 1 {
 		fmt.Println("Invalid version provided")
 		os.Exit(1)
 	}
-	r := pClient.CreateTask(t.Schedule, t.Workflow, t.Name, t.Deadline, !ctx.IsSet("no-start"))
-
-	if r.Err != nil {
-		errors := strings.Split(r.Err.Error(), " -- ")
-		fmt.Println("Error creating task:")
-		for _, err := range errors {
-			fmt.Printf("%v\n", err)
-		}
-		os.Exit(1)
-	}
-	fmt.Println("Task created")
-	fmt.Printf("ID: %s\n", r.ID)
-	fmt.Printf("Name: %s\n", r.Name)
-	fmt.Printf("State: %s\n", r.State)
+	return t
 }
 
-func createTaskUsingWFManifest(ctx *cli.Context) {
-	// Get the workflow
-	path := ctx.String("workflow-manifest")
+// readWorkflowManifest reads the YAML or JSON workflow manifest at path
+// exiting on any error
+func readWorkflowManifest(path string) *wmap.WorkflowMap {
 	ext := filepath.Ext(path)
 	file, e := ioutil.ReadFile(path)
 	if e != nil {
@@ -170,7 +190,6 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 	var wf *wmap.WorkflowMap
 	switch ext {
 	case ".yaml", ".yml":
-		// e = yaml.Unmarshal(file, &t)
 		wf, e = wmap.FromYaml(file)
 		if e != nil {
 			fmt.Printf("Error parsing YAML file input - %v\n", e)
@@ -178,14 +197,28 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 		}
 	case ".json":
 		wf, e = wmap.FromJson(file)
-		// e = json.Unmarshal(file, &t)
 		if e != nil {
 			fmt.Printf("Error parsing JSON file input - %v\n", e)
 			os.Exit(1)
 		}
+	default:
+		fmt.Printf("Unsupported file type %s\n", ext)
+		os.Exit(1)
+	}
+	return wf
+}
+
+// scheduleFromFlags returns the schedule given by the cron, interval, start,
+// stop and duration flags exiting on any error
+func scheduleFromFlags(ctx *cli.Context) *client.Schedule {
+	// A cron entry means it is a cron schedule
+	if ctx.IsSet("cron") {
+		return &client.Schedule{
+			Type:     "cron",
+			Interval: ctx.String("cron"),
+			TimeZone: ctx.String("timezone"),
+		}
 	}
-	// Get the task name
-	name := ctx.String("name")
 
 	// Get the interval
 	i := ctx.String("interval")
@@ -195,10 +228,6 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 		os.Exit(1)
 	}
 
-	// Deadline for a task
-	dl := ctx.String("deadline")
-
-	var sch *client.Schedule
 	// None of these mean it is a simple schedule
 	if !ctx.IsSet("start-date") && !ctx.IsSet("start-time") && !ctx.IsSet("stop-date") && !ctx.IsSet("stop-time") {
 		// Check if duration was set
@@ -210,55 +239,57 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 			}
 			start := time.Now().Add(createTaskNowPad)
 			stop := start.Add(d)
-			sch = &client.Schedule{
+			return &client.Schedule{
 				Type:      "windowed",
 				Interval:  i,
 				StartTime: &start,
 				StopTime:  &stop,
 			}
-		} else {
-			// No start or stop and no duration == simple schedule
-			sch = &client.Schedule{
-				Type:     "simple",
-				Interval: i,
-			}
 		}
-	} else {
-		// We have some form of windowed schedule
-		start := mergeDateTime(
-			strings.ToUpper(ctx.String("start-time")),
-			strings.ToUpper(ctx.String("start-date")),
-		)
-		stop := mergeDateTime(
-			strings.ToUpper(ctx.String("stop-time")),
-			strings.ToUpper(ctx.String("stop-date")),
-		)
+		// No start or stop and no duration == simple schedule
+		return &client.Schedule{
+			Type:     "simple",
+			Interval: i,
+		}
+	}
 
-		// Use duration to create missing start or stop
-		if ctx.IsSet("duration") {
-			d, err := time.ParseDuration(ctx.String("duration"))
-			if err != nil {
-				fmt.Printf("Bad duration format:\n%v\n", err)
-				os.Exit(1)
-			}
-			// if start is set and stop is not then use duration to create stop
-			if start != nil && stop == nil {
-				t := start.Add(d)
-				stop = &t
-			}
-			// if stop is set and start is not then use duration to create start
-			if stop != nil && start == nil {
-				t := stop.Add(d * -1)
-				start = &t
-			}
+	// We have some form of windowed schedule
+	start := mergeDateTime(
+		strings.ToUpper(ctx.String("start-time")),
+		strings.ToUpper(ctx.String("start-date")),
+	)
+	stop := mergeDateTime(
+		strings.ToUpper(ctx.String("stop-time")),
+		strings.ToUpper(ctx.String("stop-date")),
+	)
+
+	// Use duration to create missing start or stop
+	if ctx.IsSet("duration") {
+		d, err := time.ParseDuration(ctx.String("duration"))
+		if err != nil {
+			fmt.Printf("Bad duration format:\n%v\n", err)
+			os.Exit(1)
+		}
+		// if start is set and stop is not then use duration to create stop
+		if start != nil && stop == nil {
+			t := start.Add(d)
+			stop = &t
 		}
-		sch = &client.Schedule{
-			Type:      "windowed",
-			Interval:  i,
-			StartTime: start,
-			StopTime:  stop,
+		// if stop is set and start is not then use duration to create start
+		if stop != nil && start == nil {
+			t := stop.Add(d * -1)
+			start = &t
 		}
 	}
+	return &client.Schedule{
+		Type:      "windowed",
+		Interval:  i,
+		StartTime: start,
+		StopTime:  stop,
+	}
+}
+
+func createTaskWithSchedule(ctx *cli.Context, sch *client.Schedule, wf *wmap.WorkflowMap, name, dl string) {
 	// Create task
 	r := pClient.CreateTask(sch, wf, name, dl, !ctx.IsSet("no-start"))
 	if r.Err != nil {
@@ -275,6 +306,59 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 	fmt.Printf("State: %s\n", r.State)
 }
 
+func updateTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	id := ctx.Args().First()
+
+	var (
+		sch *client.Schedule
+		wf  *wmap.WorkflowMap
+	)
+	name := ctx.String("name")
+	dl := ctx.String("deadline")
+
+	if ctx.IsSet("task-manifest") {
+		t := readTaskManifest(ctx.String("task-manifest"))
+		sch, wf = t.Schedule, t.Workflow
+		if dl == "" {
+			dl = t.Deadline
+		}
+	}
+	if ctx.IsSet("workflow-manifest") {
+		wf = readWorkflowManifest(ctx.String("workflow-manifest"))
+	}
+	if wf != nil {
+		checkWorkflow(wf)
+	}
+	if ctx.IsSet("cron") || ctx.IsSet("interval") {
+		sch = scheduleFromFlags(ctx)
+	}
+
+	if sch == nil && wf == nil && name == "" && dl == "" {
+		fmt.Println("Nothing to update. Provide a manifest, schedule, name or deadline.")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	r := pClient.UpdateTask(id, sch, wf, name, dl)
+	if r.Err != nil {
+		errors := strings.Split(r.Err.Error(), " -- ")
+		fmt.Println("Error updating task:")
+		for _, err := range errors {
+			fmt.Printf("%v\n", err)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("Task updated")
+	fmt.Printf("ID: %s\n", r.ID)
+	fmt.Printf("Name: %s\n", r.Name)
+	fmt.Printf("State: %s\n", r.State)
+}
+
 func mergeDateTime(tm, dt string) *time.Time {
 	reTm := time.Now().Add(createTaskNowPad)
 	if dt == "" && tm == "" {
@@ -319,6 +403,7 @@ func listTask(ctx *cli.Context) {
 		"HIT",
 		"MISS",
 		"FAIL",
+		"TIMEOUT",
 		"CREATED",
 		"LAST FAILURE",
 	)
@@ -330,6 +415,7 @@ func listTask(ctx *cli.Context) {
 			trunc(task.HitCount),
 			trunc(task.MissCount),
 			trunc(task.FailedCount),
+			trunc(task.TimedOutCount),
 			task.CreationTime().Format(unionParseFormat),
 			task.LastFailureMessage,
 		)
@@ -493,3 +579,18 @@ func enableTask(ctx *cli.Context) {
 	fmt.Println("Task enabled:")
 	fmt.Printf("ID: %s\n", r.ID)
 }
+
+// checkWorkflow exits listing the problems with a workflow map read from a
+// manifest.  Unknown keys are only found here since they are dropped when the
+// workflow is sent to snapd.
+func checkWorkflow(wf *wmap.WorkflowMap) {
+	errs := wf.Validate()
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Println("Error validating workflow:")
+	for _, e := range errs {
+		fmt.Printf("%v\n", e)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,322 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// DefaultAutodiscoverInterval is how often the autodiscover paths are scanned
+// for changes by default
+const DefaultAutodiscoverInterval = time.Second * 5
+
+type watcherOption func(w *autodiscoverWatcher)
+
+// WatchIntervalOption sets how often the autodiscover paths are scanned
+func WatchIntervalOption(v time.Duration) watcherOption {
+	return func(w *autodiscoverWatcher) {
+		w.interval = v
+	}
+}
+
+// WatchSwapOption sets whether a plugin whose file is replaced is swapped for
+// the new file
+func WatchSwapOption(v bool) watcherOption {
+	return func(w *autodiscoverWatcher) {
+		w.swap = v
+	}
+}
+
+// WatchUnloadOption sets whether a plugin whose file is removed is unloaded
+func WatchUnloadOption(v bool) watcherOption {
+	return func(w *autodiscoverWatcher) {
+		w.unload = v
+	}
+}
+
+// fileState is what the watcher knows about a plugin file and its signature
+type fileState struct {
+	modTime    time.Time
+	size       int64
+	sigModTime time.Time
+	sigSize    int64
+}
+
+// watchedFile is the state a file was last seen in and the state the watcher
+// last acted on
+type watchedFile struct {
+	seen    fileState
+	handled fileState
+}
+
+// autodiscoverWatcher scans the autodiscover paths and keeps the plugins
+// loaded in step with the files in them.  A new or changed file is only acted
+// on once it is unchanged between two scans so a file still being written is
+// not loaded.  The paths are polled rather than watched with inotify or
+// kqueue: polling behaves the same on every platform and on mounts which do
+// not deliver file events, and the stability check needs a second look at a
+// file after any event anyway.
+type autodiscoverWatcher struct {
+	control  *pluginControl
+	paths    []string
+	interval time.Duration
+	swap     bool
+	unload   bool
+
+	files map[string]*watchedFile
+	quit  chan struct{}
+	done  sync.WaitGroup
+}
+
+func newAutodiscoverWatcher(c *pluginControl, paths []string, opts ...watcherOption) *autodiscoverWatcher {
+	w := &autodiscoverWatcher{
+		control:  c,
+		paths:    paths,
+		interval: DefaultAutodiscoverInterval,
+		files:    map[string]*watchedFile{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// WatchAutodiscoverPaths loads the plugins which appear in the autodiscover
+// paths after they were first scanned.  The files found when it is called are
+// taken as already handled.  With WatchSwapOption a plugin whose file is
+// replaced is swapped for the new file and with WatchUnloadOption a plugin
+// whose file is removed is unloaded.
+func (p *pluginControl) WatchAutodiscoverPaths(opts ...watcherOption) {
+	if p.autodiscoverWatcher != nil {
+		p.autodiscoverWatcher.stop()
+	}
+	w := newAutodiscoverWatcher(p, p.autodiscoverPaths, opts...)
+	for path, st := range w.list() {
+		w.files[path] = &watchedFile{seen: st, handled: st}
+	}
+	w.start()
+	p.autodiscoverWatcher = w
+	controlLogger.WithFields(log.Fields{
+		"_block":   "watch-autodiscover-paths",
+		"paths":    strings.Join(w.paths, string(filepath.ListSeparator)),
+		"interval": w.interval,
+		"swap":     w.swap,
+		"unload":   w.unload,
+	}).Info("watching autodiscover paths")
+}
+
+func (w *autodiscoverWatcher) start() {
+	w.quit = make(chan struct{})
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.scan()
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (w *autodiscoverWatcher) stop() {
+	close(w.quit)
+	w.done.Wait()
+}
+
+// list returns the state of the plugin files in the autodiscover paths by
+// absolute path.  Directories, signature files and hidden files, which are
+// often temporary files being written, are skipped.
+func (w *autodiscoverWatcher) list() map[string]fileState {
+	files := map[string]fileState{}
+	for _, p := range w.paths {
+		dir, err := filepath.Abs(p)
+		if err != nil {
+			w.logError(p, err)
+			continue
+		}
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			w.logError(dir, err)
+			continue
+		}
+		for _, fi := range infos {
+			if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || strings.HasSuffix(fi.Name(), ".asc") {
+				continue
+			}
+			st := fileState{modTime: fi.ModTime(), size: fi.Size()}
+			if sig, err := os.Stat(filepath.Join(dir, fi.Name()+".asc")); err == nil {
+				st.sigModTime = sig.ModTime()
+				st.sigSize = sig.Size()
+			}
+			files[filepath.Join(dir, fi.Name())] = st
+		}
+	}
+	return files
+}
+
+// scan acts on the files which changed since the last scan
+func (w *autodiscoverWatcher) scan() {
+	current := w.list()
+	for path, st := range current {
+		f, ok := w.files[path]
+		if !ok {
+			w.files[path] = &watchedFile{seen: st}
+			continue
+		}
+		if f.seen != st {
+			// still changing, wait for the next scan
+			f.seen = st
+			continue
+		}
+		if f.handled == st {
+			continue
+		}
+		f.handled = st
+		w.changed(path)
+	}
+	for path := range w.files {
+		if _, ok := current[path]; ok {
+			continue
+		}
+		delete(w.files, path)
+		w.removed(path)
+	}
+}
+
+// changed loads the plugin at path or, when swapping, replaces the plugin
+// loaded from it with a plugin of another version
+func (w *autodiscoverWatcher) changed(path string) {
+	rp, err := newAutodiscoveredPlugin(path)
+	if err != nil {
+		w.logError(path, err)
+		return
+	}
+	old := w.control.loadedFrom(path)
+	if old == nil {
+		pl, serr := w.control.Load(rp)
+		if serr != nil {
+			w.logError(path, serr)
+			return
+		}
+		w.logPlugin(path, pl, "loaded new plugin")
+		return
+	}
+	if !w.swap {
+		controlLogger.WithFields(log.Fields{
+			"_block":      "autodiscover-watcher",
+			"plugin-path": path,
+		}).Warn("plugin file changed but swapping is disabled")
+		return
+	}
+	serr := w.control.SwapPlugins(rp, old)
+	if serr != nil && serr.Error() == ErrPluginAlreadyLoaded.Error() {
+		// The new file has the same version so it cannot be loaded
+		// alongside the old one.  Unloading the old one first would
+		// drop it from the tasks using it and lose it if the new file
+		// failed to load, so the running plugin is kept.
+		controlLogger.WithFields(log.Fields{
+			"_block":         "autodiscover-watcher",
+			"plugin-path":    path,
+			"plugin-name":    old.Name(),
+			"plugin-version": old.Version(),
+			"plugin-type":    old.TypeName(),
+		}).Warn("plugin file replaced with the same version, keeping the loaded plugin")
+		return
+	}
+	if serr != nil {
+		w.logError(path, serr)
+		return
+	}
+	if lp := w.control.loadedFrom(path); lp != nil {
+		w.logPlugin(path, lp, "swapped replaced plugin")
+	}
+}
+
+// removed unloads the plugin loaded from path when unloading is enabled
+func (w *autodiscoverWatcher) removed(path string) {
+	if !w.unload {
+		return
+	}
+	lp := w.control.loadedFrom(path)
+	if lp == nil {
+		return
+	}
+	if _, serr := w.control.Unload(lp); serr != nil {
+		w.logError(path, serr)
+		return
+	}
+	w.logPlugin(path, lp, "unloaded removed plugin")
+}
+
+func (w *autodiscoverWatcher) logPlugin(path string, pl core.CatalogedPlugin, msg string) {
+	controlLogger.WithFields(log.Fields{
+		"_block":         "autodiscover-watcher",
+		"plugin-path":    path,
+		"plugin-name":    pl.Name(),
+		"plugin-version": pl.Version(),
+		"plugin-type":    pl.TypeName(),
+	}).Info(msg)
+}
+
+func (w *autodiscoverWatcher) logError(path string, err error) {
+	controlLogger.WithFields(log.Fields{
+		"_block":      "autodiscover-watcher",
+		"plugin-path": path,
+	}).Error(err)
+}
+
+// newAutodiscoveredPlugin returns the request to load the plugin at path with
+// the signature in path.asc when there is one
+func newAutodiscoveredPlugin(path string) (*core.RequestedPlugin, error) {
+	rp, err := core.NewRequestedPlugin(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path + ".asc"); err == nil {
+		if err := rp.ReadSignatureFile(path + ".asc"); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+// loadedFrom returns the plugin loaded from path or nil
+func (p *pluginControl) loadedFrom(path string) *loadedPlugin {
+	for _, lp := range p.pluginManager.all() {
+		if lp.Details != nil && lp.Details.Path == path {
+			return lp
+		}
+	}
+	return nil
+}
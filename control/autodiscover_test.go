@@ -0,0 +1,136 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// dropPlugin copies the plugin at src into dir as name the way configuration
+// management does: it is written to a hidden file which is renamed into place
+func dropPlugin(src, dir, name string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+name)
+	if err := ioutil.WriteFile(tmp, b, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+func eventually(f func() bool) bool {
+	for i := 0; i < 200; i++ {
+		if f() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWatchAutodiscoverPaths(t *testing.T) {
+	if SnapPath == "" {
+		return
+	}
+	Convey("Watching the autodiscover paths", t, func() {
+		// the directory of a plugin unloaded from the temporary directory
+		// is removed so the watched directory must not be in it
+		dir, err := ioutil.TempDir(SnapPath, "autodiscover")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		c := New()
+		c.Start()
+		defer c.Stop()
+		c.SetAutodiscoverPaths([]string{dir})
+		path := filepath.Join(dir, "snap-collector-mock")
+		loaded := func(version int) func() bool {
+			return func() bool {
+				lp := c.loadedFrom(path)
+				return lp != nil && lp.Version() == version
+			}
+		}
+
+		Convey("leaves the plugins already there to the initial scan", func() {
+			So(dropPlugin(JSONRPCPluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			c.WatchAutodiscoverPaths(WatchIntervalOption(20 * time.Millisecond))
+			time.Sleep(200 * time.Millisecond)
+			So(c.loadedFrom(path), ShouldBeNil)
+		})
+
+		Convey("loads new plugins", func() {
+			c.WatchAutodiscoverPaths(WatchIntervalOption(20 * time.Millisecond))
+			So(dropPlugin(JSONRPCPluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			So(eventually(loaded(1)), ShouldBeTrue)
+
+			Convey("and leaves replaced and removed plugins loaded by default", func() {
+				So(dropPlugin(PluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+				time.Sleep(200 * time.Millisecond)
+				So(loaded(1)(), ShouldBeTrue)
+				So(os.Remove(path), ShouldBeNil)
+				time.Sleep(200 * time.Millisecond)
+				_, err := c.pluginManager.get("collector:mock:1")
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("refuses unsigned plugins when plugin trust is enabled", func() {
+			c.SetPluginTrustLevel(PluginTrustEnabled)
+			c.WatchAutodiscoverPaths(WatchIntervalOption(20 * time.Millisecond))
+			So(dropPlugin(JSONRPCPluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			time.Sleep(500 * time.Millisecond)
+			So(c.loadedFrom(path), ShouldBeNil)
+		})
+
+		Convey("swaps replaced plugins and unloads removed ones", func() {
+			c.WatchAutodiscoverPaths(
+				WatchIntervalOption(20*time.Millisecond),
+				WatchSwapOption(true),
+				WatchUnloadOption(true),
+			)
+			So(dropPlugin(JSONRPCPluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			So(eventually(loaded(1)), ShouldBeTrue)
+
+			So(dropPlugin(PluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			So(eventually(loaded(2)), ShouldBeTrue)
+			_, err := c.pluginManager.get("collector:mock:1")
+			So(err, ShouldNotBeNil)
+
+			// a file with the same version leaves the loaded plugin
+			first := *c.loadedFrom(path).LoadedTimestamp()
+			time.Sleep(10 * time.Millisecond)
+			So(dropPlugin(PluginPath, dir, "snap-collector-mock"), ShouldBeNil)
+			time.Sleep(200 * time.Millisecond)
+			So(loaded(2)(), ShouldBeTrue)
+			So(c.loadedFrom(path).LoadedTimestamp().Equal(first), ShouldBeTrue)
+
+			So(os.Remove(path), ShouldBeNil)
+			So(eventually(func() bool { return c.loadedFrom(path) == nil }), ShouldBeTrue)
+			So(c.PluginCatalog(), ShouldBeEmpty)
+		})
+	})
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 )
 
 const (
@@ -56,6 +57,23 @@ var (
 	ErrBadKey       = errors.New("bad key")
 )
 
+var (
+	// the type, name and version of the plugin in the namespaces of
+	// /intel/snap/control/plugins
+	pluginLabels = []telemetry.Label{{Index: 4, Name: "type"}, {Index: 5, Name: "name"}, {Index: 6, Name: "version"}}
+
+	rpcLatency = telemetry.Default.Timer(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "rpc", "latency"},
+		Labels:      pluginLabels,
+		Description: "Time taken by calls to collect, process or publish made to the plugin",
+	})
+	rpcErrors = telemetry.Default.Counter(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "rpc", "errors"},
+		Labels:      pluginLabels,
+		Description: "Calls to collect, process or publish made to the plugin which failed",
+	})
+)
+
 // availablePlugin represents a plugin which is
 // running and available to respond to requests
 type availablePlugin struct {
@@ -75,6 +93,14 @@ type availablePlugin struct {
 	exec               string
 	execPath           string
 	fromPackage        bool
+	// socket is the Unix socket the plugin listens on, if any, which is
+	// removed once the plugin is stopped or killed
+	socket string
+	// remote plugins run as services which snapd connects to.  They are
+	// never started, stopped or killed.
+	remote bool
+	// inProcess plugins are implemented by the program running snap
+	inProcess bool
 }
 
 // newAvailablePlugin returns an availablePlugin with information from a
@@ -94,8 +120,16 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 		ePlugin:     ep,
 	}
 	ap.key = fmt.Sprintf("%s:%s:%d", ap.pluginType.String(), ap.name, ap.version)
+	if strings.HasPrefix(resp.ListenAddress, plugin.UnixAddressPrefix) {
+		ap.socket = strings.TrimPrefix(resp.ListenAddress, plugin.UnixAddressPrefix)
+	}
 
 	listenURL := fmt.Sprintf("http://%v/rpc", resp.ListenAddress)
+	if strings.Contains(resp.ListenAddress, "://") {
+		// the JSON-RPC clients take the URLs of remote plugins and dial
+		// Unix sockets given by their address
+		listenURL = resp.ListenAddress
+	}
 	// Create RPC Client
 	switch resp.Type {
 	case plugin.CollectorPluginType:
@@ -112,6 +146,12 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
+		case plugin.GRPC:
+			c, e := client.NewCollectorGRPCClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
 		}
 	case plugin.PublisherPluginType:
 		switch resp.Meta.RPCType {
@@ -127,6 +167,12 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
+		case plugin.GRPC:
+			c, e := client.NewPublisherGRPCClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
 		}
 	case plugin.ProcessorPluginType:
 		switch resp.Meta.RPCType {
@@ -142,6 +188,12 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
+		case plugin.GRPC:
+			c, e := client.NewProcessorGRPCClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
 		}
 	default:
 		return nil, errors.New("Cannot create a client for a plugin of the type: " + resp.Type.String())
@@ -202,18 +254,39 @@ func (a *availablePlugin) LastHit() time.Time {
 	return a.lastHitTime
 }
 
+// recordCall records the latency of a call made to the plugin and whether it
+// failed
+func (a *availablePlugin) recordCall(start time.Time, err error) {
+	labels := []string{a.TypeName(), a.name, strconv.Itoa(a.version)}
+	rpcLatency.Since(start, labels...)
+	var failed float64
+	if err != nil {
+		failed = 1
+	}
+	rpcErrors.Add(failed, labels...)
+}
+
 // Stop halts a running availablePlugin
 func (a *availablePlugin) Stop(r string) error {
+	if a.remote {
+		return nil
+	}
 	log.WithFields(log.Fields{
 		"_module": "control-aplugin",
 		"block":   "stop",
 		"aplugin": a,
 	}).Info("stopping available plugin")
-	return a.client.Kill(r)
+	err := a.client.Kill(r)
+	a.removeSocket()
+	return err
 }
 
 // Kill assumes aplugin is not able to here a Kill RPC call
 func (a *availablePlugin) Kill(r string) error {
+	if a.remote || a.inProcess {
+		// the pool only forgets a remote or in-process plugin
+		return nil
+	}
 	log.WithFields(log.Fields{
 		"_module": "control-aplugin",
 		"block":   "kill",
@@ -228,7 +301,17 @@ func (a *availablePlugin) Kill(r string) error {
 		}).Debug("deleting available plugin path")
 		os.RemoveAll(filepath.Dir(a.execPath))
 	}
-	return a.ePlugin.Kill()
+	err := a.ePlugin.Kill()
+	a.removeSocket()
+	return err
+}
+
+// removeSocket removes the Unix socket of a plugin which is no longer running.
+// A plugin which is killed cannot remove it itself.
+func (a *availablePlugin) removeSocket() {
+	if a.socket != "" {
+		os.Remove(a.socket)
+	}
 }
 
 // CheckHealth checks the health of a plugin and updates
@@ -266,7 +349,13 @@ func (a *availablePlugin) healthCheckFailed() {
 		"aplugin": a,
 	}).Warning("heartbeat missed")
 	a.failedHealthChecks++
-	if a.failedHealthChecks >= DefaultHealthCheckFailureLimit {
+	dead := a.failedHealthChecks >= DefaultHealthCheckFailureLimit
+	if a.remote {
+		// a remote plugin is not killed so it is reported dead once
+		// until it recovers
+		dead = a.failedHealthChecks == DefaultHealthCheckFailureLimit
+	}
+	if dead {
 		log.WithFields(log.Fields{
 			"_module": "control-aplugin",
 			"block":   "check-health",
@@ -359,7 +448,7 @@ func (ap *availablePlugins) getPool(key string) (strategy.Pool, serror.SnapError
 	return pool, nil
 }
 
-func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.Metric, deadline time.Time, taskID string) ([]core.Metric, error) {
 	var results []core.Metric
 	pool, serr := ap.getPool(pluginKey)
 	if serr != nil {
@@ -381,6 +470,7 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 	if serr != nil {
 		return nil, serr
 	}
+	defer pool.Done(p)
 
 	// cast client to PluginCollectorClient
 	cli, ok := p.(*availablePlugin).client.(client.PluginCollectorClient)
@@ -389,12 +479,14 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 	}
 
 	// collect metrics
-	metrics, err := cli.CollectMetrics(metricsToCollect)
+	start := time.Now()
+	metrics, err := cli.CollectMetrics(metricsToCollect, deadline)
+	p.(*availablePlugin).recordCall(start, err)
 	if err != nil {
 		return nil, serror.New(err)
 	}
 
-	pool.UpdateCache(metrics, taskID)
+	pool.UpdateCache(metricsToCollect, metrics, taskID)
 
 	results = make([]core.Metric, len(metricsFromCache)+len(metrics))
 	idx := 0
@@ -411,10 +503,10 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 	p.(*availablePlugin).hitCount++
 	p.(*availablePlugin).lastHitTime = time.Now()
 
-	return metrics, nil
+	return results, nil
 }
 
-func (ap *availablePlugins) publishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+func (ap *availablePlugins) publishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error {
 	var errs []error
 	key := strings.Join([]string{plugin.PublisherPluginType.String(), pluginName, strconv.Itoa(pluginVersion)}, ":")
 	pool, serr := ap.getPool(key)
@@ -433,13 +525,16 @@ func (ap *availablePlugins) publishMetrics(contentType string, content []byte, p
 		errs = append(errs, err)
 		return errs
 	}
+	defer pool.Done(p)
 
 	cli, ok := p.(*availablePlugin).client.(client.PluginPublisherClient)
 	if !ok {
 		return []error{errors.New("unable to cast client to PluginPublisherClient")}
 	}
 
-	errp := cli.Publish(contentType, content, config)
+	start := time.Now()
+	errp := cli.Publish(contentType, content, config, deadline)
+	p.(*availablePlugin).recordCall(start, errp)
 	if errp != nil {
 		return []error{errp}
 	}
@@ -448,7 +543,7 @@ func (ap *availablePlugins) publishMetrics(contentType string, content []byte, p
 	return nil
 }
 
-func (ap *availablePlugins) processMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+func (ap *availablePlugins) processMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error) {
 	var errs []error
 	key := strings.Join([]string{plugin.ProcessorPluginType.String(), pluginName, strconv.Itoa(pluginVersion)}, ":")
 	pool, serr := ap.getPool(key)
@@ -467,13 +562,16 @@ func (ap *availablePlugins) processMetrics(contentType string, content []byte, p
 		errs = append(errs, err)
 		return "", nil, errs
 	}
+	defer pool.Done(p)
 
 	cli, ok := p.(*availablePlugin).client.(client.PluginProcessorClient)
 	if !ok {
 		return "", nil, []error{errors.New("unable to cast client to PluginProcessorClient")}
 	}
 
-	ct, c, errp := cli.Process(contentType, content, config)
+	start := time.Now()
+	ct, c, errp := cli.Process(contentType, content, config, deadline)
+	p.(*availablePlugin).recordCall(start, errp)
 	if errp != nil {
 		return "", nil, []error{errp}
 	}
@@ -519,6 +617,15 @@ func (ap *availablePlugins) getOrCreatePool(key string) (strategy.Pool, error) {
 	return pool, nil
 }
 
+// reportPoolSizes emits the number of running instances in each pool
+func (ap *availablePlugins) reportPoolSizes(emit func(float64, ...string)) {
+	ap.RLock()
+	defer ap.RUnlock()
+	for key, pool := range ap.table {
+		emit(float64(pool.Count()), strings.Split(key, ":")...)
+	}
+}
+
 func (ap *availablePlugins) pools() map[string]strategy.Pool {
 	ap.RLock()
 	defer ap.RUnlock()
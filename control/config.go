@@ -52,14 +52,18 @@ type pluginConfigItem struct {
 }
 
 type config struct {
-	Plugins *pluginConfig `json:"plugins"`
+	Plugins         *pluginConfig    `json:"plugins"`
+	RestartPolicies *restartPolicies `json:"restart_policies"`
+	ResourceLimits  *resourceLimits  `json:"resource_limits"`
 }
 
 // NewConfig returns a reference to a global config type for the snap daemon
 // by using a newly created empty plugin config.
 func NewConfig() *config {
 	return &config{
-		Plugins: newPluginConfig(),
+		Plugins:         newPluginConfig(),
+		RestartPolicies: newRestartPolicies(),
+		ResourceLimits:  newResourceLimits(),
 	}
 }
 
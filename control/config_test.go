@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -75,6 +76,9 @@ func TestPluginConfig(t *testing.T) {
 			So(cfg.Plugins.All, ShouldNotBeNil)
 			So(cfg.Plugins.Collector.Plugins["pcm"].Versions[1].Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "john"})
 			So(cfg.Plugins.Processor.Plugins["movingaverage"].Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "jane"})
+			So(cfg.RestartPolicies.get(core.CollectorPluginType, "psutil").policy, ShouldEqual, RestartAlways)
+			So(cfg.RestartPolicies.get(core.CollectorPluginType, "pcm").policy, ShouldEqual, RestartOnFailure)
+			So(cfg.ResourceLimits.get(&loadedPlugin{Meta: plugin.PluginMeta{Name: "psutil"}}), ShouldResemble, &plugin.ResourceLimits{Memory: 268435456, CPUShares: 512, OpenFiles: 1024})
 
 			Convey("We can access the config for plugins", func() {
 				Convey("Getting the values of a specific version of a plugin", func() {
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,6 +43,7 @@ import (
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/pkg/aci"
 	"github.com/intelsdi-x/snap/pkg/psigning"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 )
 
 const (
@@ -72,16 +74,24 @@ type pluginControl struct {
 	Started        bool
 	Config         *config
 
-	autodiscoverPaths []string
-	eventManager      *gomit.EventController
+	autodiscoverPaths   []string
+	autodiscoverWatcher *autodiscoverWatcher
+	eventManager        *gomit.EventController
 
 	pluginManager  managesPlugins
 	metricCatalog  catalogsMetrics
 	pluginRunner   runsPlugins
 	signingManager managesSigning
 
-	pluginTrust  int
-	keyringFiles []string
+	pluginTrust        int
+	keyringFiles       []string
+	socketDir          string
+	pluginLogDir       string
+	allowRemotePlugins bool
+
+	// selfPlugin collects the metrics snapd keeps about itself when the
+	// self collector is enabled
+	selfPlugin *loadedPlugin
 }
 
 type runsPlugins interface {
@@ -93,7 +103,11 @@ type runsPlugins interface {
 	SetMetricCatalog(catalogsMetrics)
 	SetPluginManager(managesPlugins)
 	Monitor() *monitor
-	runPlugin(*pluginDetails) error
+	Logs() *pluginLogs
+	SetRestartPolicies(*restartPolicies)
+	SetResourceLimits(*resourceLimits)
+	SetPluginVerifier(func(*loadedPlugin) error)
+	runPlugin(*loadedPlugin) error
 }
 
 type managesPlugins interface {
@@ -101,8 +115,12 @@ type managesPlugins interface {
 	get(string) (*loadedPlugin, error)
 	all() map[string]*loadedPlugin
 	LoadPlugin(*pluginDetails, gomit.Emitter) (*loadedPlugin, serror.SnapError)
+	LoadRemotePlugin(*core.RemotePlugin, gomit.Emitter) (*loadedPlugin, *availablePlugin, serror.SnapError)
+	LoadInProcessPlugin(*plugin.PluginMeta, plugin.Plugin, gomit.Emitter) (*loadedPlugin, serror.SnapError)
 	UnloadPlugin(core.Plugin) (*loadedPlugin, serror.SnapError)
 	SetMetricCatalog(catalogsMetrics)
+	SetSocketDir(string)
+	SetResourceLimits(*resourceLimits)
 	GenerateArgs(pluginPath string) plugin.Arg
 	SetPluginConfig(*pluginConfig)
 }
@@ -142,11 +160,49 @@ func CacheExpiration(t time.Duration) PluginControlOpt {
 	}
 }
 
+// CacheSize is the PluginControlOpt which sets the maximum number of entries
+// in the metric cache of a plugin.  0 leaves the caches unbounded.
+func CacheSize(n int) PluginControlOpt {
+	return func(c *pluginControl) {
+		strategy.GlobalCacheSize = n
+	}
+}
+
+// UnixSockets has plugins listen on Unix sockets in dir instead of TCP ports.
+// Control creates dir when it starts, if needed, and lets only the user
+// running snapd use it.
+func UnixSockets(dir string) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.socketDir = dir
+	}
+}
+
+// PluginLogPath has the output of plugins written to files in dir.  Control
+// creates dir when it starts, if needed, and lets only the user running snapd
+// use it.  Without it the files are written to a private temporary directory.
+func PluginLogPath(dir string) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.pluginLogDir = dir
+	}
+}
+
+// AllowRemotePlugins lets plugins running as a service be loaded while plugin
+// trust is enabled.  Their signature cannot be checked since snapd never has
+// their file.
+func AllowRemotePlugins() PluginControlOpt {
+	return func(c *pluginControl) {
+		c.allowRemotePlugins = true
+	}
+}
+
 // OptSetConfig sets the plugin control configuration.
 func OptSetConfig(cfg *config) PluginControlOpt {
 	return func(c *pluginControl) {
 		c.Config = cfg
 		c.pluginManager.SetPluginConfig(cfg.Plugins)
+		c.pluginRunner.SetRestartPolicies(cfg.RestartPolicies)
+		c.pluginRunner.SetResourceLimits(cfg.ResourceLimits)
+		c.pluginManager.SetResourceLimits(cfg.ResourceLimits)
 	}
 }
 
@@ -193,6 +249,9 @@ func New(opts ...PluginControlOpt) *pluginControl {
 	c.pluginRunner.SetEmitter(c.eventManager)
 	c.pluginRunner.SetMetricCatalog(c.metricCatalog)
 	c.pluginRunner.SetPluginManager(c.pluginManager)
+	c.pluginRunner.SetRestartPolicies(c.Config.RestartPolicies)
+	c.pluginRunner.SetResourceLimits(c.Config.ResourceLimits)
+	c.pluginRunner.SetPluginVerifier(c.verifyPlugin)
 
 	// Start stuff
 	err := c.pluginRunner.Start()
@@ -200,6 +259,14 @@ func New(opts ...PluginControlOpt) *pluginControl {
 		panic(err)
 	}
 
+	// Report the size of the plugin pools
+	telemetry.Default.Func(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "pool", "size"},
+		Labels:      pluginLabels,
+		Kind:        telemetry.GaugeKind,
+		Description: "Running instances of the plugin",
+	}, c.pluginRunner.AvailablePlugins().reportPoolSizes)
+
 	// apply options
 
 	// it is important that this happens last, as an option may
@@ -222,19 +289,73 @@ func (p *pluginControl) RegisterEventHandler(name string, h gomit.Handler) error
 // Begin handling load, unload, and inventory
 func (p *pluginControl) Start() error {
 	// Start pluginManager when pluginControl starts
+	if p.socketDir != "" {
+		if err := privateDir(p.socketDir); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "start",
+				"path":   p.socketDir,
+				"error":  err,
+			}).Error("cannot create the plugin socket directory")
+			return err
+		}
+		removeSockets(p.socketDir)
+		p.pluginManager.SetSocketDir(p.socketDir)
+	}
+	if p.pluginLogDir != "" {
+		if err := privateDir(p.pluginLogDir); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "start",
+				"path":   p.pluginLogDir,
+				"error":  err,
+			}).Error("cannot create the plugin log directory")
+			return err
+		}
+		p.pluginRunner.Logs().setDir(p.pluginLogDir)
+	}
 	p.Started = true
+	if p.selfPlugin != nil {
+		p.addSelfMetrics()
+	}
 	controlLogger.WithFields(log.Fields{
 		"_block": "start",
 	}).Info("control started")
 	return nil
 }
 
+// privateDir creates dir if needed and lets only its owner use it
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.Chmod(dir, 0700)
+}
+
+// removeSockets removes the Unix sockets left in dir by the plugins of a
+// previous run of snapd
+func removeSockets(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if f.Mode()&os.ModeSocket != 0 && strings.HasSuffix(f.Name(), ".sock") {
+			os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+}
+
 func (p *pluginControl) Stop() {
 	p.Started = false
 	controlLogger.WithFields(log.Fields{
 		"_block": "stop",
 	}).Info("control stopped")
 
+	// stop watching the autodiscover paths
+	if p.autodiscoverWatcher != nil {
+		p.autodiscoverWatcher.stop()
+		p.autodiscoverWatcher = nil
+	}
+
 	// stop runner
 	err := p.pluginRunner.Stop()
 	if err != nil {
@@ -370,6 +491,8 @@ func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.Sna
 		return nil, err
 	}
 
+	p.pluginRunner.Logs().remove(up.Key())
+
 	event := &control_event.UnloadPluginEvent{
 		Name:    up.Meta.Name,
 		Version: up.Meta.Version,
@@ -559,7 +682,10 @@ func (p *pluginControl) validateMetricTypeSubscription(mt core.RequestedMetric,
 	return m, serrs
 }
 
-func (p *pluginControl) gatherCollectors(mts []core.Metric) ([]core.Plugin, []serror.SnapError) {
+// GatherCollectors returns the collector plugins a task collecting mts is
+// subscribed to.  A metric requested at version -1 gives its collector at
+// version -1.
+func (p *pluginControl) GatherCollectors(mts []core.Metric) ([]core.Plugin, []serror.SnapError) {
 	var (
 		plugins []core.Plugin
 		serrs   []serror.SnapError
@@ -580,6 +706,10 @@ func (p *pluginControl) gatherCollectors(mts []core.Metric) ([]core.Plugin, []se
 			}))
 			continue
 		}
+		// the self collector runs no plugin to subscribe to
+		if p.isSelfPlugin(m.Plugin) {
+			continue
+		}
 		// if the metric subscription is to version -1, we need to carry
 		// that forward in the subscription.
 		if mt.Version() < 1 {
@@ -605,7 +735,7 @@ func (p *pluginControl) gatherCollectors(mts []core.Metric) ([]core.Plugin, []se
 func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins []core.Plugin) []serror.SnapError {
 	var serrs []serror.SnapError
 
-	collectors, errs := p.gatherCollectors(mts)
+	collectors, errs := p.GatherCollectors(mts)
 	if len(errs) > 0 {
 		serrs = append(serrs)
 	}
@@ -634,7 +764,7 @@ func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins
 					serrs = append(serrs, serror.New(err))
 					return serrs
 				}
-				err = p.pluginRunner.runPlugin(latest.Details)
+				err = p.pluginRunner.runPlugin(latest)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
 					return serrs
@@ -658,7 +788,7 @@ func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins
 					serrs = append(serrs, serror.New(err))
 					return serrs
 				}
-				err = p.pluginRunner.runPlugin(pl.Details)
+				err = p.pluginRunner.runPlugin(pl)
 				if err != nil {
 					serrs = append(serrs, serror.New(err))
 					return serrs
@@ -675,6 +805,10 @@ func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins
 }
 
 func (p *pluginControl) verifyPlugin(lp *loadedPlugin) error {
+	if lp.isInProcess() {
+		// there is no file to verify
+		return nil
+	}
 	b, err := ioutil.ReadFile(lp.Details.Path)
 	if err != nil {
 		return err
@@ -713,7 +847,7 @@ func (p *pluginControl) sendPluginSubscriptionEvent(taskID string, pl core.Plugi
 func (p *pluginControl) UnsubscribeDeps(taskID string, mts []core.Metric, plugins []core.Plugin) []serror.SnapError {
 	var serrs []serror.SnapError
 
-	collectors, errs := p.gatherCollectors(mts)
+	collectors, errs := p.GatherCollectors(mts)
 	if len(errs) > 0 {
 		serrs = append(serrs, errs...)
 	}
@@ -782,6 +916,20 @@ func (p *pluginControl) AvailablePlugins() []core.AvailablePlugin {
 	return caps
 }
 
+// PluginCacheStats returns the statistics of the metric cache of a loaded
+// collector.  They are empty until an instance of the plugin has been started.
+func (p *pluginControl) PluginCacheStats(pluginType, name string, version int) (core.CacheStats, error) {
+	key := fmt.Sprintf("%s:%s:%d", pluginType, name, version)
+	if _, err := p.pluginManager.get(key); err != nil {
+		return core.CacheStats{}, ErrLoadedPluginNotFound
+	}
+	pool, err := p.pluginRunner.AvailablePlugins().getPool(key)
+	if err != nil || pool == nil || pool.Strategy() == nil {
+		return core.CacheStats{}, nil
+	}
+	return pool.CacheStats(), nil
+}
+
 // MetricCatalog returns the entire metric catalog
 // NOTE: The returned data from this function should be considered constant and read only
 func (p *pluginControl) MetricCatalog() ([]core.CatalogedMetric, error) {
@@ -835,7 +983,8 @@ func (p *pluginControl) MetricExists(mns []string, ver int) bool {
 
 // CollectMetrics is a blocking call to collector plugins returning a collection
 // of metrics and errors.  If an error is encountered no metrics will be
-// returned.
+// returned.  The calls to the plugins are given up on once the deadline
+// passes.
 func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.Time, taskID string) (metrics []core.Metric, errs []error) {
 	pluginToMetricMap, err := groupMetricTypesByPlugin(p.metricCatalog, metricTypes)
 	if err != nil {
@@ -858,8 +1007,15 @@ func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.
 
 		wg.Add(1)
 
+		if p.isSelfPlugin(pmt.plugin) {
+			go func(mt []core.Metric) {
+				cMetrics <- p.collectSelfMetrics(mt)
+			}(pmt.metricTypes)
+			continue
+		}
+
 		go func(pluginKey string, mt []core.Metric) {
-			mts, err := p.pluginRunner.AvailablePlugins().collectMetrics(pluginKey, mt, taskID)
+			mts, err := p.pluginRunner.AvailablePlugins().collectMetrics(pluginKey, mt, deadline, taskID)
 			if err != nil {
 				cError <- err
 			} else {
@@ -892,24 +1048,26 @@ func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.
 	return
 }
 
-// PublishMetrics
-func (p *pluginControl) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+// PublishMetrics is a blocking call to a publisher plugin.  The call to the
+// plugin is given up on once the deadline passes.
+func (p *pluginControl) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error {
 	// merge global plugin config into the config for this request
 	cfg := p.Config.Plugins.getPluginConfigDataNode(core.PublisherPluginType, pluginName, pluginVersion).Table()
 	for k, v := range cfg {
 		config[k] = v
 	}
-	return p.pluginRunner.AvailablePlugins().publishMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
+	return p.pluginRunner.AvailablePlugins().publishMetrics(contentType, content, pluginName, pluginVersion, config, deadline, taskID)
 }
 
-// ProcessMetrics
-func (p *pluginControl) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+// ProcessMetrics is a blocking call to a processor plugin.  The call to the
+// plugin is given up on once the deadline passes.
+func (p *pluginControl) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error) {
 	// merge global plugin config into the config for this request
 	cfg := p.Config.Plugins.getPluginConfigDataNode(core.ProcessorPluginType, pluginName, pluginVersion).Table()
 	for k, v := range cfg {
 		config[k] = v
 	}
-	return p.pluginRunner.AvailablePlugins().processMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
+	return p.pluginRunner.AvailablePlugins().processMetrics(contentType, content, pluginName, pluginVersion, config, deadline, taskID)
 }
 
 // GetPluginContentTypes returns accepted and returned content types for the
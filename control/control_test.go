@@ -25,8 +25,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -64,6 +68,12 @@ type MockPluginManagerBadSwap struct {
 func (m *MockPluginManagerBadSwap) LoadPlugin(*pluginDetails, gomit.Emitter) (*loadedPlugin, serror.SnapError) {
 	return new(loadedPlugin), nil
 }
+func (m *MockPluginManagerBadSwap) LoadRemotePlugin(*core.RemotePlugin, gomit.Emitter) (*loadedPlugin, *availablePlugin, serror.SnapError) {
+	return nil, nil, serror.New(errors.New("fake"))
+}
+func (m *MockPluginManagerBadSwap) LoadInProcessPlugin(*plugin.PluginMeta, plugin.Plugin, gomit.Emitter) (*loadedPlugin, serror.SnapError) {
+	return nil, serror.New(errors.New("fake"))
+}
 func (m *MockPluginManagerBadSwap) UnloadPlugin(c core.Plugin) (*loadedPlugin, serror.SnapError) {
 	return nil, serror.New(errors.New("fake"))
 }
@@ -71,6 +81,8 @@ func (m *MockPluginManagerBadSwap) get(string) (*loadedPlugin, error) { return n
 func (m *MockPluginManagerBadSwap) teardown()                         {}
 func (m *MockPluginManagerBadSwap) SetPluginConfig(*pluginConfig)     {}
 func (m *MockPluginManagerBadSwap) SetMetricCatalog(catalogsMetrics)  {}
+func (m *MockPluginManagerBadSwap) SetSocketDir(string)               {}
+func (m *MockPluginManagerBadSwap) SetResourceLimits(*resourceLimits) {}
 func (m *MockPluginManagerBadSwap) SetEmitter(gomit.Emitter)          {}
 func (m *MockPluginManagerBadSwap) GenerateArgs(string) plugin.Arg    { return plugin.Arg{} }
 
@@ -118,6 +130,63 @@ func TestPluginControlGenerateArgs(t *testing.T) {
 	})
 }
 
+func TestUnixSockets(t *testing.T) {
+	if SnapPath == "" {
+		return
+	}
+	Convey("plugins listen on Unix sockets", t, func() {
+		tmp, err := ioutil.TempDir("", "snap-sockets")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmp)
+		dir := filepath.Join(tmp, "plugins")
+		// a socket left behind by a previous run
+		So(os.Mkdir(dir, 0755), ShouldBeNil)
+		stale := filepath.Join(dir, "snap-collector-mock1-1.sock")
+		l, err := net.Listen("unix", stale)
+		So(err, ShouldBeNil)
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		c := New(UnixSockets(dir))
+		So(c.Start(), ShouldBeNil)
+		defer c.Stop()
+		fi, err := os.Stat(dir)
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0700))
+		_, err = os.Stat(stale)
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		for _, p := range []struct {
+			path, key string
+		}{
+			{PluginPath, "collector:mock:2"},
+			{JSONRPCPluginPath, "collector:mock:1"},
+		} {
+			_, serr := load(c, p.path)
+			So(serr, ShouldBeNil)
+			lp, err := c.pluginManager.get(p.key)
+			So(err, ShouldBeNil)
+			So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+			// the plugin instance just started listens on the last socket
+			n := c.pluginManager.(*pluginManager).sockets
+			sock := filepath.Join(dir, fmt.Sprintf("%s-%d.sock", filepath.Base(p.path), n))
+			conn, err := net.Dial("unix", sock)
+			So(err, ShouldBeNil)
+			conn.Close()
+		}
+
+		Convey("which are removed when the plugins are killed", func() {
+			aps := c.pluginRunner.AvailablePlugins().all()
+			So(aps, ShouldHaveLength, 2)
+			for _, ap := range aps {
+				So(ap.(*availablePlugin).socket, ShouldStartWith, dir)
+				ap.Kill("test")
+				_, err := os.Stat(ap.(*availablePlugin).socket)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			}
+		})
+	})
+}
+
 func TestSwapPlugin(t *testing.T) {
 	if SnapPath != "" {
 		c := New()
@@ -860,7 +929,7 @@ func TestRoutingCachingStrategy(t *testing.T) {
 			}
 			for _, id := range tasks {
 				pool.Subscribe(id, strategy.BoundSubscriptionType)
-				err = c.pluginRunner.runPlugin(lp.Details)
+				err = c.pluginRunner.runPlugin(lp)
 				So(err, ShouldBeNil)
 			}
 			// The cache ttl should be 100ms which is what the plugin exposed (no system default was provided)
@@ -922,7 +991,7 @@ func TestRoutingCachingStrategy(t *testing.T) {
 			}
 			for _, id := range tasks {
 				pool.Subscribe(id, strategy.BoundSubscriptionType)
-				err = c.pluginRunner.runPlugin(lp.Details)
+				err = c.pluginRunner.runPlugin(lp)
 				So(err, ShouldBeNil)
 			}
 			// The cache ttl should be 100ms which is what the plugin exposed (no system default was provided)
@@ -949,6 +1018,54 @@ func TestRoutingCachingStrategy(t *testing.T) {
 			})
 		})
 	})
+
+	for _, rs := range []plugin.RoutingStrategyType{plugin.RoundRobinRouting, plugin.LeastOutstandingRouting} {
+		Convey("Given registered plugins that use "+rs.String()+" routing", t, func() {
+			c := New()
+			c.Start()
+			defer c.Stop()
+			meta := plugin.NewPluginMeta("embedded", 1, plugin.CollectorPluginType, nil, nil,
+				plugin.RoutingStrategy(rs),
+				plugin.CacheTTL(time.Second),
+			)
+			_, e := c.RegisterPlugin(meta, &inProcessCollector{})
+			So(e, ShouldBeNil)
+			metric, err := c.metricCatalog.Get([]string{"intel", "embedded", "foo"}, 1)
+			So(err, ShouldBeNil)
+			metric.config = cdata.NewNode()
+			Convey("Start the plugins", func() {
+				lp, err := c.pluginManager.get("collector:embedded:1")
+				So(err, ShouldBeNil)
+				pool, errp := c.pluginRunner.AvailablePlugins().getOrCreatePool("collector:embedded:1")
+				So(errp, ShouldBeNil)
+				tasks := []string{
+					uuid.New(),
+					uuid.New(),
+					uuid.New(),
+				}
+				for _, id := range tasks {
+					pool.Subscribe(id, strategy.BoundSubscriptionType)
+					So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+				}
+				ttl, err := pool.CacheTTL(tasks[0])
+				So(err, ShouldBeNil)
+				So(ttl, ShouldResemble, time.Second)
+				So(pool.Strategy().String(), ShouldEqual, rs.String())
+				So(pool.Count(), ShouldEqual, len(tasks))
+				Convey("Collect metrics", func() {
+					taskID := tasks[rand.Intn(len(tasks))]
+					for i := 0; i < 10; i++ {
+						_, errs := c.CollectMetrics([]core.Metric{metric}, time.Now().Add(time.Second*1), taskID)
+						So(errs, ShouldBeEmpty)
+					}
+					Convey("Check cache stats", func() {
+						So(pool.AllCacheHits(), ShouldEqual, 9)
+						So(pool.AllCacheMisses(), ShouldEqual, 1)
+					})
+				})
+			})
+		})
+	}
 }
 
 func TestCollectDynamicMetrics(t *testing.T) {
@@ -1007,7 +1124,7 @@ func TestCollectDynamicMetrics(t *testing.T) {
 			So(pool.Count(), ShouldEqual, 0)
 			So(pool.SubscriptionCount(), ShouldEqual, 0)
 			pool.Subscribe(taskID, strategy.UnboundSubscriptionType)
-			err = c.pluginRunner.runPlugin(lp.Details)
+			err = c.pluginRunner.runPlugin(lp)
 			So(pool.Count(), ShouldEqual, 1)
 			So(pool.SubscriptionCount(), ShouldEqual, 1)
 			So(err, ShouldBeNil)
@@ -1045,7 +1162,7 @@ func TestCollectDynamicMetrics(t *testing.T) {
 				So(pool.Count(), ShouldEqual, 0)
 				So(pool.SubscriptionCount(), ShouldEqual, 0)
 				pool.Subscribe("1", strategy.UnboundSubscriptionType)
-				err = c.pluginRunner.runPlugin(lp.Details)
+				err = c.pluginRunner.runPlugin(lp)
 				So(pool.Count(), ShouldEqual, 1)
 				So(pool.SubscriptionCount(), ShouldEqual, 1)
 				So(err, ShouldBeNil)
@@ -1130,10 +1247,10 @@ func TestCollectMetrics(t *testing.T) {
 			pool, errp := c.pluginRunner.AvailablePlugins().getOrCreatePool("collector:mock:1")
 			So(errp, ShouldBeNil)
 			pool.Subscribe("1", strategy.UnboundSubscriptionType)
-			err = c.pluginRunner.runPlugin(lp.Details)
+			err = c.pluginRunner.runPlugin(lp)
 			So(err, ShouldBeNil)
 			pool.Subscribe("2", strategy.UnboundSubscriptionType)
-			err = c.pluginRunner.runPlugin(lp.Details)
+			err = c.pluginRunner.runPlugin(lp)
 			So(err, ShouldBeNil)
 			m = append(m, m1, m2, m3)
 			Convey("collect metrics", func() {
@@ -1232,7 +1349,7 @@ func TestPublishMetrics(t *testing.T) {
 			pool, errp := c.pluginRunner.AvailablePlugins().getOrCreatePool("publisher:file:3")
 			So(errp, ShouldBeNil)
 			pool.Subscribe("1", strategy.UnboundSubscriptionType)
-			err := c.pluginRunner.runPlugin(lp.Details)
+			err := c.pluginRunner.runPlugin(lp)
 			So(err, ShouldBeNil)
 			time.Sleep(2500 * time.Millisecond)
 
@@ -1244,7 +1361,7 @@ func TestPublishMetrics(t *testing.T) {
 				enc := gob.NewEncoder(&buf)
 				enc.Encode(metrics)
 				contentType := plugin.SnapGOBContentType
-				errs := c.PublishMetrics(contentType, buf.Bytes(), "file", 3, n.Table(), uuid.New())
+				errs := c.PublishMetrics(contentType, buf.Bytes(), "file", 3, n.Table(), time.Now().Add(time.Second*1), uuid.New())
 				So(errs, ShouldBeNil)
 				ap := c.AvailablePlugins()
 				So(ap, ShouldNotBeEmpty)
@@ -1285,7 +1402,7 @@ func TestProcessMetrics(t *testing.T) {
 			pool, errp := c.pluginRunner.AvailablePlugins().getOrCreatePool("processor:passthru:1")
 			So(errp, ShouldBeNil)
 			pool.Subscribe("1", strategy.UnboundSubscriptionType)
-			err := c.pluginRunner.runPlugin(lp.Details)
+			err := c.pluginRunner.runPlugin(lp)
 			So(err, ShouldBeNil)
 			time.Sleep(2500 * time.Millisecond)
 
@@ -1297,7 +1414,7 @@ func TestProcessMetrics(t *testing.T) {
 				enc := gob.NewEncoder(&buf)
 				enc.Encode(metrics)
 				contentType := plugin.SnapGOBContentType
-				_, ct, errs := c.ProcessMetrics(contentType, buf.Bytes(), "passthru", 1, n.Table(), uuid.New())
+				_, ct, errs := c.ProcessMetrics(contentType, buf.Bytes(), "passthru", 1, n.Table(), time.Now().Add(time.Second*1), uuid.New())
 				So(errs, ShouldBeEmpty)
 				mts := []plugin.PluginMetricType{}
 				dec := gob.NewDecoder(bytes.NewBuffer(ct))
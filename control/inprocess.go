@@ -0,0 +1,194 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrBadInProcessPlugin - error message when an in-process plugin does not implement the interface of its type
+	ErrBadInProcessPlugin = errors.New("in-process plugin must implement the interface of its type")
+	// ErrBadInProcessPluginMeta - error message when an in-process plugin is missing its name or version
+	ErrBadInProcessPluginMeta = errors.New("in-process plugin must have a name and a version greater than 0")
+)
+
+// RegisterPlugin loads a collector, processor or publisher implemented by the
+// program running snap.  Its calls are made in-process instead of over RPC
+// but it is cataloged, pooled and routed to like an executable plugin
+// following its meta.
+func (p *pluginControl) RegisterPlugin(meta *plugin.PluginMeta, impl plugin.Plugin) (core.CatalogedPlugin, serror.SnapError) {
+	f := map[string]interface{}{
+		"_block":         "register-plugin",
+		"plugin-name":    meta.Name,
+		"plugin-version": meta.Version,
+	}
+	controlLogger.WithFields(f).Info("plugin register called")
+	if !p.Started {
+		se := serror.New(ErrControllerNotStarted)
+		se.SetFields(f)
+		controlLogger.WithFields(f).Error(se)
+		return nil, se
+	}
+
+	pl, se := p.pluginManager.LoadInProcessPlugin(meta, impl, p.eventManager)
+	if se != nil {
+		return nil, se
+	}
+
+	event := &control_event.LoadPluginEvent{
+		Name:    pl.Meta.Name,
+		Version: pl.Meta.Version,
+		Type:    int(pl.Meta.Type),
+	}
+	defer p.eventManager.Emit(event)
+	return pl, nil
+}
+
+// LoadInProcessPlugin loads a plugin implemented by the program running snap
+func (p *pluginManager) LoadInProcessPlugin(meta *plugin.PluginMeta, impl plugin.Plugin, emitter gomit.Emitter) (*loadedPlugin, serror.SnapError) {
+	f := log.Fields{
+		"_block":         "load-in-process-plugin",
+		"plugin-name":    meta.Name,
+		"plugin-version": meta.Version,
+	}
+	pmLogger.WithFields(f).Info("in-process plugin load called")
+	if meta.Name == "" || meta.Version < 1 {
+		return nil, serror.New(ErrBadInProcessPluginMeta, f)
+	}
+	lPlugin := &loadedPlugin{
+		Meta:     *meta,
+		Type:     meta.Type,
+		Details:  &pluginDetails{InProcess: impl},
+		State:    DetectedState,
+		restarts: &pluginRestarts{},
+	}
+	// check before adding the metrics of the plugin to the catalog
+	if _, err := p.loadedPlugins.get(lPlugin.Key()); err == nil {
+		return nil, serror.New(ErrPluginAlreadyLoaded, map[string]interface{}{
+			"plugin-name":    lPlugin.Meta.Name,
+			"plugin-version": lPlugin.Meta.Version,
+			"plugin-type":    lPlugin.Type.String(),
+		})
+	}
+
+	ap, err := newInProcessAvailablePlugin(lPlugin, emitter)
+	if err != nil {
+		pmLogger.WithFields(f).WithField("error", err).Error("load in-process plugin error while creating available plugin")
+		return nil, serror.New(err, f)
+	}
+	resp := &plugin.Response{
+		Type:  lPlugin.Type,
+		State: plugin.PluginSuccess,
+		Meta:  lPlugin.Meta,
+	}
+	if serr := p.catalogPlugin(lPlugin, ap, resp); serr != nil {
+		return nil, serr
+	}
+
+	lPlugin.LoadedTime = time.Now()
+	lPlugin.State = LoadedState
+	if serr := p.loadedPlugins.add(lPlugin); serr != nil {
+		pmLogger.WithFields(f).WithField("error", serr).Error("load in-process plugin error while adding loaded plugin to load plugins collection")
+		return nil, serr
+	}
+	return lPlugin, nil
+}
+
+// isInProcess returns whether the plugin is implemented by the program
+// running snap
+func (lp *loadedPlugin) isInProcess() bool {
+	return lp.Details != nil && lp.Details.InProcess != nil
+}
+
+// newInProcessAvailablePlugin returns an instance of a plugin implemented by
+// the program running snap
+func newInProcessAvailablePlugin(lp *loadedPlugin, emitter gomit.Emitter) (*availablePlugin, error) {
+	ap := &availablePlugin{
+		meta:        lp.Meta,
+		name:        lp.Meta.Name,
+		version:     lp.Meta.Version,
+		pluginType:  lp.Type,
+		emitter:     emitter,
+		healthChan:  make(chan error, 1),
+		lastHitTime: time.Now(),
+		inProcess:   true,
+	}
+	ap.key = fmt.Sprintf("%s:%s:%d", ap.pluginType.String(), ap.name, ap.version)
+
+	impl := lp.Details.InProcess
+	switch lp.Type {
+	case plugin.CollectorPluginType:
+		c, ok := impl.(plugin.CollectorPlugin)
+		if !ok {
+			return nil, ErrBadInProcessPlugin
+		}
+		ap.client = client.NewCollectorInProcessClient(c)
+	case plugin.ProcessorPluginType:
+		c, ok := impl.(plugin.ProcessorPlugin)
+		if !ok {
+			return nil, ErrBadInProcessPlugin
+		}
+		ap.client = client.NewProcessorInProcessClient(c)
+	case plugin.PublisherPluginType:
+		c, ok := impl.(plugin.PublisherPlugin)
+		if !ok {
+			return nil, ErrBadInProcessPlugin
+		}
+		ap.client = client.NewPublisherInProcessClient(c)
+	default:
+		return nil, ErrBadInProcessPlugin
+	}
+	return ap, nil
+}
+
+// runInProcess adds an instance of a plugin implemented by the program
+// running snap to its pool
+func (r *runner) runInProcess(lp *loadedPlugin) error {
+	ap, err := newInProcessAvailablePlugin(lp, r.emitter)
+	if err != nil {
+		runnerLog.WithFields(log.Fields{
+			"_block": "run-in-process",
+			"plugin": lp.Key(),
+			"error":  err,
+		}).Error("error starting new plugin")
+		return err
+	}
+	if err := r.availablePlugins.insert(ap); err != nil {
+		return err
+	}
+	runnerLog.WithFields(log.Fields{
+		"_block":                "run-in-process",
+		"available-plugin":      ap.String(),
+		"available-plugin-type": ap.TypeName(),
+	}).Info("available plugin started")
+	return nil
+}
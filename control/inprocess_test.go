@@ -0,0 +1,162 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// inProcessCollector counts the calls made to collect metrics
+type inProcessCollector struct {
+	calls int32
+}
+
+func (i *inProcessCollector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	return cpolicy.New(), nil
+}
+
+func (i *inProcessCollector) GetMetricTypes(plugin.PluginConfigType) ([]plugin.PluginMetricType, error) {
+	return []plugin.PluginMetricType{
+		{Namespace_: []string{"intel", "embedded", "foo"}},
+		{Namespace_: []string{"intel", "embedded", "bar"}},
+	}, nil
+}
+
+func (i *inProcessCollector) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMetricType, error) {
+	atomic.AddInt32(&i.calls, 1)
+	for idx := range mts {
+		mts[idx].Data_ = "embedded"
+	}
+	return mts, nil
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	Convey("registering an in-process plugin", t, func() {
+		col := &inProcessCollector{}
+		meta := plugin.NewPluginMeta("embedded", 1, plugin.CollectorPluginType, nil, nil,
+			plugin.ConcurrencyCount(1),
+			plugin.CacheTTL(time.Minute),
+		)
+		c := New()
+		_, serr := c.RegisterPlugin(meta, col)
+		So(serr, ShouldNotBeNil)
+		So(serr.Error(), ShouldEqual, ErrControllerNotStarted.Error())
+		So(c.Start(), ShouldBeNil)
+		defer c.Stop()
+
+		Convey("rejects bad plugins", func() {
+			_, serr := c.RegisterPlugin(plugin.NewPluginMeta("embedded", 0, plugin.CollectorPluginType, nil, nil), col)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, ErrBadInProcessPluginMeta.Error())
+			_, serr = c.RegisterPlugin(plugin.NewPluginMeta("embedded", 1, plugin.PublisherPluginType, nil, nil), col)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, ErrBadInProcessPlugin.Error())
+			So(c.PluginCatalog(), ShouldBeEmpty)
+		})
+
+		Convey("catalogs it", func() {
+			pl, serr := c.RegisterPlugin(meta, col)
+			So(serr, ShouldBeNil)
+			So(pl.Name(), ShouldEqual, "embedded")
+			So(pl.TypeName(), ShouldEqual, "collector")
+			mts, err := c.MetricCatalog()
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 2)
+			_, serr = c.RegisterPlugin(meta, col)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, ErrPluginAlreadyLoaded.Error())
+
+			Convey("and pools and routes to it following its meta", func() {
+				lp, err := c.pluginManager.get("collector:embedded:1")
+				So(err, ShouldBeNil)
+				So(c.verifyPlugin(lp), ShouldBeNil)
+				pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool("collector:embedded:1")
+				So(err, ShouldBeNil)
+				pool.Subscribe("1", strategy.UnboundSubscriptionType)
+				So(pool.Eligible(), ShouldBeTrue)
+				So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+				pool.Subscribe("2", strategy.UnboundSubscriptionType)
+				So(pool.Eligible(), ShouldBeTrue)
+				So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+				So(pool.Count(), ShouldEqual, 2)
+				So(pool.Strategy().String(), ShouldEqual, plugin.DefaultRouting.String())
+
+				m := []core.Metric{
+					MockMetricType{namespace: []string{"intel", "embedded", "foo"}, cfg: cdata.NewNode()},
+					MockMetricType{namespace: []string{"intel", "embedded", "bar"}, cfg: cdata.NewNode()},
+				}
+				for x := 0; x < 3; x++ {
+					cr, errs := c.CollectMetrics(m, time.Now().Add(time.Second), "1")
+					So(errs, ShouldBeEmpty)
+					So(cr, ShouldHaveLength, 2)
+					So(cr[0].Data(), ShouldEqual, "embedded")
+				}
+				// the cache answers the calls after the first
+				So(atomic.LoadInt32(&col.calls), ShouldEqual, 1)
+
+				Convey("and caches metrics apart by config", func() {
+					withUser := func(user string) []core.Metric {
+						cfg := cdata.NewNode()
+						cfg.AddItem("user", ctypes.ConfigValueStr{Value: user})
+						return []core.Metric{MockMetricType{namespace: []string{"intel", "embedded", "foo"}, cfg: cfg}}
+					}
+					for _, user := range []string{"jane", "jane", "john"} {
+						_, errs := c.CollectMetrics(withUser(user), time.Now().Add(time.Second), "1")
+						So(errs, ShouldBeEmpty)
+					}
+					So(atomic.LoadInt32(&col.calls), ShouldEqual, 3)
+
+					cs, err := c.PluginCacheStats("collector", "embedded", 1)
+					So(err, ShouldBeNil)
+					So(cs.Hits, ShouldEqual, 5)
+					So(cs.Misses, ShouldEqual, 4)
+					So(cs.Entries, ShouldEqual, 4)
+					So(cs.Metrics, ShouldResemble, []core.MetricCacheStats{
+						{Namespace: "/intel/embedded/bar", Hits: 2, Misses: 1},
+						{Namespace: "/intel/embedded/foo", Hits: 3, Misses: 3},
+					})
+					_, err = c.PluginCacheStats("collector", "missing", 1)
+					So(err, ShouldEqual, ErrLoadedPluginNotFound)
+				})
+
+				Convey("until it is unloaded", func() {
+					_, serr := c.Unload(pl)
+					So(serr, ShouldBeNil)
+					So(c.PluginCatalog(), ShouldBeEmpty)
+					pool.Unsubscribe("1")
+					pool.Unsubscribe("2")
+					So(c.pluginRunner.(*runner).handleUnsubscription("collector", "embedded", 1, "2"), ShouldBeNil)
+					So(pool.Count(), ShouldEqual, 1)
+				})
+			})
+		})
+	})
+}
@@ -20,12 +20,22 @@ limitations under the License.
 package client
 
 import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/ctypes"
 )
 
+var (
+	// ErrDeadlineExceeded - The error message for a call to a plugin which did not return before its deadline
+	ErrDeadlineExceeded = errors.New("Plugin call exceeded its deadline")
+)
+
 // PluginClient A client providing common plugin method calls.
 type PluginClient interface {
 	SetKey() error
@@ -35,20 +45,32 @@ type PluginClient interface {
 }
 
 // PluginCollectorClient A client providing collector specific plugin method calls.
+// The calls which work metrics give up and return ErrDeadlineExceeded once the
+// deadline passed to them does.  A zero deadline leaves only the timeout of
+// the client.
 type PluginCollectorClient interface {
 	PluginClient
-	CollectMetrics([]core.Metric) ([]core.Metric, error)
+	CollectMetrics([]core.Metric, time.Time) ([]core.Metric, error)
 	GetMetricTypes(plugin.PluginConfigType) ([]core.Metric, error)
 }
 
 // PluginProcessorClient A client providing processor specific plugin method calls.
 type PluginProcessorClient interface {
 	PluginClient
-	Process(contentType string, content []byte, config map[string]ctypes.ConfigValue) (string, []byte, error)
+	Process(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) (string, []byte, error)
 }
 
 // PluginPublisherClient A client providing publishing specific plugin method calls.
 type PluginPublisherClient interface {
 	PluginClient
-	Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error
+	Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) error
+}
+
+// dial connects to the listen address of a plugin which is either a TCP
+// address or the path of a Unix socket prefixed by plugin.UnixAddressPrefix
+func dial(address string, timeout time.Duration) (net.Conn, error) {
+	if strings.HasPrefix(address, plugin.UnixAddressPrefix) {
+		return net.DialTimeout("unix", strings.TrimPrefix(address, plugin.UnixAddressPrefix), timeout)
+	}
+	return net.DialTimeout("tcp", address, timeout)
 }
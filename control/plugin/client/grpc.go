@@ -0,0 +1,258 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/plugin/encrypter"
+	"github.com/intelsdi-x/snap/control/plugin/rpc"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// grpcClient calls a plugin serving the gRPC services in control/plugin/rpc
+type grpcClient struct {
+	conn       *grpc.ClientConn
+	session    rpc.SessionStateClient
+	collector  rpc.CollectorClient
+	processor  rpc.ProcessorClient
+	publisher  rpc.PublisherClient
+	timeout    time.Duration
+	pluginType plugin.PluginType
+	encrypter  *encrypter.Encrypter
+	// opts are passed to every call but Ping and SetKey.  They encrypt the
+	// messages unless the plugin is unsecure.
+	opts []grpc.CallOption
+}
+
+// NewCollectorGRPCClient returns a client of a collector plugin using gRPC
+func NewCollectorGRPCClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
+	return newGRPCClient(address, timeout, plugin.CollectorPluginType, pub, secure)
+}
+
+// NewProcessorGRPCClient returns a client of a processor plugin using gRPC
+func NewProcessorGRPCClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
+	return newGRPCClient(address, timeout, plugin.ProcessorPluginType, pub, secure)
+}
+
+// NewPublisherGRPCClient returns a client of a publisher plugin using gRPC
+func NewPublisherGRPCClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
+	return newGRPCClient(address, timeout, plugin.PublisherPluginType, pub, secure)
+}
+
+func newGRPCClient(address string, timeout time.Duration, t plugin.PluginType, pub *rsa.PublicKey, secure bool) (*grpcClient, error) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(timeout), grpc.WithDialer(dial))
+	if err != nil {
+		return nil, err
+	}
+	g := &grpcClient{
+		conn:       conn,
+		session:    rpc.NewSessionStateClient(conn),
+		collector:  rpc.NewCollectorClient(conn),
+		processor:  rpc.NewProcessorClient(conn),
+		publisher:  rpc.NewPublisherClient(conn),
+		timeout:    timeout,
+		pluginType: t,
+	}
+	if secure {
+		key, err := encrypter.GenerateKey()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		e := encrypter.New(pub, nil)
+		e.Key = key
+		g.encrypter = e
+		g.opts = rpc.NewEncryptedCodec(e).CallOptions()
+	}
+	return g, nil
+}
+
+func (g *grpcClient) Ping() error {
+	ctx, cancel := g.context(time.Time{})
+	defer cancel()
+	_, err := g.session.Ping(ctx, &rpc.Empty{})
+	return grpcError(err)
+}
+
+func (g *grpcClient) SetKey() error {
+	key, err := g.encrypter.EncryptKey()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.context(time.Time{})
+	defer cancel()
+	_, err = g.session.SetKey(ctx, &rpc.SetKeyArg{Key: key})
+	return grpcError(err)
+}
+
+// Kill asks the plugin to stop and closes the connection to it
+func (g *grpcClient) Kill(reason string) error {
+	ctx, cancel := g.context(time.Time{})
+	defer cancel()
+	_, err := g.session.Kill(ctx, &rpc.KillArg{Reason: reason}, g.opts...)
+	g.conn.Close()
+	return grpcError(err)
+}
+
+func (g *grpcClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	ctx, cancel := g.context(time.Time{})
+	defer cancel()
+	reply, err := g.session.GetConfigPolicy(ctx, &rpc.Empty{}, g.opts...)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	policy := cpolicy.New()
+	if err := json.Unmarshal(reply.GetPolicy(), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (g *grpcClient) CollectMetrics(mts []core.Metric, deadline time.Time) ([]core.Metric, error) {
+	if len(mts) == 0 {
+		return nil, errors.New("no metrics to collect")
+	}
+	arg := &rpc.CollectMetricsArg{Metrics: make([]*rpc.Metric, len(mts))}
+	for i, mt := range mts {
+		// only what the plugin needs to collect the metric is sent
+		m, err := plugin.NewGRPCMetric(plugin.PluginMetricType{
+			Namespace_:          mt.Namespace(),
+			LastAdvertisedTime_: mt.LastAdvertisedTime(),
+			Version_:            mt.Version(),
+			Tags_:               mt.Tags(),
+			Labels_:             mt.Labels(),
+			Config_:             mt.Config(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		arg.Metrics[i] = m
+	}
+
+	ctx, cancel := g.context(deadline)
+	defer cancel()
+	reply, err := g.collector.CollectMetrics(ctx, arg, g.opts...)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return metrics(reply.GetMetrics(), time.Time{})
+}
+
+func (g *grpcClient) GetMetricTypes(config plugin.PluginConfigType) ([]core.Metric, error) {
+	c, err := rpc.NewConfig(config.ConfigDataNode)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := g.context(time.Time{})
+	defer cancel()
+	reply, err := g.collector.GetMetricTypes(ctx, &rpc.GetMetricTypesArg{Config: c}, g.opts...)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return metrics(reply.GetMetrics(), time.Now())
+}
+
+func (g *grpcClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) (string, []byte, error) {
+	c, err := rpc.NewConfigMap(config)
+	if err != nil {
+		return "", nil, err
+	}
+	ctx, cancel := g.context(deadline)
+	defer cancel()
+	reply, err := g.processor.Process(ctx, &rpc.ProcessArg{ContentType: contentType, Content: content, Config: c}, g.opts...)
+	if err != nil {
+		return "", nil, grpcError(err)
+	}
+	return reply.GetContentType(), reply.GetContent(), nil
+}
+
+func (g *grpcClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) error {
+	c, err := rpc.NewConfigMap(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.context(deadline)
+	defer cancel()
+	_, err = g.publisher.Publish(ctx, &rpc.PublishArg{ContentType: contentType, Content: content, Config: c}, g.opts...)
+	return grpcError(err)
+}
+
+// GetType returns the string type of the plugin
+// Note: the first letter of the type will be capitalized.
+func (g *grpcClient) GetType() string {
+	return upcaseInitial(g.pluginType.String())
+}
+
+// context returns the context of a call which ends at the deadline or, for a
+// zero deadline, after the timeout of the client
+func (g *grpcClient) context(deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return context.WithTimeout(context.Background(), g.timeout)
+	}
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+// grpcError returns the error a plugin returned without its gRPC status or
+// ErrDeadlineExceeded for a call which did not return before its deadline
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == context.DeadlineExceeded {
+		return ErrDeadlineExceeded
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if s.Code() == codes.DeadlineExceeded {
+		return ErrDeadlineExceeded
+	}
+	return errors.New(s.Message())
+}
+
+// metrics returns the metrics held by the messages, advertised at advertised
+// unless it is zero
+func metrics(ms []*rpc.Metric, advertised time.Time) ([]core.Metric, error) {
+	results := make([]core.Metric, len(ms))
+	for i, m := range ms {
+		mt, err := plugin.PluginMetricTypeFromGRPC(m)
+		if err != nil {
+			return nil, err
+		}
+		if !advertised.IsZero() {
+			mt.LastAdvertisedTime_ = advertised
+		}
+		results[i] = mt
+	}
+	return results, nil
+}
@@ -0,0 +1,204 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/plugin/encrypter"
+	"github.com/intelsdi-x/snap/control/plugin/rpc"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// mockGRPCPlugin serves the gRPC services of a plugin
+type mockGRPCPlugin struct {
+	encrypter *encrypter.Encrypter
+	published []byte
+}
+
+func (m *mockGRPCPlugin) Ping(ctx context.Context, arg *rpc.Empty) (*rpc.Empty, error) {
+	return &rpc.Empty{}, nil
+}
+
+func (m *mockGRPCPlugin) Kill(ctx context.Context, arg *rpc.KillArg) (*rpc.Empty, error) {
+	return &rpc.Empty{}, nil
+}
+
+func (m *mockGRPCPlugin) GetConfigPolicy(ctx context.Context, arg *rpc.Empty) (*rpc.GetConfigPolicyReply, error) {
+	cp := cpolicy.New()
+	n := cpolicy.NewPolicyNode()
+	r, _ := cpolicy.NewIntegerRule("SomeRequiredInt", true, 1)
+	n.Add(r)
+	cp.Add([]string{"foo", "bar"}, n)
+	b, err := cp.MarshalJSON()
+	return &rpc.GetConfigPolicyReply{Policy: b}, err
+}
+
+func (m *mockGRPCPlugin) SetKey(ctx context.Context, arg *rpc.SetKeyArg) (*rpc.Empty, error) {
+	k, err := m.encrypter.DecryptKey(arg.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	m.encrypter.Key = k
+	return &rpc.Empty{}, nil
+}
+
+func (m *mockGRPCPlugin) CollectMetrics(ctx context.Context, arg *rpc.CollectMetricsArg) (*rpc.CollectMetricsReply, error) {
+	for _, mt := range arg.GetMetrics() {
+		if mt.GetNamespace()[0] == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		mt.SetValue(int64(len(mt.GetNamespace())))
+	}
+	return &rpc.CollectMetricsReply{Metrics: arg.GetMetrics()}, nil
+}
+
+func (m *mockGRPCPlugin) GetMetricTypes(ctx context.Context, arg *rpc.GetMetricTypesArg) (*rpc.GetMetricTypesReply, error) {
+	return &rpc.GetMetricTypesReply{Metrics: []*rpc.Metric{{Namespace: []string{"foo", "bar"}, Config: arg.GetConfig()}}}, nil
+}
+
+func (m *mockGRPCPlugin) Process(ctx context.Context, arg *rpc.ProcessArg) (*rpc.ProcessReply, error) {
+	return &rpc.ProcessReply{ContentType: arg.GetContentType(), Content: arg.GetContent()}, nil
+}
+
+func (m *mockGRPCPlugin) Publish(ctx context.Context, arg *rpc.PublishArg) (*rpc.Empty, error) {
+	if _, ok := arg.GetConfig().ConfigMap()["fail"]; ok {
+		return nil, errors.New("Publish call error: failed")
+	}
+	m.published = arg.GetContent()
+	return &rpc.Empty{}, nil
+}
+
+func startGRPCPlugin(m *mockGRPCPlugin, secure bool) (string, func()) {
+	var opts []grpc.ServerOption
+	if secure {
+		opts = append(opts, grpc.ForceServerCodec(rpc.NewEncryptedCodec(m.encrypter)))
+	}
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	s := grpc.NewServer(opts...)
+	rpc.RegisterSessionStateServer(s, m)
+	rpc.RegisterCollectorServer(s, m)
+	rpc.RegisterProcessorServer(s, m)
+	rpc.RegisterPublisherServer(s, m)
+	go s.Serve(l)
+	return l.Addr().String(), s.Stop
+}
+
+func TestGRPCClientUnixSocket(t *testing.T) {
+	Convey("gRPC client dials Unix sockets", t, func() {
+		dir, err := ioutil.TempDir("", "snap-sockets")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		sock := filepath.Join(dir, "mock-1.sock")
+		l, err := net.Listen("unix", sock)
+		So(err, ShouldBeNil)
+		s := grpc.NewServer()
+		rpc.RegisterSessionStateServer(s, &mockGRPCPlugin{})
+		go s.Serve(l)
+		defer s.Stop()
+		c, err := NewCollectorGRPCClient(plugin.UnixAddressPrefix+sock, time.Second, nil, false)
+		So(err, ShouldBeNil)
+		So(c.Ping(), ShouldBeNil)
+	})
+}
+
+func TestGRPCClient(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		Convey("gRPC client", t, func() {
+			m := &mockGRPCPlugin{encrypter: encrypter.New(nil, key)}
+			addr, stop := startGRPCPlugin(m, secure)
+			defer stop()
+			c, err := NewCollectorGRPCClient(addr, time.Second, &key.PublicKey, secure)
+			So(err, ShouldBeNil)
+			if secure {
+				So(c.SetKey(), ShouldBeNil)
+			} else {
+				So(c.Ping(), ShouldBeNil)
+			}
+
+			Convey("collects metrics", func() {
+				mts, err := c.CollectMetrics([]core.Metric{
+					plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}},
+					plugin.PluginMetricType{Namespace_: []string{"foo"}},
+				}, time.Now().Add(time.Second))
+				So(err, ShouldBeNil)
+				So(mts, ShouldHaveLength, 2)
+				So(mts[0].Data(), ShouldEqual, 2)
+				So(mts[1].Data(), ShouldEqual, 1)
+				_, err = c.CollectMetrics(nil, time.Time{})
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("gives up at the deadline", func() {
+				_, err := c.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"slow"}}}, time.Now().Add(50*time.Millisecond))
+				So(err, ShouldEqual, ErrDeadlineExceeded)
+			})
+
+			Convey("gets metric types", func() {
+				cfg := plugin.NewPluginConfigType()
+				cfg.AddItem("test", ctypes.ConfigValueBool{Value: true})
+				mts, err := c.GetMetricTypes(cfg)
+				So(err, ShouldBeNil)
+				So(mts, ShouldHaveLength, 1)
+				So(mts[0].Namespace(), ShouldResemble, []string{"foo", "bar"})
+				So(mts[0].Config().Table()["test"], ShouldResemble, ctypes.ConfigValueBool{Value: true})
+				So(mts[0].LastAdvertisedTime().IsZero(), ShouldBeFalse)
+			})
+
+			Convey("gets the config policy", func() {
+				cp, err := c.GetConfigPolicy()
+				So(err, ShouldBeNil)
+				So(cp.Get([]string{"foo", "bar"}), ShouldNotBeNil)
+			})
+
+			Convey("processes and publishes", func() {
+				p := c.(PluginProcessorClient)
+				ct, content, err := p.Process("snap.gob", []byte("abc"), nil, time.Time{})
+				So(err, ShouldBeNil)
+				So(ct, ShouldEqual, "snap.gob")
+				So(string(content), ShouldEqual, "abc")
+
+				pub := c.(PluginPublisherClient)
+				So(pub.Publish("snap.gob", []byte("def"), nil, time.Time{}), ShouldBeNil)
+				So(string(m.published), ShouldEqual, "def")
+				err = pub.Publish("snap.gob", []byte("def"), map[string]ctypes.ConfigValue{"fail": ctypes.ConfigValueBool{Value: true}}, time.Time{})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Publish call error: failed")
+			})
+
+			Convey("kills the plugin", func() {
+				So(c.Kill("test"), ShouldBeNil)
+			})
+		})
+	}
+}
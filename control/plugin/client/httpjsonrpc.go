@@ -26,7 +26,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -44,6 +46,7 @@ var logger = log.WithField("_module", "client-httpjsonrpc")
 
 type httpJSONRPCClient struct {
 	url        string
+	transport  http.RoundTripper
 	id         uint64
 	timeout    time.Duration
 	pluginType plugin.PluginType
@@ -51,10 +54,14 @@ type httpJSONRPCClient struct {
 	encoder    encoding.Encoder
 }
 
-// NewCollectorHttpJSONRPCClient returns CollectorHttpJSONRPCClient
+// NewCollectorHttpJSONRPCClient returns CollectorHttpJSONRPCClient.  u is the
+// URL of the plugin or, for a plugin listening on a Unix socket, its listen
+// address.
 func NewCollectorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
+	url, transport := httpTarget(u)
 	hjr := &httpJSONRPCClient{
-		url:        u,
+		url:        url,
+		transport:  transport,
 		timeout:    timeout,
 		pluginType: plugin.CollectorPluginType,
 		encoder:    encoding.NewJsonEncoder(),
@@ -73,8 +80,10 @@ func NewCollectorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 }
 
 func NewProcessorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
+	url, transport := httpTarget(u)
 	hjr := &httpJSONRPCClient{
-		url:        u,
+		url:        url,
+		transport:  transport,
 		timeout:    timeout,
 		pluginType: plugin.ProcessorPluginType,
 		encoder:    encoding.NewJsonEncoder(),
@@ -93,8 +102,10 @@ func NewProcessorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 }
 
 func NewPublisherHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
+	url, transport := httpTarget(u)
 	hjr := &httpJSONRPCClient{
-		url:        u,
+		url:        url,
+		transport:  transport,
 		timeout:    timeout,
 		pluginType: plugin.PublisherPluginType,
 		encoder:    encoding.NewJsonEncoder(),
@@ -114,7 +125,7 @@ func NewPublisherHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 
 // Ping
 func (h *httpJSONRPCClient) Ping() error {
-	_, err := h.call("SessionState.Ping", []interface{}{})
+	_, err := h.call("SessionState.Ping", []interface{}{}, time.Time{})
 	return err
 }
 
@@ -124,7 +135,7 @@ func (h *httpJSONRPCClient) SetKey() error {
 		return err
 	}
 	a := plugin.SetKeyArgs{Key: key}
-	_, err = h.call("SessionState.SetKey", []interface{}{a})
+	_, err = h.call("SessionState.SetKey", []interface{}{a}, time.Time{})
 	return err
 }
 
@@ -136,12 +147,12 @@ func (h *httpJSONRPCClient) Kill(reason string) error {
 		return err
 	}
 
-	_, err = h.call("SessionState.Kill", []interface{}{out})
+	_, err = h.call("SessionState.Kill", []interface{}{out}, time.Time{})
 	return err
 }
 
 // CollectMetrics returns collected metrics
-func (h *httpJSONRPCClient) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+func (h *httpJSONRPCClient) CollectMetrics(mts []core.Metric, deadline time.Time) ([]core.Metric, error) {
 	var results []core.Metric
 	if len(mts) == 0 {
 		return nil, errors.New("no metrics to collect")
@@ -166,7 +177,7 @@ func (h *httpJSONRPCClient) CollectMetrics(mts []core.Metric) ([]core.Metric, er
 		return nil, err
 	}
 
-	res, err := h.call("Collector.CollectMetrics", []interface{}{out})
+	res, err := h.call("Collector.CollectMetrics", []interface{}{out}, deadline)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +214,7 @@ func (h *httpJSONRPCClient) GetMetricTypes(config plugin.PluginConfigType) ([]co
 		return nil, err
 	}
 
-	res, err := h.call("Collector.GetMetricTypes", []interface{}{out})
+	res, err := h.call("Collector.GetMetricTypes", []interface{}{out}, time.Time{})
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +233,7 @@ func (h *httpJSONRPCClient) GetMetricTypes(config plugin.PluginConfigType) ([]co
 
 // GetConfigPolicy returns a config policy
 func (h *httpJSONRPCClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
-	res, err := h.call("SessionState.GetConfigPolicy", []interface{}{})
+	res, err := h.call("SessionState.GetConfigPolicy", []interface{}{}, time.Time{})
 	if err != nil {
 		logger.WithFields(log.Fields{
 			"_block": "GetConfigPolicy",
@@ -242,26 +253,26 @@ func (h *httpJSONRPCClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	return cpr.Policy, nil
 }
 
-func (h *httpJSONRPCClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error {
+func (h *httpJSONRPCClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) error {
 	args := plugin.PublishArgs{ContentType: contentType, Content: content, Config: config}
 	out, err := h.encoder.Encode(args)
 	if err != nil {
 		return nil
 	}
-	_, err = h.call("Publisher.Publish", []interface{}{out})
+	_, err = h.call("Publisher.Publish", []interface{}{out}, deadline)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (h *httpJSONRPCClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue) (string, []byte, error) {
+func (h *httpJSONRPCClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) (string, []byte, error) {
 	args := plugin.ProcessorArgs{ContentType: contentType, Content: content, Config: config}
 	out, err := h.encoder.Encode(args)
 	if err != nil {
 		return "", nil, err
 	}
-	res, err := h.call("Processor.Process", []interface{}{out})
+	res, err := h.call("Processor.Process", []interface{}{out}, deadline)
 	if err != nil {
 		return "", nil, err
 	}
@@ -282,7 +293,20 @@ type jsonRpcResp struct {
 	Error  string `json:"error"`
 }
 
-func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcResp, error) {
+// call posts a request to the plugin.  The request times out after the
+// timeout of the client or at the deadline, when one is given, whichever
+// comes first.
+func (h *httpJSONRPCClient) call(method string, args []interface{}, deadline time.Time) (*jsonRpcResp, error) {
+	timeout := h.timeout
+	if !deadline.IsZero() {
+		d := deadline.Sub(time.Now())
+		if d <= 0 {
+			return nil, ErrDeadlineExceeded
+		}
+		if timeout == 0 || d < timeout {
+			timeout = d
+		}
+	}
 	data, err := json.Marshal(map[string]interface{}{
 		"method": method,
 		"id":     h.id,
@@ -299,9 +323,12 @@ func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcRes
 		}).Error("error encoding request to json")
 		return nil, err
 	}
-	client := http.Client{Timeout: h.timeout}
+	client := http.Client{Timeout: timeout, Transport: h.transport}
 	resp, err := client.Post(h.url, "application/json", bytes.NewReader(data))
 	if err != nil {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			err = ErrDeadlineExceeded
+		}
 		logger.WithFields(log.Fields{
 			"_block":  "call",
 			"url":     h.url,
@@ -330,3 +357,17 @@ func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcRes
 	}
 	return result, nil
 }
+
+// httpTarget returns the URL to post the calls to a plugin to and the
+// transport to post them with.  A plugin listening on a Unix socket is given
+// by its listen address instead of a URL.
+func httpTarget(u string) (string, http.RoundTripper) {
+	if !strings.HasPrefix(u, plugin.UnixAddressPrefix) {
+		return u, nil
+	}
+	return "http://unix/rpc", &http.Transport{
+		Dial: func(string, string) (net.Conn, error) {
+			return dial(u, 0)
+		},
+	}
+}
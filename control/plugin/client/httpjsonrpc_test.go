@@ -25,10 +25,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/rpc"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -220,7 +223,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 					Namespace_: []string{"foo", "bar"},
 					Config_:    cdn,
 				},
-			})
+			}, time.Now().Add(time.Second))
 			So(err, ShouldBeNil)
 			So(mts, ShouldNotBeNil)
 			So(mts, ShouldHaveSameTypeAs, []core.Metric{})
@@ -253,7 +256,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 					Namespace_: []string{"foo", "bar"},
 					Config_:    cdn,
 				},
-			})
+			}, time.Now().Add(time.Second))
 			So(err, ShouldBeNil)
 			So(mts, ShouldNotBeNil)
 			So(mts, ShouldHaveSameTypeAs, []core.Metric{})
@@ -304,7 +307,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 		Convey("Process metrics", func() {
 			pmt := plugin.NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1)
 			b, _ := json.Marshal([]plugin.PluginMetricType{*pmt})
-			contentType, content, err := p.Process(plugin.SnapJSONContentType, b, nil)
+			contentType, content, err := p.Process(plugin.SnapJSONContentType, b, nil, time.Now().Add(time.Second))
 			So(contentType, ShouldResemble, plugin.SnapJSONContentType)
 			So(content, ShouldNotBeNil)
 			So(err, ShouldEqual, nil)
@@ -344,9 +347,49 @@ func TestHTTPJSONRPC(t *testing.T) {
 		Convey("Publish metrics", func() {
 			pmt := plugin.NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1)
 			b, _ := json.Marshal([]plugin.PluginMetricType{*pmt})
-			err := p.Publish(plugin.SnapJSONContentType, b, nil)
+			err := p.Publish(plugin.SnapJSONContentType, b, nil, time.Now().Add(time.Second))
 			So(err, ShouldBeNil)
 		})
 
+		Convey("Publish metrics past the deadline", func() {
+			pmt := plugin.NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1)
+			b, _ := json.Marshal([]plugin.PluginMetricType{*pmt})
+			err := p.Publish(plugin.SnapJSONContentType, b, nil, time.Now().Add(-time.Second))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+		})
+
+	})
+
+	Convey("Clients of plugins listening on Unix sockets", t, func() {
+		dir, err := ioutil.TempDir("", "snap-sockets")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("JSON-RPC", func() {
+			sock := filepath.Join(dir, "mock-1.sock")
+			l, err := net.Listen("unix", sock)
+			So(err, ShouldBeNil)
+			defer l.Close()
+			go http.Serve(l, nil)
+			c, err := NewCollectorHttpJSONRPCClient(plugin.UnixAddressPrefix+sock, time.Second, &key.PublicKey, false)
+			So(err, ShouldBeNil)
+			So(c.Ping(), ShouldBeNil)
+		})
+
+		Convey("native", func() {
+			sock := filepath.Join(dir, "mock-2.sock")
+			l, err := net.Listen("unix", sock)
+			So(err, ShouldBeNil)
+			defer l.Close()
+			go func() {
+				conn, err := l.Accept()
+				if err == nil {
+					rpc.ServeConn(conn)
+				}
+			}()
+			c, err := NewCollectorNativeClient(plugin.UnixAddressPrefix+sock, time.Second, &key.PublicKey, false)
+			So(err, ShouldBeNil)
+			So(c.Ping(), ShouldBeNil)
+		})
 	})
 }
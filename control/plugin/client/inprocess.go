@@ -0,0 +1,184 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// inProcessClient calls a plugin implemented in the program running snap
+// directly.  A panic of the plugin is returned as an error as it would
+// otherwise stop snapd.
+type inProcessClient struct {
+	plugin plugin.Plugin
+}
+
+// NewCollectorInProcessClient returns a client of a collector plugin running
+// in-process
+func NewCollectorInProcessClient(c plugin.CollectorPlugin) PluginCollectorClient {
+	return &inProcessClient{plugin: c}
+}
+
+// NewProcessorInProcessClient returns a client of a processor plugin running
+// in-process
+func NewProcessorInProcessClient(p plugin.ProcessorPlugin) PluginProcessorClient {
+	return &inProcessClient{plugin: p}
+}
+
+// NewPublisherInProcessClient returns a client of a publisher plugin running
+// in-process
+func NewPublisherInProcessClient(p plugin.PublisherPlugin) PluginPublisherClient {
+	return &inProcessClient{plugin: p}
+}
+
+// SetKey does nothing as calls made in-process are not encrypted
+func (i *inProcessClient) SetKey() error {
+	return nil
+}
+
+// Ping does nothing as a plugin running in-process is always available
+func (i *inProcessClient) Ping() error {
+	return nil
+}
+
+// Kill does nothing as there is no process to kill
+func (i *inProcessClient) Kill(string) error {
+	return nil
+}
+
+func (i *inProcessClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	var policy *cpolicy.ConfigPolicy
+	err := i.call(time.Time{}, func() (err error) {
+		policy, err = i.plugin.GetConfigPolicy()
+		return err
+	})
+	return policy, err
+}
+
+func (i *inProcessClient) CollectMetrics(mts []core.Metric, deadline time.Time) ([]core.Metric, error) {
+	if len(mts) == 0 {
+		return nil, errors.New("no metrics to collect")
+	}
+	// the plugin gets copies as it would over RPC
+	metricsToCollect := make([]plugin.PluginMetricType, len(mts))
+	for idx, mt := range mts {
+		metricsToCollect[idx] = plugin.PluginMetricType{
+			Namespace_:          mt.Namespace(),
+			LastAdvertisedTime_: mt.LastAdvertisedTime(),
+			Version_:            mt.Version(),
+			Tags_:               mt.Tags(),
+			Labels_:             mt.Labels(),
+			Config_:             mt.Config(),
+		}
+	}
+
+	var collected []plugin.PluginMetricType
+	err := i.call(deadline, func() (err error) {
+		collected, err = i.plugin.(plugin.CollectorPlugin).CollectMetrics(metricsToCollect)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]core.Metric, len(collected))
+	for idx, m := range collected {
+		results[idx] = m
+	}
+	return results, nil
+}
+
+func (i *inProcessClient) GetMetricTypes(config plugin.PluginConfigType) ([]core.Metric, error) {
+	var mts []plugin.PluginMetricType
+	err := i.call(time.Time{}, func() (err error) {
+		mts, err = i.plugin.(plugin.CollectorPlugin).GetMetricTypes(config)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	retMetricTypes := make([]core.Metric, len(mts))
+	for idx, mt := range mts {
+		// Set the advertised time
+		mt.LastAdvertisedTime_ = time.Now()
+		retMetricTypes[idx] = mt
+	}
+	return retMetricTypes, nil
+}
+
+func (i *inProcessClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) (string, []byte, error) {
+	var (
+		ct  string
+		out []byte
+	)
+	err := i.call(deadline, func() (err error) {
+		ct, out, err = i.plugin.(plugin.ProcessorPlugin).Process(contentType, content, config)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return ct, out, nil
+}
+
+func (i *inProcessClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) error {
+	return i.call(deadline, func() error {
+		return i.plugin.(plugin.PublisherPlugin).Publish(contentType, content, config)
+	})
+}
+
+// call calls f returning ErrDeadlineExceeded, without waiting for f, once
+// the deadline passes.  The results set by a call which is given up on are
+// never read.
+func (i *inProcessClient) call(deadline time.Time, f func() error) error {
+	var timeout time.Duration
+	if !deadline.IsZero() {
+		timeout = deadline.Sub(time.Now())
+		if timeout <= 0 {
+			return ErrDeadlineExceeded
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("plugin panicked: %v", r)
+			}
+		}()
+		done <- f()
+	}()
+	if deadline.IsZero() {
+		return <-done
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return ErrDeadlineExceeded
+	}
+}
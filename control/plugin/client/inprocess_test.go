@@ -0,0 +1,132 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// inProcessPlugin is a collector, processor and publisher which takes delay
+// to answer and panics when asked to
+type inProcessPlugin struct {
+	delay     time.Duration
+	panics    bool
+	published []byte
+}
+
+func (i *inProcessPlugin) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	return cpolicy.New(), nil
+}
+
+func (i *inProcessPlugin) GetMetricTypes(plugin.PluginConfigType) ([]plugin.PluginMetricType, error) {
+	return []plugin.PluginMetricType{{Namespace_: []string{"foo", "bar"}}}, nil
+}
+
+func (i *inProcessPlugin) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMetricType, error) {
+	time.Sleep(i.delay)
+	if i.panics {
+		panic("collect")
+	}
+	for idx := range mts {
+		mts[idx].Data_ = 1
+	}
+	return mts, nil
+}
+
+func (i *inProcessPlugin) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue) (string, []byte, error) {
+	if _, ok := config["fail"]; ok {
+		return "", nil, errors.New("process failed")
+	}
+	return contentType, append(content, '!'), nil
+}
+
+func (i *inProcessPlugin) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error {
+	i.published = content
+	return nil
+}
+
+func TestInProcessClient(t *testing.T) {
+	Convey("In-process client", t, func() {
+		p := &inProcessPlugin{}
+
+		Convey("calls a collector", func() {
+			c := NewCollectorInProcessClient(p)
+			So(c.Ping(), ShouldBeNil)
+			So(c.SetKey(), ShouldBeNil)
+			cp, err := c.GetConfigPolicy()
+			So(err, ShouldBeNil)
+			So(cp, ShouldNotBeNil)
+			mts, err := c.GetMetricTypes(plugin.PluginConfigType{})
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].LastAdvertisedTime().IsZero(), ShouldBeFalse)
+
+			_, err = c.CollectMetrics(nil, time.Time{})
+			So(err, ShouldNotBeNil)
+			mts, err = c.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}}}, time.Now().Add(time.Second))
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Data(), ShouldEqual, 1)
+		})
+
+		Convey("calls a processor", func() {
+			c := NewProcessorInProcessClient(p)
+			ct, out, err := c.Process(plugin.SnapGOBContentType, []byte("a"), nil, time.Time{})
+			So(err, ShouldBeNil)
+			So(ct, ShouldEqual, plugin.SnapGOBContentType)
+			So(string(out), ShouldEqual, "a!")
+			_, _, err = c.Process(plugin.SnapGOBContentType, []byte("a"), map[string]ctypes.ConfigValue{"fail": ctypes.ConfigValueBool{Value: true}}, time.Time{})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("calls a publisher", func() {
+			c := NewPublisherInProcessClient(p)
+			So(c.Publish(plugin.SnapGOBContentType, []byte("a"), nil, time.Time{}), ShouldBeNil)
+			So(string(p.published), ShouldEqual, "a")
+		})
+
+		Convey("returns once the deadline passes", func() {
+			p.delay = time.Millisecond * 200
+			c := NewCollectorInProcessClient(p)
+			start := time.Now()
+			_, err := c.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}}}, time.Now().Add(time.Millisecond*20))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+			So(time.Since(start), ShouldBeLessThan, time.Millisecond*150)
+			_, err = c.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}}}, time.Now().Add(-time.Second))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+		})
+
+		Convey("returns a panic as an error", func() {
+			p.panics = true
+			c := NewCollectorInProcessClient(p)
+			_, err := c.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}}}, time.Time{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "plugin panicked: collect")
+		})
+	})
+}
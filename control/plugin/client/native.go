@@ -23,7 +23,6 @@ import (
 	"crypto/rsa"
 	"encoding/gob"
 	"errors"
-	"net"
 	"net/rpc"
 	"time"
 	"unicode"
@@ -42,6 +41,7 @@ import (
 // CallsRPC provides an interface for RPC clients
 type CallsRPC interface {
 	Call(methd string, args interface{}, reply interface{}) error
+	Go(method string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call
 }
 
 // Native clients use golang net/rpc for communication to a native rpc server.
@@ -92,7 +92,7 @@ func (p *PluginNativeClient) Kill(reason string) error {
 	return err
 }
 
-func (p *PluginNativeClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error {
+func (p *PluginNativeClient) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) error {
 	args := plugin.PublishArgs{ContentType: contentType, Content: content, Config: config}
 
 	out, err := p.encoder.Encode(args)
@@ -101,11 +101,11 @@ func (p *PluginNativeClient) Publish(contentType string, content []byte, config
 	}
 
 	var reply []byte
-	err = p.connection.Call("Publisher.Publish", out, &reply)
+	err = p.call("Publisher.Publish", out, &reply, deadline)
 	return err
 }
 
-func (p *PluginNativeClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue) (string, []byte, error) {
+func (p *PluginNativeClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue, deadline time.Time) (string, []byte, error) {
 	args := plugin.ProcessorArgs{ContentType: contentType, Content: content, Config: config}
 
 	out, err := p.encoder.Encode(args)
@@ -114,7 +114,7 @@ func (p *PluginNativeClient) Process(contentType string, content []byte, config
 	}
 
 	var reply []byte
-	err = p.connection.Call("Processor.Process", out, &reply)
+	err = p.call("Processor.Process", out, &reply, deadline)
 	if err != nil {
 		return "", nil, err
 	}
@@ -128,7 +128,7 @@ func (p *PluginNativeClient) Process(contentType string, content []byte, config
 	return r.ContentType, r.Content, nil
 }
 
-func (p *PluginNativeClient) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+func (p *PluginNativeClient) CollectMetrics(mts []core.Metric, deadline time.Time) ([]core.Metric, error) {
 	// Convert core.MetricType slice into plugin.PluginMetricType slice as we have
 	// to send structs over RPC
 	var results []core.Metric
@@ -156,7 +156,7 @@ func (p *PluginNativeClient) CollectMetrics(mts []core.Metric) ([]core.Metric, e
 	}
 
 	var reply []byte
-	err = p.connection.Call("Collector.CollectMetrics", out, &reply)
+	err = p.call("Collector.CollectMetrics", out, &reply, deadline)
 	if err != nil {
 		return nil, err
 	}
@@ -224,6 +224,30 @@ func (p *PluginNativeClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	return r.Policy, nil
 }
 
+// call makes an RPC call to the plugin which returns ErrDeadlineExceeded,
+// without waiting for the reply, once the deadline passes.  The reply of a
+// call which is given up on is discarded when it arrives.
+func (p *PluginNativeClient) call(method string, args interface{}, reply *[]byte, deadline time.Time) error {
+	if deadline.IsZero() {
+		return p.connection.Call(method, args, reply)
+	}
+	timeout := deadline.Sub(time.Now())
+	if timeout <= 0 {
+		return ErrDeadlineExceeded
+	}
+	var r []byte
+	call := p.connection.Go(method, args, &r, nil)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		*reply = r
+		return call.Error
+	case <-timer.C:
+		return ErrDeadlineExceeded
+	}
+}
+
 // GetType returns the string type of the plugin
 // Note: the first letter of the type will be capitalized.
 func (p *PluginNativeClient) GetType() string {
@@ -232,7 +256,7 @@ func (p *PluginNativeClient) GetType() string {
 
 func newNativeClient(address string, timeout time.Duration, t plugin.PluginType, pub *rsa.PublicKey, secure bool) (*PluginNativeClient, error) {
 	// Attempt to dial address error on timeout or problem
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	conn, err := dial(address, timeout)
 	// Return nil RPCClient and err if encoutered
 	if err != nil {
 		return nil, err
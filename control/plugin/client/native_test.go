@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/encoding"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// slowRPC answers every call after a delay
+type slowRPC struct {
+	delay time.Duration
+	calls int
+}
+
+func (s *slowRPC) Call(method string, args interface{}, reply interface{}) error {
+	s.calls++
+	time.Sleep(s.delay)
+	*reply.(*[]byte) = []byte{}
+	return nil
+}
+
+func (s *slowRPC) Go(method string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call {
+	s.calls++
+	call := &rpc.Call{ServiceMethod: method, Args: args, Reply: reply, Done: make(chan *rpc.Call, 1)}
+	go func() {
+		time.Sleep(s.delay)
+		*reply.(*[]byte) = []byte{}
+		call.Done <- call
+	}()
+	return call
+}
+
+func TestNativeClientDeadline(t *testing.T) {
+	Convey("Native client calls", t, func() {
+		conn := &slowRPC{delay: time.Millisecond * 200}
+		p := &PluginNativeClient{
+			connection: conn,
+			pluginType: plugin.PublisherPluginType,
+			encoder:    encoding.NewGobEncoder(),
+		}
+
+		Convey("return once the deadline passes", func() {
+			start := time.Now()
+			err := p.Publish(plugin.SnapGOBContentType, []byte{}, nil, time.Now().Add(time.Millisecond*20))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+			So(time.Since(start), ShouldBeLessThan, time.Millisecond*150)
+
+			_, err = p.CollectMetrics([]core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo"}}}, time.Now().Add(time.Millisecond*20))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+		})
+		Convey("are not made past the deadline", func() {
+			_, _, err := p.Process(plugin.SnapGOBContentType, []byte{}, nil, time.Now().Add(-time.Second))
+			So(err, ShouldEqual, ErrDeadlineExceeded)
+			So(conn.calls, ShouldEqual, 0)
+		})
+		Convey("wait for the reply without a deadline", func() {
+			err := p.Publish(plugin.SnapGOBContentType, []byte{}, nil, time.Time{})
+			So(err, ShouldBeNil)
+			So(conn.calls, ShouldEqual, 1)
+		})
+	})
+}
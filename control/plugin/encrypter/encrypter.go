@@ -31,7 +31,10 @@ import (
 	"io/ioutil"
 )
 
-var ErrKeyNotValid = errors.New("given key length is invalid. did you set it?")
+var (
+	ErrKeyNotValid        = errors.New("given key length is invalid. did you set it?")
+	ErrCiphertextTooShort = errors.New("ciphertext is shorter than its nonce")
+)
 
 const (
 	nonceSize = 12
@@ -104,6 +107,9 @@ func (e *Encrypter) Decrypt(in io.Reader) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(bytes) < nonceSize {
+		return nil, ErrCiphertextTooShort
+	}
 	nonce := bytes[:nonceSize]
 	return gcm.Open(nil, nonce, bytes[nonceSize:], nil)
 }
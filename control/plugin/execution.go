@@ -46,12 +46,30 @@ const (
 	pluginResponseBad                   // plugin response received (invalid)
 )
 
+const (
+	// StdoutStream names the lines a plugin writes to STDOUT after its response
+	StdoutStream = "stdout"
+	// StderrStream names the lines a plugin writes to STDERR
+	StderrStream = "stderr"
+)
+
+// LogSink receives the output of a running plugin a line at a time
+type LogSink interface {
+	WriteLine(stream, line string)
+}
+
 // A plugin that is executable as a forked process on *Linux.
 type ExecutablePlugin struct {
 	cmd    *exec.Cmd
+	path   string
 	stdout io.Reader
 	stderr io.Reader
 	args   Arg
+	sink   LogSink
+
+	limits   *ResourceLimits
+	onLimit  func(LimitViolation)
+	enforcer *limitEnforcer
 }
 
 // A interface representing an executable plugin.
@@ -60,6 +78,7 @@ type pluginExecutor interface {
 	WaitForExit() error
 	ResponseReader() io.Reader
 	ErrorResponseReader() io.Reader
+	LogSink() LogSink
 }
 
 type waitSignal int
@@ -71,29 +90,56 @@ type waitSignalValue struct {
 }
 
 // Starts the plugin and returns error if one occurred. This is non blocking.
+// The plugin is not started when its resource limits cannot be enforced.
 func (e *ExecutablePlugin) Start() error {
+	path := e.path
+	if !e.limits.IsZero() {
+		enforcer, err := prepareLimits(filepath.Base(path), e.cmd, e.limits, e.reportLimit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"_module":  "control-executableplugin",
+				"_block":   "start",
+				"cmd path": path,
+				"error":    err.Error(),
+			}).Error("error enforcing plugin resource limits")
+			return err
+		}
+		e.enforcer = enforcer
+	}
 	err := e.cmd.Start()
 	if err != nil {
+		if e.enforcer != nil {
+			e.enforcer.abort()
+			e.enforcer = nil
+		}
 		logrus.WithFields(logrus.Fields{
 			"_module":  "control-executableplugin",
 			"_block":   "start",
-			"cmd path": e.cmd.Path,
+			"cmd path": path,
 			"cmd args": e.cmd.Args,
 			"error":    err.Error(),
 		}).Error("error in starting executable plugin")
+		return err
 	}
-	return err
+	if e.enforcer != nil {
+		e.enforcer.started(e.cmd.Process.Pid)
+	}
+	return nil
 }
 
 // Kills the plugin and returns error if one occurred. This is blocking.
 func (e *ExecutablePlugin) Kill() error {
-	execLogger.WithField("path", e.cmd.Path).Debug("Hard killing plugin")
+	execLogger.WithField("path", e.path).Debug("Hard killing plugin")
 	return e.cmd.Process.Kill()
 }
 
 // Waits for plugin to halt. If error is returned then plugin stopped with error. If not plugin stopped safely.
 func (e *ExecutablePlugin) WaitForExit() error {
-	return e.cmd.Wait()
+	err := e.cmd.Wait()
+	if e.enforcer != nil {
+		e.enforcer.stop()
+	}
+	return err
 }
 
 // The STDOUT pipe for the plugin as io.Reader. Use to read from plugin process STDOUT.
@@ -106,6 +152,36 @@ func (e *ExecutablePlugin) ErrorResponseReader() io.Reader {
 	return e.stderr
 }
 
+// SetLogSink sends the output of the plugin to sink instead of the .stdout
+// and .stderr files next to its log.  It must be called before Start.
+func (e *ExecutablePlugin) SetLogSink(sink LogSink) {
+	e.sink = sink
+}
+
+// The sink the output of the plugin is sent to or nil when it is written to
+// files
+func (e *ExecutablePlugin) LogSink() LogSink {
+	return e.sink
+}
+
+// SetResourceLimits puts limits on the plugin process calling onLimit when
+// it reaches them.  It must be called before Start.
+func (e *ExecutablePlugin) SetResourceLimits(l *ResourceLimits, onLimit func(LimitViolation)) {
+	e.limits = l
+	e.onLimit = onLimit
+}
+
+func (e *ExecutablePlugin) reportLimit(v LimitViolation) {
+	execLogger.WithFields(logrus.Fields{
+		"_block": "limits",
+		"path":   e.path,
+		"limit":  v.Limit,
+	}).Warning(v.Message)
+	if e.onLimit != nil {
+		e.onLimit(v)
+	}
+}
+
 // Initialize a new ExecutablePlugin from path to executable and daemon mode (true or false)
 func NewExecutablePlugin(a Arg, path string) (*ExecutablePlugin, error) {
 	jsonArgs, err := json.Marshal(a)
@@ -128,6 +204,7 @@ func NewExecutablePlugin(a Arg, path string) (*ExecutablePlugin, error) {
 	// Init the ExecutablePlugin and return
 	ePlugin := new(ExecutablePlugin)
 	ePlugin.cmd = cmd
+	ePlugin.path = path
 	ePlugin.stdout = stdout
 	ePlugin.args = a
 	ePlugin.stderr = stderr
@@ -183,12 +260,18 @@ func waitHandling(p pluginExecutor, timeout time.Duration, logpath string) (*Res
 	log.Debug("timeout chan start")
 	go waitForPluginTimeout(timeout, p, waitChannel)
 
+	// without a sink the output of the plugin goes to files next to its log
+	sink := p.LogSink()
+	if sink == nil {
+		sink = newFileLogSink(logpath)
+	}
+
 	// send response received signal to our channel on response
 	log.Debug("response chan start")
-	go waitForResponseFromPlugin(p.ResponseReader(), waitChannel, logpath)
+	go waitForResponseFromPlugin(p.ResponseReader(), waitChannel, sink)
 
 	// log stderr from the plugin
-	go logStdErr(p.ErrorResponseReader(), logpath)
+	go logStdErr(p.ErrorResponseReader(), sink)
 
 	// send killed plugin signal to our channel on kill
 	log.Debug("kill chan start")
@@ -273,11 +356,8 @@ func waitForPluginTimeout(timeout time.Duration, p pluginExecutor, waitChannel c
 	waitChannel <- waitSignalValue{Signal: pluginTimeout}
 }
 
-func waitForResponseFromPlugin(r io.Reader, waitChannel chan waitSignalValue, logpath string) {
-	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
-	lf, _ := os.OpenFile(lp+".stdout", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	defer lf.Close()
-	logger := log.New(lf, "", log.Ldate|log.Ltime)
+func waitForResponseFromPlugin(r io.Reader, waitChannel chan waitSignalValue, sink LogSink) {
+	defer closeStream(sink, StdoutStream)
 	processedResponse := false
 	scanner := bufio.NewScanner(r)
 	resp := new(Response)
@@ -302,36 +382,79 @@ OK:
 			waitChannel <- waitSignalValue{Signal: pluginResponseOk, Response: resp}
 			processedResponse = true
 		} else {
-			logger.Println(scanner.Text())
+			sink.WriteLine(StdoutStream, scanner.Text())
 		}
 	}
 	if err := scanner.Err(); err != nil {
 		if err == bufio.ErrTooLong {
 			reader := bufio.NewReader(r)
-			logger.Println(reader.ReadLine())
+			line, _, _ := reader.ReadLine()
+			sink.WriteLine(StdoutStream, string(line))
 			goto OK
 		}
-		logger.Println(err)
+		sink.WriteLine(StdoutStream, err.Error())
 	}
 }
 
-func logStdErr(r io.Reader, logpath string) {
-	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
-	lf, _ := os.OpenFile(lp+".stderr", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	defer lf.Close()
-	logger := log.New(lf, "", log.Ldate|log.Ltime)
+func logStdErr(r io.Reader, sink LogSink) {
+	defer closeStream(sink, StderrStream)
 	scanner := bufio.NewScanner(r)
 OK:
 	for scanner.Scan() {
-		logger.Println(scanner.Text())
+		sink.WriteLine(StderrStream, scanner.Text())
 	}
 	if err := scanner.Err(); err != nil {
 		if err == bufio.ErrTooLong {
 			reader := bufio.NewReader(r)
-			logger.Println(reader.ReadLine())
+			line, _, _ := reader.ReadLine()
+			sink.WriteLine(StderrStream, string(line))
 			goto OK
 		}
-		logger.Println(err)
+		sink.WriteLine(StderrStream, err.Error())
+	}
+}
+
+// fileLogSink writes the output of a plugin to <log>.stdout and <log>.stderr
+// next to the log of the plugin
+type fileLogSink struct {
+	files   map[string]*os.File
+	loggers map[string]*log.Logger
+}
+
+func newFileLogSink(logpath string) *fileLogSink {
+	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
+	s := &fileLogSink{files: map[string]*os.File{}, loggers: map[string]*log.Logger{}}
+	for _, stream := range []string{StdoutStream, StderrStream} {
+		lf, err := os.OpenFile(lp+"."+stream, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			continue
+		}
+		s.files[stream] = lf
+		s.loggers[stream] = log.New(lf, "", log.Ldate|log.Ltime)
+	}
+	return s
+}
+
+func (s *fileLogSink) WriteLine(stream, line string) {
+	if l, ok := s.loggers[stream]; ok {
+		l.Println(line)
+	}
+}
+
+// closeStream closes the file of a stream once the plugin has closed it
+func (s *fileLogSink) closeStream(stream string) {
+	if f, ok := s.files[stream]; ok {
+		f.Close()
+	}
+}
+
+// closeStream lets the sink release what it holds for a stream the plugin has
+// closed
+func closeStream(sink LogSink, stream string) {
+	if c, ok := sink.(interface {
+		closeStream(string)
+	}); ok {
+		c.closeStream(stream)
 	}
 }
 
@@ -26,6 +26,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"testing"
@@ -56,6 +57,26 @@ type MockPluginExecutor struct {
 	WaitTime        time.Duration
 	WaitError       error
 	WaitForResponse func(time.Duration) (Response, error)
+	ErrorResponse   string
+	Sink            LogSink
+}
+
+// MockLogSink records the lines written to it
+type MockLogSink struct {
+	sync.Mutex
+	lines []string
+}
+
+func (m *MockLogSink) WriteLine(stream, line string) {
+	m.Lock()
+	defer m.Unlock()
+	m.lines = append(m.lines, stream+": "+line)
+}
+
+func (m *MockLogSink) Lines() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.lines...)
 }
 
 // Mock
@@ -86,11 +107,19 @@ func (m *MockPluginExecutor) ResponseReader() io.Reader {
 }
 
 func (m *MockPluginExecutor) ErrorResponseReader() io.Reader {
+	if m.ErrorResponse != "" {
+		return bytes.NewBufferString(m.ErrorResponse)
+	}
 	readbuffer := bytes.NewBuffer([]byte(m.Response))
 	reader := bufio.NewReader(readbuffer)
 	return reader
 }
 
+// Mock
+func (m *MockPluginExecutor) LogSink() LogSink {
+	return m.Sink
+}
+
 func TestNewExecutablePlugin(t *testing.T) {
 	Convey("pluginControl.WaitForResponse", t, func() {
 		c := new(MockController)
@@ -132,6 +161,23 @@ func TestWaitForPluginResponse(t *testing.T) {
 			})
 		})
 
+		Convey("called with PluginExecutor that has a log sink", func() {
+			mockExecutor := new(MockPluginExecutor)
+			mockExecutor.Response = "{}\nafter the response\n"
+			mockExecutor.ErrorResponse = "panic: oops\ngoroutine 1\n"
+			mockExecutor.WaitTime = time.Millisecond * 1
+			sink := &MockLogSink{}
+			mockExecutor.Sink = sink
+			resp, err := waitHandling(mockExecutor, time.Second*3, "/tmp/some.log")
+			So(resp, ShouldNotBeNil)
+			So(err, ShouldBeNil)
+			time.Sleep(time.Millisecond * 50)
+			So(sink.Lines(), ShouldContain, "stdout: after the response")
+			So(sink.Lines(), ShouldContain, "stderr: panic: oops")
+			So(sink.Lines(), ShouldContain, "stderr: goroutine 1")
+			So(sink.Lines(), ShouldNotContain, "stdout: {}")
+		})
+
 		Convey("called with PluginExecutor that returns an invalid response", func() {
 			mockExecutor := new(MockPluginExecutor)
 			mockExecutor.Response = "junk"
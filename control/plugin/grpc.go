@@ -0,0 +1,294 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/intelsdi-x/snap/control/plugin/rpc"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// NewGRPCMetric returns the gRPC message for the metric
+func NewGRPCMetric(m core.Metric) (*rpc.Metric, error) {
+	config, err := rpc.NewConfig(m.Config())
+	if err != nil {
+		return nil, err
+	}
+	gm := &rpc.Metric{
+		Namespace:          m.Namespace(),
+		Version:            int64(m.Version()),
+		Config:             config,
+		Tags:               m.Tags(),
+		Source:             m.Source(),
+		Timestamp:          newGRPCTime(m.Timestamp()),
+		LastAdvertisedTime: newGRPCTime(m.LastAdvertisedTime()),
+	}
+	for _, l := range m.Labels() {
+		gm.Labels = append(gm.Labels, &rpc.Label{Index: int64(l.Index), Name: l.Name})
+	}
+	if err := gm.SetValue(m.Data()); err != nil {
+		return nil, err
+	}
+	return gm, nil
+}
+
+// PluginMetricTypeFromGRPC returns the metric held by the gRPC message
+func PluginMetricTypeFromGRPC(m *rpc.Metric) (PluginMetricType, error) {
+	data, err := m.Value()
+	if err != nil {
+		return PluginMetricType{}, err
+	}
+	mt := PluginMetricType{
+		Namespace_:          m.GetNamespace(),
+		Version_:            int(m.GetVersion()),
+		Config_:             m.GetConfig().ConfigDataNode(),
+		Data_:               data,
+		Tags_:               m.GetTags(),
+		Source_:             m.GetSource(),
+		Timestamp_:          grpcTime(m.GetTimestamp()),
+		LastAdvertisedTime_: grpcTime(m.GetLastAdvertisedTime()),
+	}
+	for _, l := range m.GetLabels() {
+		mt.Labels_ = append(mt.Labels_, core.Label{Index: int(l.GetIndex()), Name: l.GetName()})
+	}
+	return mt, nil
+}
+
+func newGRPCTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts := timestamppb.New(t)
+	if ts.CheckValid() != nil {
+		return nil
+	}
+	return ts
+}
+
+func grpcTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil || ts.CheckValid() != nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// unencryptedGRPCMethods are the methods of a secure plugin which may be
+// called before the session key is exchanged
+var unencryptedGRPCMethods = map[string]bool{
+	"/rpc.SessionState/Ping":   true,
+	"/rpc.SessionState/SetKey": true,
+}
+
+// serveGRPC serves the gRPC services of the plugin on l.  Unless the plugin is
+// unsecure the messages of the calls are encrypted with the session key and
+// calls not using the encrypted codec are refused.
+func serveGRPC(l net.Listener, m *PluginMeta, c Plugin, s *SessionState) error {
+	var opts []grpc.ServerOption
+	if !m.Unsecure {
+		opts = append(opts,
+			grpc.ForceServerCodec(rpc.NewEncryptedCodec(s.Encrypter)),
+			grpc.UnaryInterceptor(requireEncryptedGRPC),
+		)
+	}
+	g := grpc.NewServer(opts...)
+	rpc.RegisterSessionStateServer(g, &sessionGRPCServer{session: s})
+	switch m.Type {
+	case CollectorPluginType:
+		rpc.RegisterCollectorServer(g, &collectorGRPCServer{Plugin: c.(CollectorPlugin), Session: s})
+	case ProcessorPluginType:
+		rpc.RegisterProcessorServer(g, &processorGRPCServer{Plugin: c.(ProcessorPlugin), Session: s})
+	case PublisherPluginType:
+		rpc.RegisterPublisherServer(g, &publisherGRPCServer{Plugin: c.(PublisherPlugin), Session: s})
+	}
+	return g.Serve(l)
+}
+
+// requireEncryptedGRPC refuses the calls which are not made with the encrypted
+// codec except those in unencryptedGRPCMethods
+func requireEncryptedGRPC(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !unencryptedGRPCMethods[info.FullMethod] && !encryptedGRPCCall(ctx) {
+		return nil, status.Errorf(codes.Unauthenticated, "%s must be called with the %s codec", info.FullMethod, rpc.EncryptedCodecName)
+	}
+	return handler(ctx, req)
+}
+
+// encryptedGRPCCall returns whether the call of ctx uses the encrypted codec
+// from its content-type, application/grpc+<codec>
+func encryptedGRPCCall(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, ct := range md["content-type"] {
+		if strings.HasSuffix(ct, "+"+rpc.EncryptedCodecName) {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionGRPCServer serves the SessionState gRPC service of a plugin
+type sessionGRPCServer struct {
+	session *SessionState
+}
+
+func (s *sessionGRPCServer) Ping(ctx context.Context, arg *rpc.Empty) (*rpc.Empty, error) {
+	s.session.ResetHeartbeat()
+	s.session.Logger().Println("Ping received")
+	return &rpc.Empty{}, nil
+}
+
+func (s *sessionGRPCServer) Kill(ctx context.Context, arg *rpc.KillArg) (*rpc.Empty, error) {
+	s.session.kill(arg.GetReason())
+	return &rpc.Empty{}, nil
+}
+
+func (s *sessionGRPCServer) GetConfigPolicy(ctx context.Context, arg *rpc.Empty) (*rpc.GetConfigPolicyReply, error) {
+	defer catchPluginPanic(s.session.Logger())
+	s.session.Logger().Println("GetConfigPolicy called")
+
+	policy, err := s.session.plugin.GetConfigPolicy()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("GetConfigPolicy call error : %s", err.Error()))
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.GetConfigPolicyReply{Policy: b}, nil
+}
+
+func (s *sessionGRPCServer) SetKey(ctx context.Context, arg *rpc.SetKeyArg) (*rpc.Empty, error) {
+	var reply []byte
+	if err := s.session.SetKey(SetKeyArgs{Key: arg.GetKey()}, &reply); err != nil {
+		return nil, err
+	}
+	return &rpc.Empty{}, nil
+}
+
+// collectorGRPCServer serves the Collector gRPC service of a plugin
+type collectorGRPCServer struct {
+	Plugin  CollectorPlugin
+	Session Session
+}
+
+func (c *collectorGRPCServer) GetMetricTypes(ctx context.Context, arg *rpc.GetMetricTypesArg) (*rpc.GetMetricTypesReply, error) {
+	defer catchPluginPanic(c.Session.Logger())
+	c.Session.Logger().Println("GetMetricTypes called")
+	c.Session.ResetHeartbeat()
+
+	config := PluginConfigType{ConfigDataNode: arg.GetConfig().ConfigDataNode()}
+	if config.ConfigDataNode == nil {
+		config.ConfigDataNode = cdata.NewNode()
+	}
+	mts, err := c.Plugin.GetMetricTypes(config)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("GetMetricTypes call error : %s", err.Error()))
+	}
+	metrics, err := newGRPCMetrics(mts)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.GetMetricTypesReply{Metrics: metrics}, nil
+}
+
+func (c *collectorGRPCServer) CollectMetrics(ctx context.Context, arg *rpc.CollectMetricsArg) (*rpc.CollectMetricsReply, error) {
+	defer catchPluginPanic(c.Session.Logger())
+	c.Session.Logger().Println("CollectMetrics called")
+	c.Session.ResetHeartbeat()
+
+	mts := make([]PluginMetricType, len(arg.GetMetrics()))
+	for i, m := range arg.GetMetrics() {
+		mt, err := PluginMetricTypeFromGRPC(m)
+		if err != nil {
+			return nil, err
+		}
+		mts[i] = mt
+	}
+	ms, err := c.Plugin.CollectMetrics(mts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("CollectMetrics call error : %s", err.Error()))
+	}
+	metrics, err := newGRPCMetrics(ms)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.CollectMetricsReply{Metrics: metrics}, nil
+}
+
+func newGRPCMetrics(mts []PluginMetricType) ([]*rpc.Metric, error) {
+	metrics := make([]*rpc.Metric, len(mts))
+	for i, mt := range mts {
+		m, err := NewGRPCMetric(mt)
+		if err != nil {
+			return nil, err
+		}
+		metrics[i] = m
+	}
+	return metrics, nil
+}
+
+// processorGRPCServer serves the Processor gRPC service of a plugin
+type processorGRPCServer struct {
+	Plugin  ProcessorPlugin
+	Session Session
+}
+
+func (p *processorGRPCServer) Process(ctx context.Context, arg *rpc.ProcessArg) (*rpc.ProcessReply, error) {
+	defer catchPluginPanic(p.Session.Logger())
+	p.Session.ResetHeartbeat()
+
+	contentType, content, err := p.Plugin.Process(arg.GetContentType(), arg.GetContent(), arg.GetConfig().ConfigMap())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Processor call error: %v", err.Error()))
+	}
+	return &rpc.ProcessReply{ContentType: contentType, Content: content}, nil
+}
+
+// publisherGRPCServer serves the Publisher gRPC service of a plugin
+type publisherGRPCServer struct {
+	Plugin  PublisherPlugin
+	Session Session
+}
+
+func (p *publisherGRPCServer) Publish(ctx context.Context, arg *rpc.PublishArg) (*rpc.Empty, error) {
+	defer catchPluginPanic(p.Session.Logger())
+	p.Session.ResetHeartbeat()
+
+	err := p.Plugin.Publish(arg.GetContentType(), arg.GetContent(), arg.GetConfig().ConfigMap())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Publish call error: %v", err.Error()))
+	}
+	return &rpc.Empty{}, nil
+}
@@ -0,0 +1,190 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/plugin/encrypter"
+	"github.com/intelsdi-x/snap/control/plugin/rpc"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// echoCollector returns the metrics it is asked for with their namespace
+// length as data
+type echoCollector struct {
+	MockPlugin
+}
+
+func (e *echoCollector) CollectMetrics(mts []PluginMetricType) ([]PluginMetricType, error) {
+	if len(mts) == 0 {
+		return nil, errors.New("nothing to collect")
+	}
+	for i := range mts {
+		mts[i].Data_ = len(mts[i].Namespace_)
+	}
+	return mts, nil
+}
+
+func (e *echoCollector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	cp := cpolicy.New()
+	n := cpolicy.NewPolicyNode()
+	r, _ := cpolicy.NewStringRule("name", false, "bob")
+	n.Add(r)
+	cp.Add([]string{"foo"}, n)
+	return cp, nil
+}
+
+func TestGRPCMetric(t *testing.T) {
+	Convey("gRPC metrics", t, func() {
+		config := cdata.NewNode()
+		config.AddItem("user", ctypes.ConfigValueStr{Value: "root"})
+		config.AddItem("port", ctypes.ConfigValueInt{Value: 22})
+		config.AddItem("ratio", ctypes.ConfigValueFloat{Value: 0.5})
+		config.AddItem("debug", ctypes.ConfigValueBool{Value: true})
+		now := time.Now()
+		mt := PluginMetricType{
+			Namespace_:          []string{"intel", "mock", "foo"},
+			Version_:            2,
+			Config_:             config,
+			Labels_:             []core.Label{{Index: 1, Name: "host"}},
+			Tags_:               map[string]string{"rack": "1"},
+			Source_:             "host1",
+			Timestamp_:          now,
+			LastAdvertisedTime_: now.Add(-time.Minute),
+		}
+
+		Convey("keep the metric", func() {
+			for _, data := range []interface{}{float32(1.5), 2.5, int32(3), int64(4), uint32(5), uint64(6), "seven", true, []byte("eight"), nil} {
+				mt.Data_ = data
+				m, err := NewGRPCMetric(mt)
+				So(err, ShouldBeNil)
+				back, err := PluginMetricTypeFromGRPC(m)
+				So(err, ShouldBeNil)
+				So(back.Data(), ShouldResemble, data)
+				So(back.Namespace(), ShouldResemble, mt.Namespace())
+				So(back.Version(), ShouldEqual, 2)
+				So(back.Config().Table(), ShouldResemble, config.Table())
+				So(back.Labels(), ShouldResemble, mt.Labels())
+				So(back.Tags(), ShouldResemble, mt.Tags())
+				So(back.Source(), ShouldEqual, "host1")
+				So(back.Timestamp().Equal(now), ShouldBeTrue)
+				So(back.LastAdvertisedTime().Equal(mt.LastAdvertisedTime()), ShouldBeTrue)
+			}
+		})
+
+		Convey("encode other data as JSON", func() {
+			mt.Data_ = map[string]int{"a": 1}
+			m, err := NewGRPCMetric(mt)
+			So(err, ShouldBeNil)
+			So(string(m.GetJsonData()), ShouldEqual, `{"a":1}`)
+			back, err := PluginMetricTypeFromGRPC(m)
+			So(err, ShouldBeNil)
+			So(back.Data(), ShouldResemble, map[string]interface{}{"a": float64(1)})
+		})
+
+		Convey("keep zero times and a missing config", func() {
+			m, err := NewGRPCMetric(PluginMetricType{Namespace_: []string{"foo"}})
+			So(err, ShouldBeNil)
+			So(m.GetTimestamp(), ShouldBeNil)
+			back, err := PluginMetricTypeFromGRPC(m)
+			So(err, ShouldBeNil)
+			So(back.Timestamp().IsZero(), ShouldBeTrue)
+			So(back.Config(), ShouldBeNil)
+		})
+	})
+}
+
+func TestServeGRPC(t *testing.T) {
+	Convey("A collector served over gRPC", t, func() {
+		m := &PluginMeta{Name: "echo", RPCType: GRPC, Type: CollectorPluginType}
+		s, err, _ := NewSessionState("{}", &echoCollector{}, m)
+		So(err, ShouldBeNil)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		go serveGRPC(l, m, &echoCollector{}, s)
+		defer l.Close()
+		conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(time.Second))
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		session := rpc.NewSessionStateClient(conn)
+		collector := rpc.NewCollectorClient(conn)
+		ctx := context.Background()
+
+		_, err = session.Ping(ctx, &rpc.Empty{})
+		So(err, ShouldBeNil)
+
+		Convey("exchanges the session key and encrypts the calls", func() {
+			e := encrypter.New(&s.privateKey.PublicKey, nil)
+			e.Key, _ = encrypter.GenerateKey()
+			key, err := e.EncryptKey()
+			So(err, ShouldBeNil)
+			_, err = session.SetKey(ctx, &rpc.SetKeyArg{Key: key})
+			So(err, ShouldBeNil)
+			So(s.Key, ShouldResemble, e.Key)
+			codec := rpc.NewEncryptedCodec(e).CallOptions()
+
+			mt, _ := NewGRPCMetric(PluginMetricType{Namespace_: []string{"intel", "echo"}})
+			reply, err := collector.CollectMetrics(ctx, &rpc.CollectMetricsArg{Metrics: []*rpc.Metric{mt}}, codec...)
+			So(err, ShouldBeNil)
+			So(reply.GetMetrics(), ShouldHaveLength, 1)
+			So(reply.GetMetrics()[0].GetInt64Data(), ShouldEqual, 2)
+
+			policy, err := session.GetConfigPolicy(ctx, &rpc.Empty{}, codec...)
+			So(err, ShouldBeNil)
+			So(string(policy.GetPolicy()), ShouldContainSubstring, "bob")
+
+			Convey("and returns the errors of the plugin", func() {
+				_, err := collector.CollectMetrics(ctx, &rpc.CollectMetricsArg{}, codec...)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "CollectMetrics call error : nothing to collect")
+			})
+
+			Convey("and refuses calls which are not encrypted", func() {
+				// the plain request cannot be decrypted by the server codec
+				_, err := collector.CollectMetrics(ctx, &rpc.CollectMetricsArg{Metrics: []*rpc.Metric{mt}})
+				So(err, ShouldNotBeNil)
+				_, err = session.GetConfigPolicy(ctx, &rpc.Empty{})
+				So(status.Code(err), ShouldEqual, codes.Unauthenticated)
+				_, err = session.Ping(ctx, &rpc.Empty{})
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and refuses calls encrypted with another key", func() {
+				other := encrypter.New(nil, nil)
+				other.Key, _ = encrypter.GenerateKey()
+				_, err := collector.CollectMetrics(ctx, &rpc.CollectMetricsArg{Metrics: []*rpc.Metric{mt}}, rpc.NewEncryptedCodec(other).CallOptions()...)
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
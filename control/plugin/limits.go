@@ -0,0 +1,131 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// LimitsShimArg is the first argument snapd is run with as the limits
+	// shim.  It is followed by the rlimits, the path of the plugin and the
+	// arguments of the plugin, which snapd passes to RunLimitsShim.
+	LimitsShimArg = "--plugin-limits-shim"
+
+	// MemoryLimit names the limit on the memory of a plugin
+	MemoryLimit = "memory"
+	// OpenFilesLimit names the limit on the files a plugin has open
+	OpenFilesLimit = "open_files"
+)
+
+var (
+	// LimitCheckInterval is how often a running plugin is checked for
+	// reaching its limits
+	LimitCheckInterval = 5 * time.Second
+
+	// LimitsShim is the command, with its arguments, a plugin process starts
+	// as to set its rlimits before it executes the plugin.  A child can only
+	// be given rlimits before it executes its program by running another in
+	// its place.  snapd sets it to itself with LimitsShimArg.  Open files
+	// cannot be limited without it.
+	LimitsShim []string
+
+	// ErrBadCPUShares - error message when the CPU shares of a plugin are out
+	// of range
+	ErrBadCPUShares = errors.New("cpu_shares must be between 2 and 262144")
+	// ErrLimitsNotSupported - error message when resource limits cannot be
+	// enforced on the platform
+	ErrLimitsNotSupported = errors.New("resource limits are not supported on this platform")
+)
+
+// ResourceLimits are the limits put on a plugin process before it executes.
+// A zero value is no limit.  Memory and CPU shares are enforced with a cgroup
+// v2 and open files with an rlimit set by the LimitsShim.
+type ResourceLimits struct {
+	// Memory is the most memory in bytes the plugin may use
+	Memory uint64 `json:"memory,omitempty"`
+	// CPUShares is the share of CPU time the plugin gets where a process
+	// without limits has 1024
+	CPUShares uint64 `json:"cpu_shares,omitempty"`
+	// OpenFiles is the most files the plugin may have open
+	OpenFiles uint64 `json:"open_files,omitempty"`
+	// WorkingDir is the directory the plugin runs in
+	WorkingDir string `json:"working_dir,omitempty"`
+	// UID is the user the plugin runs as
+	UID *uint32 `json:"uid,omitempty"`
+	// GID is the group the plugin runs as, by default the group of UID
+	GID *uint32 `json:"gid,omitempty"`
+}
+
+// Validate returns an error when a limit is out of range
+func (l *ResourceLimits) Validate() error {
+	if l.CPUShares != 0 && (l.CPUShares < 2 || l.CPUShares > 262144) {
+		return ErrBadCPUShares
+	}
+	return nil
+}
+
+// Merge returns the limits of l overridden by the limits set in o
+func (l *ResourceLimits) Merge(o *ResourceLimits) *ResourceLimits {
+	m := &ResourceLimits{}
+	if l != nil {
+		*m = *l
+	}
+	if o == nil {
+		return m
+	}
+	if o.Memory != 0 {
+		m.Memory = o.Memory
+	}
+	if o.CPUShares != 0 {
+		m.CPUShares = o.CPUShares
+	}
+	if o.OpenFiles != 0 {
+		m.OpenFiles = o.OpenFiles
+	}
+	if o.WorkingDir != "" {
+		m.WorkingDir = o.WorkingDir
+	}
+	if o.UID != nil {
+		m.UID = o.UID
+	}
+	if o.GID != nil {
+		m.GID = o.GID
+	}
+	return m
+}
+
+// IsZero returns whether no limit is set
+func (l *ResourceLimits) IsZero() bool {
+	return l == nil || *l == ResourceLimits{}
+}
+
+// LimitViolation is a plugin reaching one of its limits
+type LimitViolation struct {
+	// Limit is MemoryLimit or OpenFilesLimit
+	Limit   string
+	Message string
+}
+
+// cpuWeight converts CPU shares to the weight of a cgroup v2
+func cpuWeight(shares uint64) uint64 {
+	return 1 + ((shares-2)*9999)/262142
+}
@@ -0,0 +1,303 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// CgroupRoot is where the cgroup v2 hierarchy is mounted
+	CgroupRoot = "/sys/fs/cgroup"
+	// CgroupParent is the cgroup, relative to CgroupRoot, which the cgroups
+	// of plugin processes are created under
+	CgroupParent = "snap"
+
+	errNoCgroup2    = errors.New("no cgroup v2 hierarchy")
+	errNoLimitsShim = errors.New("open files cannot be limited without the limits shim")
+)
+
+// RunLimitsShim sets the rlimits in args[0] and executes the plugin at args[1]
+// with the arguments args[2:] in place of the process.  It exits when a limit
+// cannot be set.  It is run by snapd when started with LimitsShimArg.
+func RunLimitsShim(args []string) {
+	if len(args) < 3 {
+		limitsShimFail(errors.New("missing the rlimits or the plugin"))
+	}
+	for _, r := range strings.Split(args[0], ",") {
+		var resource int
+		var value uint64
+		if _, err := fmt.Sscanf(r, "%d=%d", &resource, &value); err != nil {
+			limitsShimFail(fmt.Errorf("bad rlimit %q", r))
+		}
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			limitsShimFail(fmt.Errorf("rlimit %d: %v", resource, err))
+		}
+	}
+	limitsShimFail(syscall.Exec(args[1], args[2:], os.Environ()))
+}
+
+func limitsShimFail(err error) {
+	fmt.Fprintln(os.Stderr, "unable to enforce plugin resource limits:", err)
+	os.Exit(126)
+}
+
+// limitEnforcer holds the limits of a running plugin process and reports the
+// process reaching them
+type limitEnforcer struct {
+	sync.Mutex
+	pid      int
+	limits   *ResourceLimits
+	cgroup   string
+	cgroupFD *os.File
+	report   func(LimitViolation)
+	done     chan struct{}
+
+	// the counts of memory.events last seen
+	memoryMax uint64
+	oomKills  uint64
+	// whether the process was at its open files limit when last checked
+	atOpenFiles bool
+}
+
+// prepareLimits sets up cmd so the plugin process starts with the limits
+// already in place: in its cgroup, with its rlimits, in its working directory
+// and as its user.  An error is returned when a limit cannot be enforced,
+// such as memory or CPU shares without a cgroup.
+func prepareLimits(name string, cmd *exec.Cmd, l *ResourceLimits, report func(LimitViolation)) (*limitEnforcer, error) {
+	e := &limitEnforcer{
+		limits: l,
+		report: report,
+		done:   make(chan struct{}),
+	}
+	cmd.Dir = l.WorkingDir
+	attr := &syscall.SysProcAttr{}
+	if l.UID != nil {
+		gid, err := limitsGID(l)
+		if err != nil {
+			return nil, err
+		}
+		attr.Credential = &syscall.Credential{Uid: *l.UID, Gid: gid}
+	}
+	rlimits := []string{}
+	if l.OpenFiles > 0 {
+		rlimits = append(rlimits, fmt.Sprintf("%d=%d", syscall.RLIMIT_NOFILE, l.OpenFiles))
+	}
+	if l.Memory > 0 || l.CPUShares > 0 {
+		cg, err := createCgroup(name, l)
+		switch {
+		case err == nil:
+			f, err := os.Open(cg)
+			if err != nil {
+				os.Remove(cg)
+				return nil, fmt.Errorf("cgroup: %v", err)
+			}
+			e.cgroup, e.cgroupFD = cg, f
+			attr.UseCgroupFD = true
+			attr.CgroupFD = int(f.Fd())
+		case l.CPUShares > 0:
+			return nil, fmt.Errorf("cpu shares need a cgroup: %v", err)
+		default:
+			return nil, fmt.Errorf("memory needs a cgroup: %v", err)
+		}
+	}
+	if len(rlimits) > 0 {
+		if len(LimitsShim) == 0 {
+			e.abort()
+			return nil, errNoLimitsShim
+		}
+		args := append(append([]string{}, LimitsShim...), strings.Join(rlimits, ","), cmd.Path)
+		cmd.Args = append(args, cmd.Args...)
+		cmd.Path = LimitsShim[0]
+	}
+	cmd.SysProcAttr = attr
+	return e, nil
+}
+
+// limitsGID returns the group the plugin runs as
+func limitsGID(l *ResourceLimits) (uint32, error) {
+	if l.GID != nil {
+		return *l.GID, nil
+	}
+	u, err := user.LookupId(strconv.FormatUint(uint64(*l.UID), 10))
+	if err != nil {
+		return 0, err
+	}
+	g, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(g), nil
+}
+
+// started watches the process pid, started with the limits, reach them
+// until the enforcer is stopped
+func (e *limitEnforcer) started(pid int) {
+	e.closeCgroupFD()
+	e.pid = pid
+	go e.watch()
+}
+
+// abort removes the cgroup of a process which did not start
+func (e *limitEnforcer) abort() {
+	e.closeCgroupFD()
+	if e.cgroup != "" {
+		os.Remove(e.cgroup)
+	}
+}
+
+func (e *limitEnforcer) closeCgroupFD() {
+	if e.cgroupFD != nil {
+		e.cgroupFD.Close()
+		e.cgroupFD = nil
+	}
+}
+
+func (e *limitEnforcer) watch() {
+	t := time.NewTicker(LimitCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-t.C:
+			e.check(true)
+		}
+	}
+}
+
+// stop stops watching the process, which has exited, and removes its cgroup
+func (e *limitEnforcer) stop() {
+	close(e.done)
+	e.check(false)
+	if e.cgroup != "" {
+		os.Remove(e.cgroup)
+	}
+}
+
+// check reports the limits reached since the last check.  The open files of
+// the process are only counted while it runs.
+func (e *limitEnforcer) check(running bool) {
+	e.Lock()
+	defer e.Unlock()
+	if e.cgroup != "" {
+		events := readKeyedFile(filepath.Join(e.cgroup, "memory.events"))
+		if n := events["max"]; n > e.memoryMax {
+			e.memoryMax = n
+			e.report(LimitViolation{
+				Limit:   MemoryLimit,
+				Message: fmt.Sprintf("memory limit of %d bytes reached", e.limits.Memory),
+			})
+		}
+		if n := events["oom_kill"]; n > e.oomKills {
+			e.oomKills = n
+			e.report(LimitViolation{
+				Limit:   MemoryLimit,
+				Message: fmt.Sprintf("killed for exceeding the memory limit of %d bytes", e.limits.Memory),
+			})
+		}
+	}
+	if e.limits.OpenFiles > 0 && running {
+		fds, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/fd", e.pid))
+		if err != nil {
+			return
+		}
+		at := uint64(len(fds)) >= e.limits.OpenFiles
+		if at && !e.atOpenFiles {
+			e.report(LimitViolation{
+				Limit:   OpenFilesLimit,
+				Message: fmt.Sprintf("open files limit of %d reached", e.limits.OpenFiles),
+			})
+		}
+		e.atOpenFiles = at
+	}
+}
+
+// createCgroup creates a cgroup named after name under CgroupParent with the
+// memory and CPU limits
+func createCgroup(name string, l *ResourceLimits) (string, error) {
+	if _, err := os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errNoCgroup2
+	}
+	parent := filepath.Join(CgroupRoot, CgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	// the controllers must be enabled in every cgroup above the one of the
+	// plugin.  Errors are ignored as they may be enabled already.
+	dir := CgroupRoot
+	for _, p := range append([]string{""}, strings.Split(filepath.Clean(CgroupParent), "/")...) {
+		dir = filepath.Join(dir, p)
+		for _, c := range []string{"+memory", "+cpu"} {
+			ioutil.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(c), 0644)
+		}
+	}
+	cg, err := ioutil.TempDir(parent, name+"-")
+	if err != nil {
+		return "", err
+	}
+	settings := [][2]string{}
+	if l.Memory > 0 {
+		settings = append(settings, [2]string{"memory.max", strconv.FormatUint(l.Memory, 10)})
+	}
+	if l.CPUShares > 0 {
+		settings = append(settings, [2]string{"cpu.weight", strconv.FormatUint(cpuWeight(l.CPUShares), 10)})
+	}
+	for _, s := range settings {
+		if err := ioutil.WriteFile(filepath.Join(cg, s[0]), []byte(s[1]), 0644); err != nil {
+			os.Remove(cg)
+			return "", err
+		}
+	}
+	return cg, nil
+}
+
+// readKeyedFile reads a cgroup file of lines holding a key and a value
+func readKeyedFile(path string) map[string]uint64 {
+	m := map[string]uint64{}
+	f, err := os.Open(path)
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			m[fields[0]] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,191 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestMain runs the test binary as the limits shim, the way snapd runs itself
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == LimitsShimArg {
+		RunLimitsShim(os.Args[2:])
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	LimitsShim = []string{exe, LimitsShimArg}
+	os.Exit(m.Run())
+}
+
+// procLimit returns the soft limit of the process pid from /proc/<pid>/limits
+func procLimit(pid int, name string) string {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, name) {
+			return strings.Fields(strings.TrimPrefix(line, name))[0]
+		}
+	}
+	return ""
+}
+
+type violations struct {
+	sync.Mutex
+	v []LimitViolation
+}
+
+func (v *violations) report(l LimitViolation) {
+	v.Lock()
+	defer v.Unlock()
+	v.v = append(v.v, l)
+}
+
+func (v *violations) get() []LimitViolation {
+	v.Lock()
+	defer v.Unlock()
+	return append([]LimitViolation{}, v.v...)
+}
+
+func TestEnforceLimits(t *testing.T) {
+	Convey("Enforcing limits on a process", t, func() {
+		root, err := ioutil.TempDir("", "snap-cgroup")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(root)
+		oldRoot, oldInterval := CgroupRoot, LimitCheckInterval
+		CgroupRoot, LimitCheckInterval = root, 10*time.Millisecond
+		defer func() { CgroupRoot, LimitCheckInterval = oldRoot, oldInterval }()
+
+		v := &violations{}
+		start := func(l *ResourceLimits, cmd *exec.Cmd) (*exec.Cmd, *limitEnforcer, error) {
+			e, err := prepareLimits(filepath.Base(cmd.Path), cmd, l, v.report)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := cmd.Start(); err != nil {
+				e.abort()
+				return nil, nil, err
+			}
+			e.started(cmd.Process.Pid)
+			return cmd, e, nil
+		}
+
+		Convey("limits its open files before it executes and reports reaching them", func() {
+			// the shell opens files up to its limit then waits on its input
+			sh := exec.Command("sh", "-c", "exec 3</dev/null 4</dev/null; read x")
+			in, err := sh.StdinPipe()
+			So(err, ShouldBeNil)
+			defer in.Close()
+			cmd, e, err := start(&ResourceLimits{OpenFiles: 5}, sh)
+			So(err, ShouldBeNil)
+			defer cmd.Process.Kill()
+			time.Sleep(200 * time.Millisecond)
+			So(procLimit(cmd.Process.Pid, "Max open files"), ShouldEqual, "5")
+			e.stop()
+			// reported once while the process stays at its limit
+			So(v.get(), ShouldResemble, []LimitViolation{{Limit: OpenFilesLimit, Message: "open files limit of 5 reached"}})
+		})
+
+		Convey("fails to limit its open files without the limits shim", func() {
+			shim := LimitsShim
+			LimitsShim = nil
+			defer func() { LimitsShim = shim }()
+			_, _, err := start(&ResourceLimits{OpenFiles: 5}, exec.Command("sleep", "10"))
+			So(err, ShouldEqual, errNoLimitsShim)
+		})
+
+		Convey("fails to limit its memory without a cgroup v2 hierarchy", func() {
+			_, _, err := start(&ResourceLimits{Memory: 1 << 30}, exec.Command("sleep", "10"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, errNoCgroup2.Error())
+		})
+
+		Convey("fails to limit its CPU shares without a cgroup v2 hierarchy", func() {
+			_, _, err := start(&ResourceLimits{CPUShares: 512}, exec.Command("sleep", "10"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, errNoCgroup2.Error())
+		})
+
+		Convey("fails to start it when a limit cannot be set", func() {
+			cmd, _, err := start(&ResourceLimits{OpenFiles: 1 << 62}, exec.Command("sleep", "10"))
+			So(err, ShouldBeNil)
+			So(cmd.Wait(), ShouldNotBeNil)
+		})
+
+		Convey("starts it in a cgroup", func() {
+			So(ioutil.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644), ShouldBeNil)
+			cmd := exec.Command("sleep", "10")
+			e, err := prepareLimits("sleep", cmd, &ResourceLimits{Memory: 1 << 30, CPUShares: 512}, v.report)
+			So(err, ShouldBeNil)
+			So(filepath.Dir(e.cgroup), ShouldEqual, filepath.Join(root, CgroupParent))
+			So(filepath.Base(e.cgroup), ShouldStartWith, "sleep-")
+			So(cmd.SysProcAttr.UseCgroupFD, ShouldBeTrue)
+			So(cmd.SysProcAttr.CgroupFD, ShouldEqual, int(e.cgroupFD.Fd()))
+			read := func(f string) string {
+				b, _ := ioutil.ReadFile(filepath.Join(e.cgroup, f))
+				return string(b)
+			}
+			So(read("memory.max"), ShouldEqual, fmt.Sprint(1<<30))
+			So(read("cpu.weight"), ShouldEqual, "20")
+			b, _ := ioutil.ReadFile(filepath.Join(root, CgroupParent, "cgroup.subtree_control"))
+			So(string(b), ShouldEqual, "+cpu")
+
+			Convey("and reports it running out of memory", func() {
+				// the hierarchy is not a real one so the process is not started
+				// in the cgroup
+				e.started(os.Getpid())
+				So(e.cgroupFD, ShouldBeNil)
+				So(ioutil.WriteFile(filepath.Join(e.cgroup, "memory.events"), []byte("low 0\nhigh 0\nmax 2\noom 1\noom_kill 1\n"), 0644), ShouldBeNil)
+				time.Sleep(100 * time.Millisecond)
+				So(v.get(), ShouldResemble, []LimitViolation{
+					{Limit: MemoryLimit, Message: "memory limit of 1073741824 bytes reached"},
+					{Limit: MemoryLimit, Message: "killed for exceeding the memory limit of 1073741824 bytes"},
+				})
+				e.stop()
+			})
+
+			Convey("which is removed when it does not start", func() {
+				// unlike in a real hierarchy the files of the cgroup keep it
+				// from being removed
+				for _, f := range []string{"memory.max", "cpu.weight"} {
+					So(os.Remove(filepath.Join(e.cgroup, f)), ShouldBeNil)
+				}
+				e.abort()
+				So(e.cgroupFD, ShouldBeNil)
+				_, err := os.Stat(e.cgroup)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+	})
+}
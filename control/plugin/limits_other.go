@@ -0,0 +1,53 @@
+//go:build !linux
+// +build !linux
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+type limitEnforcer struct{}
+
+// prepareLimits sets the working directory the plugin process starts in and
+// returns an error for any other limit as they are only supported on Linux
+func prepareLimits(name string, cmd *exec.Cmd, l *ResourceLimits, report func(LimitViolation)) (*limitEnforcer, error) {
+	cmd.Dir = l.WorkingDir
+	if l.Memory > 0 || l.CPUShares > 0 || l.OpenFiles > 0 || l.UID != nil || l.GID != nil {
+		return nil, ErrLimitsNotSupported
+	}
+	return &limitEnforcer{}, nil
+}
+
+// RunLimitsShim exits as rlimits are only supported on Linux
+func RunLimitsShim(args []string) {
+	fmt.Fprintln(os.Stderr, "unable to enforce plugin resource limits:", ErrLimitsNotSupported)
+	os.Exit(126)
+}
+
+func (e *limitEnforcer) started(pid int) {}
+
+func (e *limitEnforcer) abort() {}
+
+func (e *limitEnforcer) stop() {}
@@ -0,0 +1,65 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResourceLimits(t *testing.T) {
+	Convey("Resource limits", t, func() {
+		uid := uint32(1000)
+
+		Convey("are overridden by the limits set in another", func() {
+			l := &ResourceLimits{Memory: 1 << 20, OpenFiles: 64, WorkingDir: "/tmp"}
+			m := l.Merge(&ResourceLimits{OpenFiles: 128, UID: &uid})
+			So(m, ShouldResemble, &ResourceLimits{Memory: 1 << 20, OpenFiles: 128, WorkingDir: "/tmp", UID: &uid})
+			// the limits merged are left alone
+			So(l.OpenFiles, ShouldEqual, 64)
+			So(l.UID, ShouldBeNil)
+		})
+
+		Convey("can be merged when unset", func() {
+			var l *ResourceLimits
+			So(l.IsZero(), ShouldBeTrue)
+			So(l.Merge(nil).IsZero(), ShouldBeTrue)
+			So(l.Merge(&ResourceLimits{CPUShares: 512}), ShouldResemble, &ResourceLimits{CPUShares: 512})
+		})
+
+		Convey("are validated", func() {
+			So((&ResourceLimits{CPUShares: 1024}).Validate(), ShouldBeNil)
+			So((&ResourceLimits{CPUShares: 1}).Validate(), ShouldEqual, ErrBadCPUShares)
+			So((&ResourceLimits{CPUShares: 300000}).Validate(), ShouldEqual, ErrBadCPUShares)
+		})
+
+		Convey("convert CPU shares to a cgroup v2 weight", func() {
+			So(cpuWeight(2), ShouldEqual, 1)
+			So(cpuWeight(1024), ShouldEqual, 39)
+			So(cpuWeight(262144), ShouldEqual, 10000)
+		})
+
+		Convey("can be declared in the plugin meta", func() {
+			m := NewPluginMeta("mock", 1, CollectorPluginType, nil, nil, Limits(ResourceLimits{Memory: 1 << 20}))
+			So(m.ResourceLimits, ShouldResemble, &ResourceLimits{Memory: 1 << 20})
+		})
+	})
+}
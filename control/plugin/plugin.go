@@ -31,6 +31,7 @@ import (
 	"net/http"
 	"net/rpc"
 	"net/rpc/jsonrpc"
+	"os"
 	"regexp"
 	"runtime"
 	"time"
@@ -69,6 +70,12 @@ const (
 	// Using this strategy enables a running database plugin that has the same connection info between
 	// two tasks to be shared.
 	ConfigRouting
+	// RoundRobinRouting sends requests to each running instance of a plugin
+	// in turn.
+	RoundRobinRouting
+	// LeastOutstandingRouting sends requests to the running instance of a
+	// plugin with the fewest requests in flight.
+	LeastOutstandingRouting
 )
 
 // Plugin response states
@@ -81,9 +88,15 @@ const (
 
 type RPCType int
 
+// UnixAddressPrefix prefixes the listen address of a plugin listening on a
+// Unix socket
+const UnixAddressPrefix = "unix://"
+
 const (
 	NativeRPC RPCType = iota
 	JSONRPC
+	// GRPC plugins serve the gRPC services defined in control/plugin/rpc
+	GRPC
 )
 
 var (
@@ -105,6 +118,8 @@ var (
 		"least-recently-used",
 		"sticky",
 		"config",
+		"round-robin",
+		"least-outstanding-requests",
 	}
 )
 
@@ -138,6 +153,9 @@ type PluginMeta struct {
 	// RoutingStrategy will override the routing strategy this plugin requires.
 	// The default routing strategy round-robin.
 	RoutingStrategy RoutingStrategyType
+	// ResourceLimits are the limits put on the processes of the plugin.  The
+	// config of snapd overrides them.
+	ResourceLimits *ResourceLimits
 }
 
 type metaOp func(m *PluginMeta)
@@ -177,6 +195,13 @@ func CacheTTL(t time.Duration) metaOp {
 	}
 }
 
+// Limits is an option that can be be provided to the func NewPluginMeta.
+func Limits(l ResourceLimits) metaOp {
+	return func(m *PluginMeta) {
+		m.ResourceLimits = &l
+	}
+}
+
 // NewPluginMeta constructs and returns a PluginMeta struct
 func NewPluginMeta(name string, version int, pluginType PluginType, acceptContentTypes, returnContentTypes []string, opts ...metaOp) *PluginMeta {
 	// An empty accepted content type default to "snap.*"
@@ -231,6 +256,10 @@ type Arg struct {
 	NoDaemon bool
 	// The listen port
 	listenPort string
+	// ListenSocket is the path of the Unix socket to listen on.  The plugin
+	// listens on a TCP port of 127.0.0.1 when it is empty or the socket
+	// cannot be listened on.
+	ListenSocket string
 }
 
 func NewArg(logpath string) Arg {
@@ -332,14 +361,13 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 		}
 	}
 
-	l, err := net.Listen("tcp", "127.0.0.1:"+s.ListenPort())
+	l, err := listen(s)
 	if err != nil {
 		s.Logger().Println(err.Error())
 		panic(err)
 	}
-	s.SetListenAddress(l.Addr().String())
-	s.Logger().Printf("Listening %s\n", l.Addr())
-	s.Logger().Printf("Session token %s\n", s.Token())
+	s.SetListenAddress(listenAddress(l))
+	s.Logger().Printf("Listening %s\n", s.ListenAddress())
 
 	switch r.Meta.RPCType {
 	case JSONRPC:
@@ -374,6 +402,8 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 				go rpc.ServeConn(conn)
 			}
 		}()
+	case GRPC:
+		go serveGRPC(l, m, c, s)
 	default:
 		panic("Unsupported RPC type")
 	}
@@ -391,6 +421,32 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 	return nil, exitCode
 }
 
+// listen listens on the Unix socket given by the plugin args, which only the
+// user running the plugin may connect to, falling back to a TCP port of
+// 127.0.0.1
+func listen(s *SessionState) (net.Listener, error) {
+	if s.ListenSocket != "" {
+		l, err := net.Listen("unix", s.ListenSocket)
+		if err == nil {
+			if err = os.Chmod(s.ListenSocket, 0600); err == nil {
+				return l, nil
+			}
+			l.Close()
+		}
+		s.Logger().Printf("Cannot listen on %s, falling back to TCP: %v\n", s.ListenSocket, err)
+	}
+	return net.Listen("tcp", "127.0.0.1:"+s.ListenPort())
+}
+
+// listenAddress returns the address to give in the response of the plugin.
+// The path of a Unix socket is prefixed by UnixAddressPrefix.
+func listenAddress(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return UnixAddressPrefix + l.Addr().String()
+	}
+	return l.Addr().String()
+}
+
 // rpcRequest represents a RPC request.
 // rpcRequest implements the io.ReadWriteCloser interface.
 type rpcRequest struct {
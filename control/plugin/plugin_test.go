@@ -20,6 +20,11 @@ limitations under the License.
 package plugin
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -97,3 +102,41 @@ func TestPlugin(t *testing.T) {
 		So(mockPluginMeta.CacheTTL, ShouldEqual, time.Duration(100*time.Millisecond))
 	})
 }
+
+func TestListen(t *testing.T) {
+	Convey("listen", t, func() {
+		dir, err := ioutil.TempDir("", "snap-sockets")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		m := &PluginMeta{Name: "mock", Type: CollectorPluginType, Unsecure: true}
+		session := func(socket string) *SessionState {
+			a, _ := json.Marshal(Arg{ListenSocket: socket})
+			s, err, _ := NewSessionState(string(a), &MockPlugin{}, m)
+			So(err, ShouldBeNil)
+			return s
+		}
+
+		Convey("listens on the Unix socket given by the args", func() {
+			sock := filepath.Join(dir, "mock-1.sock")
+			l, err := listen(session(sock))
+			So(err, ShouldBeNil)
+			defer l.Close()
+			So(listenAddress(l), ShouldEqual, UnixAddressPrefix+sock)
+			fi, err := os.Stat(sock)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		})
+
+		Convey("falls back to TCP", func() {
+			l, err := listen(session(filepath.Join(dir, "missing", "mock-1.sock")))
+			So(err, ShouldBeNil)
+			defer l.Close()
+			So(strings.HasPrefix(listenAddress(l), "127.0.0.1:"), ShouldBeTrue)
+
+			l2, err := listen(session(""))
+			So(err, ShouldBeNil)
+			defer l2.Close()
+			So(strings.HasPrefix(listenAddress(l2), "127.0.0.1:"), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/intelsdi-x/snap/control/plugin/encrypter"
+)
+
+// EncryptedCodecName is the content-subtype of the calls to a plugin whose
+// messages are encrypted with the session key
+const EncryptedCodecName = "snap-encrypted"
+
+// EncryptedCodec marshals messages as protobuf and encrypts them with the
+// session key exchanged by SetKey.  A plugin serves its calls with it through
+// grpc.ForceServerCodec and the client passes its CallOptions to each call but
+// Ping and SetKey.  SetKeyArg, whose key is encrypted with the public key of
+// the plugin, and Empty, which carries nothing, are left as plain protobuf so
+// that Ping and SetKey can be served before the session key is known.
+type EncryptedCodec struct {
+	encrypter *encrypter.Encrypter
+}
+
+// NewEncryptedCodec returns a codec encrypting with the key of e
+func NewEncryptedCodec(e *encrypter.Encrypter) *EncryptedCodec {
+	return &EncryptedCodec{encrypter: e}
+}
+
+// Marshal returns the encrypted protobuf encoding of v
+func (c *EncryptedCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil || plainMessage(m) {
+		return b, err
+	}
+	return c.encrypter.Encrypt(bytes.NewReader(b))
+}
+
+// Unmarshal decrypts data into v
+func (c *EncryptedCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+	if plainMessage(m) {
+		return proto.Unmarshal(data, m)
+	}
+	b, err := c.encrypter.Decrypt(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// plainMessage reports whether m is exchanged without encryption
+func plainMessage(m proto.Message) bool {
+	switch m.(type) {
+	case *SetKeyArg, *Empty:
+		return true
+	}
+	return false
+}
+
+// CallOptions returns the options making a call use the codec
+func (c *EncryptedCodec) CallOptions() []grpc.CallOption {
+	return []grpc.CallOption{
+		grpc.CallContentSubtype(EncryptedCodecName),
+		grpc.ForceCodec(c),
+	}
+}
+
+// Name returns the content-subtype of the codec
+func (c *EncryptedCodec) Name() string {
+	return EncryptedCodecName
+}
+
+// String returns the content-subtype of the codec
+func (c *EncryptedCodec) String() string {
+	return EncryptedCodecName
+}
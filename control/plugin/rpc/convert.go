@@ -0,0 +1,159 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// NewConfig returns the message holding the config node or nil for a nil
+// node
+func NewConfig(node *cdata.ConfigDataNode) (*Config, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return NewConfigMap(node.Table())
+}
+
+// NewConfigMap returns the message holding the config values
+func NewConfigMap(table map[string]ctypes.ConfigValue) (*Config, error) {
+	c := &Config{Table: make(map[string]*ConfigValue, len(table))}
+	for k, v := range table {
+		cv := &ConfigValue{}
+		switch v := v.(type) {
+		case ctypes.ConfigValueStr:
+			cv.Value = &ConfigValue_Str{Str: v.Value}
+		case ctypes.ConfigValueInt:
+			cv.Value = &ConfigValue_Int{Int: int64(v.Value)}
+		case ctypes.ConfigValueFloat:
+			cv.Value = &ConfigValue_Float{Float: v.Value}
+		case ctypes.ConfigValueBool:
+			cv.Value = &ConfigValue_Bool{Bool: v.Value}
+		default:
+			return nil, fmt.Errorf("unsupported type of config value %s: %T", k, v)
+		}
+		c.Table[k] = cv
+	}
+	return c, nil
+}
+
+// ConfigMap returns the config values held by the message
+func (c *Config) ConfigMap() map[string]ctypes.ConfigValue {
+	table := map[string]ctypes.ConfigValue{}
+	for k, v := range c.GetTable() {
+		switch v := v.GetValue().(type) {
+		case *ConfigValue_Str:
+			table[k] = ctypes.ConfigValueStr{Value: v.Str}
+		case *ConfigValue_Int:
+			table[k] = ctypes.ConfigValueInt{Value: int(v.Int)}
+		case *ConfigValue_Float:
+			table[k] = ctypes.ConfigValueFloat{Value: v.Float}
+		case *ConfigValue_Bool:
+			table[k] = ctypes.ConfigValueBool{Value: v.Bool}
+		}
+	}
+	return table
+}
+
+// ConfigDataNode returns the config node held by the message or nil for a
+// nil message
+func (c *Config) ConfigDataNode() *cdata.ConfigDataNode {
+	if c == nil {
+		return nil
+	}
+	node := cdata.NewNode()
+	for k, v := range c.ConfigMap() {
+		node.AddItem(k, v)
+	}
+	return node
+}
+
+// SetValue sets the data of the metric.  Data of a type without a field of
+// its own is encoded as JSON.
+func (m *Metric) SetValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		m.Data = nil
+	case float32:
+		m.Data = &Metric_Float32Data{Float32Data: v}
+	case float64:
+		m.Data = &Metric_Float64Data{Float64Data: v}
+	case int:
+		m.Data = &Metric_Int64Data{Int64Data: int64(v)}
+	case int32:
+		m.Data = &Metric_Int32Data{Int32Data: v}
+	case int64:
+		m.Data = &Metric_Int64Data{Int64Data: v}
+	case uint:
+		m.Data = &Metric_Uint64Data{Uint64Data: uint64(v)}
+	case uint32:
+		m.Data = &Metric_Uint32Data{Uint32Data: v}
+	case uint64:
+		m.Data = &Metric_Uint64Data{Uint64Data: v}
+	case string:
+		m.Data = &Metric_StringData{StringData: v}
+	case bool:
+		m.Data = &Metric_BoolData{BoolData: v}
+	case []byte:
+		m.Data = &Metric_BytesData{BytesData: v}
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		m.Data = &Metric_JsonData{JsonData: b}
+	}
+	return nil
+}
+
+// Value returns the data of the metric
+func (m *Metric) Value() (interface{}, error) {
+	switch d := m.GetData().(type) {
+	case *Metric_Float32Data:
+		return d.Float32Data, nil
+	case *Metric_Float64Data:
+		return d.Float64Data, nil
+	case *Metric_Int32Data:
+		return d.Int32Data, nil
+	case *Metric_Int64Data:
+		return d.Int64Data, nil
+	case *Metric_Uint32Data:
+		return d.Uint32Data, nil
+	case *Metric_Uint64Data:
+		return d.Uint64Data, nil
+	case *Metric_StringData:
+		return d.StringData, nil
+	case *Metric_BoolData:
+		return d.BoolData, nil
+	case *Metric_BytesData:
+		return d.BytesData, nil
+	case *Metric_JsonData:
+		var v interface{}
+		if err := json.Unmarshal(d.JsonData, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return nil, nil
+}
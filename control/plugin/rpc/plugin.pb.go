@@ -0,0 +1,1253 @@
+// http://www.apache.org/licenses/LICENSE-2.0.txt
+//
+//
+// Copyright 2015 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: plugin.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_plugin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{0}
+}
+
+type KillArg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KillArg) Reset() {
+	*x = KillArg{}
+	mi := &file_plugin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KillArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KillArg) ProtoMessage() {}
+
+func (x *KillArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KillArg.ProtoReflect.Descriptor instead.
+func (*KillArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *KillArg) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type SetKeyArg struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The session key encrypted with the public key of the plugin
+	Key           []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetKeyArg) Reset() {
+	*x = SetKeyArg{}
+	mi := &file_plugin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetKeyArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetKeyArg) ProtoMessage() {}
+
+func (x *SetKeyArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetKeyArg.ProtoReflect.Descriptor instead.
+func (*SetKeyArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetKeyArg) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type GetConfigPolicyReply struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The config policy of the plugin encoded as JSON
+	Policy        []byte `protobuf:"bytes,1,opt,name=policy,proto3" json:"policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConfigPolicyReply) Reset() {
+	*x = GetConfigPolicyReply{}
+	mi := &file_plugin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConfigPolicyReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConfigPolicyReply) ProtoMessage() {}
+
+func (x *GetConfigPolicyReply) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConfigPolicyReply.ProtoReflect.Descriptor instead.
+func (*GetConfigPolicyReply) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetConfigPolicyReply) GetPolicy() []byte {
+	if x != nil {
+		return x.Policy
+	}
+	return nil
+}
+
+type ConfigValue struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*ConfigValue_Str
+	//	*ConfigValue_Int
+	//	*ConfigValue_Float
+	//	*ConfigValue_Bool
+	Value         isConfigValue_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigValue) Reset() {
+	*x = ConfigValue{}
+	mi := &file_plugin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigValue) ProtoMessage() {}
+
+func (x *ConfigValue) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigValue.ProtoReflect.Descriptor instead.
+func (*ConfigValue) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConfigValue) GetValue() isConfigValue_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *ConfigValue) GetStr() string {
+	if x != nil {
+		if x, ok := x.Value.(*ConfigValue_Str); ok {
+			return x.Str
+		}
+	}
+	return ""
+}
+
+func (x *ConfigValue) GetInt() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*ConfigValue_Int); ok {
+			return x.Int
+		}
+	}
+	return 0
+}
+
+func (x *ConfigValue) GetFloat() float64 {
+	if x != nil {
+		if x, ok := x.Value.(*ConfigValue_Float); ok {
+			return x.Float
+		}
+	}
+	return 0
+}
+
+func (x *ConfigValue) GetBool() bool {
+	if x != nil {
+		if x, ok := x.Value.(*ConfigValue_Bool); ok {
+			return x.Bool
+		}
+	}
+	return false
+}
+
+type isConfigValue_Value interface {
+	isConfigValue_Value()
+}
+
+type ConfigValue_Str struct {
+	Str string `protobuf:"bytes,1,opt,name=str,proto3,oneof"`
+}
+
+type ConfigValue_Int struct {
+	Int int64 `protobuf:"varint,2,opt,name=int,proto3,oneof"`
+}
+
+type ConfigValue_Float struct {
+	Float float64 `protobuf:"fixed64,3,opt,name=float,proto3,oneof"`
+}
+
+type ConfigValue_Bool struct {
+	Bool bool `protobuf:"varint,4,opt,name=bool,proto3,oneof"`
+}
+
+func (*ConfigValue_Str) isConfigValue_Value() {}
+
+func (*ConfigValue_Int) isConfigValue_Value() {}
+
+func (*ConfigValue_Float) isConfigValue_Value() {}
+
+func (*ConfigValue_Bool) isConfigValue_Value() {}
+
+type Config struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Table         map[string]*ConfigValue `protobuf:"bytes,1,rep,name=table,proto3" json:"table,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	mi := &file_plugin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Config) GetTable() map[string]*ConfigValue {
+	if x != nil {
+		return x.Table
+	}
+	return nil
+}
+
+type Label struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Label) Reset() {
+	*x = Label{}
+	mi := &file_plugin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Label) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Label) ProtoMessage() {}
+
+func (x *Label) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Label.ProtoReflect.Descriptor instead.
+func (*Label) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Label) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Label) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Metric struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace []string               `protobuf:"bytes,1,rep,name=namespace,proto3" json:"namespace,omitempty"`
+	Version   int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Config    *Config                `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*Metric_Float32Data
+	//	*Metric_Float64Data
+	//	*Metric_Int32Data
+	//	*Metric_Int64Data
+	//	*Metric_Uint32Data
+	//	*Metric_Uint64Data
+	//	*Metric_StringData
+	//	*Metric_BoolData
+	//	*Metric_BytesData
+	//	*Metric_JsonData
+	Data               isMetric_Data          `protobuf_oneof:"data"`
+	Labels             []*Label               `protobuf:"bytes,14,rep,name=labels,proto3" json:"labels,omitempty"`
+	Tags               map[string]string      `protobuf:"bytes,15,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Source             string                 `protobuf:"bytes,16,opt,name=source,proto3" json:"source,omitempty"`
+	Timestamp          *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	LastAdvertisedTime *timestamppb.Timestamp `protobuf:"bytes,18,opt,name=last_advertised_time,json=lastAdvertisedTime,proto3" json:"last_advertised_time,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Metric) GetNamespace() []string {
+	if x != nil {
+		return x.Namespace
+	}
+	return nil
+}
+
+func (x *Metric) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Metric) GetConfig() *Config {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *Metric) GetData() isMetric_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Metric) GetFloat32Data() float32 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Float32Data); ok {
+			return x.Float32Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetFloat64Data() float64 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Float64Data); ok {
+			return x.Float64Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetInt32Data() int32 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Int32Data); ok {
+			return x.Int32Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetInt64Data() int64 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Int64Data); ok {
+			return x.Int64Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetUint32Data() uint32 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Uint32Data); ok {
+			return x.Uint32Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetUint64Data() uint64 {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_Uint64Data); ok {
+			return x.Uint64Data
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetStringData() string {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_StringData); ok {
+			return x.StringData
+		}
+	}
+	return ""
+}
+
+func (x *Metric) GetBoolData() bool {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_BoolData); ok {
+			return x.BoolData
+		}
+	}
+	return false
+}
+
+func (x *Metric) GetBytesData() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_BytesData); ok {
+			return x.BytesData
+		}
+	}
+	return nil
+}
+
+func (x *Metric) GetJsonData() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*Metric_JsonData); ok {
+			return x.JsonData
+		}
+	}
+	return nil
+}
+
+func (x *Metric) GetLabels() []*Label {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Metric) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Metric) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Metric) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Metric) GetLastAdvertisedTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAdvertisedTime
+	}
+	return nil
+}
+
+type isMetric_Data interface {
+	isMetric_Data()
+}
+
+type Metric_Float32Data struct {
+	Float32Data float32 `protobuf:"fixed32,4,opt,name=float32_data,json=float32Data,proto3,oneof"`
+}
+
+type Metric_Float64Data struct {
+	Float64Data float64 `protobuf:"fixed64,5,opt,name=float64_data,json=float64Data,proto3,oneof"`
+}
+
+type Metric_Int32Data struct {
+	Int32Data int32 `protobuf:"varint,6,opt,name=int32_data,json=int32Data,proto3,oneof"`
+}
+
+type Metric_Int64Data struct {
+	Int64Data int64 `protobuf:"varint,7,opt,name=int64_data,json=int64Data,proto3,oneof"`
+}
+
+type Metric_Uint32Data struct {
+	Uint32Data uint32 `protobuf:"varint,8,opt,name=uint32_data,json=uint32Data,proto3,oneof"`
+}
+
+type Metric_Uint64Data struct {
+	Uint64Data uint64 `protobuf:"varint,9,opt,name=uint64_data,json=uint64Data,proto3,oneof"`
+}
+
+type Metric_StringData struct {
+	StringData string `protobuf:"bytes,10,opt,name=string_data,json=stringData,proto3,oneof"`
+}
+
+type Metric_BoolData struct {
+	BoolData bool `protobuf:"varint,11,opt,name=bool_data,json=boolData,proto3,oneof"`
+}
+
+type Metric_BytesData struct {
+	BytesData []byte `protobuf:"bytes,12,opt,name=bytes_data,json=bytesData,proto3,oneof"`
+}
+
+type Metric_JsonData struct {
+	// Data of any other type encoded as JSON
+	JsonData []byte `protobuf:"bytes,13,opt,name=json_data,json=jsonData,proto3,oneof"`
+}
+
+func (*Metric_Float32Data) isMetric_Data() {}
+
+func (*Metric_Float64Data) isMetric_Data() {}
+
+func (*Metric_Int32Data) isMetric_Data() {}
+
+func (*Metric_Int64Data) isMetric_Data() {}
+
+func (*Metric_Uint32Data) isMetric_Data() {}
+
+func (*Metric_Uint64Data) isMetric_Data() {}
+
+func (*Metric_StringData) isMetric_Data() {}
+
+func (*Metric_BoolData) isMetric_Data() {}
+
+func (*Metric_BytesData) isMetric_Data() {}
+
+func (*Metric_JsonData) isMetric_Data() {}
+
+type CollectMetricsArg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectMetricsArg) Reset() {
+	*x = CollectMetricsArg{}
+	mi := &file_plugin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectMetricsArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectMetricsArg) ProtoMessage() {}
+
+func (x *CollectMetricsArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectMetricsArg.ProtoReflect.Descriptor instead.
+func (*CollectMetricsArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CollectMetricsArg) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type CollectMetricsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectMetricsReply) Reset() {
+	*x = CollectMetricsReply{}
+	mi := &file_plugin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectMetricsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectMetricsReply) ProtoMessage() {}
+
+func (x *CollectMetricsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectMetricsReply.ProtoReflect.Descriptor instead.
+func (*CollectMetricsReply) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CollectMetricsReply) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type GetMetricTypesArg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Config        *Config                `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricTypesArg) Reset() {
+	*x = GetMetricTypesArg{}
+	mi := &file_plugin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricTypesArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricTypesArg) ProtoMessage() {}
+
+func (x *GetMetricTypesArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricTypesArg.ProtoReflect.Descriptor instead.
+func (*GetMetricTypesArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetMetricTypesArg) GetConfig() *Config {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type GetMetricTypesReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricTypesReply) Reset() {
+	*x = GetMetricTypesReply{}
+	mi := &file_plugin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricTypesReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricTypesReply) ProtoMessage() {}
+
+func (x *GetMetricTypesReply) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricTypesReply.ProtoReflect.Descriptor instead.
+func (*GetMetricTypesReply) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetMetricTypesReply) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type ProcessArg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Config        *Config                `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessArg) Reset() {
+	*x = ProcessArg{}
+	mi := &file_plugin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessArg) ProtoMessage() {}
+
+func (x *ProcessArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessArg.ProtoReflect.Descriptor instead.
+func (*ProcessArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ProcessArg) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ProcessArg) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *ProcessArg) GetConfig() *Config {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type ProcessReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessReply) Reset() {
+	*x = ProcessReply{}
+	mi := &file_plugin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessReply) ProtoMessage() {}
+
+func (x *ProcessReply) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessReply.ProtoReflect.Descriptor instead.
+func (*ProcessReply) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ProcessReply) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ProcessReply) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type PublishArg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Config        *Config                `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishArg) Reset() {
+	*x = PublishArg{}
+	mi := &file_plugin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishArg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishArg) ProtoMessage() {}
+
+func (x *PublishArg) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishArg.ProtoReflect.Descriptor instead.
+func (*PublishArg) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PublishArg) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *PublishArg) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *PublishArg) GetConfig() *Config {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+var File_plugin_proto protoreflect.FileDescriptor
+
+const file_plugin_proto_rawDesc = "" +
+	"\n" +
+	"\fplugin.proto\x12\x03rpc\x1a\x1fgoogle/protobuf/timestamp.proto\"\a\n" +
+	"\x05Empty\"!\n" +
+	"\aKillArg\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"\x1d\n" +
+	"\tSetKeyArg\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\".\n" +
+	"\x14GetConfigPolicyReply\x12\x16\n" +
+	"\x06policy\x18\x01 \x01(\fR\x06policy\"l\n" +
+	"\vConfigValue\x12\x12\n" +
+	"\x03str\x18\x01 \x01(\tH\x00R\x03str\x12\x12\n" +
+	"\x03int\x18\x02 \x01(\x03H\x00R\x03int\x12\x16\n" +
+	"\x05float\x18\x03 \x01(\x01H\x00R\x05float\x12\x14\n" +
+	"\x04bool\x18\x04 \x01(\bH\x00R\x04boolB\a\n" +
+	"\x05value\"\x82\x01\n" +
+	"\x06Config\x12,\n" +
+	"\x05table\x18\x01 \x03(\v2\x16.rpc.Config.TableEntryR\x05table\x1aJ\n" +
+	"\n" +
+	"TableEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\x05value\x18\x02 \x01(\v2\x10.rpc.ConfigValueR\x05value:\x028\x01\"1\n" +
+	"\x05Label\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\xe9\x05\n" +
+	"\x06Metric\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x03(\tR\tnamespace\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12#\n" +
+	"\x06config\x18\x03 \x01(\v2\v.rpc.ConfigR\x06config\x12#\n" +
+	"\ffloat32_data\x18\x04 \x01(\x02H\x00R\vfloat32Data\x12#\n" +
+	"\ffloat64_data\x18\x05 \x01(\x01H\x00R\vfloat64Data\x12\x1f\n" +
+	"\n" +
+	"int32_data\x18\x06 \x01(\x05H\x00R\tint32Data\x12\x1f\n" +
+	"\n" +
+	"int64_data\x18\a \x01(\x03H\x00R\tint64Data\x12!\n" +
+	"\vuint32_data\x18\b \x01(\rH\x00R\n" +
+	"uint32Data\x12!\n" +
+	"\vuint64_data\x18\t \x01(\x04H\x00R\n" +
+	"uint64Data\x12!\n" +
+	"\vstring_data\x18\n" +
+	" \x01(\tH\x00R\n" +
+	"stringData\x12\x1d\n" +
+	"\tbool_data\x18\v \x01(\bH\x00R\bboolData\x12\x1f\n" +
+	"\n" +
+	"bytes_data\x18\f \x01(\fH\x00R\tbytesData\x12\x1d\n" +
+	"\tjson_data\x18\r \x01(\fH\x00R\bjsonData\x12\"\n" +
+	"\x06labels\x18\x0e \x03(\v2\n" +
+	".rpc.LabelR\x06labels\x12)\n" +
+	"\x04tags\x18\x0f \x03(\v2\x15.rpc.Metric.TagsEntryR\x04tags\x12\x16\n" +
+	"\x06source\x18\x10 \x01(\tR\x06source\x128\n" +
+	"\ttimestamp\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12L\n" +
+	"\x14last_advertised_time\x18\x12 \x01(\v2\x1a.google.protobuf.TimestampR\x12lastAdvertisedTime\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x06\n" +
+	"\x04data\":\n" +
+	"\x11CollectMetricsArg\x12%\n" +
+	"\ametrics\x18\x01 \x03(\v2\v.rpc.MetricR\ametrics\"<\n" +
+	"\x13CollectMetricsReply\x12%\n" +
+	"\ametrics\x18\x01 \x03(\v2\v.rpc.MetricR\ametrics\"8\n" +
+	"\x11GetMetricTypesArg\x12#\n" +
+	"\x06config\x18\x01 \x01(\v2\v.rpc.ConfigR\x06config\"<\n" +
+	"\x13GetMetricTypesReply\x12%\n" +
+	"\ametrics\x18\x01 \x03(\v2\v.rpc.MetricR\ametrics\"n\n" +
+	"\n" +
+	"ProcessArg\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12#\n" +
+	"\x06config\x18\x03 \x01(\v2\v.rpc.ConfigR\x06config\"K\n" +
+	"\fProcessReply\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"n\n" +
+	"\n" +
+	"PublishArg\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12#\n" +
+	"\x06config\x18\x03 \x01(\v2\v.rpc.ConfigR\x06config2\xb0\x01\n" +
+	"\fSessionState\x12\x1e\n" +
+	"\x04Ping\x12\n" +
+	".rpc.Empty\x1a\n" +
+	".rpc.Empty\x12 \n" +
+	"\x04Kill\x12\f.rpc.KillArg\x1a\n" +
+	".rpc.Empty\x128\n" +
+	"\x0fGetConfigPolicy\x12\n" +
+	".rpc.Empty\x1a\x19.rpc.GetConfigPolicyReply\x12$\n" +
+	"\x06SetKey\x12\x0e.rpc.SetKeyArg\x1a\n" +
+	".rpc.Empty2\x93\x01\n" +
+	"\tCollector\x12B\n" +
+	"\x0eCollectMetrics\x12\x16.rpc.CollectMetricsArg\x1a\x18.rpc.CollectMetricsReply\x12B\n" +
+	"\x0eGetMetricTypes\x12\x16.rpc.GetMetricTypesArg\x1a\x18.rpc.GetMetricTypesReply2:\n" +
+	"\tProcessor\x12-\n" +
+	"\aProcess\x12\x0f.rpc.ProcessArg\x1a\x11.rpc.ProcessReply23\n" +
+	"\tPublisher\x12&\n" +
+	"\aPublish\x12\x0f.rpc.PublishArg\x1a\n" +
+	".rpc.EmptyB/Z-github.com/intelsdi-x/snap/control/plugin/rpcb\x06proto3"
+
+var (
+	file_plugin_proto_rawDescOnce sync.Once
+	file_plugin_proto_rawDescData []byte
+)
+
+func file_plugin_proto_rawDescGZIP() []byte {
+	file_plugin_proto_rawDescOnce.Do(func() {
+		file_plugin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugin_proto_rawDesc), len(file_plugin_proto_rawDesc)))
+	})
+	return file_plugin_proto_rawDescData
+}
+
+var file_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_plugin_proto_goTypes = []any{
+	(*Empty)(nil),                 // 0: rpc.Empty
+	(*KillArg)(nil),               // 1: rpc.KillArg
+	(*SetKeyArg)(nil),             // 2: rpc.SetKeyArg
+	(*GetConfigPolicyReply)(nil),  // 3: rpc.GetConfigPolicyReply
+	(*ConfigValue)(nil),           // 4: rpc.ConfigValue
+	(*Config)(nil),                // 5: rpc.Config
+	(*Label)(nil),                 // 6: rpc.Label
+	(*Metric)(nil),                // 7: rpc.Metric
+	(*CollectMetricsArg)(nil),     // 8: rpc.CollectMetricsArg
+	(*CollectMetricsReply)(nil),   // 9: rpc.CollectMetricsReply
+	(*GetMetricTypesArg)(nil),     // 10: rpc.GetMetricTypesArg
+	(*GetMetricTypesReply)(nil),   // 11: rpc.GetMetricTypesReply
+	(*ProcessArg)(nil),            // 12: rpc.ProcessArg
+	(*ProcessReply)(nil),          // 13: rpc.ProcessReply
+	(*PublishArg)(nil),            // 14: rpc.PublishArg
+	nil,                           // 15: rpc.Config.TableEntry
+	nil,                           // 16: rpc.Metric.TagsEntry
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+}
+var file_plugin_proto_depIdxs = []int32{
+	15, // 0: rpc.Config.table:type_name -> rpc.Config.TableEntry
+	5,  // 1: rpc.Metric.config:type_name -> rpc.Config
+	6,  // 2: rpc.Metric.labels:type_name -> rpc.Label
+	16, // 3: rpc.Metric.tags:type_name -> rpc.Metric.TagsEntry
+	17, // 4: rpc.Metric.timestamp:type_name -> google.protobuf.Timestamp
+	17, // 5: rpc.Metric.last_advertised_time:type_name -> google.protobuf.Timestamp
+	7,  // 6: rpc.CollectMetricsArg.metrics:type_name -> rpc.Metric
+	7,  // 7: rpc.CollectMetricsReply.metrics:type_name -> rpc.Metric
+	5,  // 8: rpc.GetMetricTypesArg.config:type_name -> rpc.Config
+	7,  // 9: rpc.GetMetricTypesReply.metrics:type_name -> rpc.Metric
+	5,  // 10: rpc.ProcessArg.config:type_name -> rpc.Config
+	5,  // 11: rpc.PublishArg.config:type_name -> rpc.Config
+	4,  // 12: rpc.Config.TableEntry.value:type_name -> rpc.ConfigValue
+	0,  // 13: rpc.SessionState.Ping:input_type -> rpc.Empty
+	1,  // 14: rpc.SessionState.Kill:input_type -> rpc.KillArg
+	0,  // 15: rpc.SessionState.GetConfigPolicy:input_type -> rpc.Empty
+	2,  // 16: rpc.SessionState.SetKey:input_type -> rpc.SetKeyArg
+	8,  // 17: rpc.Collector.CollectMetrics:input_type -> rpc.CollectMetricsArg
+	10, // 18: rpc.Collector.GetMetricTypes:input_type -> rpc.GetMetricTypesArg
+	12, // 19: rpc.Processor.Process:input_type -> rpc.ProcessArg
+	14, // 20: rpc.Publisher.Publish:input_type -> rpc.PublishArg
+	0,  // 21: rpc.SessionState.Ping:output_type -> rpc.Empty
+	0,  // 22: rpc.SessionState.Kill:output_type -> rpc.Empty
+	3,  // 23: rpc.SessionState.GetConfigPolicy:output_type -> rpc.GetConfigPolicyReply
+	0,  // 24: rpc.SessionState.SetKey:output_type -> rpc.Empty
+	9,  // 25: rpc.Collector.CollectMetrics:output_type -> rpc.CollectMetricsReply
+	11, // 26: rpc.Collector.GetMetricTypes:output_type -> rpc.GetMetricTypesReply
+	13, // 27: rpc.Processor.Process:output_type -> rpc.ProcessReply
+	0,  // 28: rpc.Publisher.Publish:output_type -> rpc.Empty
+	21, // [21:29] is the sub-list for method output_type
+	13, // [13:21] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_plugin_proto_init() }
+func file_plugin_proto_init() {
+	if File_plugin_proto != nil {
+		return
+	}
+	file_plugin_proto_msgTypes[4].OneofWrappers = []any{
+		(*ConfigValue_Str)(nil),
+		(*ConfigValue_Int)(nil),
+		(*ConfigValue_Float)(nil),
+		(*ConfigValue_Bool)(nil),
+	}
+	file_plugin_proto_msgTypes[7].OneofWrappers = []any{
+		(*Metric_Float32Data)(nil),
+		(*Metric_Float64Data)(nil),
+		(*Metric_Int32Data)(nil),
+		(*Metric_Int64Data)(nil),
+		(*Metric_Uint32Data)(nil),
+		(*Metric_Uint64Data)(nil),
+		(*Metric_StringData)(nil),
+		(*Metric_BoolData)(nil),
+		(*Metric_BytesData)(nil),
+		(*Metric_JsonData)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugin_proto_rawDesc), len(file_plugin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   4,
+		},
+		GoTypes:           file_plugin_proto_goTypes,
+		DependencyIndexes: file_plugin_proto_depIdxs,
+		MessageInfos:      file_plugin_proto_msgTypes,
+	}.Build()
+	File_plugin_proto = out.File
+	file_plugin_proto_goTypes = nil
+	file_plugin_proto_depIdxs = nil
+}
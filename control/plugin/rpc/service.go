@@ -0,0 +1,257 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rpc holds the gRPC services plugins using the GRPC RPC type serve.
+// The messages are defined in plugin.proto and generated with protoc-gen-go
+// of the google.golang.org/protobuf version pinned in Godeps; the services
+// are registered with the functions in this file.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative plugin.proto
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// SessionStateClient is the client of the SessionState service
+type SessionStateClient interface {
+	Ping(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Kill(ctx context.Context, in *KillArg, opts ...grpc.CallOption) (*Empty, error)
+	GetConfigPolicy(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetConfigPolicyReply, error)
+	SetKey(ctx context.Context, in *SetKeyArg, opts ...grpc.CallOption) (*Empty, error)
+}
+
+// SessionStateServer is implemented by every plugin
+type SessionStateServer interface {
+	Ping(context.Context, *Empty) (*Empty, error)
+	Kill(context.Context, *KillArg) (*Empty, error)
+	GetConfigPolicy(context.Context, *Empty) (*GetConfigPolicyReply, error)
+	SetKey(context.Context, *SetKeyArg) (*Empty, error)
+}
+
+// CollectorClient is the client of the Collector service
+type CollectorClient interface {
+	CollectMetrics(ctx context.Context, in *CollectMetricsArg, opts ...grpc.CallOption) (*CollectMetricsReply, error)
+	GetMetricTypes(ctx context.Context, in *GetMetricTypesArg, opts ...grpc.CallOption) (*GetMetricTypesReply, error)
+}
+
+// CollectorServer is implemented by collector plugins
+type CollectorServer interface {
+	CollectMetrics(context.Context, *CollectMetricsArg) (*CollectMetricsReply, error)
+	GetMetricTypes(context.Context, *GetMetricTypesArg) (*GetMetricTypesReply, error)
+}
+
+// ProcessorClient is the client of the Processor service
+type ProcessorClient interface {
+	Process(ctx context.Context, in *ProcessArg, opts ...grpc.CallOption) (*ProcessReply, error)
+}
+
+// ProcessorServer is implemented by processor plugins
+type ProcessorServer interface {
+	Process(context.Context, *ProcessArg) (*ProcessReply, error)
+}
+
+// PublisherClient is the client of the Publisher service
+type PublisherClient interface {
+	Publish(ctx context.Context, in *PublishArg, opts ...grpc.CallOption) (*Empty, error)
+}
+
+// PublisherServer is implemented by publisher plugins
+type PublisherServer interface {
+	Publish(context.Context, *PublishArg) (*Empty, error)
+}
+
+type client struct {
+	cc      *grpc.ClientConn
+	service string
+}
+
+func (c *client) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	return c.cc.Invoke(ctx, "/rpc."+c.service+"/"+method, in, out, opts...)
+}
+
+type sessionStateClient struct{ client }
+
+// NewSessionStateClient returns a client of the SessionState service
+func NewSessionStateClient(cc *grpc.ClientConn) SessionStateClient {
+	return &sessionStateClient{client{cc, "SessionState"}}
+}
+
+func (c *sessionStateClient) Ping(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	return out, c.invoke(ctx, "Ping", in, out, opts)
+}
+
+func (c *sessionStateClient) Kill(ctx context.Context, in *KillArg, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	return out, c.invoke(ctx, "Kill", in, out, opts)
+}
+
+func (c *sessionStateClient) GetConfigPolicy(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetConfigPolicyReply, error) {
+	out := new(GetConfigPolicyReply)
+	return out, c.invoke(ctx, "GetConfigPolicy", in, out, opts)
+}
+
+func (c *sessionStateClient) SetKey(ctx context.Context, in *SetKeyArg, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	return out, c.invoke(ctx, "SetKey", in, out, opts)
+}
+
+type collectorClient struct{ client }
+
+// NewCollectorClient returns a client of the Collector service
+func NewCollectorClient(cc *grpc.ClientConn) CollectorClient {
+	return &collectorClient{client{cc, "Collector"}}
+}
+
+func (c *collectorClient) CollectMetrics(ctx context.Context, in *CollectMetricsArg, opts ...grpc.CallOption) (*CollectMetricsReply, error) {
+	out := new(CollectMetricsReply)
+	return out, c.invoke(ctx, "CollectMetrics", in, out, opts)
+}
+
+func (c *collectorClient) GetMetricTypes(ctx context.Context, in *GetMetricTypesArg, opts ...grpc.CallOption) (*GetMetricTypesReply, error) {
+	out := new(GetMetricTypesReply)
+	return out, c.invoke(ctx, "GetMetricTypes", in, out, opts)
+}
+
+type processorClient struct{ client }
+
+// NewProcessorClient returns a client of the Processor service
+func NewProcessorClient(cc *grpc.ClientConn) ProcessorClient {
+	return &processorClient{client{cc, "Processor"}}
+}
+
+func (c *processorClient) Process(ctx context.Context, in *ProcessArg, opts ...grpc.CallOption) (*ProcessReply, error) {
+	out := new(ProcessReply)
+	return out, c.invoke(ctx, "Process", in, out, opts)
+}
+
+type publisherClient struct{ client }
+
+// NewPublisherClient returns a client of the Publisher service
+func NewPublisherClient(cc *grpc.ClientConn) PublisherClient {
+	return &publisherClient{client{cc, "Publisher"}}
+}
+
+func (c *publisherClient) Publish(ctx context.Context, in *PublishArg, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	return out, c.invoke(ctx, "Publish", in, out, opts)
+}
+
+// unary returns the description of a unary method whose argument is made by
+// newArg and which is served by call
+func unary(service, method string, newArg func() interface{}, call func(srv interface{}, ctx context.Context, arg interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := newArg()
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv, ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/rpc." + service + "/" + method,
+			}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv, ctx, req)
+			})
+		},
+	}
+}
+
+var sessionStateServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.SessionState",
+	HandlerType: (*SessionStateServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("SessionState", "Ping", func() interface{} { return new(Empty) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(SessionStateServer).Ping(ctx, in.(*Empty))
+		}),
+		unary("SessionState", "Kill", func() interface{} { return new(KillArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(SessionStateServer).Kill(ctx, in.(*KillArg))
+		}),
+		unary("SessionState", "GetConfigPolicy", func() interface{} { return new(Empty) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(SessionStateServer).GetConfigPolicy(ctx, in.(*Empty))
+		}),
+		unary("SessionState", "SetKey", func() interface{} { return new(SetKeyArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(SessionStateServer).SetKey(ctx, in.(*SetKeyArg))
+		}),
+	},
+	Metadata: "plugin.proto",
+}
+
+var collectorServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.Collector",
+	HandlerType: (*CollectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Collector", "CollectMetrics", func() interface{} { return new(CollectMetricsArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(CollectorServer).CollectMetrics(ctx, in.(*CollectMetricsArg))
+		}),
+		unary("Collector", "GetMetricTypes", func() interface{} { return new(GetMetricTypesArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(CollectorServer).GetMetricTypes(ctx, in.(*GetMetricTypesArg))
+		}),
+	},
+	Metadata: "plugin.proto",
+}
+
+var processorServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.Processor",
+	HandlerType: (*ProcessorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Processor", "Process", func() interface{} { return new(ProcessArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(ProcessorServer).Process(ctx, in.(*ProcessArg))
+		}),
+	},
+	Metadata: "plugin.proto",
+}
+
+var publisherServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.Publisher",
+	HandlerType: (*PublisherServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Publisher", "Publish", func() interface{} { return new(PublishArg) }, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return srv.(PublisherServer).Publish(ctx, in.(*PublishArg))
+		}),
+	},
+	Metadata: "plugin.proto",
+}
+
+// RegisterSessionStateServer registers srv as the SessionState service of s
+func RegisterSessionStateServer(s *grpc.Server, srv SessionStateServer) {
+	s.RegisterService(&sessionStateServiceDesc, srv)
+}
+
+// RegisterCollectorServer registers srv as the Collector service of s
+func RegisterCollectorServer(s *grpc.Server, srv CollectorServer) {
+	s.RegisterService(&collectorServiceDesc, srv)
+}
+
+// RegisterProcessorServer registers srv as the Processor service of s
+func RegisterProcessorServer(s *grpc.Server, srv ProcessorServer) {
+	s.RegisterService(&processorServiceDesc, srv)
+}
+
+// RegisterPublisherServer registers srv as the Publisher service of s
+func RegisterPublisherServer(s *grpc.Server, srv PublisherServer) {
+	s.RegisterService(&publisherServiceDesc, srv)
+}
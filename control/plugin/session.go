@@ -27,6 +27,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
@@ -129,13 +130,18 @@ func (s *SessionState) Kill(args []byte, reply *[]byte) error {
 	if err != nil {
 		return err
 	}
-	s.logger.Printf("Kill called by agent, reason: %s\n", a.Reason)
+	s.kill(a.Reason)
+	*reply = []byte{}
+	return nil
+}
+
+// kill stops the plugin shortly so the reply to the kill call can be sent
+func (s *SessionState) kill(reason string) {
+	s.logger.Printf("Kill called by agent, reason: %s\n", reason)
 	go func() {
 		time.Sleep(time.Second * 2)
 		s.killChan <- 0
 	}()
-	*reply = []byte{}
-	return nil
 }
 
 // Logger gets the SessionState logger
@@ -259,7 +265,9 @@ func NewSessionState(pluginArgsMsg string, plugin Plugin, meta *PluginMeta) (*Se
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("error opening log file: %v", err)), 3
 	}
-	logger := log.New(lf, ">>>", log.Ldate|log.Ltime)
+	// The log is written to STDERR too where snapd captures it with the rest
+	// of the output of the plugin
+	logger := log.New(io.MultiWriter(lf, os.Stderr), ">>>", log.Ldate|log.Ltime)
 
 	var enc encoding.Encoder
 	switch meta.RPCType {
@@ -267,6 +275,10 @@ func NewSessionState(pluginArgsMsg string, plugin Plugin, meta *PluginMeta) (*Se
 		enc = encoding.NewJsonEncoder()
 	case NativeRPC:
 		enc = encoding.NewGobEncoder()
+	case GRPC:
+		// gRPC messages are encoded by gRPC.  The encoder only keeps
+		// the Session methods working.
+		enc = encoding.NewGobEncoder()
 	}
 	ss := &SessionState{
 		Arg:     pluginArg,
@@ -0,0 +1,377 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// defaultPluginLogLines is the number of lines kept in memory for each
+	// plugin
+	defaultPluginLogLines = 1000
+	// defaultPluginLogFileSize is the size the log file of a plugin grows to
+	// before it is rotated
+	defaultPluginLogFileSize = 10 * 1024 * 1024
+	// defaultPluginLogFiles is the number of rotated log files kept for each
+	// plugin
+	defaultPluginLogFiles = 3
+	// pluginLogFollowBuffer is the number of lines held for a slow follower
+	// before further lines are dropped
+	pluginLogFollowBuffer = 256
+)
+
+var errPluginLogSymlink = errors.New("plugin log file is a symbolic link")
+
+// pluginLogs holds the output of the running instances of each plugin by
+// plugin key.  The files are written to dir which only the user running
+// snapd can use.  Without a dir a private temporary directory is created with
+// the first file.
+type pluginLogs struct {
+	sync.Mutex
+	dir      string
+	lines    int
+	fileSize int64
+	files    int
+	logs     map[string]*pluginLog
+}
+
+func newPluginLogs(dir string) *pluginLogs {
+	return &pluginLogs{
+		dir:      dir,
+		lines:    defaultPluginLogLines,
+		fileSize: defaultPluginLogFileSize,
+		files:    defaultPluginLogFiles,
+		logs:     map[string]*pluginLog{},
+	}
+}
+
+// setDir has the logs of plugins that start from now on written to dir
+func (pl *pluginLogs) setDir(dir string) {
+	pl.Lock()
+	pl.dir = dir
+	pl.Unlock()
+}
+
+// fileDir returns the directory the files are written to creating the
+// temporary directory if needed
+func (pl *pluginLogs) fileDir() (string, error) {
+	if pl.dir == "" {
+		dir, err := ioutil.TempDir("", "snap-plugin-logs-")
+		if err != nil {
+			return "", err
+		}
+		pl.dir = dir
+	}
+	return pl.dir, nil
+}
+
+// get returns the log of a plugin creating it the first time
+func (pl *pluginLogs) get(key string) *pluginLog {
+	pl.Lock()
+	defer pl.Unlock()
+	l, ok := pl.logs[key]
+	if !ok {
+		var file *rotatingFile
+		if dir, err := pl.fileDir(); err == nil {
+			name := "snap-plugin-" + strings.Replace(key, ":", "-", -1) + ".log"
+			file = &rotatingFile{
+				path:     filepath.Join(dir, name),
+				maxSize:  pl.fileSize,
+				maxFiles: pl.files,
+			}
+		} else {
+			controlLogger.WithFields(log.Fields{
+				"_block": "plugin-log",
+				"plugin": key,
+				"error":  err.Error(),
+			}).Error("unable to create a directory for plugin output")
+		}
+		l = newPluginLog(pl.lines, file)
+		pl.logs[key] = l
+	}
+	return l
+}
+
+// remove closes the log of a plugin.  Its files are left in place.
+func (pl *pluginLogs) remove(key string) {
+	pl.Lock()
+	l, ok := pl.logs[key]
+	delete(pl.logs, key)
+	pl.Unlock()
+	if ok {
+		l.close()
+	}
+}
+
+// pluginLog keeps the most recent lines written by a plugin in a ring buffer
+// and every line in a rotating file
+type pluginLog struct {
+	sync.Mutex
+	ring      []core.PluginLogLine
+	next      int
+	full      bool
+	file      *rotatingFile
+	followers map[chan core.PluginLogLine]struct{}
+	closed    bool
+}
+
+func newPluginLog(lines int, file *rotatingFile) *pluginLog {
+	return &pluginLog{
+		ring:      make([]core.PluginLogLine, lines),
+		file:      file,
+		followers: map[chan core.PluginLogLine]struct{}{},
+	}
+}
+
+func (l *pluginLog) write(line core.PluginLogLine) {
+	l.Lock()
+	defer l.Unlock()
+	if l.closed {
+		return
+	}
+	l.ring[l.next] = line
+	l.next = (l.next + 1) % len(l.ring)
+	if l.next == 0 {
+		l.full = true
+	}
+	if l.file != nil {
+		l.file.write(line)
+	}
+	for ch := range l.followers {
+		select {
+		case ch <- line:
+		default:
+			// Never block a plugin on a follower which is not keeping up
+		}
+	}
+}
+
+// tail returns up to n of the most recent lines, oldest first, or every line
+// kept when n < 1
+func (l *pluginLog) tail(n int) []core.PluginLogLine {
+	var lines []core.PluginLogLine
+	if l.full {
+		lines = append(lines, l.ring[l.next:]...)
+	}
+	lines = append(lines, l.ring[:l.next]...)
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+func (l *pluginLog) recent(n int) []core.PluginLogLine {
+	l.Lock()
+	defer l.Unlock()
+	return l.tail(n)
+}
+
+// follow returns up to n of the most recent lines and a channel the lines
+// written afterwards are sent on until unfollow is called or the log is
+// closed
+func (l *pluginLog) follow(n int) ([]core.PluginLogLine, chan core.PluginLogLine) {
+	l.Lock()
+	defer l.Unlock()
+	ch := make(chan core.PluginLogLine, pluginLogFollowBuffer)
+	if l.closed {
+		close(ch)
+	} else {
+		l.followers[ch] = struct{}{}
+	}
+	return l.tail(n), ch
+}
+
+func (l *pluginLog) unfollow(ch chan core.PluginLogLine) {
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := l.followers[ch]; ok {
+		delete(l.followers, ch)
+		close(ch)
+	}
+}
+
+func (l *pluginLog) close() {
+	l.Lock()
+	defer l.Unlock()
+	l.closed = true
+	for ch := range l.followers {
+		delete(l.followers, ch)
+		close(ch)
+	}
+	if l.file != nil {
+		l.file.close()
+	}
+}
+
+// rotatingFile writes lines to path moving it to path.1, path.1 to path.2 and
+// so on once it grows beyond maxSize.  Only maxFiles rotated files are kept.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+	failed   bool
+}
+
+func (r *rotatingFile) write(line core.PluginLogLine) {
+	if r.failed {
+		return
+	}
+	b := []byte(fmt.Sprintf("%s %s: %s\n", line.Time.Format(time.RFC3339), line.Stream, line.Line))
+	if r.f != nil && r.size+int64(len(b)) > r.maxSize {
+		r.rotate()
+	}
+	if r.f == nil && !r.open() {
+		return
+	}
+	n, _ := r.f.Write(b)
+	r.size += int64(n)
+}
+
+func (r *rotatingFile) open() bool {
+	var f *os.File
+	fi, err := os.Lstat(r.path)
+	switch {
+	case err == nil && fi.Mode()&os.ModeSymlink != 0:
+		err = errPluginLogSymlink
+	case err == nil || os.IsNotExist(err):
+		f, err = os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	}
+	if err == nil {
+		fi, err = f.Stat()
+		if err == nil {
+			r.f = f
+			r.size = fi.Size()
+			return true
+		}
+		f.Close()
+	}
+	// give up on the file rather than logging the error for every line
+	r.failed = true
+	controlLogger.WithFields(log.Fields{
+		"_block": "plugin-log",
+		"path":   r.path,
+		"error":  err.Error(),
+	}).Error("unable to write plugin output to file")
+	return false
+}
+
+func (r *rotatingFile) rotate() {
+	r.close()
+	for i := r.maxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.maxFiles > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+}
+
+func (r *rotatingFile) close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+		r.size = 0
+	}
+}
+
+// pluginOutput is the LogSink of one running plugin.  Which plugin runs is
+// only known once it responds so the lines it writes before are held until
+// the output is bound to the log of the plugin.
+type pluginOutput struct {
+	sync.Mutex
+	log     *pluginLog
+	pending []core.PluginLogLine
+}
+
+// WriteLine implements plugin.LogSink
+func (o *pluginOutput) WriteLine(stream, line string) {
+	l := core.PluginLogLine{Time: time.Now(), Stream: stream, Line: line}
+	o.Lock()
+	defer o.Unlock()
+	if o.log == nil {
+		if len(o.pending) < defaultPluginLogLines {
+			o.pending = append(o.pending, l)
+		}
+		return
+	}
+	o.log.write(l)
+}
+
+// bind sends the lines held and every line written afterwards to l
+func (o *pluginOutput) bind(l *pluginLog) {
+	o.Lock()
+	defer o.Unlock()
+	o.log = l
+	for _, line := range o.pending {
+		l.write(line)
+	}
+	o.pending = nil
+}
+
+// unbound returns the lines written by a plugin which was never bound, such as
+// one which failed to start
+func (o *pluginOutput) unbound() string {
+	o.Lock()
+	defer o.Unlock()
+	lines := make([]string, len(o.pending))
+	for i, l := range o.pending {
+		lines[i] = l.Line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PluginLogs returns up to n of the most recent lines written by the running
+// instances of a plugin, or every line kept when n < 1
+func (p *pluginControl) PluginLogs(pluginType, name string, version, n int) ([]core.PluginLogLine, error) {
+	key := fmt.Sprintf("%s:%s:%d", pluginType, name, version)
+	if _, err := p.pluginManager.get(key); err != nil {
+		return nil, ErrLoadedPluginNotFound
+	}
+	return p.pluginRunner.Logs().get(key).recent(n), nil
+}
+
+// FollowPluginLogs returns up to n of the most recent lines written by the
+// running instances of a plugin and a channel the lines written afterwards are
+// sent on.  The channel is closed once stop is called or the plugin is
+// unloaded.
+func (p *pluginControl) FollowPluginLogs(pluginType, name string, version, n int) ([]core.PluginLogLine, <-chan core.PluginLogLine, func(), error) {
+	key := fmt.Sprintf("%s:%s:%d", pluginType, name, version)
+	if _, err := p.pluginManager.get(key); err != nil {
+		return nil, nil, nil, ErrLoadedPluginNotFound
+	}
+	l := p.pluginRunner.Logs().get(key)
+	lines, ch := l.follow(n)
+	return lines, ch, func() { l.unfollow(ch) }, nil
+}
@@ -0,0 +1,217 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func logLine(s string) core.PluginLogLine {
+	return core.PluginLogLine{Time: time.Now(), Stream: plugin.StderrStream, Line: s}
+}
+
+func lineTexts(lines []core.PluginLogLine) []string {
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.Line
+	}
+	return texts
+}
+
+func TestPluginLog(t *testing.T) {
+	Convey("A plugin log", t, func() {
+		l := newPluginLog(3, nil)
+
+		Convey("keeps the most recent lines", func() {
+			So(l.recent(0), ShouldBeEmpty)
+			l.write(logLine("a"))
+			l.write(logLine("b"))
+			So(lineTexts(l.recent(0)), ShouldResemble, []string{"a", "b"})
+			l.write(logLine("c"))
+			l.write(logLine("d"))
+			So(lineTexts(l.recent(0)), ShouldResemble, []string{"b", "c", "d"})
+			So(lineTexts(l.recent(2)), ShouldResemble, []string{"c", "d"})
+			So(lineTexts(l.recent(10)), ShouldResemble, []string{"b", "c", "d"})
+		})
+
+		Convey("sends new lines to followers", func() {
+			l.write(logLine("a"))
+			lines, ch := l.follow(5)
+			So(lineTexts(lines), ShouldResemble, []string{"a"})
+			l.write(logLine("b"))
+			So((<-ch).Line, ShouldEqual, "b")
+			l.unfollow(ch)
+			_, ok := <-ch
+			So(ok, ShouldBeFalse)
+			// unfollowing again does nothing
+			l.unfollow(ch)
+		})
+
+		Convey("closes followers when it is closed", func() {
+			_, ch := l.follow(0)
+			l.close()
+			_, ok := <-ch
+			So(ok, ShouldBeFalse)
+			l.write(logLine("a"))
+			So(l.recent(0), ShouldBeEmpty)
+			_, ch = l.follow(0)
+			_, ok = <-ch
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("A rotating file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-plugin-log")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "plugin.log")
+		f := &rotatingFile{path: path, maxSize: 100, maxFiles: 2}
+		for i := 0; i < 10; i++ {
+			f.write(logLine(fmt.Sprintf("line %d %s", i, strings.Repeat("x", 20))))
+		}
+		f.close()
+
+		b, err := ioutil.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(len(b), ShouldBeLessThanOrEqualTo, 100)
+		So(string(b), ShouldContainSubstring, "stderr: line 9")
+		b, err = ioutil.ReadFile(path + ".1")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldContainSubstring, "line 8")
+		_, err = os.Stat(path + ".2")
+		So(err, ShouldBeNil)
+		_, err = os.Stat(path + ".3")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("A rotating file is not written through a symbolic link", t, func() {
+		dir, err := ioutil.TempDir("", "snap-plugin-log")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		target := filepath.Join(dir, "target")
+		So(ioutil.WriteFile(target, nil, 0600), ShouldBeNil)
+		path := filepath.Join(dir, "plugin.log")
+		So(os.Symlink(target, path), ShouldBeNil)
+		f := &rotatingFile{path: path, maxSize: 100, maxFiles: 2}
+		f.write(logLine("line"))
+		f.close()
+
+		So(f.failed, ShouldBeTrue)
+		b, err := ioutil.ReadFile(target)
+		So(err, ShouldBeNil)
+		So(b, ShouldBeEmpty)
+	})
+
+	Convey("Plugin logs without a directory", t, func() {
+		pl := newPluginLogs("")
+		l := pl.get("collector:mock:1")
+		defer os.RemoveAll(pl.dir)
+
+		Convey("write to a private temporary directory", func() {
+			So(l.file, ShouldNotBeNil)
+			So(filepath.Dir(l.file.path), ShouldEqual, pl.dir)
+			fi, err := os.Stat(pl.dir)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0700))
+		})
+	})
+
+	Convey("The output of a running plugin", t, func() {
+		out := &pluginOutput{}
+		out.WriteLine(plugin.StderrStream, "starting")
+		So(out.unbound(), ShouldEqual, "starting")
+
+		Convey("is held until it is bound to a log", func() {
+			l := newPluginLog(10, nil)
+			out.bind(l)
+			out.WriteLine(plugin.StdoutStream, "started")
+			lines := l.recent(0)
+			So(lineTexts(lines), ShouldResemble, []string{"starting", "started"})
+			So(lines[1].Stream, ShouldEqual, plugin.StdoutStream)
+		})
+	})
+}
+
+func TestPluginLogs(t *testing.T) {
+	Convey("given a running plugin", t, func() {
+		tmp, err := ioutil.TempDir("", "snap-plugin-log")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmp)
+		dir := filepath.Join(tmp, "plugins")
+		c := New(PluginLogPath(dir))
+		So(c.Start(), ShouldBeNil)
+		fi, err := os.Stat(dir)
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0700))
+		_, serr := load(c, JSONRPCPluginPath)
+		So(serr, ShouldBeNil)
+		lp, err := c.pluginManager.get("collector:mock:1")
+		So(err, ShouldBeNil)
+		So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+
+		Convey("its output is captured", func() {
+			var lines []core.PluginLogLine
+			for i := 0; i < 50 && len(lines) == 0; i++ {
+				time.Sleep(100 * time.Millisecond)
+				lines, err = c.PluginLogs("collector", "mock", 1, 0)
+				So(err, ShouldBeNil)
+			}
+			So(lines, ShouldNotBeEmpty)
+			So(lines[0].Stream, ShouldEqual, plugin.StderrStream)
+			So(lines[0].Line, ShouldContainSubstring, "Listening")
+
+			path := filepath.Join(dir, "snap-plugin-collector-mock-1.log")
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldContainSubstring, "Listening")
+			fi, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		})
+
+		Convey("its output can be followed until it is unloaded", func() {
+			_, ch, stop, err := c.FollowPluginLogs("collector", "mock", 1, 0)
+			So(err, ShouldBeNil)
+			So(stop, ShouldNotBeNil)
+			_, serr := c.Unload(lp)
+			So(serr, ShouldBeNil)
+			for range ch {
+			}
+			stop()
+		})
+
+		Convey("an error is returned for a plugin which is not loaded", func() {
+			_, err := c.PluginLogs("collector", "mock", 2, 0)
+			So(err, ShouldEqual, ErrLoadedPluginNotFound)
+			_, _, _, err = c.FollowPluginLogs("collector", "foo", 1, 0)
+			So(err, ShouldEqual, ErrLoadedPluginNotFound)
+		})
+	})
+}
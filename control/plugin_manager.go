@@ -28,9 +28,11 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -163,6 +165,12 @@ type pluginDetails struct {
 	Path      string
 	Signed    bool
 	Signature []byte
+	// URL is where a remote plugin listens.  Remote plugins run as
+	// services snapd connects to instead of executables it starts.
+	URL string
+	// InProcess is the implementation of a plugin registered by the program
+	// running snap.  It is called directly instead of being started.
+	InProcess plugin.Plugin
 }
 
 type loadedPlugin struct {
@@ -173,6 +181,14 @@ type loadedPlugin struct {
 	Token        string
 	LoadedTime   time.Time
 	ConfigPolicy *cpolicy.ConfigPolicy
+
+	restarts *pluginRestarts
+}
+
+// pluginRestarts records the restarts of the instances of a loaded plugin
+type pluginRestarts struct {
+	sync.Mutex
+	core.PluginRestarts
 }
 
 // Name returns plugin name
@@ -221,6 +237,29 @@ func (lp *loadedPlugin) LoadedTimestamp() *time.Time {
 	return &lp.LoadedTime
 }
 
+// Restarts returns the restarts of the instances of the plugin
+// implements the CatalogedPlugin interface
+func (lp *loadedPlugin) Restarts() core.PluginRestarts {
+	if lp.restarts == nil {
+		return core.PluginRestarts{}
+	}
+	lp.restarts.Lock()
+	defer lp.restarts.Unlock()
+	return lp.restarts.PluginRestarts
+}
+
+// recordRestart records a restart of an instance of the plugin
+func (lp *loadedPlugin) recordRestart(reason string) {
+	if lp.restarts == nil {
+		return
+	}
+	lp.restarts.Lock()
+	defer lp.restarts.Unlock()
+	lp.restarts.Count++
+	lp.restarts.LastRestart = time.Now()
+	lp.restarts.LastReason = reason
+}
+
 // the struct representing the object responsible for
 // loading and unloading plugins
 type pluginManager struct {
@@ -228,6 +267,12 @@ type pluginManager struct {
 	loadedPlugins *loadedPlugins
 	logPath       string
 	pluginConfig  *pluginConfig
+	// resourceLimits are also put on the processes started to load plugins
+	resourceLimits *resourceLimits
+	// socketDir holds the Unix sockets plugins listen on.  Plugins listen
+	// on TCP ports when it is empty.
+	socketDir string
+	sockets   uint64
 }
 
 func newPluginManager(opts ...pluginManagerOpt) *pluginManager {
@@ -258,6 +303,16 @@ func (p *pluginManager) SetPluginConfig(cf *pluginConfig) {
 	p.pluginConfig = cf
 }
 
+// SetResourceLimits sets the resource limits of plugins
+func (p *pluginManager) SetResourceLimits(l *resourceLimits) {
+	p.resourceLimits = l
+}
+
+// SetSocketDir sets the directory of the Unix sockets plugins listen on
+func (p *pluginManager) SetSocketDir(dir string) {
+	p.socketDir = dir
+}
+
 // SetMetricCatalog sets metric catalog
 func (p *pluginManager) SetMetricCatalog(mc catalogsMetrics) {
 	p.metricCatalog = mc
@@ -268,40 +323,31 @@ func (p *pluginManager) SetMetricCatalog(mc catalogsMetrics) {
 func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter) (*loadedPlugin, serror.SnapError) {
 	lPlugin := new(loadedPlugin)
 	lPlugin.Details = details
+	lPlugin.restarts = &pluginRestarts{}
 	lPlugin.State = DetectedState
 
 	pmLogger.WithFields(log.Fields{
 		"_block": "load-plugin",
 		"path":   filepath.Base(lPlugin.Details.Exec),
 	}).Info("plugin load called")
-	ePlugin, err := plugin.NewExecutablePlugin(p.GenerateArgs(lPlugin.Details.Exec), path.Join(lPlugin.Details.ExecPath, lPlugin.Details.Exec))
-
+	// the limits of the plugin are only known from its response so it is
+	// started with those of every plugin and restarted with its own before
+	// it is called
+	limits := p.resourceLimits.forAll()
+	ePlugin, resp, err := p.startLoadPlugin(lPlugin.Details, limits)
 	if err != nil {
-		pmLogger.WithFields(log.Fields{
-			"_block": "load-plugin",
-			"error":  err.Error(),
-		}).Error("load plugin error while creating executable plugin")
-		return nil, serror.New(err)
-	}
-
-	err = ePlugin.Start()
-	if err != nil {
-		pmLogger.WithFields(log.Fields{
-			"_block": "load-plugin",
-			"error":  err.Error(),
-		}).Error("load plugin error while starting plugin")
 		return nil, serror.New(err)
 	}
-
-	var resp *plugin.Response
-	resp, err = ePlugin.WaitForResponse(time.Second * 3)
-
-	if err != nil {
+	if own := p.resourceLimits.get(&loadedPlugin{Meta: resp.Meta, Type: resp.Type}); resp.State == plugin.PluginSuccess && !reflect.DeepEqual(own, limits) {
 		pmLogger.WithFields(log.Fields{
 			"_block": "load-plugin",
-			"error":  err.Error(),
-		}).Error("load plugin error while waiting for response from plugin")
-		return nil, serror.New(err)
+			"path":   filepath.Base(lPlugin.Details.Exec),
+		}).Debug("restarting plugin with its resource limits")
+		ePlugin.Kill()
+		removeResponseSocket(resp)
+		if ePlugin, resp, err = p.startLoadPlugin(lPlugin.Details, own); err != nil {
+			return nil, serror.New(err)
+		}
 	}
 
 	ap, err := newAvailablePlugin(resp, emitter, ePlugin)
@@ -313,90 +359,8 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 		return nil, serror.New(err)
 	}
 
-	if resp.Meta.Unsecure {
-		err = ap.client.Ping()
-	} else {
-		err = ap.client.SetKey()
-	}
-	if err != nil {
-		pmLogger.WithFields(log.Fields{
-			"_block": "load-plugin",
-			"error":  err.Error(),
-		}).Error("load plugin error while pinging the plugin")
-		return nil, serror.New(err)
-	}
-
-	// Get the ConfigPolicy and add it to the loaded plugin
-	c, ok := ap.client.(plugin.Plugin)
-	if !ok {
-		return nil, serror.New(errors.New("missing GetConfigPolicy function"))
-	}
-	cp, err := c.GetConfigPolicy()
-	if err != nil {
-		pmLogger.WithFields(log.Fields{
-			"_block":         "load-plugin",
-			"plugin-type":    "collector",
-			"error":          err.Error(),
-			"plugin-name":    ap.Name(),
-			"plugin-version": ap.Version(),
-			"plugin-id":      ap.ID(),
-		}).Error("error in getting config policy")
-		return nil, serror.New(err)
-	}
-	lPlugin.ConfigPolicy = cp
-
-	if resp.Type == plugin.CollectorPluginType {
-		colClient := ap.client.(client.PluginCollectorClient)
-
-		cfg := plugin.PluginConfigType{
-			ConfigDataNode: p.pluginConfig.getPluginConfigDataNode(core.PluginType(resp.Type), resp.Meta.Name, resp.Meta.Version),
-		}
-
-		metricTypes, err := colClient.GetMetricTypes(cfg)
-		if err != nil {
-			pmLogger.WithFields(log.Fields{
-				"_block":      "load-plugin",
-				"plugin-type": "collector",
-				"error":       err.Error(),
-			}).Error("error in getting metric types")
-			return nil, serror.New(err)
-		}
-
-		// Add metric types to metric catalog
-		for _, nmt := range metricTypes {
-			// If the version is 0 default it to the plugin version
-			// This honors the plugins explicit version but falls back
-			// to the plugin version as default
-			if nmt.Version() < 1 {
-				// Since we have to override version we convert to a internal struct
-				nmt = &metricType{
-					namespace:          nmt.Namespace(),
-					version:            resp.Meta.Version,
-					lastAdvertisedTime: nmt.LastAdvertisedTime(),
-					config:             nmt.Config(),
-					data:               nmt.Data(),
-					tags:               nmt.Tags(),
-					labels:             nmt.Labels(),
-				}
-			}
-			// We quit and throw an error on bad metric versions (<1)
-			// the is a safety catch otherwise the catalog will be corrupted
-			if nmt.Version() < 1 {
-				err := errors.New("Bad metric version from plugin")
-				pmLogger.WithFields(log.Fields{
-					"_block":           "load-plugin",
-					"plugin-name":      resp.Meta.Name,
-					"plugin-version":   resp.Meta.Version,
-					"plugin-type":      resp.Meta.Type.String(),
-					"plugin-path":      filepath.Base(lPlugin.Details.ExecPath),
-					"metric-namespace": nmt.Namespace(),
-					"metric-version":   nmt.Version(),
-					"error":            err.Error(),
-				}).Error("received metric with bad version")
-				return nil, serror.New(err)
-			}
-			p.metricCatalog.AddLoadedMetricType(lPlugin, nmt)
-		}
+	if serr := p.catalogPlugin(lPlugin, ap, resp); serr != nil {
+		return nil, serr
 	}
 
 	err = ePlugin.Kill()
@@ -436,6 +400,49 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 	return lPlugin, nil
 }
 
+// startLoadPlugin starts the plugin of details with limits and waits for its
+// response
+func (p *pluginManager) startLoadPlugin(details *pluginDetails, limits *plugin.ResourceLimits) (*plugin.ExecutablePlugin, *plugin.Response, error) {
+	ePlugin, err := plugin.NewExecutablePlugin(p.GenerateArgs(details.Exec), path.Join(details.ExecPath, details.Exec))
+	if err != nil {
+		pmLogger.WithFields(log.Fields{
+			"_block": "load-plugin",
+			"error":  err.Error(),
+		}).Error("load plugin error while creating executable plugin")
+		return nil, nil, err
+	}
+	if !limits.IsZero() {
+		ePlugin.SetResourceLimits(limits, nil)
+	}
+
+	err = ePlugin.Start()
+	if err != nil {
+		pmLogger.WithFields(log.Fields{
+			"_block": "load-plugin",
+			"error":  err.Error(),
+		}).Error("load plugin error while starting plugin")
+		return nil, nil, err
+	}
+
+	resp, err := ePlugin.WaitForResponse(time.Second * 3)
+	if err != nil {
+		pmLogger.WithFields(log.Fields{
+			"_block": "load-plugin",
+			"error":  err.Error(),
+		}).Error("load plugin error while waiting for response from plugin")
+		return nil, nil, err
+	}
+	return ePlugin, resp, nil
+}
+
+// removeResponseSocket removes the Unix socket, if any, a plugin killed
+// before an available plugin was made from its response listened on
+func removeResponseSocket(resp *plugin.Response) {
+	if strings.HasPrefix(resp.ListenAddress, plugin.UnixAddressPrefix) {
+		os.Remove(strings.TrimPrefix(resp.ListenAddress, plugin.UnixAddressPrefix))
+	}
+}
+
 // UnloadPlugin unloads a plugin from the LoadedPlugins table
 func (p *pluginManager) UnloadPlugin(pl core.Plugin) (*loadedPlugin, serror.SnapError) {
 
@@ -494,10 +501,108 @@ func (p *pluginManager) UnloadPlugin(pl core.Plugin) (*loadedPlugin, serror.Snap
 	return plugin, nil
 }
 
+// catalogPlugin gets the config policy of a plugin, and the metric types of a
+// collector, from the available plugin started to load it
+func (p *pluginManager) catalogPlugin(lPlugin *loadedPlugin, ap *availablePlugin, resp *plugin.Response) serror.SnapError {
+	var err error
+	if resp.Meta.Unsecure {
+		err = ap.client.Ping()
+	} else {
+		err = ap.client.SetKey()
+	}
+	if err != nil {
+		pmLogger.WithFields(log.Fields{
+			"_block": "load-plugin",
+			"error":  err.Error(),
+		}).Error("load plugin error while pinging the plugin")
+		return serror.New(err)
+	}
+
+	// Get the ConfigPolicy and add it to the loaded plugin
+	c, ok := ap.client.(plugin.Plugin)
+	if !ok {
+		return serror.New(errors.New("missing GetConfigPolicy function"))
+	}
+	cp, err := c.GetConfigPolicy()
+	if err != nil {
+		pmLogger.WithFields(log.Fields{
+			"_block":         "load-plugin",
+			"plugin-type":    "collector",
+			"error":          err.Error(),
+			"plugin-name":    ap.Name(),
+			"plugin-version": ap.Version(),
+			"plugin-id":      ap.ID(),
+		}).Error("error in getting config policy")
+		return serror.New(err)
+	}
+	lPlugin.ConfigPolicy = cp
+
+	if resp.Type == plugin.CollectorPluginType {
+		colClient := ap.client.(client.PluginCollectorClient)
+
+		cfg := plugin.PluginConfigType{
+			ConfigDataNode: p.pluginConfig.getPluginConfigDataNode(core.PluginType(resp.Type), resp.Meta.Name, resp.Meta.Version),
+		}
+
+		metricTypes, err := colClient.GetMetricTypes(cfg)
+		if err != nil {
+			pmLogger.WithFields(log.Fields{
+				"_block":      "load-plugin",
+				"plugin-type": "collector",
+				"error":       err.Error(),
+			}).Error("error in getting metric types")
+			return serror.New(err)
+		}
+
+		// Add metric types to metric catalog
+		for _, nmt := range metricTypes {
+			// If the version is 0 default it to the plugin version
+			// This honors the plugins explicit version but falls back
+			// to the plugin version as default
+			if nmt.Version() < 1 {
+				// Since we have to override version we convert to a internal struct
+				nmt = &metricType{
+					namespace:          nmt.Namespace(),
+					version:            resp.Meta.Version,
+					lastAdvertisedTime: nmt.LastAdvertisedTime(),
+					config:             nmt.Config(),
+					data:               nmt.Data(),
+					tags:               nmt.Tags(),
+					labels:             nmt.Labels(),
+				}
+			}
+			// We quit and throw an error on bad metric versions (<1)
+			// the is a safety catch otherwise the catalog will be corrupted
+			if nmt.Version() < 1 {
+				err := errors.New("Bad metric version from plugin")
+				pmLogger.WithFields(log.Fields{
+					"_block":           "load-plugin",
+					"plugin-name":      resp.Meta.Name,
+					"plugin-version":   resp.Meta.Version,
+					"plugin-type":      resp.Meta.Type.String(),
+					"plugin-path":      filepath.Base(lPlugin.Details.ExecPath),
+					"metric-namespace": nmt.Namespace(),
+					"metric-version":   nmt.Version(),
+					"error":            err.Error(),
+				}).Error("received metric with bad version")
+				return serror.New(err)
+			}
+			p.metricCatalog.AddLoadedMetricType(lPlugin, nmt)
+		}
+	}
+	return nil
+}
+
 // GenerateArgs generates the cli args to send when stating a plugin
 func (p *pluginManager) GenerateArgs(pluginPath string) plugin.Arg {
 	pluginLog := filepath.Join(p.logPath, filepath.Base(pluginPath)) + ".log"
-	return plugin.NewArg(pluginLog)
+	arg := plugin.NewArg(pluginLog)
+	if p.socketDir != "" {
+		// every instance of a plugin gets its own socket
+		n := atomic.AddUint64(&p.sockets, 1)
+		arg.ListenSocket = filepath.Join(p.socketDir, fmt.Sprintf("%s-%d.sock", filepath.Base(pluginPath), n))
+	}
+	return arg
 }
 
 func (p *pluginManager) teardown() {
@@ -518,8 +623,13 @@ func (p *pluginManager) get(key string) (*loadedPlugin, error) {
 	return p.loadedPlugins.get(key)
 }
 
+// all returns a copy of the table of loaded plugins taken under its lock
 func (p *pluginManager) all() map[string]*loadedPlugin {
 	p.loadedPlugins.RLock()
 	defer p.loadedPlugins.RUnlock()
-	return p.loadedPlugins.table
+	table := make(map[string]*loadedPlugin, len(p.loadedPlugins.table))
+	for k, lp := range p.loadedPlugins.table {
+		table[k] = lp
+	}
+	return table
 }
@@ -70,6 +70,16 @@ func TestLoadedPlugins(t *testing.T) {
 
 		})
 	})
+	Convey("all", t, func() {
+		Convey("returns a copy of the table", func() {
+			p := newPluginManager()
+			p.loadedPlugins.add(&loadedPlugin{Meta: plugin.PluginMeta{Name: "test1"}})
+			table := p.all()
+			p.loadedPlugins.add(&loadedPlugin{Meta: plugin.PluginMeta{Name: "test2"}})
+			So(len(table), ShouldEqual, 1)
+			So(len(p.all()), ShouldEqual, 2)
+		})
+	})
 }
 
 func loadPlugin(p *pluginManager, path string) (*loadedPlugin, serror.SnapError) {
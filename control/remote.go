@@ -0,0 +1,228 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/gomit"
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	// ErrBadRemotePluginURL - error message when the URL of a remote plugin is not one snapd can connect to
+	ErrBadRemotePluginURL = errors.New("remote plugin URL must be http, https, tcp or grpc with a host")
+	// ErrBadRemotePluginKey - error message when the public key of a remote plugin cannot be read
+	ErrBadRemotePluginKey = errors.New("remote plugin public key must be a PEM encoded RSA public key")
+	// ErrBadRemotePluginType - error message when the type of a remote plugin is unknown
+	ErrBadRemotePluginType = errors.New("remote plugin type must be collector, processor or publisher")
+	// ErrBadRemotePluginMeta - error message when a remote plugin is missing its name or version
+	ErrBadRemotePluginMeta = errors.New("remote plugin must have a name and a version greater than 0")
+	// ErrRemotePluginUntrusted - error message when a remote plugin is loaded while plugin trust is enabled
+	ErrRemotePluginUntrusted = errors.New("remote plugins cannot be signed and are refused while plugin trust is enabled")
+)
+
+// LoadRemote loads a plugin running as a service which snapd connects to
+// instead of starting it.  The plugin is health checked and routed to like
+// any other but it is never started or killed.  A remote plugin has no file
+// to check the signature of so it is refused while plugin trust is enabled
+// unless AllowRemotePlugins was given.
+func (p *pluginControl) LoadRemote(rp *core.RemotePlugin) (core.CatalogedPlugin, serror.SnapError) {
+	f := map[string]interface{}{
+		"_block": "load-remote",
+		"url":    rp.URL,
+	}
+	controlLogger.WithFields(f).Info("remote plugin load called")
+	if !p.Started {
+		se := serror.New(ErrControllerNotStarted)
+		se.SetFields(f)
+		controlLogger.WithFields(f).Error(se)
+		return nil, se
+	}
+	switch p.pluginTrust {
+	case PluginTrustEnabled:
+		if !p.allowRemotePlugins {
+			se := serror.New(ErrRemotePluginUntrusted)
+			se.SetFields(f)
+			controlLogger.WithFields(f).Error(se)
+			return nil, se
+		}
+		controlLogger.WithFields(f).Warn("Loading unsigned remote plugin")
+	case PluginTrustWarn:
+		controlLogger.WithFields(f).Warn("Loading unsigned remote plugin")
+	}
+
+	pl, ap, se := p.pluginManager.LoadRemotePlugin(rp, p.eventManager)
+	if se != nil {
+		return nil, se
+	}
+	// the remote plugin is the only instance of its pool
+	if err := p.pluginRunner.AvailablePlugins().insert(ap); err != nil {
+		p.pluginManager.UnloadPlugin(pl)
+		return nil, serror.New(err)
+	}
+
+	event := &control_event.LoadPluginEvent{
+		Name:    pl.Meta.Name,
+		Version: pl.Meta.Version,
+		Type:    int(pl.Meta.Type),
+	}
+	defer p.eventManager.Emit(event)
+	return pl, nil
+}
+
+// LoadRemotePlugin loads a plugin running as a service returning the
+// available plugin snapd connects to it through
+func (p *pluginManager) LoadRemotePlugin(rp *core.RemotePlugin, emitter gomit.Emitter) (*loadedPlugin, *availablePlugin, serror.SnapError) {
+	f := log.Fields{
+		"_block": "load-remote-plugin",
+		"url":    rp.URL,
+	}
+	pmLogger.WithFields(f).Info("remote plugin load called")
+	resp, err := remotePluginResponse(rp)
+	if err != nil {
+		pmLogger.WithFields(f).WithField("error", err).Error("load remote plugin error")
+		return nil, nil, serror.New(err)
+	}
+	lPlugin := &loadedPlugin{
+		Meta:     resp.Meta,
+		Type:     resp.Type,
+		Details:  &pluginDetails{URL: rp.URL},
+		State:    DetectedState,
+		restarts: &pluginRestarts{},
+	}
+	// check before adding the metrics of the plugin to the catalog
+	if _, err := p.loadedPlugins.get(lPlugin.Key()); err == nil {
+		return nil, nil, serror.New(ErrPluginAlreadyLoaded, map[string]interface{}{
+			"plugin-name":    lPlugin.Meta.Name,
+			"plugin-version": lPlugin.Meta.Version,
+			"plugin-type":    lPlugin.Type.String(),
+		})
+	}
+
+	ap, err := newAvailablePlugin(resp, emitter, nil)
+	if err != nil {
+		pmLogger.WithFields(f).WithField("error", err).Error("load remote plugin error while creating available plugin")
+		return nil, nil, serror.New(err)
+	}
+	ap.remote = true
+	if serr := p.catalogPlugin(lPlugin, ap, resp); serr != nil {
+		return nil, nil, serr
+	}
+
+	lPlugin.LoadedTime = time.Now()
+	lPlugin.State = LoadedState
+	if serr := p.loadedPlugins.add(lPlugin); serr != nil {
+		pmLogger.WithFields(f).WithField("error", serr).Error("load remote plugin error while adding loaded plugin to load plugins collection")
+		return nil, nil, serr
+	}
+	return lPlugin, ap, nil
+}
+
+// isRemote returns whether the plugin runs as a service snapd connects to
+func (lp *loadedPlugin) isRemote() bool {
+	return lp.Details != nil && lp.Details.URL != ""
+}
+
+// remotePluginResponse returns the response a remote plugin would give if
+// snapd had started it
+func remotePluginResponse(rp *core.RemotePlugin) (*plugin.Response, error) {
+	if rp.Type < core.CollectorPluginType || rp.Type > core.PublisherPluginType {
+		return nil, ErrBadRemotePluginType
+	}
+	if rp.Name == "" || rp.Version < 1 {
+		return nil, ErrBadRemotePluginMeta
+	}
+	u, err := url.Parse(rp.URL)
+	if err != nil || u.Host == "" {
+		return nil, ErrBadRemotePluginURL
+	}
+	// snapd always encrypts what it sends to a remote plugin
+	key, err := parsePublicKey(rp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	meta := plugin.NewPluginMeta(rp.Name, rp.Version, plugin.PluginType(rp.Type), nil, nil,
+		plugin.Exclusive(true),
+	)
+	resp := &plugin.Response{
+		Type:      meta.Type,
+		State:     plugin.PluginSuccess,
+		PublicKey: key,
+	}
+	switch u.Scheme {
+	case "http", "https":
+		meta.RPCType = plugin.JSONRPC
+		resp.ListenAddress = rp.URL
+	case "tcp":
+		meta.RPCType = plugin.NativeRPC
+		resp.ListenAddress = u.Host
+	case "grpc":
+		meta.RPCType = plugin.GRPC
+		resp.ListenAddress = u.Host
+	default:
+		return nil, ErrBadRemotePluginURL
+	}
+	resp.Meta = *meta
+	return resp, nil
+}
+
+// parsePublicKey reads a PEM encoded RSA public key, in the PKIX or PKCS#1
+// form, or the JSON of one as plugins give it in their response
+func parsePublicKey(b []byte) (*rsa.PublicKey, error) {
+	if len(b) == 0 {
+		return nil, ErrBadRemotePluginKey
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		key := &rsa.PublicKey{}
+		if err := json.Unmarshal(b, key); err != nil || key.N == nil {
+			return nil, ErrBadRemotePluginKey
+		}
+		return key, nil
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, ErrBadRemotePluginKey
+		}
+		return key, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrBadRemotePluginKey
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrBadRemotePluginKey
+	}
+	return key, nil
+}
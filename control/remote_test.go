@@ -0,0 +1,212 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func TestRemotePluginResponse(t *testing.T) {
+	Convey("remotePluginResponse", t, func() {
+		key, err := rsa.GenerateKey(rand.Reader, 1024)
+		So(err, ShouldBeNil)
+		pub := &key.PublicKey
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		So(err, ShouldBeNil)
+		rp := &core.RemotePlugin{
+			URL:       "tcp://10.0.0.1:8183",
+			PublicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+			Name:      "mock",
+			Version:   1,
+			Type:      core.CollectorPluginType,
+		}
+
+		Convey("connects to an exclusive plugin by the scheme of its URL", func() {
+			resp, err := remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.Meta.RPCType, ShouldEqual, plugin.NativeRPC)
+			So(resp.ListenAddress, ShouldEqual, "10.0.0.1:8183")
+			So(resp.Meta.Exclusive, ShouldBeTrue)
+			So(resp.Meta.Unsecure, ShouldBeFalse)
+
+			rp.URL = "grpc://10.0.0.1:8183"
+			resp, err = remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.Meta.RPCType, ShouldEqual, plugin.GRPC)
+
+			rp.URL = "https://10.0.0.1:8183/rpc"
+			resp, err = remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.Meta.RPCType, ShouldEqual, plugin.JSONRPC)
+			So(resp.ListenAddress, ShouldEqual, rp.URL)
+		})
+
+		Convey("reads the public key as PEM or as plugins give it", func() {
+			resp, err := remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.PublicKey, ShouldResemble, pub)
+
+			rp.PublicKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(pub)})
+			resp, err = remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.PublicKey, ShouldResemble, pub)
+
+			rp.PublicKey, _ = json.Marshal(pub)
+			resp, err = remotePluginResponse(rp)
+			So(err, ShouldBeNil)
+			So(resp.PublicKey, ShouldResemble, pub)
+		})
+
+		Convey("requires the public key", func() {
+			rp.PublicKey = nil
+			_, err := remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginKey)
+			rp.PublicKey = []byte("not a key")
+			_, err = remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginKey)
+		})
+
+		Convey("refuses what it cannot connect to", func() {
+			rp.URL = "ftp://10.0.0.1"
+			_, err := remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginURL)
+			rp.URL = "10.0.0.1:8183"
+			_, err = remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginURL)
+			rp.URL = "tcp://10.0.0.1:8183"
+			rp.Type = 3
+			_, err = remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginType)
+			rp.Type = core.CollectorPluginType
+			rp.Version = 0
+			_, err = remotePluginResponse(rp)
+			So(err, ShouldEqual, ErrBadRemotePluginMeta)
+		})
+	})
+}
+
+func TestLoadRemote(t *testing.T) {
+	if SnapPath == "" {
+		return
+	}
+	Convey("a plugin running as a service", t, func() {
+		ep, err := plugin.NewExecutablePlugin(plugin.NewArg(filepath.Join(os.TempDir(), "snap-remote-mock.log")), PluginPath)
+		So(err, ShouldBeNil)
+		So(ep.Start(), ShouldBeNil)
+		defer ep.Kill()
+		resp, err := ep.WaitForResponse(3 * time.Second)
+		So(err, ShouldBeNil)
+		So(resp.PublicKey, ShouldNotBeNil)
+		key, err := json.Marshal(resp.PublicKey)
+		So(err, ShouldBeNil)
+		rp := &core.RemotePlugin{
+			URL:       "tcp://" + resp.ListenAddress,
+			PublicKey: key,
+			Name:      "mock",
+			Version:   2,
+			Type:      core.CollectorPluginType,
+		}
+
+		Convey("is refused while plugin trust is enabled", func() {
+			c := New()
+			So(c.Start(), ShouldBeNil)
+			defer c.Stop()
+			c.SetPluginTrustLevel(PluginTrustEnabled)
+			_, serr := c.LoadRemote(rp)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, ErrRemotePluginUntrusted.Error())
+
+			Convey("unless remote plugins are allowed", func() {
+				AllowRemotePlugins()(c)
+				pl, serr := c.LoadRemote(rp)
+				So(serr, ShouldBeNil)
+				So(pl.Name(), ShouldEqual, "mock")
+			})
+		})
+
+		c := New()
+		c.pluginRunner.(*runner).monitor.duration = time.Millisecond * 100
+		So(c.Start(), ShouldBeNil)
+		defer c.Stop()
+
+		pl, serr := c.LoadRemote(rp)
+		So(serr, ShouldBeNil)
+		So(pl.Name(), ShouldEqual, "mock")
+		mts, err := c.MetricCatalog()
+		So(err, ShouldBeNil)
+		So(mts, ShouldHaveLength, 3)
+		_, serr = c.LoadRemote(rp)
+		So(serr, ShouldNotBeNil)
+		So(serr.Error(), ShouldEqual, ErrPluginAlreadyLoaded.Error())
+
+		Convey("is routed to and never killed", func() {
+			pool, err := c.pluginRunner.AvailablePlugins().getPool("collector:mock:2")
+			So(err, ShouldBeNil)
+			So(pool.Count(), ShouldEqual, 1)
+			pool.Subscribe("1", strategy.UnboundSubscriptionType)
+			So(pool.Eligible(), ShouldBeFalse)
+
+			cd := cdata.NewNode()
+			cd.AddItem("password", ctypes.ConfigValueStr{Value: "testval"})
+			cr, errs := c.CollectMetrics([]core.Metric{
+				MockMetricType{namespace: []string{"intel", "mock", "foo"}, cfg: cd},
+			}, time.Now().Add(time.Second), "1")
+			So(errs, ShouldBeEmpty)
+			So(cr, ShouldHaveLength, 1)
+			So(cr[0].Data(), ShouldBeBetween, 64, 90)
+
+			pool.Unsubscribe("1")
+			So(c.pluginRunner.(*runner).handleUnsubscription("collector", "mock", 2, "1"), ShouldBeNil)
+			// the health checks keep the plugin alive
+			time.Sleep(time.Millisecond * 300)
+			So(pool.Count(), ShouldEqual, 1)
+
+			Convey("until it is unloaded", func() {
+				_, serr := c.Unload(pl)
+				So(serr, ShouldBeNil)
+				for i := 0; i < 50 && pool.Count() > 0; i++ {
+					time.Sleep(time.Millisecond * 10)
+				}
+				So(pool.Count(), ShouldEqual, 0)
+				// the service is still running
+				cl, err := client.NewCollectorNativeClient(resp.ListenAddress, time.Second, resp.PublicKey, true)
+				So(err, ShouldBeNil)
+				So(cl.Ping(), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,96 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// resourceLimits holds the resource limits given in the config.  The limits
+// of a plugin are those declared in its meta overridden by "all", then by
+// "all" of its type and then by its name.  An example is in
+// github.com/intelsdi-x/snap/examples/configs/snap-config-sample.
+type resourceLimits struct {
+	All       *plugin.ResourceLimits            `json:"all"`
+	Collector map[string]*plugin.ResourceLimits `json:"collector"`
+	Processor map[string]*plugin.ResourceLimits `json:"processor"`
+	Publisher map[string]*plugin.ResourceLimits `json:"publisher"`
+}
+
+func newResourceLimits() *resourceLimits {
+	return &resourceLimits{}
+}
+
+// UnmarshalJSON unmarshals the resource limits validating each of them
+func (r *resourceLimits) UnmarshalJSON(data []byte) error {
+	type limits resourceLimits
+	if err := json.Unmarshal(data, (*limits)(r)); err != nil {
+		return err
+	}
+	all := []*plugin.ResourceLimits{r.All}
+	for _, byName := range []map[string]*plugin.ResourceLimits{r.Collector, r.Processor, r.Publisher} {
+		for _, l := range byName {
+			all = append(all, l)
+		}
+	}
+	for _, l := range all {
+		if l == nil {
+			continue
+		}
+		if err := l.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forAll returns the resource limits every plugin has, which are those of a
+// plugin not known yet
+func (r *resourceLimits) forAll() *plugin.ResourceLimits {
+	if r == nil {
+		return &plugin.ResourceLimits{}
+	}
+	return r.All.Merge(nil)
+}
+
+// get returns the resource limits of the plugin
+func (r *resourceLimits) get(lp *loadedPlugin) *plugin.ResourceLimits {
+	l := lp.Meta.ResourceLimits.Merge(nil)
+	if r == nil {
+		return l
+	}
+	var byName map[string]*plugin.ResourceLimits
+	switch core.PluginType(lp.Type) {
+	case core.CollectorPluginType:
+		byName = r.Collector
+	case core.ProcessorPluginType:
+		byName = r.Processor
+	case core.PublisherPluginType:
+		byName = r.Publisher
+	}
+	l = l.Merge(r.All).Merge(byName["all"])
+	if lp.Name() != "all" {
+		l = l.Merge(byName[lp.Name()])
+	}
+	return l
+}
@@ -0,0 +1,161 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+// TestMain runs the test binary as the limits shim, the way snapd runs itself
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == plugin.LimitsShimArg {
+		plugin.RunLimitsShim(os.Args[2:])
+	}
+	if exe, err := os.Executable(); err == nil {
+		plugin.LimitsShim = []string{exe, plugin.LimitsShimArg}
+	}
+	os.Exit(m.Run())
+}
+
+// childPids returns the pids of the child processes of snapd running path
+func childPids(path string) map[int]bool {
+	pids := map[int]bool{}
+	dirs, _ := ioutil.ReadDir("/proc")
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		cmdline, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+		stat, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		fields := strings.Fields(string(stat))
+		if strings.HasPrefix(string(cmdline), path+"\x00") && len(fields) > 3 && fields[3] == strconv.Itoa(os.Getpid()) {
+			pids[pid] = true
+		}
+	}
+	return pids
+}
+
+func TestResourceLimitsConfig(t *testing.T) {
+	Convey("Resource limits", t, func() {
+		lp := &loadedPlugin{
+			Meta: plugin.PluginMeta{
+				Name:           "mock",
+				ResourceLimits: &plugin.ResourceLimits{Memory: 1 << 20, OpenFiles: 10},
+			},
+			Type: plugin.CollectorPluginType,
+		}
+
+		Convey("default to those of the plugin meta", func() {
+			So(NewConfig().ResourceLimits.get(lp), ShouldResemble, &plugin.ResourceLimits{Memory: 1 << 20, OpenFiles: 10})
+		})
+
+		Convey("are overridden by the config", func() {
+			cfg := NewConfig()
+			So(json.Unmarshal([]byte(`{
+				"resource_limits": {
+					"all": {"open_files": 64, "cpu_shares": 512},
+					"collector": {
+						"all": {"working_dir": "/tmp"},
+						"mock": {"memory": 2097152}
+					},
+					"publisher": {
+						"mock": {"memory": 1}
+					}
+				}
+			}`), cfg), ShouldBeNil)
+			So(cfg.ResourceLimits.get(lp), ShouldResemble, &plugin.ResourceLimits{
+				Memory:     2 << 20,
+				OpenFiles:  64,
+				CPUShares:  512,
+				WorkingDir: "/tmp",
+			})
+		})
+
+		Convey("are validated", func() {
+			err := json.Unmarshal([]byte(`{"resource_limits": {"collector": {"mock": {"cpu_shares": 1}}}}`), NewConfig())
+			So(err, ShouldEqual, plugin.ErrBadCPUShares)
+		})
+	})
+}
+
+func TestRunPluginWithLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	Convey("given a plugin with limits in the config", t, func() {
+		cfg := NewConfig()
+		So(json.Unmarshal([]byte(`{"resource_limits": {"collector": {"mock": {"open_files": 1000}}}}`), cfg), ShouldBeNil)
+		c := New(OptSetConfig(cfg))
+		c.Start()
+		_, serr := load(c, JSONRPCPluginPath)
+		So(serr, ShouldBeNil)
+		lp, err := c.pluginManager.get("collector:mock:1")
+		So(err, ShouldBeNil)
+
+		Convey("its processes are run with the limits", func() {
+			before := childPids(JSONRPCPluginPath)
+			So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+			var pid int
+			for p := range childPids(JSONRPCPluginPath) {
+				if !before[p] {
+					pid = p
+				}
+			}
+			So(pid, ShouldNotEqual, 0)
+			limits, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+			So(err, ShouldBeNil)
+			So(string(limits), ShouldContainSubstring, "Max open files            1000                 1000")
+		})
+	})
+	Convey("given limits which cannot be enforced", t, func() {
+		loadWith := func(limits string) serror.SnapError {
+			cfg := NewConfig()
+			So(json.Unmarshal([]byte(`{"resource_limits": `+limits+`}`), cfg), ShouldBeNil)
+			c := New(OptSetConfig(cfg))
+			c.Start()
+			defer c.Stop()
+			_, serr := load(c, JSONRPCPluginPath)
+			So(c.pluginManager.all(), ShouldBeEmpty)
+			return serr
+		}
+
+		Convey("on every plugin its load fails before it responds", func() {
+			serr := loadWith(`{"all": {"working_dir": "/does/not/exist"}}`)
+			So(serr, ShouldNotBeNil)
+		})
+		Convey("on the plugin its load fails before it is called", func() {
+			serr := loadWith(`{"collector": {"mock": {"working_dir": "/does/not/exist"}}}`)
+			So(serr, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,222 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
+)
+
+type restartPolicyType string
+
+const (
+	// RestartNever leaves a plugin instance which failed its health checks dead
+	RestartNever restartPolicyType = "never"
+	// RestartOnFailure restarts a plugin instance which failed its health
+	// checks while tasks are subscribed to the plugin, up to max_restarts times
+	RestartOnFailure restartPolicyType = "on-failure"
+	// RestartAlways restarts every plugin instance which failed its health
+	// checks
+	RestartAlways restartPolicyType = "always"
+)
+
+const (
+	defaultMaxRestarts       = 5
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = time.Minute
+)
+
+var (
+	// ErrBadRestartPolicy - error message when a restart policy is not one of
+	// never, on-failure or always
+	ErrBadRestartPolicy = errors.New("restart policy must be one of never, on-failure or always")
+	// ErrBadRestartBackoff - error message when a restart backoff is not positive
+	ErrBadRestartBackoff = errors.New("restart backoff must be positive")
+	// ErrBadMaxRestarts - error message when max_restarts is negative
+	ErrBadMaxRestarts = errors.New("max_restarts must not be negative")
+
+	restartCount = telemetry.Default.Counter(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "restarts"},
+		Labels:      pluginLabels,
+		Description: "Instances of the plugin restarted after failing their health checks",
+	})
+)
+
+// restartPolicy is what happens to a plugin instance which failed its health
+// checks.  The nth restart of a plugin waits backoff*2^(n-1), at most
+// maxBackoff.
+type restartPolicy struct {
+	policy      restartPolicyType
+	maxRestarts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+func defaultRestartPolicy() restartPolicy {
+	return restartPolicy{
+		policy:      RestartOnFailure,
+		maxRestarts: defaultMaxRestarts,
+		backoff:     defaultRestartBackoff,
+		maxBackoff:  defaultRestartMaxBackoff,
+	}
+}
+
+// allows returns whether a plugin restarted n times before may be restarted
+// again
+func (r restartPolicy) allows(n int) bool {
+	switch r.policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return n < r.maxRestarts
+	}
+	return false
+}
+
+// delay returns how long to wait before restarting a plugin restarted n times
+// before
+func (r restartPolicy) delay(n int) time.Duration {
+	d := r.backoff
+	for i := 0; i < n && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		return r.maxBackoff
+	}
+	return d
+}
+
+// restartPolicyConfig is a restart policy as given in the config.  The fields
+// it omits are inherited.
+type restartPolicyConfig struct {
+	policy      *restartPolicyType
+	maxRestarts *int
+	backoff     *time.Duration
+	maxBackoff  *time.Duration
+}
+
+// UnmarshalJSON unmarshals a restart policy such as
+// {"policy": "on-failure", "max_restarts": 5, "backoff": "1s", "max_backoff": "1m"}
+func (r *restartPolicyConfig) UnmarshalJSON(data []byte) error {
+	var c struct {
+		Policy      *restartPolicyType `json:"policy"`
+		MaxRestarts *int               `json:"max_restarts"`
+		Backoff     *string            `json:"backoff"`
+		MaxBackoff  *string            `json:"max_backoff"`
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	if c.Policy != nil {
+		switch *c.Policy {
+		case RestartNever, RestartOnFailure, RestartAlways:
+		default:
+			return ErrBadRestartPolicy
+		}
+	}
+	if c.MaxRestarts != nil && *c.MaxRestarts < 0 {
+		return ErrBadMaxRestarts
+	}
+	r.policy = c.Policy
+	r.maxRestarts = c.MaxRestarts
+	var err error
+	if r.backoff, err = parseBackoff(c.Backoff); err != nil {
+		return err
+	}
+	if r.maxBackoff, err = parseBackoff(c.MaxBackoff); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseBackoff(s *string) (*time.Duration, error) {
+	if s == nil {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(*s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid restart backoff %q: %v", *s, err)
+	}
+	if d <= 0 {
+		return nil, ErrBadRestartBackoff
+	}
+	return &d, nil
+}
+
+func (r *restartPolicyConfig) apply(p *restartPolicy) {
+	if r == nil {
+		return
+	}
+	if r.policy != nil {
+		p.policy = *r.policy
+	}
+	if r.maxRestarts != nil {
+		p.maxRestarts = *r.maxRestarts
+	}
+	if r.backoff != nil {
+		p.backoff = *r.backoff
+	}
+	if r.maxBackoff != nil {
+		p.maxBackoff = *r.maxBackoff
+	}
+}
+
+// restartPolicies holds the restart policies given in the config.  The
+// policy of a plugin is the default policy overridden by "all", then by "all"
+// of its type and then by its name.  An example is in
+// github.com/intelsdi-x/snap/examples/configs/snap-config-sample.
+type restartPolicies struct {
+	All       *restartPolicyConfig            `json:"all"`
+	Collector map[string]*restartPolicyConfig `json:"collector"`
+	Processor map[string]*restartPolicyConfig `json:"processor"`
+	Publisher map[string]*restartPolicyConfig `json:"publisher"`
+}
+
+func newRestartPolicies() *restartPolicies {
+	return &restartPolicies{}
+}
+
+// get returns the restart policy of the plugin
+func (r *restartPolicies) get(pluginType core.PluginType, name string) restartPolicy {
+	p := defaultRestartPolicy()
+	if r == nil {
+		return p
+	}
+	r.All.apply(&p)
+	var byName map[string]*restartPolicyConfig
+	switch pluginType {
+	case core.CollectorPluginType:
+		byName = r.Collector
+	case core.ProcessorPluginType:
+		byName = r.Processor
+	case core.PublisherPluginType:
+		byName = r.Publisher
+	}
+	byName["all"].apply(&p)
+	if name != "all" {
+		byName[name].apply(&p)
+	}
+	return p
+}
@@ -0,0 +1,173 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+func TestRestartPolicies(t *testing.T) {
+	Convey("Restart policies", t, func() {
+		Convey("default to on-failure", func() {
+			p := NewConfig().RestartPolicies.get(core.CollectorPluginType, "mock")
+			So(p, ShouldResemble, defaultRestartPolicy())
+		})
+
+		Convey("are inherited from all", func() {
+			cfg := NewConfig()
+			So(json.Unmarshal([]byte(`{
+				"restart_policies": {
+					"all": {"max_restarts": 2, "backoff": "10ms"},
+					"collector": {
+						"all": {"max_backoff": "50ms"},
+						"mock": {"policy": "always"}
+					},
+					"publisher": {
+						"file": {"policy": "never"}
+					}
+				}
+			}`), cfg), ShouldBeNil)
+			So(cfg.RestartPolicies.get(core.CollectorPluginType, "mock"), ShouldResemble, restartPolicy{
+				policy:      RestartAlways,
+				maxRestarts: 2,
+				backoff:     10 * time.Millisecond,
+				maxBackoff:  50 * time.Millisecond,
+			})
+			So(cfg.RestartPolicies.get(core.CollectorPluginType, "other"), ShouldResemble, restartPolicy{
+				policy:      RestartOnFailure,
+				maxRestarts: 2,
+				backoff:     10 * time.Millisecond,
+				maxBackoff:  50 * time.Millisecond,
+			})
+			So(cfg.RestartPolicies.get(core.PublisherPluginType, "file").policy, ShouldEqual, RestartNever)
+			So(cfg.RestartPolicies.get(core.ProcessorPluginType, "file").maxBackoff, ShouldEqual, defaultRestartMaxBackoff)
+		})
+
+		Convey("are validated", func() {
+			for doc, err := range map[string]error{
+				`{"restart_policies": {"all": {"policy": "sometimes"}}}`: ErrBadRestartPolicy,
+				`{"restart_policies": {"all": {"max_restarts": -1}}}`:    ErrBadMaxRestarts,
+				`{"restart_policies": {"all": {"backoff": "0s"}}}`:       ErrBadRestartBackoff,
+			} {
+				So(json.Unmarshal([]byte(doc), NewConfig()), ShouldEqual, err)
+			}
+			So(json.Unmarshal([]byte(`{"restart_policies": {"all": {"max_backoff": "soon"}}}`), NewConfig()), ShouldNotBeNil)
+		})
+
+		Convey("back off exponentially", func() {
+			p := restartPolicy{policy: RestartOnFailure, maxRestarts: 3, backoff: time.Second, maxBackoff: 5 * time.Second}
+			So(p.delay(0), ShouldEqual, time.Second)
+			So(p.delay(1), ShouldEqual, 2*time.Second)
+			So(p.delay(2), ShouldEqual, 4*time.Second)
+			So(p.delay(3), ShouldEqual, 5*time.Second)
+			So(p.delay(100), ShouldEqual, 5*time.Second)
+		})
+
+		Convey("limit restarts", func() {
+			p := restartPolicy{policy: RestartOnFailure, maxRestarts: 3}
+			So(p.allows(2), ShouldBeTrue)
+			So(p.allows(3), ShouldBeFalse)
+			p.policy = RestartAlways
+			So(p.allows(3), ShouldBeTrue)
+			p.policy = RestartNever
+			So(p.allows(0), ShouldBeFalse)
+		})
+	})
+}
+
+func TestRestartPlugin(t *testing.T) {
+	Convey("given a running plugin a task is subscribed to", t, func() {
+		cfg := NewConfig()
+		So(json.Unmarshal([]byte(`{
+			"restart_policies": {
+				"all": {"max_restarts": 1, "backoff": "10ms"}
+			}
+		}`), cfg), ShouldBeNil)
+		c := New(OptSetConfig(cfg))
+		c.Start()
+		_, serr := load(c, JSONRPCPluginPath)
+		So(serr, ShouldBeNil)
+		lp, err := c.pluginManager.get("collector:mock:1")
+		So(err, ShouldBeNil)
+		pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+		So(err, ShouldBeNil)
+		pool.Subscribe("task1", strategy.BoundSubscriptionType)
+		So(c.pluginRunner.runPlugin(lp), ShouldBeNil)
+		sp, serr := pool.SelectAP("task1")
+		So(serr, ShouldBeNil)
+		r := c.pluginRunner.(*runner)
+		dead := func(id uint32) {
+			r.HandleGomitEvent(gomit.Event{Body: &control_event.DeadAvailablePluginEvent{
+				Name:    "mock",
+				Version: 1,
+				Key:     lp.Key(),
+				Id:      id,
+			}})
+		}
+		restarted := func() bool {
+			for i := 0; i < 100; i++ {
+				if pool.Count() == 1 && lp.Restarts().Count > 0 {
+					return true
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			return false
+		}
+
+		Convey("it is restarted once when it dies", func() {
+			dead(sp.ID())
+			dead(sp.ID())
+			So(restarted(), ShouldBeTrue)
+			restarts := lp.Restarts()
+			So(restarts.Count, ShouldEqual, 1)
+			So(restarts.LastReason, ShouldEqual, "health check failed")
+			So(restarts.LastRestart.IsZero(), ShouldBeFalse)
+
+			// the task moves onto the new instance
+			nsp, serr := pool.SelectAP("task1")
+			So(serr, ShouldBeNil)
+			So(nsp.ID(), ShouldNotEqual, sp.ID())
+
+			Convey("until it has been restarted max_restarts times", func() {
+				dead(nsp.ID())
+				time.Sleep(200 * time.Millisecond)
+				So(pool.Count(), ShouldEqual, 0)
+				So(lp.Restarts().Count, ShouldEqual, 1)
+			})
+		})
+
+		Convey("it is not restarted when no task needs it", func() {
+			pool.Unsubscribe("task1")
+			dead(sp.ID())
+			time.Sleep(200 * time.Millisecond)
+			So(pool.Count(), ShouldEqual, 0)
+			So(lp.Restarts().Count, ShouldEqual, 0)
+		})
+	})
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -70,12 +71,24 @@ type runner struct {
 	availablePlugins *availablePlugins
 	metricCatalog    catalogsMetrics
 	pluginManager    managesPlugins
+	logs             *pluginLogs
+	restartPolicies  *restartPolicies
+	resourceLimits   *resourceLimits
+	// verifyPlugin checks a plugin has not changed since it was loaded
+	// before it is restarted
+	verifyPlugin func(*loadedPlugin) error
+	// deadMutex makes sure a dead instance is restarted once however many
+	// times it is reported dead
+	deadMutex sync.Mutex
 }
 
 func newRunner() *runner {
 	r := &runner{
 		monitor:          newMonitor(),
 		availablePlugins: newAvailablePlugins(),
+		logs:             newPluginLogs(""),
+		restartPolicies:  newRestartPolicies(),
+		resourceLimits:   newResourceLimits(),
 	}
 	return r
 }
@@ -92,10 +105,31 @@ func (r *runner) SetPluginManager(m managesPlugins) {
 	r.pluginManager = m
 }
 
+// SetRestartPolicies sets the policies deciding whether dead plugin instances
+// are restarted
+func (r *runner) SetRestartPolicies(p *restartPolicies) {
+	r.restartPolicies = p
+}
+
+// SetResourceLimits sets the limits put on the processes of plugins
+func (r *runner) SetResourceLimits(l *resourceLimits) {
+	r.resourceLimits = l
+}
+
+// SetPluginVerifier sets the check made on a plugin before restarting it
+func (r *runner) SetPluginVerifier(f func(*loadedPlugin) error) {
+	r.verifyPlugin = f
+}
+
 func (r *runner) AvailablePlugins() *availablePlugins {
 	return r.availablePlugins
 }
 
+// Logs returns the output of the running plugins
+func (r *runner) Logs() *pluginLogs {
+	return r.logs
+}
+
 func (r *runner) Monitor() *monitor {
 	return r.monitor
 }
@@ -239,6 +273,13 @@ func (r *runner) HandleGomitEvent(e gomit.Event) {
 			"event":   v.Namespace(),
 			"aplugin": v.String,
 		}).Warning("handling dead available plugin event")
+		if r.isRemote(v.Key) {
+			runnerLog.WithFields(log.Fields{
+				"_block":  "handle-events",
+				"aplugin": v.String,
+			}).Warning("remote plugin is not responding")
+			return
+		}
 		pool, err := r.availablePlugins.getPool(v.Key)
 		if err != nil {
 			runnerLog.WithFields(log.Fields{
@@ -247,8 +288,8 @@ func (r *runner) HandleGomitEvent(e gomit.Event) {
 			}).Error(err.Error())
 			return
 		}
-		if pool != nil {
-			pool.Kill(v.Id, "plugin dead")
+		if pool != nil && r.killDead(pool, v.Id) {
+			r.restartPlugin(v.Key, "health check failed")
 		}
 	case *control_event.PluginUnsubscriptionEvent:
 		runnerLog.WithFields(log.Fields{
@@ -263,6 +304,8 @@ func (r *runner) HandleGomitEvent(e gomit.Event) {
 		if err != nil {
 			return
 		}
+	case *control_event.UnloadPluginEvent:
+		r.forgetRemote(fmt.Sprintf("%s:%s:%d", core.PluginType(v.Type).String(), v.Name, v.Version))
 	case *control_event.LoadPluginEvent:
 		var pool strategy.Pool
 		r.availablePlugins.RLock()
@@ -353,7 +396,11 @@ func (r *runner) HandleGomitEvent(e gomit.Event) {
 	}
 }
 
-func (r *runner) runPlugin(details *pluginDetails) error {
+func (r *runner) runPlugin(lp *loadedPlugin) error {
+	if lp.isInProcess() {
+		return r.runInProcess(lp)
+	}
+	details := lp.Details
 	if details.IsPackage {
 		f, err := os.Open(details.Path)
 		if err != nil {
@@ -375,15 +422,34 @@ func (r *runner) runPlugin(details *pluginDetails) error {
 		}).Error("error creating executable plugin")
 		return err
 	}
+	out := &pluginOutput{}
+	ePlugin.SetLogSink(out)
+	if limits := r.resourceLimits.get(lp); !limits.IsZero() {
+		ePlugin.SetResourceLimits(limits, func(v plugin.LimitViolation) {
+			r.emitter.Emit(&control_event.PluginLimitReachedEvent{
+				Name:    lp.Name(),
+				Version: lp.Version(),
+				Type:    int(lp.Type),
+				Limit:   v.Limit,
+				Message: v.Message,
+			})
+		})
+	}
 	ap, err := r.startPlugin(ePlugin)
 	if err != nil {
-		runnerLog.WithFields(log.Fields{
+		fields := log.Fields{
 			"_block": "run-plugin",
 			"path":   path.Join(details.ExecPath, details.Exec),
 			"error":  err,
-		}).Error("error starting new plugin")
+		}
+		// the output of a plugin which failed to start belongs to no log
+		if output := out.unbound(); output != "" {
+			fields["output"] = output
+		}
+		runnerLog.WithFields(fields).Error("error starting new plugin")
 		return err
 	}
+	out.bind(r.logs.get(fmt.Sprintf("%s:%s:%d", ap.TypeName(), ap.Name(), ap.Version())))
 	ap.exec = details.Exec
 	ap.execPath = details.ExecPath
 	if details.IsPackage {
@@ -392,6 +458,74 @@ func (r *runner) runPlugin(details *pluginDetails) error {
 	return nil
 }
 
+// killDead kills a dead instance returning false when it was already killed
+func (r *runner) killDead(pool strategy.Pool, id uint32) bool {
+	r.deadMutex.Lock()
+	defer r.deadMutex.Unlock()
+	pool.RLock()
+	_, ok := pool.Plugins()[id]
+	pool.RUnlock()
+	if !ok {
+		return false
+	}
+	pool.Kill(id, "plugin dead")
+	return true
+}
+
+// restartPlugin starts a new instance of the plugin with the given key in
+// place of one which died when the restart policy of the plugin allows it.
+// The restart waits longer each time the plugin is restarted.
+func (r *runner) restartPlugin(key, reason string) {
+	lp, err := r.pluginManager.get(key)
+	if err != nil {
+		// the plugin was unloaded
+		return
+	}
+	policy := r.restartPolicies.get(core.PluginType(lp.Type), lp.Name())
+	n := lp.Restarts().Count
+	fields := log.Fields{
+		"_block":   "restart-plugin",
+		"plugin":   key,
+		"policy":   policy.policy,
+		"restarts": n,
+		"reason":   reason,
+	}
+	if !policy.allows(n) {
+		runnerLog.WithFields(fields).Warning("dead plugin will not be restarted")
+		return
+	}
+	delay := policy.delay(n)
+	runnerLog.WithFields(fields).Info("restarting dead plugin in ", delay)
+	time.AfterFunc(delay, func() {
+		// the plugin may have been unloaded or its tasks stopped meanwhile
+		lp, err := r.pluginManager.get(key)
+		if err != nil {
+			return
+		}
+		pool, err := r.availablePlugins.getPool(key)
+		if err != nil || pool == nil {
+			return
+		}
+		if !pool.Eligible() && (policy.policy != RestartAlways || pool.Count() > 0) {
+			runnerLog.WithFields(fields).Info("dead plugin no longer needed")
+			return
+		}
+		lp.recordRestart(reason)
+		restartCount.Inc(strings.Split(key, ":")...)
+		if r.verifyPlugin != nil {
+			if err := r.verifyPlugin(lp); err != nil {
+				runnerLog.WithFields(fields).WithField("error", err).Error("dead plugin cannot be restarted")
+				return
+			}
+		}
+		if err := r.runPlugin(lp); err != nil {
+			r.restartPlugin(key, err.Error())
+			return
+		}
+		runnerLog.WithFields(fields).Info("dead plugin restarted")
+	})
+}
+
 func (r *runner) handleUnsubscription(pType, pName string, pVersion int, taskID string) error {
 	pool, err := r.availablePlugins.getPool(fmt.Sprintf("%s:%s:%d", pType, pName, pVersion))
 	if err != nil {
@@ -412,6 +546,10 @@ func (r *runner) handleUnsubscription(pType, pName string, pVersion int, taskID
 		}).Error("pool not found")
 		return errors.New("pool not found")
 	}
+	if r.isRemote(fmt.Sprintf("%s:%s:%d", pType, pName, pVersion)) {
+		// a remote plugin stays in its pool until it is unloaded
+		return nil
+	}
 	if pool.SubscriptionCount() < pool.Count() {
 		runnerLog.WithFields(log.Fields{
 			"_block":                  "handle-unsubscription",
@@ -422,3 +560,30 @@ func (r *runner) handleUnsubscription(pType, pName string, pVersion int, taskID
 	}
 	return nil
 }
+
+// isRemote returns whether the loaded plugin with the given key runs as a
+// service snapd connects to
+func (r *runner) isRemote(key string) bool {
+	lp, err := r.pluginManager.get(key)
+	return err == nil && lp.isRemote()
+}
+
+// forgetRemote removes the remote plugin with the given key, which was
+// unloaded, from its pool
+func (r *runner) forgetRemote(key string) {
+	pool, err := r.availablePlugins.getPool(key)
+	if err != nil || pool == nil {
+		return
+	}
+	var ids []uint32
+	pool.RLock()
+	for id, ap := range pool.Plugins() {
+		if a, ok := ap.(*availablePlugin); ok && a.remote {
+			ids = append(ids, id)
+		}
+	}
+	pool.RUnlock()
+	for _, id := range ids {
+		pool.Kill(id, "plugin unloaded")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
+)
+
+const (
+	// SelfCollectorName is the name of the built-in collector of the metrics
+	// snapd keeps about itself under /intel/snap
+	SelfCollectorName = "snap"
+	// SelfCollectorVersion is the version of the built-in collector
+	SelfCollectorVersion = 1
+)
+
+// EnableSelfCollector adds the metrics snapd keeps about itself to the metric
+// catalog when control starts.  They are collected by a built-in collector
+// which runs no plugin.
+func EnableSelfCollector() PluginControlOpt {
+	return func(c *pluginControl) {
+		c.selfPlugin = &loadedPlugin{
+			Meta: plugin.PluginMeta{
+				Name:    SelfCollectorName,
+				Version: SelfCollectorVersion,
+			},
+			Type:         plugin.CollectorPluginType,
+			State:        LoadedState,
+			LoadedTime:   time.Now(),
+			ConfigPolicy: cpolicy.New(),
+			Details:      &pluginDetails{},
+		}
+	}
+}
+
+// addSelfMetrics adds every metric registered with telemetry.Default to the
+// metric catalog
+func (p *pluginControl) addSelfMetrics() {
+	now := time.Now()
+	for _, d := range telemetry.Default.Describe() {
+		p.metricCatalog.Add(&metricType{
+			Plugin:             p.selfPlugin,
+			namespace:          d.Namespace,
+			version:            SelfCollectorVersion,
+			lastAdvertisedTime: now,
+			labels:             selfLabels(d),
+			policy:             p.selfPlugin.ConfigPolicy.Get(d.Namespace),
+		})
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block": "add-self-metrics",
+	}).Info("self collector enabled")
+}
+
+// collectSelfMetrics returns the current values of the metrics snapd keeps
+// about itself.  A requested namespace holding "*" returns a metric for each
+// value of its dynamic elements.
+func (p *pluginControl) collectSelfMetrics(mts []core.Metric) []core.Metric {
+	host, _ := os.Hostname()
+	now := time.Now()
+	samples := telemetry.Default.Gather()
+	var metrics []core.Metric
+	for _, mt := range mts {
+		for _, s := range samples {
+			ns := s.Namespace()
+			if !matchNamespace(mt.Namespace(), ns) {
+				continue
+			}
+			metrics = append(metrics, &plugin.PluginMetricType{
+				Namespace_: ns,
+				Version_:   SelfCollectorVersion,
+				Data_:      s.Value,
+				Labels_:    selfLabels(s.Desc),
+				Source_:    host,
+				Timestamp_: now,
+				Config_:    mt.Config(),
+			})
+		}
+	}
+	return metrics
+}
+
+func (p *pluginControl) isSelfPlugin(lp *loadedPlugin) bool {
+	return p.selfPlugin != nil && lp == p.selfPlugin
+}
+
+func selfLabels(d *telemetry.Desc) []core.Label {
+	if len(d.Labels) == 0 {
+		return nil
+	}
+	labels := make([]core.Label, len(d.Labels))
+	for i, l := range d.Labels {
+		labels[i] = core.Label{Index: l.Index, Name: l.Name}
+	}
+	return labels
+}
+
+// matchNamespace returns whether ns is the namespace requested where "*"
+// matches any element
+func matchNamespace(requested, ns []string) bool {
+	if len(requested) != len(ns) {
+		return false
+	}
+	for i, e := range requested {
+		if e != "*" && e != ns[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSelfCollector(t *testing.T) {
+	Convey("given the self collector is enabled", t, func() {
+		jobs := telemetry.Default.Gauge(telemetry.Desc{
+			Namespace:   []string{"intel", "snap", "test", "*", "jobs"},
+			Labels:      []telemetry.Label{{Index: 3, Name: "queue"}},
+			Description: "jobs in the test queue",
+		})
+		jobs.Set(3, "a")
+		jobs.Set(5, "b")
+		defer jobs.Delete("a")
+		defer jobs.Delete("b")
+
+		c := New(EnableSelfCollector())
+		c.Start()
+
+		Convey("the metrics snapd keeps about itself are in the catalog", func() {
+			mt, err := c.metricCatalog.Get([]string{"intel", "snap", "test", "*", "jobs"}, -1)
+			So(err, ShouldBeNil)
+			So(mt.Plugin.Name(), ShouldEqual, SelfCollectorName)
+			So(mt.Labels(), ShouldResemble, []core.Label{{Index: 3, Name: "queue"}})
+		})
+
+		Convey("they are collected without running a plugin", func() {
+			m := MockMetricType{
+				namespace: []string{"intel", "snap", "test", "*", "jobs"},
+				cfg:       cdata.NewNode(),
+			}
+			mts, errs := c.CollectMetrics([]core.Metric{m}, time.Now().Add(time.Second), "1")
+			So(errs, ShouldBeEmpty)
+			So(mts, ShouldHaveLength, 2)
+			So(mts[0].Namespace(), ShouldResemble, []string{"intel", "snap", "test", "a", "jobs"})
+			So(mts[0].Data(), ShouldEqual, 3)
+			So(mts[1].Namespace(), ShouldResemble, []string{"intel", "snap", "test", "b", "jobs"})
+			So(mts[1].Data(), ShouldEqual, 5)
+		})
+
+		Convey("subscribing to them starts no plugin", func() {
+			m := MockMetricType{
+				namespace: []string{"intel", "snap", "test", "*", "jobs"},
+				cfg:       cdata.NewNode(),
+			}
+			So(c.SubscribeDeps("1", []core.Metric{m}, []core.Plugin{}), ShouldBeEmpty)
+			So(c.pluginRunner.AvailablePlugins().all(), ShouldBeEmpty)
+		})
+	})
+}
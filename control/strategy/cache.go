@@ -20,12 +20,18 @@ limitations under the License.
 package strategy
 
 import (
+	"container/list"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
@@ -33,6 +39,15 @@ import (
 // A plugin can override the GlobalCacheExpiration (default).
 var GlobalCacheExpiration time.Duration
 
+// GlobalCacheSize is the most entries a cache holds.  Once it is reached the
+// least recently used entries which expired and then the least recently used
+// one are evicted to make room for a new entry.  A size of 0 or less leaves
+// caches unbounded.
+var GlobalCacheSize = DefaultCacheSize
+
+// DefaultCacheSize is the default GlobalCacheSize
+const DefaultCacheSize = 10000
+
 var (
 	cacheLog = log.WithField("_module", "routing-cache")
 
@@ -40,34 +55,94 @@ var (
 )
 
 type cachecell struct {
+	key     string
+	elem    *list.Element
 	time    time.Time
+	used    time.Time
 	metric  core.Metric
 	metrics []core.Metric
 	hits    uint64
 	misses  uint64
 }
 
+// cachestats are the hits and misses of a metric across its configs
+type cachestats struct {
+	ns      string
+	version int
+	hits    uint64
+	misses  uint64
+}
+
+// cache holds collected metrics by namespace, version and config.  Metrics
+// collected with different configs, for example for different targets or
+// with different credentials, are kept apart.  The entries are also kept in
+// lru, the most recently used first, so evicting one takes constant time.
 type cache struct {
-	table map[string]*cachecell
-	ttl   time.Duration
+	sync.Mutex
+	table     map[string]*cachecell
+	lru       *list.List
+	stats     map[string]*cachestats
+	ttl       time.Duration
+	size      int
+	evictions uint64
 }
 
 func NewCache(expiration time.Duration) *cache {
 	return &cache{
 		table: make(map[string]*cachecell),
+		lru:   list.New(),
+		stats: make(map[string]*cachestats),
 		ttl:   expiration,
+		size:  GlobalCacheSize,
+	}
+}
+
+// cacheKey returns the key of the entry of a metric collected with the config
+// hashed to cfg
+func cacheKey(ns string, version int, cfg string) string {
+	if cfg == "" {
+		return fmt.Sprintf("%v:%v", ns, version)
+	}
+	return fmt.Sprintf("%v:%v:%v", ns, version, cfg)
+}
+
+// configHash returns a hash of the config which is the same for configs
+// holding the same items.  An empty config hashes to "".
+func configHash(cfg *cdata.ConfigDataNode) string {
+	if cfg == nil {
+		return ""
 	}
+	table := cfg.Table()
+	if len(table) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%q=%T:%v;", k, table[k], table[k])
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
 }
 
-func (c *cache) get(ns string, version int) interface{} {
+func (c *cache) get(ns string, version int, cfg string) interface{} {
+	c.Lock()
+	defer c.Unlock()
 	var (
 		cell *cachecell
 		ok   bool
 	)
 
-	key := fmt.Sprintf("%v:%v", ns, version)
+	stats := c.statsFor(ns, version)
+	key := cacheKey(ns, version, cfg)
 	if cell, ok = c.table[key]; ok && chrono.Chrono.Now().Sub(cell.time) < c.ttl {
 		cell.hits++
+		stats.hits++
+		cell.used = chrono.Chrono.Now()
+		c.lru.MoveToFront(cell.elem)
 		cacheLog.WithFields(log.Fields{
 			"namespace": key,
 			"hits":      cell.hits,
@@ -78,56 +153,100 @@ func (c *cache) get(ns string, version int) interface{} {
 		}
 		return cell.metrics
 	}
-	if !ok {
-		c.table[key] = &cachecell{
-			time:    time.Time{},
-			metrics: nil,
-		}
+	stats.misses++
+	if ok {
+		// an expired entry is left for the put of the metrics collected
+		// in its place or, if it is not used again, to makeRoom
+		cell.misses++
 	}
-	c.table[key].misses++
 	cacheLog.WithFields(log.Fields{
 		"namespace": key,
-		"hits":      c.table[key].hits,
-		"misses":    c.table[key].misses,
+		"hits":      stats.hits,
+		"misses":    stats.misses,
 	}).Debug(fmt.Sprintf("cache miss [%s]", key))
 	return nil
 }
 
-func (c *cache) put(ns string, version int, m interface{}) {
-	key := fmt.Sprintf("%v:%v", ns, version)
+func (c *cache) put(ns string, version int, cfg string, m interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	key := cacheKey(ns, version, cfg)
+	cell, ok := c.table[key]
+	if !ok {
+		c.makeRoom()
+		cell = &cachecell{key: key}
+	}
 	switch metric := m.(type) {
 	case core.Metric:
-		if _, ok := c.table[key]; ok {
-			c.table[key].time = chrono.Chrono.Now()
-			c.table[key].metric = metric
-		} else {
-			log.Errorf("%+v", c.table)
-			c.table[key] = &cachecell{
-				time:   chrono.Chrono.Now(),
-				metric: metric,
-			}
-		}
+		cell.metric = metric
+		cell.metrics = nil
 	case []core.Metric:
-		if _, ok := c.table[key]; ok {
-			c.table[key].time = time.Now()
-			c.table[key].metrics = metric
-		} else {
-			c.table[key] = &cachecell{
-				time:    chrono.Chrono.Now(),
-				metrics: metric,
-			}
-		}
+		cell.metric = nil
+		cell.metrics = metric
 	default:
 		cacheLog.WithFields(log.Fields{
 			"namespace": key,
 			"_block":    "put",
 		}).Error("unsupported type")
+		return
+	}
+	cell.time = chrono.Chrono.Now()
+	cell.used = cell.time
+	if ok {
+		c.lru.MoveToFront(cell.elem)
+		return
 	}
+	cell.elem = c.lru.PushFront(cell)
+	c.table[key] = cell
+}
+
+// makeRoom evicts entries when the cache is full.  The least recently used
+// entries which expired are evicted first and the least recently used one
+// when none of them expired.  Expired entries are only evicted once they are
+// the least recently used, which they become as they are no longer hit, so
+// making room never walks the whole cache.
+func (c *cache) makeRoom() {
+	if c.size <= 0 || len(c.table) < c.size {
+		return
+	}
+	now := chrono.Chrono.Now()
+	for e := c.lru.Back(); e != nil; e = c.lru.Back() {
+		cell := e.Value.(*cachecell)
+		if now.Sub(cell.time) < c.ttl {
+			break
+		}
+		c.evict(cell)
+	}
+	if len(c.table) >= c.size {
+		c.evict(c.lru.Back().Value.(*cachecell))
+	}
+	cacheLog.WithFields(log.Fields{
+		"_block":    "make-room",
+		"entries":   len(c.table),
+		"evictions": c.evictions,
+	}).Debug("cache entries evicted")
+}
+
+// evict removes an entry from the cache
+func (c *cache) evict(cell *cachecell) {
+	c.lru.Remove(cell.elem)
+	delete(c.table, cell.key)
+	c.evictions++
+}
+
+func (c *cache) statsFor(ns string, version int) *cachestats {
+	key := fmt.Sprintf("%v:%v", ns, version)
+	stats, ok := c.stats[key]
+	if !ok {
+		stats = &cachestats{ns: ns, version: version}
+		c.stats[key] = stats
+	}
+	return stats
 }
 
 func (c *cache) checkCache(mts []core.Metric) (metricsToCollect []core.Metric, fromCache []core.Metric) {
 	for _, mt := range mts {
-		if m := c.get(core.JoinNamespace(mt.Namespace()), mt.Version()); m != nil {
+		if m := c.get(core.JoinNamespace(mt.Namespace()), mt.Version(), configHash(mt.Config())); m != nil {
 			switch metric := m.(type) {
 			case core.Metric:
 				fromCache = append(fromCache, metric)
@@ -147,58 +266,138 @@ func (c *cache) checkCache(mts []core.Metric) (metricsToCollect []core.Metric, f
 	return metricsToCollect, fromCache
 }
 
-func (c *cache) updateCache(mts []core.Metric) {
-	results := []core.Metric{}
+// updateCache caches the metrics collected for the requested ones under the
+// config they were requested with
+func (c *cache) updateCache(requested, collected []core.Metric) {
+	configs := map[string]string{}
+	for _, mt := range requested {
+		configs[core.JoinNamespace(mt.Namespace())] = configHash(mt.Config())
+	}
+	configOf := func(ns string, mt core.Metric) string {
+		if cfg, ok := configs[ns]; ok {
+			return cfg
+		}
+		return configHash(mt.Config())
+	}
 	dc := map[string][]core.Metric{}
-	for _, mt := range mts {
+	for _, mt := range collected {
 		if mt.Labels() == nil {
 			// cache the individual metric
-			c.put(core.JoinNamespace(mt.Namespace()), mt.Version(), mt)
+			ns := core.JoinNamespace(mt.Namespace())
+			c.put(ns, mt.Version(), configOf(ns, mt), mt)
 		} else {
 			// collect the dynamic query results so we can cache
-			ns := make([]string, len(mt.Namespace()))
-			copy(ns, mt.Namespace())
+			nss := make([]string, len(mt.Namespace()))
+			copy(nss, mt.Namespace())
 			for _, label := range mt.Labels() {
-				ns[label.Index] = "*"
+				nss[label.Index] = "*"
 			}
-			if _, ok := dc[core.JoinNamespace(ns)]; !ok {
-				dc[core.JoinNamespace(ns)] = []core.Metric{}
-			}
-			dc[core.JoinNamespace(ns)] = append(dc[core.JoinNamespace(ns)], mt)
-			c.put(core.JoinNamespace(ns), mt.Version(), dc[core.JoinNamespace(ns)])
+			ns := core.JoinNamespace(nss)
+			dc[ns] = append(dc[ns], mt)
+			c.put(ns, mt.Version(), configOf(ns, mt), dc[ns])
 		}
-		results = append(results, mt)
 	}
 }
 
 func (c *cache) allCacheHits() uint64 {
+	c.Lock()
+	defer c.Unlock()
 	var hits uint64
-	for _, v := range c.table {
+	for _, v := range c.stats {
 		hits += v.hits
 	}
 	return hits
 }
 
 func (c *cache) allCacheMisses() uint64 {
+	c.Lock()
+	defer c.Unlock()
 	var misses uint64
-	for _, v := range c.table {
+	for _, v := range c.stats {
 		misses += v.misses
 	}
 	return misses
 }
 
+// cacheHits returns the hits of a metric across its configs
 func (c *cache) cacheHits(ns string, version int) (uint64, error) {
+	c.Lock()
+	defer c.Unlock()
 	key := fmt.Sprintf("%v:%v", ns, version)
-	if v, ok := c.table[key]; ok {
+	if v, ok := c.stats[key]; ok {
 		return v.hits, nil
 	}
 	return 0, ErrCacheEntryDoesNotExist
 }
 
+// cacheMisses returns the misses of a metric across its configs
 func (c *cache) cacheMisses(ns string, version int) (uint64, error) {
+	c.Lock()
+	defer c.Unlock()
 	key := fmt.Sprintf("%v:%v", ns, version)
-	if v, ok := c.table[key]; ok {
+	if v, ok := c.stats[key]; ok {
 		return v.misses, nil
 	}
 	return 0, ErrCacheEntryDoesNotExist
 }
+
+// cacheStats returns the statistics of the cache and of each metric in it
+func (c *cache) cacheStats() core.CacheStats {
+	c.Lock()
+	defer c.Unlock()
+	cs := core.CacheStats{
+		Entries:   len(c.table),
+		Evictions: c.evictions,
+	}
+	for _, v := range c.stats {
+		cs.Hits += v.hits
+		cs.Misses += v.misses
+		cs.Metrics = append(cs.Metrics, core.MetricCacheStats{
+			Namespace: v.ns,
+			Version:   v.version,
+			Hits:      v.hits,
+			Misses:    v.misses,
+		})
+	}
+	sort.Sort(byNamespace(cs.Metrics))
+	return cs
+}
+
+// mergeCacheStats adds the statistics of caches kept by task
+func mergeCacheStats(caches map[string]*cache) core.CacheStats {
+	metrics := map[string]*core.MetricCacheStats{}
+	var cs core.CacheStats
+	for _, c := range caches {
+		s := c.cacheStats()
+		cs.Hits += s.Hits
+		cs.Misses += s.Misses
+		cs.Entries += s.Entries
+		cs.Evictions += s.Evictions
+		for _, m := range s.Metrics {
+			key := fmt.Sprintf("%v:%v", m.Namespace, m.Version)
+			if mt, ok := metrics[key]; ok {
+				mt.Hits += m.Hits
+				mt.Misses += m.Misses
+				continue
+			}
+			m := m
+			metrics[key] = &m
+		}
+	}
+	for _, m := range metrics {
+		cs.Metrics = append(cs.Metrics, *m)
+	}
+	sort.Sort(byNamespace(cs.Metrics))
+	return cs
+}
+
+type byNamespace []core.MetricCacheStats
+
+func (b byNamespace) Len() int      { return len(b) }
+func (b byNamespace) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byNamespace) Less(i, j int) bool {
+	if b[i].Namespace == b[j].Namespace {
+		return b[i].Version < b[j].Version
+	}
+	return b[i].Namespace < b[j].Namespace
+}
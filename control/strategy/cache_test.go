@@ -26,6 +26,9 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/pkg/chrono"
 )
 
@@ -37,15 +40,15 @@ func TestCache(t *testing.T) {
 			Namespace_: []string{"foo", "bar"},
 		}
 
-		mc.put("/foo/bar", 1, foo)
-		ret := mc.get("/foo/bar", 1)
+		mc.put("/foo/bar", 1, "", foo)
+		ret := mc.get("/foo/bar", 1, "")
 
 		So(ret, ShouldNotBeNil)
 		So(ret, ShouldEqual, foo)
 	})
 	Convey("returns nil if the cache cell does not exist", t, func() {
 		mc := NewCache(GlobalCacheExpiration)
-		ret := mc.get("/foo/bar", 1)
+		ret := mc.get("/foo/bar", 1, "")
 		So(ret, ShouldBeNil)
 	})
 	Convey("returns nil if the cache cell has expired", t, func() {
@@ -60,10 +63,10 @@ func TestCache(t *testing.T) {
 		foo := &plugin.PluginMetricType{
 			Namespace_: []string{"foo", "bar"},
 		}
-		mc.put("/foo/bar", 1, foo)
+		mc.put("/foo/bar", 1, "", foo)
 		chrono.Chrono.Forward(401 * time.Millisecond)
 
-		ret := mc.get("/foo/bar", 1)
+		ret := mc.get("/foo/bar", 1, "")
 		So(ret, ShouldBeNil)
 	})
 	Convey("hit and miss counts", t, func() {
@@ -72,8 +75,8 @@ func TestCache(t *testing.T) {
 			foo := &plugin.PluginMetricType{
 				Namespace_: []string{"foo", "bar"},
 			}
-			mc.put("/foo/bar", 1, foo)
-			mc.get("/foo/bar", 1)
+			mc.put("/foo/bar", 1, "", foo)
+			mc.get("/foo/bar", 1, "")
 			So(mc.table["/foo/bar:1"].hits, ShouldEqual, 1)
 		})
 		Convey("ticks miss count when a cache entry is still a hit", func() {
@@ -87,9 +90,9 @@ func TestCache(t *testing.T) {
 				Namespace_: []string{"foo", "bar"},
 			}
 
-			mc.put("/foo/bar", 1, foo)
+			mc.put("/foo/bar", 1, "", foo)
 			chrono.Chrono.Forward(250 * time.Millisecond)
-			mc.get("/foo/bar", 1)
+			mc.get("/foo/bar", 1, "")
 			So(mc.table["/foo/bar:1"].hits, ShouldEqual, 1)
 		})
 		Convey("ticks miss count when a cache entry is missed", func() {
@@ -102,11 +105,128 @@ func TestCache(t *testing.T) {
 			foo := &plugin.PluginMetricType{
 				Namespace_: []string{"foo", "bar"},
 			}
-			mc.put("/foo/bar", 1, foo)
+			mc.put("/foo/bar", 1, "", foo)
 			chrono.Chrono.Forward(301 * time.Millisecond)
-			mc.get("/foo/bar", 1)
+			mc.get("/foo/bar", 1, "")
 			So(mc.table["/foo/bar:1"].misses, ShouldEqual, 1)
 		})
 	})
+	Convey("keeps metrics collected with different configs apart", t, func() {
+		mc := NewCache(time.Minute)
+		alice := cdata.NewNode()
+		alice.AddItem("user", ctypes.ConfigValueStr{Value: "alice"})
+		alice.AddItem("port", ctypes.ConfigValueInt{Value: 80})
+		bob := cdata.NewNode()
+		bob.AddItem("user", ctypes.ConfigValueStr{Value: "bob"})
+		bob.AddItem("port", ctypes.ConfigValueInt{Value: 80})
+		forAlice := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}, Version_: 1, Config_: alice}}
+		forBob := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}, Version_: 1, Config_: bob}}
+		// the plugin does not return the config
+		collected := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}, Version_: 1, Data_: "alice's"}}
+
+		collect, _ := mc.checkCache(forAlice)
+		So(collect, ShouldHaveLength, 1)
+		mc.updateCache(collect, collected)
+		_, cached := mc.checkCache(forAlice)
+		So(cached, ShouldHaveLength, 1)
+		So(cached[0].Data(), ShouldEqual, "alice's")
+		collect, cached = mc.checkCache(forBob)
+		So(collect, ShouldHaveLength, 1)
+		So(cached, ShouldBeEmpty)
+
+		Convey("with the same hash for the same items", func() {
+			again := cdata.NewNode()
+			again.AddItem("port", ctypes.ConfigValueInt{Value: 80})
+			again.AddItem("user", ctypes.ConfigValueStr{Value: "alice"})
+			So(configHash(again), ShouldEqual, configHash(alice))
+			So(configHash(alice), ShouldNotEqual, configHash(bob))
+			// a string and an int of the same value differ
+			s, i := cdata.NewNode(), cdata.NewNode()
+			s.AddItem("port", ctypes.ConfigValueStr{Value: "80"})
+			i.AddItem("port", ctypes.ConfigValueInt{Value: 80})
+			So(configHash(s), ShouldNotEqual, configHash(i))
+			So(configHash(nil), ShouldEqual, "")
+			So(configHash(cdata.NewNode()), ShouldEqual, "")
+		})
+
+		Convey("for dynamic metrics", func() {
+			requested := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "*", "baz"}, Version_: 1, Config_: alice}}
+			labels := []core.Label{{Index: 1, Name: "host"}}
+			mc.updateCache(requested, []core.Metric{
+				plugin.PluginMetricType{Namespace_: []string{"foo", "host0", "baz"}, Version_: 1, Labels_: labels},
+				plugin.PluginMetricType{Namespace_: []string{"foo", "host1", "baz"}, Version_: 1, Labels_: labels},
+			})
+			_, cached := mc.checkCache(requested)
+			So(cached, ShouldHaveLength, 2)
+			other := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"foo", "*", "baz"}, Version_: 1, Config_: bob}}
+			collect, _ := mc.checkCache(other)
+			So(collect, ShouldHaveLength, 1)
+		})
+
+		Convey("and counts hits and misses by metric across configs", func() {
+			cs := mc.cacheStats()
+			So(cs.Hits, ShouldEqual, 1)
+			So(cs.Misses, ShouldEqual, 2)
+			So(cs.Entries, ShouldEqual, 1)
+			So(cs.Metrics, ShouldResemble, []core.MetricCacheStats{{Namespace: "/foo/bar", Version: 1, Hits: 1, Misses: 2}})
+		})
+	})
+	Convey("evicts entries once it is full", t, func() {
+		defer chrono.Chrono.Reset()
+		defer chrono.Chrono.Continue()
+		chrono.Chrono.Pause()
 
+		mc := NewCache(time.Second)
+		mc.size = 2
+		foo := &plugin.PluginMetricType{Namespace_: []string{"foo"}}
+		mc.put("/foo", 1, "a", foo)
+		// the clock is forwarded from the time it was paused at
+		chrono.Chrono.Forward(10 * time.Millisecond)
+		mc.put("/foo", 1, "b", foo)
+		chrono.Chrono.Forward(20 * time.Millisecond)
+		// a is used more recently than b
+		So(mc.get("/foo", 1, "a"), ShouldNotBeNil)
+
+		Convey("the least recently used first", func() {
+			mc.put("/foo", 1, "c", foo)
+			So(mc.table, ShouldHaveLength, 2)
+			So(mc.lru.Len(), ShouldEqual, 2)
+			So(mc.get("/foo", 1, "b"), ShouldBeNil)
+			So(mc.get("/foo", 1, "a"), ShouldNotBeNil)
+			So(mc.get("/foo", 1, "c"), ShouldNotBeNil)
+			So(mc.cacheStats().Evictions, ShouldEqual, 1)
+			// updating an entry evicts nothing
+			mc.put("/foo", 1, "c", foo)
+			So(mc.cacheStats().Evictions, ShouldEqual, 1)
+			// c is used more recently than a
+			mc.put("/foo", 1, "d", foo)
+			So(mc.get("/foo", 1, "a"), ShouldBeNil)
+			So(mc.get("/foo", 1, "c"), ShouldNotBeNil)
+		})
+		Convey("the expired ones before", func() {
+			chrono.Chrono.Forward(1010 * time.Millisecond)
+			mc.put("/foo", 1, "c", foo)
+			So(mc.table, ShouldHaveLength, 1)
+			So(mc.lru.Len(), ShouldEqual, 1)
+			So(mc.cacheStats().Evictions, ShouldEqual, 2)
+		})
+		Convey("the expired ones only once they are the least recently used", func() {
+			chrono.Chrono.Forward(470 * time.Millisecond)
+			mc.put("/foo", 1, "b", foo)
+			chrono.Chrono.Forward(20 * time.Millisecond)
+			So(mc.get("/foo", 1, "a"), ShouldNotBeNil)
+			// a expires while it is used more recently than b
+			chrono.Chrono.Forward(500 * time.Millisecond)
+			mc.put("/foo", 1, "c", foo)
+			So(mc.table, ShouldHaveLength, 2)
+			So(mc.table, ShouldContainKey, "/foo:1:a")
+			So(mc.get("/foo", 1, "b"), ShouldBeNil)
+			So(mc.cacheStats().Evictions, ShouldEqual, 1)
+		})
+		Convey("unless it is unbounded", func() {
+			mc.size = 0
+			mc.put("/foo", 1, "c", foo)
+			So(mc.table, ShouldHaveLength, 3)
+		})
+	})
 }
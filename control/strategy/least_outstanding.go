@@ -0,0 +1,162 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// leastOutstanding provides a strategy that selects the available plugin with
+// the fewest calls in flight, the least recently used of them on a tie.
+type leastOutstanding struct {
+	*cache
+	logger *log.Entry
+	mutex  sync.Mutex
+	// outstanding holds the calls in flight by plugin id
+	outstanding map[uint32]int
+}
+
+func NewLeastOutstanding(cacheTTL time.Duration) *leastOutstanding {
+	return &leastOutstanding{
+		cache: NewCache(cacheTTL),
+		logger: log.WithFields(log.Fields{
+			"_module": "control-routing",
+		}),
+		outstanding: map[uint32]int{},
+	}
+}
+
+// String returns the strategy name.
+func (l *leastOutstanding) String() string {
+	return "least-outstanding-requests"
+}
+
+// CacheTTL returns the TTL for the cache.
+func (l *leastOutstanding) CacheTTL(taskID string) (time.Duration, error) {
+	return l.ttl, nil
+}
+
+// Select selects the available plugin with the fewest calls in flight and
+// counts the call about to be made to it until Done is called.
+func (l *leastOutstanding) Select(spa []SelectablePlugin, _ string) (SelectablePlugin, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	sp := l.least(spa)
+	if sp == nil {
+		l.logger.WithFields(log.Fields{
+			"block":    "select",
+			"strategy": l.String(),
+			"error":    ErrCouldNotSelect,
+		}).Error("error selecting")
+		return nil, ErrCouldNotSelect
+	}
+	l.outstanding[sp.ID()]++
+	l.logger.WithFields(log.Fields{
+		"block":       "select",
+		"strategy":    l.String(),
+		"pool size":   len(spa),
+		"index":       sp.String(),
+		"outstanding": l.outstanding[sp.ID()],
+	}).Debug("plugin selected")
+	return sp, nil
+}
+
+// Done counts a call made to the plugin as returned
+func (l *leastOutstanding) Done(sp SelectablePlugin) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if n, ok := l.outstanding[sp.ID()]; ok {
+		if n <= 1 {
+			delete(l.outstanding, sp.ID())
+		} else {
+			l.outstanding[sp.ID()] = n - 1
+		}
+	}
+}
+
+// Remove selects the plugin with the fewest calls in flight, which is the
+// one to stop, and forgets its calls
+func (l *leastOutstanding) Remove(spa []SelectablePlugin, taskID string) (SelectablePlugin, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	sp := l.least(spa)
+	if sp == nil {
+		return nil, ErrCouldNotSelect
+	}
+	delete(l.outstanding, sp.ID())
+	return sp, nil
+}
+
+func (l *leastOutstanding) least(spa []SelectablePlugin) SelectablePlugin {
+	var selected SelectablePlugin
+	for _, sp := range spa {
+		if selected == nil {
+			selected = sp
+			continue
+		}
+		n, least := l.outstanding[sp.ID()], l.outstanding[selected.ID()]
+		if n < least || (n == least && sp.LastHit().Before(selected.LastHit())) {
+			selected = sp
+		}
+	}
+	return selected
+}
+
+// CheckCache checks the cache for metric types.
+// returns:
+//   - array of metrics that need to be collected
+//   - array of metrics that were returned from the cache
+func (l *leastOutstanding) CheckCache(mts []core.Metric, _ string) ([]core.Metric, []core.Metric) {
+	return l.checkCache(mts)
+}
+
+// UpdateCache caches the metrics collected for the requested ones.
+func (l *leastOutstanding) UpdateCache(requested, collected []core.Metric, _ string) {
+	l.updateCache(requested, collected)
+}
+
+// CacheStats returns the statistics of the cache.
+func (l *leastOutstanding) CacheStats() core.CacheStats {
+	return l.cacheStats()
+}
+
+// AllCacheHits returns cache hits across all metrics.
+func (l *leastOutstanding) AllCacheHits() uint64 {
+	return l.allCacheHits()
+}
+
+// AllCacheMisses returns cache misses across all metrics.
+func (l *leastOutstanding) AllCacheMisses() uint64 {
+	return l.allCacheMisses()
+}
+
+// CacheHits returns the cache hits for a given metric namespace and version.
+func (l *leastOutstanding) CacheHits(ns string, version int, _ string) (uint64, error) {
+	return l.cacheHits(ns, version)
+}
+
+// CacheMisses returns the cache misses for a given metric namespace and version.
+func (l *leastOutstanding) CacheMisses(ns string, version int, _ string) (uint64, error) {
+	return l.cacheMisses(ns, version)
+}
@@ -0,0 +1,91 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLeastOutstandingRouter(t *testing.T) {
+	Convey("Given a least outstanding requests router", t, func() {
+		router := NewLeastOutstanding(100 * time.Millisecond)
+		So(router, ShouldNotBeNil)
+		So(router.String(), ShouldResemble, "least-outstanding-requests")
+		now := time.Now()
+		p1 := &mockPlugin{name: "p1", id: 1, lastHit: now}
+		p2 := &mockPlugin{name: "p2", id: 2, lastHit: now.Add(-time.Second)}
+		p3 := &mockPlugin{name: "p3", id: 3, lastHit: now.Add(-time.Minute)}
+		spa := []SelectablePlugin{p1, p2, p3}
+		Convey("Select the plugins with the fewest calls in flight", func() {
+			// ties go to the least recently used
+			sp, err := router.Select(spa, "task1")
+			So(err, ShouldBeNil)
+			So(sp, ShouldEqual, p3)
+			sp, err = router.Select(spa, "task1")
+			So(err, ShouldBeNil)
+			So(sp, ShouldEqual, p2)
+			sp, err = router.Select(spa, "task1")
+			So(err, ShouldBeNil)
+			So(sp, ShouldEqual, p1)
+			// p2 returns while the others are still busy
+			router.Done(p2)
+			sp, err = router.Select(spa, "task1")
+			So(err, ShouldBeNil)
+			So(sp, ShouldEqual, p2)
+			sp, err = router.Select(spa, "task1")
+			So(err, ShouldBeNil)
+			So(sp, ShouldEqual, p3)
+			So(router.outstanding, ShouldResemble, map[uint32]int{1: 1, 2: 1, 3: 2})
+			Convey("Remove the plugin with the fewest calls in flight", func() {
+				router.Done(p1)
+				sp, err := router.Remove(spa, "task1")
+				So(err, ShouldBeNil)
+				So(sp, ShouldEqual, p1)
+				// calls returning from a removed plugin are ignored
+				router.Done(p1)
+				So(router.outstanding, ShouldResemble, map[uint32]int{2: 1, 3: 2})
+			})
+		})
+		Convey("Select a plugin when there are NONE available", func() {
+			sp, err := router.Select(nil, "task1")
+			So(sp, ShouldBeNil)
+			So(err, ShouldEqual, ErrCouldNotSelect)
+		})
+		Convey("Cache metrics for every task", func() {
+			mts := []core.Metric{newMockMetricType("intel/mock/foo")}
+			for i := 0; i < 10; i++ {
+				collect, _ := router.CheckCache(mts, "task1")
+				router.UpdateCache(collect, collect, "task2")
+			}
+			So(router.AllCacheHits(), ShouldEqual, 9)
+			So(router.AllCacheMisses(), ShouldEqual, 1)
+			misses, err := router.CacheMisses("/intel/mock/foo", 1, "")
+			So(err, ShouldBeNil)
+			So(misses, ShouldEqual, 1)
+			ttl, err := router.CacheTTL("task1")
+			So(err, ShouldBeNil)
+			So(ttl, ShouldEqual, 100*time.Millisecond)
+		})
+	})
+}
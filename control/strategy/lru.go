@@ -98,9 +98,14 @@ func (l *lru) CheckCache(mts []core.Metric, _ string) ([]core.Metric, []core.Met
 	return l.checkCache(mts)
 }
 
-// updateCache updates the cache with the given array of metrics.
-func (l *lru) UpdateCache(mts []core.Metric, _ string) {
-	l.updateCache(mts)
+// UpdateCache caches the metrics collected for the requested ones.
+func (l *lru) UpdateCache(requested, collected []core.Metric, _ string) {
+	l.updateCache(requested, collected)
+}
+
+// CacheStats returns the statistics of the cache.
+func (l *lru) CacheStats() core.CacheStats {
+	return l.cacheStats()
 }
 
 // AllCacheHits returns cache hits across all metrics.
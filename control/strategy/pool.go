@@ -32,6 +32,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 )
 
 type subscriptionType int
@@ -49,6 +50,23 @@ var (
 	MaximumRunningPlugins = 3
 )
 
+var (
+	// the type, name and version of the plugin in the namespaces of
+	// /intel/snap/control/plugins
+	pluginLabels = []telemetry.Label{{Index: 4, Name: "type"}, {Index: 5, Name: "name"}, {Index: 6, Name: "version"}}
+
+	cacheHits = telemetry.Default.Counter(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "cache", "hits"},
+		Labels:      pluginLabels,
+		Description: "Metrics served from the cache instead of collecting them from the plugin",
+	})
+	cacheMisses = telemetry.Default.Counter(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "control", "plugins", "*", "*", "*", "cache", "misses"},
+		Labels:      pluginLabels,
+		Description: "Metrics which were not in the cache so were collected from the plugin",
+	})
+)
+
 var (
 	ErrBadType     = errors.New("bad plugin type")
 	ErrBadStrategy = errors.New("bad strategy")
@@ -58,6 +76,7 @@ var (
 type Pool interface {
 	RoutingAndCaching
 	Count() int
+	Done(sp SelectablePlugin)
 	Eligible() bool
 	Insert(a AvailablePlugin) error
 	Kill(id uint32, reason string)
@@ -164,6 +183,16 @@ func (p *pool) Strategy() RoutingAndCaching {
 	return p.RoutingAndCaching
 }
 
+// CheckCache returns the metrics which must be collected and those found in
+// the cache counting the hits and misses for the plugin
+func (p *pool) CheckCache(mts []core.Metric, taskID string) ([]core.Metric, []core.Metric) {
+	collect, cached := p.RoutingAndCaching.CheckCache(mts, taskID)
+	labels := strings.Split(p.key, ":")
+	cacheHits.Add(float64(len(mts)-len(collect)), labels...)
+	cacheMisses.Add(float64(len(collect)), labels...)
+	return collect, cached
+}
+
 // Insert inserts an AvailablePlugin into the pool
 func (p *pool) Insert(a AvailablePlugin) error {
 	if a.Type() != plugin.CollectorPluginType && a.Type() != plugin.ProcessorPluginType && a.Type() != plugin.PublisherPluginType {
@@ -210,6 +239,10 @@ func (p *pool) applyPluginMeta(a AvailablePlugin) error {
 	case plugin.StickyRouting:
 		p.RoutingAndCaching = NewSticky(cacheTTL)
 		p.concurrencyCount = 1
+	case plugin.RoundRobinRouting:
+		p.RoutingAndCaching = NewRoundRobin(cacheTTL)
+	case plugin.LeastOutstandingRouting:
+		p.RoutingAndCaching = NewLeastOutstanding(cacheTTL)
 	default:
 		return ErrBadStrategy
 	}
@@ -347,6 +380,14 @@ func (p *pool) SelectAP(taskID string) (SelectablePlugin, serror.SnapError) {
 	return sap, nil
 }
 
+// Done tells the strategy of the pool, when it routes on the calls in
+// flight, that a call made to a plugin returned by SelectAP returned
+func (p *pool) Done(sp SelectablePlugin) {
+	if t, ok := p.RoutingAndCaching.(CallTracker); ok {
+		t.Done(sp)
+	}
+}
+
 // generatePID returns the next availble pid for the pool
 func (p *pool) generatePID() uint32 {
 	atomic.AddUint32(&p.pidCounter, 1)
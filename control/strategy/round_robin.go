@@ -0,0 +1,137 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// roundRobin provides a strategy that selects the available plugins in turn
+// by their id.
+type roundRobin struct {
+	*cache
+	logger *log.Entry
+	mutex  sync.Mutex
+	// last is the id of the plugin selected last
+	last uint32
+}
+
+func NewRoundRobin(cacheTTL time.Duration) *roundRobin {
+	return &roundRobin{
+		cache: NewCache(cacheTTL),
+		logger: log.WithFields(log.Fields{
+			"_module": "control-routing",
+		}),
+	}
+}
+
+// String returns the strategy name.
+func (r *roundRobin) String() string {
+	return "round-robin"
+}
+
+// CacheTTL returns the TTL for the cache.
+func (r *roundRobin) CacheTTL(taskID string) (time.Duration, error) {
+	return r.ttl, nil
+}
+
+// Select selects the available plugin with the lowest id greater than the one
+// of the plugin selected last, starting over from the lowest id.
+func (r *roundRobin) Select(spa []SelectablePlugin, _ string) (SelectablePlugin, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var first, next SelectablePlugin
+	for _, sp := range spa {
+		if first == nil || sp.ID() < first.ID() {
+			first = sp
+		}
+		if sp.ID() > r.last && (next == nil || sp.ID() < next.ID()) {
+			next = sp
+		}
+	}
+	if next == nil {
+		next = first
+	}
+	if next == nil {
+		r.logger.WithFields(log.Fields{
+			"block":    "select",
+			"strategy": r.String(),
+			"error":    ErrCouldNotSelect,
+		}).Error("error selecting")
+		return nil, ErrCouldNotSelect
+	}
+	r.last = next.ID()
+	r.logger.WithFields(log.Fields{
+		"block":     "select",
+		"strategy":  r.String(),
+		"pool size": len(spa),
+		"index":     next.String(),
+		"hitcount":  next.HitCount(),
+	}).Debug("plugin selected")
+	return next, nil
+}
+
+// Remove selects a plugin
+// Since there is no state to cleanup we only need to return the selected plugin
+func (r *roundRobin) Remove(sp []SelectablePlugin, taskID string) (SelectablePlugin, error) {
+	return r.Select(sp, taskID)
+}
+
+// CheckCache checks the cache for metric types.
+// returns:
+//   - array of metrics that need to be collected
+//   - array of metrics that were returned from the cache
+func (r *roundRobin) CheckCache(mts []core.Metric, _ string) ([]core.Metric, []core.Metric) {
+	return r.checkCache(mts)
+}
+
+// UpdateCache caches the metrics collected for the requested ones.
+func (r *roundRobin) UpdateCache(requested, collected []core.Metric, _ string) {
+	r.updateCache(requested, collected)
+}
+
+// CacheStats returns the statistics of the cache.
+func (r *roundRobin) CacheStats() core.CacheStats {
+	return r.cacheStats()
+}
+
+// AllCacheHits returns cache hits across all metrics.
+func (r *roundRobin) AllCacheHits() uint64 {
+	return r.allCacheHits()
+}
+
+// AllCacheMisses returns cache misses across all metrics.
+func (r *roundRobin) AllCacheMisses() uint64 {
+	return r.allCacheMisses()
+}
+
+// CacheHits returns the cache hits for a given metric namespace and version.
+func (r *roundRobin) CacheHits(ns string, version int, _ string) (uint64, error) {
+	return r.cacheHits(ns, version)
+}
+
+// CacheMisses returns the cache misses for a given metric namespace and version.
+func (r *roundRobin) CacheMisses(ns string, version int, _ string) (uint64, error) {
+	return r.cacheMisses(ns, version)
+}
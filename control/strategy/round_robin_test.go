@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRoundRobinRouter(t *testing.T) {
+	Convey("Given a round robin router", t, func() {
+		router := NewRoundRobin(100 * time.Millisecond)
+		So(router, ShouldNotBeNil)
+		So(router.String(), ShouldResemble, "round-robin")
+		p1 := &mockPlugin{name: "p1", id: 1}
+		p2 := &mockPlugin{name: "p2", id: 2}
+		p3 := &mockPlugin{name: "p3", id: 3}
+		Convey("Select the plugins in turn whatever their order", func() {
+			var selected []SelectablePlugin
+			for _, spa := range [][]SelectablePlugin{{p3, p1, p2}, {p2, p3, p1}, {p1, p2, p3}, {p3, p2, p1}} {
+				sp, err := router.Select(spa, "task1")
+				So(err, ShouldBeNil)
+				selected = append(selected, sp)
+			}
+			So(selected, ShouldResemble, []SelectablePlugin{p1, p2, p3, p1})
+			Convey("Skip the plugins which are gone", func() {
+				sp, err := router.Select([]SelectablePlugin{p1, p3}, "task2")
+				So(err, ShouldBeNil)
+				So(sp, ShouldEqual, p3)
+				sp, err = router.Select([]SelectablePlugin{p1, p3}, "task2")
+				So(err, ShouldBeNil)
+				So(sp, ShouldEqual, p1)
+			})
+		})
+		Convey("Select a plugin when there are NONE available", func() {
+			sp, err := router.Select(nil, "task1")
+			So(sp, ShouldBeNil)
+			So(err, ShouldEqual, ErrCouldNotSelect)
+		})
+		Convey("Cache metrics for every task", func() {
+			mts := []core.Metric{newMockMetricType("intel/mock/foo")}
+			for i := 0; i < 10; i++ {
+				collect, _ := router.CheckCache(mts, "task1")
+				router.UpdateCache(collect, collect, "task2")
+			}
+			So(router.AllCacheHits(), ShouldEqual, 9)
+			So(router.AllCacheMisses(), ShouldEqual, 1)
+			hits, err := router.CacheHits("/intel/mock/foo", 1, "")
+			So(err, ShouldBeNil)
+			So(hits, ShouldEqual, 9)
+			ttl, err := router.CacheTTL("task1")
+			So(err, ShouldBeNil)
+			So(ttl, ShouldEqual, 100*time.Millisecond)
+		})
+	})
+}
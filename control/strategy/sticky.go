@@ -22,6 +22,7 @@ package strategy
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/intelsdi-x/snap/core"
@@ -37,6 +38,7 @@ var (
 type sticky struct {
 	plugins     map[string]SelectablePlugin
 	metricCache map[string]*cache
+	cacheMutex  sync.Mutex
 	logger      *log.Entry
 	cacheTTL    time.Duration
 }
@@ -55,7 +57,14 @@ func NewSticky(cacheTTL time.Duration) *sticky {
 // Select selects an available plugin using the sticky plugin strategy.
 func (s *sticky) Select(spa []SelectablePlugin, taskID string) (SelectablePlugin, error) {
 	if sp, ok := s.plugins[taskID]; ok && sp != nil {
-		return sp, nil
+		for _, p := range spa {
+			if p == sp {
+				return sp, nil
+			}
+		}
+		// the plugin the task was bound to is gone so the task moves to
+		// another one
+		delete(s.plugins, taskID)
 	}
 	return s.selectPlugin(spa, taskID)
 }
@@ -66,7 +75,9 @@ func (s *sticky) Remove(sp []SelectablePlugin, taskID string) (SelectablePlugin,
 	if err != nil {
 		return nil, err
 	}
+	s.cacheMutex.Lock()
 	delete(s.metricCache, taskID)
+	s.cacheMutex.Unlock()
 	delete(s.plugins, taskID)
 	return p, nil
 }
@@ -86,22 +97,35 @@ func (s *sticky) CacheTTL(taskID string) (time.Duration, error) {
 //  - array of metrics that need to be collected
 //  - array of metrics that were returned from the cache
 func (s *sticky) CheckCache(mts []core.Metric, taskID string) ([]core.Metric, []core.Metric) {
-	if _, ok := s.metricCache[taskID]; !ok {
-		s.metricCache[taskID] = NewCache(s.cacheTTL)
-	}
-	return s.metricCache[taskID].checkCache(mts)
+	return s.taskCache(taskID).checkCache(mts)
+}
+
+// UpdateCache caches the metrics collected for the requested ones.
+func (s *sticky) UpdateCache(requested, collected []core.Metric, taskID string) {
+	s.taskCache(taskID).updateCache(requested, collected)
+}
+
+// CacheStats returns the statistics of the caches of every task.
+func (s *sticky) CacheStats() core.CacheStats {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+	return mergeCacheStats(s.metricCache)
 }
 
-// updateCache updates the cache with the given array of metrics.
-func (s *sticky) UpdateCache(mts []core.Metric, taskID string) {
+// taskCache returns the cache of the task creating it the first time
+func (s *sticky) taskCache(taskID string) *cache {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
 	if _, ok := s.metricCache[taskID]; !ok {
 		s.metricCache[taskID] = NewCache(s.cacheTTL)
 	}
-	s.metricCache[taskID].updateCache(mts)
+	return s.metricCache[taskID]
 }
 
 // AllCacheHits returns cache hits across all metrics.
 func (s *sticky) AllCacheHits() uint64 {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
 	var total uint64
 	for _, cache := range s.metricCache {
 		total += cache.allCacheHits()
@@ -111,6 +135,8 @@ func (s *sticky) AllCacheHits() uint64 {
 
 // AllCacheMisses returns cache misses across all metrics.
 func (s *sticky) AllCacheMisses() uint64 {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
 	var total uint64
 	for _, cache := range s.metricCache {
 		total += cache.allCacheMisses()
@@ -120,6 +146,8 @@ func (s *sticky) AllCacheMisses() uint64 {
 
 // CacheHits returns the cache hits for a given metric namespace and version.
 func (s *sticky) CacheHits(ns string, version int, taskID string) (uint64, error) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
 	if cache, ok := s.metricCache[taskID]; ok {
 		return cache.cacheHits(ns, version)
 	}
@@ -128,6 +156,8 @@ func (s *sticky) CacheHits(ns string, version int, taskID string) (uint64, error
 
 // CacheMisses returns the cache misses for a given metric namespace and version.
 func (s *sticky) CacheMisses(ns string, version int, taskID string) (uint64, error) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
 	if cache, ok := s.metricCache[taskID]; ok {
 		return cache.cacheMisses(ns, version)
 	}
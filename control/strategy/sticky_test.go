@@ -30,14 +30,16 @@ import (
 )
 
 type mockPlugin struct {
-	name string
+	name    string
+	id      uint32
+	lastHit time.Time
 }
 
 func (m *mockPlugin) HitCount() int      { return 0 }
-func (m *mockPlugin) LastHit() time.Time { return time.Time{} }
-func (m *mockPlugin) String() string     { return "" }
+func (m *mockPlugin) LastHit() time.Time { return m.lastHit }
+func (m *mockPlugin) String() string     { return m.name }
 func (m *mockPlugin) Kill(string) error  { return nil }
-func (m *mockPlugin) ID() uint32         { return 0 }
+func (m *mockPlugin) ID() uint32         { return m.id }
 
 func newMockMetricType(ns string) mockMetricType {
 	return mockMetricType{
@@ -96,6 +98,16 @@ func TestStickyRouter(t *testing.T) {
 				So(sp, ShouldBeNil)
 				So(err, ShouldEqual, ErrCouldNotSelect)
 			})
+			Convey("Select another plugin when the selected one is gone", func() {
+				p3 := &mockPlugin{name: "p3"}
+				sp, err := router.Select([]SelectablePlugin{p2, p3}, "task1")
+				So(err, ShouldBeNil)
+				So(sp, ShouldEqual, p3)
+				// task2 keeps its plugin
+				sp, err = router.Select([]SelectablePlugin{p2, p3}, "task2")
+				So(err, ShouldBeNil)
+				So(sp, ShouldEqual, p2)
+			})
 		})
 
 	})
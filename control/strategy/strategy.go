@@ -40,11 +40,19 @@ type SelectablePlugin interface {
 	ID() uint32
 }
 
+// CallTracker is implemented by strategies which route on the calls in flight
+// to the plugins they select.  Done is called when a call made to a plugin
+// returned by Select returns.
+type CallTracker interface {
+	Done(selectablePlugin SelectablePlugin)
+}
+
 type RoutingAndCaching interface {
 	Select(selectablePlugins []SelectablePlugin, taskID string) (SelectablePlugin, error)
 	Remove(selectablePlugins []SelectablePlugin, taskID string) (SelectablePlugin, error)
 	CheckCache(metrics []core.Metric, taskID string) ([]core.Metric, []core.Metric)
-	UpdateCache(metrics []core.Metric, taskID string)
+	UpdateCache(requested []core.Metric, collected []core.Metric, taskID string)
+	CacheStats() core.CacheStats
 	CacheHits(ns string, ver int, taskID string) (uint64, error)
 	CacheMisses(ns string, ver int, taskID string) (uint64, error)
 	AllCacheHits() uint64
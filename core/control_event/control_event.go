@@ -32,6 +32,7 @@ const (
 	MetricUnsubscribed    = "Control.MetricUnsubscribed"
 	HealthCheckFailed     = "Control.PluginHealthCheckFailed"
 	MoveSubscription      = "Control.PluginSubscriptionMoved"
+	PluginLimitReached    = "Control.PluginLimitReached"
 )
 
 type LoadPluginEvent struct {
@@ -124,3 +125,17 @@ type MovePluginSubscriptionEvent struct {
 func (mse MovePluginSubscriptionEvent) Namespace() string {
 	return MoveSubscription
 }
+
+// PluginLimitReachedEvent is emitted when a running plugin reaches one of its
+// resource limits
+type PluginLimitReachedEvent struct {
+	Name    string
+	Version int
+	Type    int
+	Limit   string
+	Message string
+}
+
+func (e PluginLimitReachedEvent) Namespace() string {
+	return PluginLimitReached
+}
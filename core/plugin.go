@@ -80,17 +80,68 @@ type CatalogedPlugin interface {
 	Status() string
 	PluginPath() string
 	LoadedTimestamp() *time.Time
+	Restarts() PluginRestarts
+}
+
+// PluginRestarts records the restarts of the instances of a plugin which
+// failed their health checks
+type PluginRestarts struct {
+	Count       int
+	LastRestart time.Time
+	LastReason  string
 }
 
 // the collection of cataloged plugins used
 // by mgmt modules
 type PluginCatalog []CatalogedPlugin
 
+// CacheStats are the statistics of the metric cache of a plugin
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Entries is the number of metrics held by the cache
+	Entries int
+	// Evictions counts the entries dropped to keep the cache in its size
+	Evictions uint64
+	Metrics   []MetricCacheStats
+}
+
+// MetricCacheStats are the cache hits and misses of a metric across the
+// configs it is collected with
+type MetricCacheStats struct {
+	Namespace string
+	Version   int
+	Hits      uint64
+	Misses    uint64
+}
+
+// PluginLogLine is a line a running plugin wrote to its STDOUT or STDERR
+type PluginLogLine struct {
+	Time   time.Time
+	Stream string
+	Line   string
+}
+
 type SubscribedPlugin interface {
 	Plugin
 	Config() *cdata.ConfigDataNode
 }
 
+// RemotePlugin is a plugin running as a service, usually on another host,
+// which snapd connects to instead of starting it
+type RemotePlugin struct {
+	// URL is where the plugin listens.  Its scheme is the RPC the plugin
+	// speaks: http or https for JSON-RPC, tcp for the native RPC and grpc
+	// for gRPC.
+	URL string
+	// PublicKey is the PEM encoded RSA public key of the plugin.  It is
+	// required as the calls made to a remote plugin are always encrypted.
+	PublicKey []byte
+	Name      string
+	Version   int
+	Type      PluginType
+}
+
 type RequestedPlugin struct {
 	path      string
 	checkSum  [sha256.Size]byte
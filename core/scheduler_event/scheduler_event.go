@@ -29,6 +29,8 @@ const (
 	TaskStarted            = "Scheduler.TaskStarted"
 	TaskStopped            = "Scheduler.TaskStopped"
 	TaskDisabled           = "Scheduler.TaskDisabled"
+	TaskEnded              = "Scheduler.TaskEnded"
+	TaskUpdated            = "Scheduler.TaskUpdated"
 	MetricCollected        = "Scheduler.MetricsCollected"
 	MetricCollectionFailed = "Scheduler.MetricCollectionFailed"
 )
@@ -79,6 +81,23 @@ func (e TaskDisabledEvent) Namespace() string {
 	return TaskDisabled
 }
 
+type TaskEndedEvent struct {
+	TaskID string
+}
+
+func (e TaskEndedEvent) Namespace() string {
+	return TaskEnded
+}
+
+type TaskUpdatedEvent struct {
+	TaskID string
+	Source string
+}
+
+func (e TaskUpdatedEvent) Namespace() string {
+	return TaskUpdated
+}
+
 type MetricCollectedEvent struct {
 	TaskID  string
 	Metrics []core.Metric
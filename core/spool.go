@@ -0,0 +1,45 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"time"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// SpooledBatch is a batch of metrics which could not be published after all
+// of its retries.  It is kept in the publish spool of the task until it is
+// replayed, expires or is purged.
+type SpooledBatch struct {
+	ID            string `json:"id"`
+	TaskID        string `json:"task_id"`
+	PluginName    string `json:"plugin_name"`
+	PluginVersion int    `json:"plugin_version"`
+	// Node identifies the publish node the batch failed for by its plugin
+	// and config.  The batch is only replayed through the same node.
+	Node         string                `json:"node"`
+	Config       *cdata.ConfigDataNode `json:"config,omitempty"`
+	ContentType  string                `json:"content_type"`
+	Content      []byte                `json:"content"`
+	CreationTime time.Time             `json:"creation_timestamp"`
+	Attempts     int                   `json:"attempts"`
+	LastError    string                `json:"last_error"`
+}
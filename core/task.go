@@ -76,6 +76,7 @@ type Task interface {
 	SetID(string)
 	MissedCount() uint
 	FailedCount() uint
+	TimedOutCount() uint
 	LastFailureMessage() string
 	LastRunTime() *time.Time
 	CreationTime() *time.Time
@@ -150,3 +151,22 @@ func SetTaskID(id string) TaskOption {
 type TaskErrors interface {
 	Errors() []serror.SnapError
 }
+
+// TaskTestResult is the outcome of running the workflow of a task once
+// without creating the task
+type TaskTestResult struct {
+	Nodes []*TaskTestNode
+}
+
+// TaskTestNode holds the metrics a node of the workflow output during a task
+// test or, for a publisher, the metrics it would have been sent
+type TaskTestNode struct {
+	// Path to the node in the workflow map (e.g. collect.process[0].publish[1])
+	Path          string
+	Type          string
+	PluginName    string
+	PluginVersion int
+	ContentType   string
+	Metrics       []Metric
+	Errors        []error
+}
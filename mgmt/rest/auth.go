@@ -0,0 +1,241 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+var (
+	// ErrNoCredentials - The error message for a request which carries no credentials an authenticator understands
+	ErrNoCredentials = errors.New("No credentials given")
+	// ErrInvalidCredentials - The error message for a request whose credentials are not valid
+	ErrInvalidCredentials = errors.New("Invalid credentials")
+	// ErrUnauthorized - The error message returned to a client which failed to authenticate
+	ErrUnauthorized = errors.New("Authentication required")
+	// ErrBadPasswordFile - The error message for a malformed line in a password file
+	ErrBadPasswordFile = errors.New("Password file lines must be in the form user:bcrypt-hash")
+	// ErrBadClientCA - The error message for a client CA file without any certificates
+	ErrBadClientCA = errors.New("No certificates found in client CA file")
+)
+
+type principalKey struct{}
+
+// AuthConfig configures how the REST API authenticates requests.  It is read
+// from the "restapi" section of the snapd config file.
+type AuthConfig struct {
+	// PasswordFile is the path to a file of user:bcrypt-hash lines used for
+	// HTTP basic auth (e.g. as written by htpasswd -B)
+	PasswordFile string `json:"password_file"`
+	// Tokens maps the name of a principal to a static bearer token
+	Tokens map[string]string `json:"tokens"`
+	// ClientCAFile is the path to the PEM encoded CAs which sign client
+	// certificates.  It requires HTTPS.
+	ClientCAFile string `json:"client_ca_file"`
+}
+
+// Enabled returns whether any form of authentication is configured
+func (a *AuthConfig) Enabled() bool {
+	return a != nil && (a.PasswordFile != "" || len(a.Tokens) > 0 || a.ClientCAFile != "")
+}
+
+// Authenticator identifies the principal making a request
+type Authenticator interface {
+	// Authenticate returns the name of the principal making the request,
+	// ErrNoCredentials if the request carries no credentials the
+	// authenticator understands or ErrInvalidCredentials if they are wrong.
+	Authenticate(*http.Request) (string, error)
+	// Challenge is the value of the WWW-Authenticate header sent with a 401 or
+	// an empty string if the scheme has none.
+	Challenge() string
+}
+
+// Principal returns the name of the principal authenticated for a request or
+// an empty string if authentication is disabled.
+func Principal(r *http.Request) string {
+	p, _ := r.Context().Value(principalKey{}).(string)
+	return p
+}
+
+// authHandler is a negroni middleware which rejects requests no authenticator
+// accepts
+type authHandler struct {
+	authenticators []Authenticator
+}
+
+// NewAuthHandler returns a middleware which requires each request to be
+// accepted by one of the authenticators
+func NewAuthHandler(a ...Authenticator) *authHandler {
+	return &authHandler{authenticators: a}
+}
+
+func (a *authHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	for _, au := range a.authenticators {
+		p, err := au.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		if err != nil {
+			restLogger.WithFields(log.Fields{
+				"_block": "authenticate",
+				"method": r.Method,
+				"url":    r.URL.Path,
+				"remote": r.RemoteAddr,
+				"_error": err.Error(),
+			}).Warn("rejected request")
+			break
+		}
+		next(rw, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+		return
+	}
+	for _, au := range a.authenticators {
+		if c := au.Challenge(); c != "" {
+			rw.Header().Add("WWW-Authenticate", c)
+		}
+	}
+	respond(401, rbody.FromError(ErrUnauthorized), rw)
+}
+
+// basicAuthenticator checks HTTP basic auth credentials against bcrypt hashes
+type basicAuthenticator struct {
+	users map[string][]byte
+}
+
+// NewBasicAuthenticator returns an Authenticator for HTTP basic auth which
+// reads users from the password file at path
+func NewBasicAuthenticator(path string) (Authenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := &basicAuthenticator{users: map[string][]byte{}}
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("%v (%s:%d)", ErrBadPasswordFile, path, n)
+		}
+		if _, err := bcrypt.Cost([]byte(line[i+1:])); err != nil {
+			return nil, fmt.Errorf("%v (%s:%d)", ErrBadPasswordFile, path, n)
+		}
+		b.users[line[:i]] = []byte(line[i+1:])
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *basicAuthenticator) Authenticate(r *http.Request) (string, error) {
+	u, p, ok := r.BasicAuth()
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	h, ok := b.users[u]
+	if !ok || bcrypt.CompareHashAndPassword(h, []byte(p)) != nil {
+		return "", ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+func (b *basicAuthenticator) Challenge() string {
+	return `Basic realm="snap"`
+}
+
+// tokenAuthenticator checks bearer tokens against a static set
+type tokenAuthenticator struct {
+	tokens map[string]string
+}
+
+// NewTokenAuthenticator returns an Authenticator for bearer tokens given a
+// map of principal names to their tokens
+func NewTokenAuthenticator(tokens map[string]string) Authenticator {
+	return &tokenAuthenticator{tokens: tokens}
+}
+
+func (t *tokenAuthenticator) Authenticate(r *http.Request) (string, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return "", ErrNoCredentials
+	}
+	token := []byte(strings.TrimSpace(h[len("Bearer "):]))
+	for name, tok := range t.tokens {
+		if tok != "" && subtle.ConstantTimeCompare(token, []byte(tok)) == 1 {
+			return name, nil
+		}
+	}
+	return "", ErrInvalidCredentials
+}
+
+func (t *tokenAuthenticator) Challenge() string {
+	return `Bearer realm="snap"`
+}
+
+// certAuthenticator accepts requests made with a client certificate verified
+// during the TLS handshake.  The principal is the common name of the
+// certificate's subject.
+type certAuthenticator struct{}
+
+// NewCertAuthenticator returns an Authenticator for TLS client certificates
+func NewCertAuthenticator() Authenticator {
+	return &certAuthenticator{}
+}
+
+func (c *certAuthenticator) Authenticate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", ErrNoCredentials
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, nil
+}
+
+func (c *certAuthenticator) Challenge() string {
+	return ""
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, ErrBadClientCA
+	}
+	return pool, nil
+}
@@ -0,0 +1,249 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	cryptotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/negroni"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/intelsdi-x/snap/control"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writePasswordFile(dir string, users map[string]string) string {
+	path := filepath.Join(dir, "passwd")
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	fmt.Fprintln(f, "# snap users")
+	for u, p := range users {
+		h, err := bcrypt.GenerateFromPassword([]byte(p), bcrypt.MinCost)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(f, "%s:%s\n", u, h)
+	}
+	return path
+}
+
+// authServer returns a server which responds with the principal of each
+// request it accepts
+func authServer(a ...Authenticator) *httptest.Server {
+	n := negroni.New(NewAuthHandler(a...))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Principal(r))
+	})
+	return httptest.NewServer(n)
+}
+
+func get(url string, setup func(*http.Request)) (int, string) {
+	req, err := http.NewRequest("GET", url, nil)
+	So(err, ShouldBeNil)
+	setup(req)
+	resp, err := http.DefaultClient.Do(req)
+	So(err, ShouldBeNil)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	So(err, ShouldBeNil)
+	return resp.StatusCode, string(b)
+}
+
+func TestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snap-rest-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	Convey("AuthConfig", t, func() {
+		var cfg *AuthConfig
+		So(cfg.Enabled(), ShouldBeFalse)
+		So((&AuthConfig{}).Enabled(), ShouldBeFalse)
+		So((&AuthConfig{Tokens: map[string]string{"ci": "secret"}}).Enabled(), ShouldBeTrue)
+	})
+
+	Convey("Basic and token auth", t, func() {
+		basic, err := NewBasicAuthenticator(writePasswordFile(dir, map[string]string{"jane": "p@ssw0rd"}))
+		So(err, ShouldBeNil)
+		ts := authServer(NewTokenAuthenticator(map[string]string{"ci": "secret"}), basic)
+		defer ts.Close()
+
+		Convey("accepts a valid user and password", func() {
+			code, body := get(ts.URL, func(r *http.Request) { r.SetBasicAuth("jane", "p@ssw0rd") })
+			So(code, ShouldEqual, 200)
+			So(body, ShouldEqual, "jane")
+		})
+
+		Convey("accepts a valid token", func() {
+			code, body := get(ts.URL, func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") })
+			So(code, ShouldEqual, 200)
+			So(body, ShouldEqual, "ci")
+		})
+
+		Convey("rejects a wrong password", func() {
+			code, body := get(ts.URL, func(r *http.Request) { r.SetBasicAuth("jane", "guess") })
+			So(code, ShouldEqual, 401)
+			So(body, ShouldContainSubstring, ErrUnauthorized.Error())
+		})
+
+		Convey("rejects an unknown token", func() {
+			code, _ := get(ts.URL, func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") })
+			So(code, ShouldEqual, 401)
+		})
+
+		Convey("rejects a request without credentials with a challenge", func() {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, 401)
+			So(resp.Header["Www-Authenticate"], ShouldResemble, []string{`Bearer realm="snap"`, `Basic realm="snap"`})
+		})
+	})
+
+	Convey("NewBasicAuthenticator()", t, func() {
+		Convey("fails on a missing file", func() {
+			_, err := NewBasicAuthenticator(filepath.Join(dir, "missing"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("fails on a line which is not user:bcrypt-hash", func() {
+			path := filepath.Join(dir, "bad")
+			So(ioutil.WriteFile(path, []byte("jane:p@ssw0rd\n"), 0600), ShouldBeNil)
+			_, err := NewBasicAuthenticator(path)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrBadPasswordFile.Error())
+		})
+	})
+
+	Convey("EnableAuth()", t, func() {
+		Convey("requires HTTPS for client certificates", func() {
+			s, err := New(false, "", "")
+			So(err, ShouldBeNil)
+			So(s.EnableAuth(&AuthConfig{ClientCAFile: "ca.pem"}), ShouldEqual, ErrClientCertsRequireHTTPS)
+		})
+
+		Convey("authenticates client certificates signed by the CA", func() {
+			caCert, caKey := newTestCert("snap test CA", nil, nil)
+			caPath := filepath.Join(dir, "ca.pem")
+			So(ioutil.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}), 0600), ShouldBeNil)
+			clientCert, clientKey := newTestCert("ops", caCert, caKey)
+			otherCA, otherKey := newTestCert("other CA", nil, nil)
+			strangerCert, strangerKey := newTestCert("stranger", otherCA, otherKey)
+
+			s, err := New(true, "", "")
+			So(err, ShouldBeNil)
+			So(s.EnableAuth(&AuthConfig{ClientCAFile: caPath, Tokens: map[string]string{"ci": "secret"}}), ShouldBeNil)
+			c := control.New()
+			c.Start()
+			defer c.Stop()
+			s.BindMetricManager(c)
+			So(s.Start("127.0.0.1:0"), ShouldBeNil)
+			url := fmt.Sprintf("https://127.0.0.1:%d/v1/plugins", s.Port())
+
+			client := func(cert *x509.Certificate, key *rsa.PrivateKey) *http.Client {
+				cfg := &cryptotls.Config{InsecureSkipVerify: true}
+				if cert != nil {
+					cfg.Certificates = []cryptotls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}}
+				}
+				return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+			}
+
+			resp, err := client(clientCert, clientKey).Get(url)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, 200)
+
+			resp, err = client(nil, nil).Get(url)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, 401)
+
+			req, _ := http.NewRequest("GET", url, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err = client(nil, nil).Do(req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, 200)
+
+			// A certificate signed by another CA is never accepted
+			resp, err = client(strangerCert, strangerKey).Get(url)
+			if err == nil {
+				resp.Body.Close()
+				So(resp.StatusCode, ShouldEqual, 401)
+			}
+		})
+	})
+}
+
+// newTestCert returns a certificate for cn signed by parent or self signed
+// when parent is nil
+func newTestCert(cn string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		panic(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		panic(err)
+	}
+	temp := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		temp.IsCA = true
+		temp.BasicConstraintsValid = true
+		temp.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = temp, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, temp, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return cert, key
+}
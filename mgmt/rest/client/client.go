@@ -67,6 +67,8 @@ type Client struct {
 	Version string
 	// http is a pointer to a net/http client.
 	http *http.Client
+	// auth adds the client's credentials to each request.
+	auth *authTransport
 	// prefix is the string concatenation of a request URL, forward slash
 	// and the request client version.
 	prefix string
@@ -82,19 +84,70 @@ func New(url, ver string, insecure bool) *Client {
 		URL:     url,
 		Version: ver,
 
-		http: &http.Client{
-			Transport: &http.Transport{
+		auth: &authTransport{
+			transport: &http.Transport{
 				TLSClientConfig: &tls.Config{
 					InsecureSkipVerify: insecure,
 				},
 			},
 		},
 	}
+	c.http = &http.Client{Transport: c.auth}
 	c.prefix = url + "/" + ver
 	// TODO (danielscottt): assert that path is valid and target is available
 	return c
 }
 
+// SetBasicAuth makes the client authenticate each request using HTTP basic
+// auth with the given user and password.
+func (c *Client) SetBasicAuth(user, password string) {
+	c.auth.user = user
+	c.auth.password = password
+}
+
+// SetToken makes the client authenticate each request using the given bearer
+// token.  A token takes precedence over basic auth.
+func (c *Client) SetToken(token string) {
+	c.auth.token = token
+}
+
+// SetClientCert makes the client present the certificate in certFile, whose
+// key is in keyFile, when the API is running HTTPS.
+func (c *Client) SetClientCert(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	c.auth.transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	return nil
+}
+
+// authTransport is a http.RoundTripper which adds credentials to requests
+type authTransport struct {
+	transport      *http.Transport
+	user, password string
+	token          string
+}
+
+func (a *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if a.token == "" && a.user == "" {
+		return a.transport.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request it is given
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if a.token != "" {
+		r.Header.Set("Authorization", "Bearer "+a.token)
+	} else {
+		r.SetBasicAuth(a.user, a.password)
+	}
+	return a.transport.RoundTrip(r)
+}
+
 // String returns the string representation of the content type given a content number.
 func (t contentType) String() string {
 	return contentTypes[t]
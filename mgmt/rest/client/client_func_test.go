@@ -24,6 +24,8 @@ package client
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
 	"testing"
@@ -31,9 +33,11 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/intelsdi-x/snap/control"
 	"github.com/intelsdi-x/snap/mgmt/rest"
+	"github.com/intelsdi-x/snap/mgmt/webhook"
 	"github.com/intelsdi-x/snap/scheduler"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	. "github.com/smartystreets/goconvey/convey"
@@ -67,10 +71,17 @@ func getWMFromSample(sample string) *wmap.WorkflowMap {
 // REST API instances that are started are killed when the tests end.
 // When we eventually have a REST API Stop command this can be killed.
 func startAPI() string {
+	return startAPIWithAuth(nil)
+}
+
+func startAPIWithAuth(auth *rest.AuthConfig) string {
 	// Start a REST API to talk to
 	rest.StreamingBufferWindow = 0.01
 	log.SetLevel(LOG_LEVEL)
 	r, _ := rest.New(false, "", "")
+	if err := r.EnableAuth(auth); err != nil {
+		panic(err)
+	}
 	c := control.New()
 	c.Start()
 	s := scheduler.New()
@@ -79,6 +90,12 @@ func startAPI() string {
 	r.BindConfigManager(c.Config)
 	r.BindMetricManager(c)
 	r.BindTaskManager(s)
+	c.RegisterEventHandler("rest", r)
+	s.RegisterEventHandler("rest", r)
+	wh := webhook.New(webhook.DefaultConfig())
+	r.BindWebhookManager(wh)
+	c.RegisterEventHandler("webhook", wh)
+	s.RegisterEventHandler("webhook", wh)
 	err := r.Start("127.0.0.1:0")
 	if err != nil {
 		// Panic on an error
@@ -266,6 +283,34 @@ func TestSnapClient(t *testing.T) {
 			ttb := c.CreateTask(sch, wfb, "bad", "", true)
 			So(ttb.Err, ShouldNotBeNil)
 		})
+		Convey("ValidateTask", func() {
+			vt := c.ValidateTask(sch, wf, "")
+			So(vt.Err, ShouldBeNil)
+			So(vt.Valid, ShouldBeTrue)
+			So(vt.Errors, ShouldBeEmpty)
+
+			wfb := getWMFromSample("bad.json")
+			wfb.CollectNode.Add(wmap.NewProcessNode("passthru", 1))
+			vt = c.ValidateTask(sch, wfb, "soon")
+			So(vt.Err, ShouldBeNil)
+			So(vt.Valid, ShouldBeFalse)
+			So(vt.Errors, ShouldHaveLength, 2)
+			So(vt.Errors[0].Path, ShouldEqual, "deadline")
+			So(vt.Errors[1].Path, ShouldEqual, "collect.process[0]")
+			So(vt.Errors[1].Message, ShouldEqual, "processor output never reaches a publisher")
+		})
+
+		Convey("TestTask", func() {
+			tt := c.TestTask(wf)
+			So(tt.Err, ShouldBeNil)
+			So(tt.Nodes, ShouldHaveLength, 2)
+			So(tt.Nodes[0].Type, ShouldEqual, "collector")
+			So(tt.Nodes[0].Metrics, ShouldNotBeEmpty)
+			So(tt.Nodes[1].Type, ShouldEqual, "publisher")
+
+			tt = c.TestTask(wmap.NewWorkflowMap())
+			So(tt.Err, ShouldNotBeNil)
+		})
 
 		tf := c.CreateTask(sch, wf, "baron", "", false)
 		Convey("valid task not started on creation", func() {
@@ -363,6 +408,63 @@ func TestSnapClient(t *testing.T) {
 				So(et.Err, ShouldNotBeNil)
 				So(et.Err.Error(), ShouldEqual, "Task must be disabled")
 			})
+			Convey("UpdateTask", func() {
+				ut := c.UpdateTask(tt.ID, &Schedule{Type: "simple", Interval: "2s"}, nil, "renamed", "")
+				So(ut.Err, ShouldBeNil)
+				So(ut.ID, ShouldEqual, tt.ID)
+				So(ut.Name, ShouldEqual, "renamed")
+				So(ut.Schedule.Interval, ShouldEqual, "2s")
+
+				ut = c.UpdateTask("1234", nil, nil, "renamed", "")
+				So(ut.Err, ShouldNotBeNil)
+			})
+			Convey("GetPluginLogs and FollowPluginLogs", func() {
+				// the plugins of the task start in the background
+				var ps *GetPluginsResult
+				for i := 0; i < 50; i++ {
+					ps = c.GetPlugins(true)
+					if ps.Err != nil || len(ps.AvailablePlugins) > 0 {
+						break
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+				So(ps.Err, ShouldBeNil)
+				So(ps.AvailablePlugins, ShouldNotBeEmpty)
+				ap := ps.AvailablePlugins[0]
+
+				var logs *GetPluginLogsResult
+				for i := 0; i < 50; i++ {
+					logs = c.GetPluginLogs(ap.Type, ap.Name, ap.Version, 0)
+					if logs.Err != nil || len(logs.Lines) > 0 {
+						break
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+				So(logs.Err, ShouldBeNil)
+				So(logs.Name, ShouldEqual, ap.Name)
+				So(logs.Lines, ShouldNotBeEmpty)
+				So(logs.Lines[0].Line, ShouldContainSubstring, "Listening")
+
+				f := c.FollowPluginLogs(ap.Type, ap.Name, ap.Version, 1)
+				So(f.Err, ShouldBeNil)
+				l := <-f.LineChan
+				So(l.Stream, ShouldNotBeEmpty)
+				f.Close()
+
+				nf := c.GetPluginLogs(ap.Type, "not-loaded", 1, 0)
+				So(nf.Err, ShouldNotBeNil)
+				So(nf.Err.Error(), ShouldEqual, "Loaded plugin not found")
+				nff := c.FollowPluginLogs(ap.Type, "not-loaded", 1, 0)
+				So(nff.Err, ShouldNotBeNil)
+			})
+			Convey("GetTaskSpool and PurgeTaskSpool without a spool", func() {
+				gs := c.GetTaskSpool(tt.ID)
+				So(gs.Err, ShouldNotBeNil)
+				So(gs.Err.Error(), ShouldEqual, "Publish spool is not enabled.")
+
+				ps := c.PurgeTaskSpool("1234")
+				So(ps.Err, ShouldNotBeNil)
+			})
 			Convey("WatchTasks", func() {
 				Convey("invalid task ID", func() {
 					rest.StreamingBufferWindow = 0.01
@@ -492,3 +594,162 @@ func TestSnapClient(t *testing.T) {
 		So(p3.Err, ShouldNotBeNil)
 	})
 }
+
+func TestSnapClientAuth(t *testing.T) {
+	f, err := ioutil.TempFile("", "snap-passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	h, _ := bcrypt.GenerateFromPassword([]byte("p@ssw0rd"), bcrypt.MinCost)
+	fmt.Fprintf(f, "jane:%s\n", h)
+	f.Close()
+
+	uri := startAPIWithAuth(&rest.AuthConfig{
+		PasswordFile: f.Name(),
+		Tokens:       map[string]string{"ci": "secret"},
+	})
+
+	Convey("Client with authentication", t, func() {
+		Convey("fails without credentials", func() {
+			c := New(uri, "v1", true)
+			p := c.GetPlugins(false)
+			So(p.Err, ShouldNotBeNil)
+			So(p.Err.Error(), ShouldEqual, rest.ErrUnauthorized.Error())
+		})
+
+		Convey("fails with a wrong password", func() {
+			c := New(uri, "v1", true)
+			c.SetBasicAuth("jane", "guess")
+			So(c.GetPlugins(false).Err, ShouldNotBeNil)
+		})
+
+		Convey("succeeds with basic auth", func() {
+			c := New(uri, "v1", true)
+			c.SetBasicAuth("jane", "p@ssw0rd")
+			So(c.GetPlugins(false).Err, ShouldBeNil)
+			So(c.LoadPlugin(MOCK_PLUGIN_PATH1).Err, ShouldBeNil)
+			So(c.UnloadPlugin("collector", "mock", 1).Err, ShouldBeNil)
+		})
+
+		Convey("succeeds with a bearer token", func() {
+			c := New(uri, "v1", true)
+			c.SetToken("secret")
+			So(c.GetPlugins(false).Err, ShouldBeNil)
+			w := c.WatchTask("1234")
+			So(w.Err, ShouldNotBeNil)
+			So(w.Err.Error(), ShouldNotEqual, rest.ErrUnauthorized.Error())
+		})
+
+		Convey("fails to load a missing client certificate", func() {
+			c := New(uri, "v1", true)
+			So(c.SetClientCert("/does/not/exist.pem", "/does/not/exist.key"), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSnapClientEvents(t *testing.T) {
+	CompressUpload = false
+
+	uri := startAPI()
+
+	Convey("Client watching events", t, func() {
+		c := New(uri, "v1", true)
+
+		Convey("receives the events matching its filter", func() {
+			w := c.WatchEvents(EventFilter{Namespaces: []string{"Control.PluginLoaded"}, Plugins: []string{"mock"}})
+			So(w.Err, ShouldBeNil)
+			defer w.Close()
+
+			So(c.LoadPlugin(FILE_PLUGIN_PATH).Err, ShouldBeNil)
+			So(c.LoadPlugin(MOCK_PLUGIN_PATH1).Err, ShouldBeNil)
+			select {
+			case e := <-w.EventChan:
+				So(e.Namespace, ShouldEqual, "Control.PluginLoaded")
+				So(e.PluginName, ShouldEqual, "mock")
+				So(e.PluginVersion, ShouldEqual, 1)
+			case <-time.After(5 * time.Second):
+				So("timed out waiting for event", ShouldBeEmpty)
+			}
+
+			w.Close()
+			_, ok := <-w.DoneChan
+			So(ok, ShouldBeFalse)
+			So(w.Err, ShouldBeNil)
+		})
+
+		Convey("fails when the API cannot be reached", func() {
+			w := New("http://localhost:-1", "v1", true).WatchEvents(EventFilter{})
+			So(w.Err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSnapClientWebhooks(t *testing.T) {
+	CompressUpload = false
+
+	uri := startAPI()
+	received := make(chan *http.Request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer ts.Close()
+
+	Convey("Client managing webhooks", t, func() {
+		c := New(uri, "v1", true)
+
+		Convey("rejects an invalid webhook", func() {
+			a := c.AddWebhook("not a url", nil, "s3cret")
+			So(a.Err, ShouldNotBeNil)
+			So(a.Err.Error(), ShouldEqual, webhook.ErrInvalidURL.Error())
+		})
+
+		Convey("fails for an unknown webhook", func() {
+			So(c.GetWebhook("nope").Err, ShouldNotBeNil)
+			So(c.RemoveWebhook("nope").Err, ShouldNotBeNil)
+			So(c.GetWebhookDeliveries("nope").Err, ShouldNotBeNil)
+		})
+
+		Convey("delivers events to a webhook", func() {
+			a := c.AddWebhook(ts.URL, []string{"Control.PluginLoaded"}, "s3cret")
+			So(a.Err, ShouldBeNil)
+			So(a.URL, ShouldEqual, ts.URL)
+			So(a.Namespaces, ShouldResemble, []string{"Control.PluginLoaded"})
+
+			l := c.GetWebhooks()
+			So(l.Err, ShouldBeNil)
+			So(l.Webhooks, ShouldHaveLength, 1)
+			So(l.Webhooks[0].ID, ShouldEqual, a.ID)
+			g := c.GetWebhook(a.ID)
+			So(g.Err, ShouldBeNil)
+			So(g.Href, ShouldEndWith, "/v1/webhooks/"+a.ID)
+
+			So(c.LoadPlugin(MOCK_PLUGIN_PATH1).Err, ShouldBeNil)
+			select {
+			case r := <-received:
+				So(r.Header.Get(webhook.EventHeader), ShouldEqual, "Control.PluginLoaded")
+				So(r.Header.Get(webhook.SignatureHeader), ShouldStartWith, "sha256=")
+			case <-time.After(5 * time.Second):
+				So("timed out waiting for delivery", ShouldBeEmpty)
+			}
+
+			var d *GetWebhookDeliveriesResult
+			for i := 0; i < 50; i++ {
+				d = c.GetWebhookDeliveries(a.ID)
+				if d.Err != nil || (len(d.Deliveries) == 1 && d.Deliveries[0].Delivered) {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			So(d.Err, ShouldBeNil)
+			So(d.Deliveries, ShouldHaveLength, 1)
+			So(d.Deliveries[0].Delivered, ShouldBeTrue)
+			So(d.Deliveries[0].StatusCode, ShouldEqual, 200)
+
+			rm := c.RemoveWebhook(a.ID)
+			So(rm.Err, ShouldBeNil)
+			So(rm.ID, ShouldEqual, a.ID)
+			So(c.GetWebhooks().Webhooks, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,168 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// EventFilter selects the events streamed by WatchEvents.  Each empty list
+// matches every event and a list with values matches any of them.
+type EventFilter struct {
+	// Namespaces match an event namespace or its prefix (e.g. Control or
+	// Scheduler.TaskStarted)
+	Namespaces []string
+	// Plugins match the name of the plugin an event is about
+	Plugins []string
+	// Tasks match the id of the task an event is about
+	Tasks []string
+}
+
+func (f EventFilter) query() string {
+	q := url.Values{}
+	for _, ns := range f.Namespaces {
+		q.Add("namespace", ns)
+	}
+	for _, p := range f.Plugins {
+		q.Add("plugin", p)
+	}
+	for _, t := range f.Tasks {
+		q.Add("task", t)
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// WatchEvents streams the events emitted by snapd's control and scheduler
+// modules, such as plugins loading and tasks starting, which match the filter.
+// Events are sent on EventChan until Close is called or the stream ends.
+func (c *Client) WatchEvents(f EventFilter) *WatchEventsResult {
+	r := &WatchEventsResult{
+		EventChan: make(chan *rbody.StreamedEvent),
+		DoneChan:  make(chan struct{}),
+	}
+
+	resp, err := c.http.Get(c.prefix + "/events" + f.query())
+	if err != nil {
+		r.Err = err
+		r.Close()
+		return r
+	}
+	if resp.StatusCode != 200 {
+		ar, err := httpRespToAPIResp(resp)
+		if err != nil {
+			r.Err = err
+		} else {
+			r.Err = errors.New(ar.Meta.Message)
+		}
+		r.Close()
+		return r
+	}
+
+	r.body = resp.Body
+	go func() {
+		err := readEventStream(resp.Body, func(data []byte) bool {
+			e := &rbody.StreamedEvent{}
+			if err := json.Unmarshal(data, e); err != nil {
+				r.setErr(err)
+				return false
+			}
+			select {
+			case r.EventChan <- e:
+				return true
+			case <-r.DoneChan:
+				return false
+			}
+		})
+		if err != nil {
+			r.setErr(err)
+		}
+		r.Close()
+	}()
+	return r
+}
+
+// readEventStream calls handle with the data of each server sent event read
+// from body until handle returns false or reading fails
+func readEventStream(body io.Reader, handle func(data []byte) bool) error {
+	reader := bufio.NewReader(body)
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			// A blank line ends an event
+			if data.Len() == 0 {
+				continue
+			}
+			ok := handle(data.Bytes())
+			data.Reset()
+			if !ok {
+				return nil
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// Comments, ids and event names carry nothing beyond the data
+	}
+}
+
+// WatchEventsResult is the response from snap/client on a WatchEvents call.
+type WatchEventsResult struct {
+	Err       error
+	EventChan chan *rbody.StreamedEvent
+	DoneChan  chan struct{}
+	body      io.Closer
+	once      sync.Once
+}
+
+// Close stops watching events
+func (w *WatchEventsResult) Close() {
+	w.once.Do(func() {
+		close(w.DoneChan)
+		if w.body != nil {
+			w.body.Close()
+		}
+	})
+}
+
+func (w *WatchEventsResult) setErr(err error) {
+	select {
+	case <-w.DoneChan:
+		// The stream ends with an error once the caller closes it
+	default:
+		w.Err = err
+	}
+}
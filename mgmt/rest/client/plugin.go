@@ -20,12 +20,14 @@ limitations under the License.
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
 
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 )
 
 // LoadPlugin loads plugins for the given plugin names.
@@ -55,6 +57,39 @@ func (c *Client) LoadPlugin(p []string) *LoadPluginResult {
 	return r
 }
 
+// LoadRemotePlugin loads a plugin running as a service which snapd connects to
+// through an HTTP POST request.  The loaded plugin returns if succeeded.
+// Otherwise, an error is returned.
+func (c *Client) LoadRemotePlugin(rp *request.RemotePluginRequest) *LoadPluginResult {
+	r := new(LoadPluginResult)
+	b, err := json.Marshal(rp)
+	if err != nil {
+		r.Err = serror.New(err)
+		return r
+	}
+	resp, err := c.do("POST", "/plugins", ContentTypeJSON, b)
+	if err != nil {
+		r.Err = serror.New(err)
+		return r
+	}
+
+	switch resp.Meta.Type {
+	case rbody.PluginsLoadedType:
+		pl := resp.Body.(*rbody.PluginsLoaded)
+		r.LoadedPlugins = convertLoadedPlugins(pl.LoadedPlugins)
+	case rbody.ErrorType:
+		f := resp.Body.(*rbody.Error).Fields
+		fields := make(map[string]interface{})
+		for k, v := range f {
+			fields[k] = v
+		}
+		r.Err = serror.New(resp.Body.(*rbody.Error), fields)
+	default:
+		r.Err = serror.New(ErrAPIResponseMetaType)
+	}
+	return r
+}
+
 // UnloadPlugin unloads a plugin given plugin type, name, and version through an HTTP DELETE request.
 // The unloaded plugin returns if succeeded. Otherwise, an error is returned.
 func (c *Client) UnloadPlugin(pluginType, name string, version int) *UnloadPluginResult {
@@ -0,0 +1,149 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+func pluginLogsPath(pluginType, name string, version, lines int, follow bool) string {
+	q := url.Values{}
+	q.Set("lines", fmt.Sprintf("%d", lines))
+	if follow {
+		q.Set("follow", "true")
+	}
+	return fmt.Sprintf("/plugins/%s/%s/%d/logs?%s", pluginType, url.QueryEscape(name), version, q.Encode())
+}
+
+// GetPluginLogs returns up to lines of the most recent lines the running
+// instances of a plugin wrote to their stdout and stderr, or every line snapd
+// keeps when lines < 1.
+func (c *Client) GetPluginLogs(pluginType, name string, version, lines int) *GetPluginLogsResult {
+	r := &GetPluginLogsResult{}
+	resp, err := c.do("GET", pluginLogsPath(pluginType, name, version, lines, false), ContentTypeJSON)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	switch resp.Meta.Type {
+	case rbody.PluginLogsType:
+		// Success
+		r.PluginLogs = resp.Body.(*rbody.PluginLogs)
+	case rbody.ErrorType:
+		r.Err = resp.Body.(*rbody.Error)
+	default:
+		r.Err = ErrAPIResponseMetaType
+	}
+	return r
+}
+
+// FollowPluginLogs streams the lines the running instances of a plugin write
+// to their stdout and stderr starting with up to lines of the most recent
+// ones.  Lines are sent on LineChan until Close is called, the plugin is
+// unloaded or the stream ends.
+func (c *Client) FollowPluginLogs(pluginType, name string, version, lines int) *FollowPluginLogsResult {
+	r := &FollowPluginLogsResult{
+		LineChan: make(chan rbody.PluginLogLine),
+		DoneChan: make(chan struct{}),
+	}
+
+	resp, err := c.http.Get(c.prefix + pluginLogsPath(pluginType, name, version, lines, true))
+	if err != nil {
+		r.Err = err
+		r.Close()
+		return r
+	}
+	if resp.StatusCode != 200 {
+		ar, err := httpRespToAPIResp(resp)
+		if err != nil {
+			r.Err = err
+		} else {
+			r.Err = errors.New(ar.Meta.Message)
+		}
+		r.Close()
+		return r
+	}
+
+	r.body = resp.Body
+	go func() {
+		err := readEventStream(resp.Body, func(data []byte) bool {
+			var l rbody.PluginLogLine
+			if err := json.Unmarshal(data, &l); err != nil {
+				r.setErr(err)
+				return false
+			}
+			select {
+			case r.LineChan <- l:
+				return true
+			case <-r.DoneChan:
+				return false
+			}
+		})
+		// The stream ends without an error once the plugin is unloaded
+		if err != nil && err != io.EOF {
+			r.setErr(err)
+		}
+		r.Close()
+	}()
+	return r
+}
+
+// GetPluginLogsResult is the response from snap/client on a GetPluginLogs call.
+type GetPluginLogsResult struct {
+	*rbody.PluginLogs
+	Err error
+}
+
+// FollowPluginLogsResult is the response from snap/client on a
+// FollowPluginLogs call.
+type FollowPluginLogsResult struct {
+	Err      error
+	LineChan chan rbody.PluginLogLine
+	DoneChan chan struct{}
+	body     io.Closer
+	once     sync.Once
+}
+
+// Close stops following the logs
+func (f *FollowPluginLogsResult) Close() {
+	f.once.Do(func() {
+		close(f.DoneChan)
+		if f.body != nil {
+			f.body.Close()
+		}
+	})
+}
+
+func (f *FollowPluginLogsResult) setErr(err error) {
+	select {
+	case <-f.DoneChan:
+		// The stream ends with an error once the caller closes it
+	default:
+		f.Err = err
+	}
+}
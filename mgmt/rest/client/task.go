@@ -24,7 +24,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
@@ -33,14 +32,16 @@ import (
 )
 
 type Schedule struct {
-	// Type specifies the type of the schedule. Currently,the type of "simple" and "windowed" are supported.
+	// Type specifies the type of the schedule. Currently,the type of "simple", "windowed" and "cron" are supported.
 	Type string
-	// Interval specifies the time duration.
+	// Interval specifies the time duration or, for a "cron" schedule, the cron entry.
 	Interval string
 	// StartTime specifies the beginning time.
 	StartTime *time.Time
 	// StopTime specifies the end time.
 	StopTime *time.Time
+	// TimeZone specifies the IANA time zone a "cron" schedule is evaluated in. Defaults to local time.
+	TimeZone string
 }
 
 // CreateTask creates a task given the schedule, workflow, task name, and task state.
@@ -48,10 +49,82 @@ type Schedule struct {
 // Otherwise, it's in the Stopped state. CreateTask is accomplished through a POST HTTP JSON request.
 // A ScheduledTask is returned if it succeeds, otherwise an error is returned.
 func (c *Client) CreateTask(s *Schedule, wf *wmap.WorkflowMap, name string, deadline string, startTask bool) *CreateTaskResult {
+	// Marshal to JSON for request body
+	j, err := json.Marshal(taskCreationRequest(s, wf, name, deadline, startTask))
+	if err != nil {
+		return &CreateTaskResult{Err: err}
+	}
+
+	resp, err := c.do("POST", "/tasks", ContentTypeJSON, j)
+	if err != nil {
+		return &CreateTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.AddScheduledTaskType:
+		// Success
+		return &CreateTaskResult{resp.Body.(*rbody.AddScheduledTask), nil}
+	case rbody.ErrorType:
+		return &CreateTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &CreateTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ValidateTask checks a task the way CreateTask would without creating it.
+// The request is an HTTP POST call. The problems found with the schedule and
+// workflow return if it succeeds, or an error is returned.
+func (c *Client) ValidateTask(s *Schedule, wf *wmap.WorkflowMap, deadline string) *ValidateTaskResult {
+	j, err := json.Marshal(taskCreationRequest(s, wf, "", deadline, false))
+	if err != nil {
+		return &ValidateTaskResult{Err: err}
+	}
+
+	resp, err := c.do("POST", "/tasks/validate", ContentTypeJSON, j)
+	if err != nil {
+		return &ValidateTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.TaskValidatedType:
+		return &ValidateTaskResult{resp.Body.(*rbody.TaskValidated), nil}
+	case rbody.ErrorType:
+		return &ValidateTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ValidateTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// TestTask runs a workflow once without creating a task or publishing its
+// metrics. The request is an HTTP POST call. The output of each node of the
+// workflow returns if it succeeds, or an error is returned.
+func (c *Client) TestTask(wf *wmap.WorkflowMap) *TestTaskResult {
+	j, err := json.Marshal(request.TaskCreationRequest{Workflow: wf})
+	if err != nil {
+		return &TestTaskResult{Err: err}
+	}
+
+	resp, err := c.do("POST", "/tasks/test", ContentTypeJSON, j)
+	if err != nil {
+		return &TestTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.TaskTestedType:
+		return &TestTaskResult{resp.Body.(*rbody.TaskTested), nil}
+	case rbody.ErrorType:
+		return &TestTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &TestTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+func taskCreationRequest(s *Schedule, wf *wmap.WorkflowMap, name string, deadline string, startTask bool) request.TaskCreationRequest {
 	t := request.TaskCreationRequest{
 		Schedule: request.Schedule{
 			Type:     s.Type,
 			Interval: s.Interval,
+			TimeZone: s.TimeZone,
 		},
 		Workflow: wf,
 		Start:    startTask,
@@ -72,26 +145,7 @@ func (c *Client) CreateTask(s *Schedule, wf *wmap.WorkflowMap, name string, dead
 	if deadline != "" {
 		t.Deadline = deadline
 	}
-	// Marshal to JSON for request body
-	j, err := json.Marshal(t)
-	if err != nil {
-		return &CreateTaskResult{Err: err}
-	}
-
-	resp, err := c.do("POST", "/tasks", ContentTypeJSON, j)
-	if err != nil {
-		return &CreateTaskResult{Err: err}
-	}
-
-	switch resp.Meta.Type {
-	case rbody.AddScheduledTaskType:
-		// Success
-		return &CreateTaskResult{resp.Body.(*rbody.AddScheduledTask), nil}
-	case rbody.ErrorType:
-		return &CreateTaskResult{Err: resp.Body.(*rbody.Error)}
-	default:
-		return &CreateTaskResult{Err: ErrAPIResponseMetaType}
-	}
+	return t
 }
 
 // WatchTask retrieves running tasks by running a goroutine to
@@ -104,7 +158,7 @@ func (c *Client) WatchTask(id string) *WatchTasksResult {
 	}
 
 	url := fmt.Sprintf("%s/tasks/%v/watch", c.prefix, id)
-	resp, err := http.Get(url)
+	resp, err := c.http.Get(url)
 	if err != nil {
 		r.Err = err
 		r.Close()
@@ -268,12 +322,107 @@ func (c *Client) EnableTask(id string) *EnableTaskResult {
 	}
 }
 
+// UpdateTask changes the schedule, workflow, name and/or deadline of an existing task
+// without changing its id. A nil schedule or workflow and an empty name or deadline are left
+// unchanged. The request is an HTTP PUT call. The updated task returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) UpdateTask(id string, s *Schedule, wf *wmap.WorkflowMap, name string, deadline string) *UpdateTaskResult {
+	t := request.TaskUpdateRequest{
+		Name:     name,
+		Deadline: deadline,
+		Workflow: wf,
+	}
+	if s != nil {
+		t.Schedule = &request.Schedule{
+			Type:     s.Type,
+			Interval: s.Interval,
+			TimeZone: s.TimeZone,
+		}
+		if s.StartTime != nil {
+			u := s.StartTime.Unix()
+			t.Schedule.StartTimestamp = &u
+		}
+		if s.StopTime != nil {
+			u := s.StopTime.Unix()
+			t.Schedule.StopTimestamp = &u
+		}
+	}
+	j, err := json.Marshal(t)
+	if err != nil {
+		return &UpdateTaskResult{Err: err}
+	}
+
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v", id), ContentTypeJSON, j)
+	if err != nil {
+		return &UpdateTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskUpdatedType:
+		return &UpdateTaskResult{resp.Body.(*rbody.ScheduledTaskUpdated), nil}
+	case rbody.ErrorType:
+		return &UpdateTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &UpdateTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetTaskSpool retrieves the batches a task could not publish given the task id.
+// The request is an HTTP GET call. The spooled batches return if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) GetTaskSpool(id string) *GetTaskSpoolResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tasks/%v/spool", id), ContentTypeJSON)
+	if err != nil {
+		return &GetTaskSpoolResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.TaskSpoolReturnedType:
+		return &GetTaskSpoolResult{resp.Body.(*rbody.TaskSpoolReturned), nil}
+	case rbody.ErrorType:
+		return &GetTaskSpoolResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTaskSpoolResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// PurgeTaskSpool drops the batches a task could not publish given the task id.
+// The request is an HTTP DELETE call. The number of dropped batches returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) PurgeTaskSpool(id string) *PurgeTaskSpoolResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/tasks/%v/spool", id), ContentTypeJSON)
+	if err != nil {
+		return &PurgeTaskSpoolResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.TaskSpoolPurgedType:
+		return &PurgeTaskSpoolResult{resp.Body.(*rbody.TaskSpoolPurged), nil}
+	case rbody.ErrorType:
+		return &PurgeTaskSpoolResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &PurgeTaskSpoolResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
 // CreateTaskResult is the response from snap/client on a CreateTask call.
 type CreateTaskResult struct {
 	*rbody.AddScheduledTask
 	Err error
 }
 
+// ValidateTaskResult is the response from snap/client on a ValidateTask call.
+type ValidateTaskResult struct {
+	*rbody.TaskValidated
+	Err error
+}
+
+// TestTaskResult is the response from snap/client on a TestTask call.
+type TestTaskResult struct {
+	*rbody.TaskTested
+	Err error
+}
+
 // WatchTaskResult is the response from snap/client on a WatchTask call.
 type WatchTasksResult struct {
 	count     int
@@ -321,3 +470,21 @@ type EnableTaskResult struct {
 	*rbody.ScheduledTaskEnabled
 	Err error
 }
+
+// UpdateTaskResult is the response from snap/client on an UpdateTask call.
+type UpdateTaskResult struct {
+	*rbody.ScheduledTaskUpdated
+	Err error
+}
+
+// GetTaskSpoolResult is the response from snap/client on a GetTaskSpool call.
+type GetTaskSpoolResult struct {
+	*rbody.TaskSpoolReturned
+	Err error
+}
+
+// PurgeTaskSpoolResult is the response from snap/client on a PurgeTaskSpool call.
+type PurgeTaskSpoolResult struct {
+	*rbody.TaskSpoolPurged
+	Err error
+}
@@ -0,0 +1,152 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+// AddWebhook registers a webhook which receives the events in namespaces, or
+// every event when there are none, signed with secret through an HTTP POST
+// call. The new webhook returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) AddWebhook(url string, namespaces []string, secret string) *AddWebhookResult {
+	b, err := json.Marshal(&request.WebhookCreationRequest{
+		URL:        url,
+		Namespaces: namespaces,
+		Secret:     secret,
+	})
+	if err != nil {
+		return &AddWebhookResult{Err: err}
+	}
+	resp, err := c.do("POST", "/webhooks", ContentTypeJSON, b)
+	if err != nil {
+		return &AddWebhookResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AddWebhookType:
+		return &AddWebhookResult{resp.Body.(*rbody.AddWebhook), nil}
+	case rbody.ErrorType:
+		return &AddWebhookResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AddWebhookResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetWebhooks retrieves the registered webhooks through an HTTP GET call.
+// A list of webhooks returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetWebhooks() *GetWebhooksResult {
+	resp, err := c.do("GET", "/webhooks", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetWebhooksResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WebhookListType:
+		return &GetWebhooksResult{resp.Body.(*rbody.WebhookList), nil}
+	case rbody.ErrorType:
+		return &GetWebhooksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetWebhooksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetWebhook retrieves the webhook given its id through an HTTP GET call.
+// The webhook returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetWebhook(id string) *GetWebhookResult {
+	resp, err := c.do("GET", fmt.Sprintf("/webhooks/%s", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetWebhookResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WebhookType:
+		return &GetWebhookResult{resp.Body.(*rbody.Webhook), nil}
+	case rbody.ErrorType:
+		return &GetWebhookResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetWebhookResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RemoveWebhook unregisters the webhook given its id through an HTTP DELETE
+// call. Deliveries waiting for the webhook are abandoned.
+func (c *Client) RemoveWebhook(id string) *RemoveWebhookResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/webhooks/%s", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &RemoveWebhookResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WebhookRemovedType:
+		return &RemoveWebhookResult{resp.Body.(*rbody.WebhookRemoved), nil}
+	case rbody.ErrorType:
+		return &RemoveWebhookResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RemoveWebhookResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetWebhookDeliveries retrieves the most recent deliveries to the webhook
+// given its id through an HTTP GET call. Otherwise, an error is returned.
+func (c *Client) GetWebhookDeliveries(id string) *GetWebhookDeliveriesResult {
+	resp, err := c.do("GET", fmt.Sprintf("/webhooks/%s/deliveries", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetWebhookDeliveriesResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WebhookDeliveryListType:
+		return &GetWebhookDeliveriesResult{resp.Body.(*rbody.WebhookDeliveryList), nil}
+	case rbody.ErrorType:
+		return &GetWebhookDeliveriesResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetWebhookDeliveriesResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// AddWebhookResult is the response from snap/client on an AddWebhook call.
+type AddWebhookResult struct {
+	*rbody.AddWebhook
+	Err error
+}
+
+// GetWebhooksResult is the response from snap/client on a GetWebhooks call.
+type GetWebhooksResult struct {
+	*rbody.WebhookList
+	Err error
+}
+
+// GetWebhookResult is the response from snap/client on a GetWebhook call.
+type GetWebhookResult struct {
+	*rbody.Webhook
+	Err error
+}
+
+// RemoveWebhookResult is the response from snap/client on a RemoveWebhook call.
+type RemoveWebhookResult struct {
+	*rbody.WebhookRemoved
+	Err error
+}
+
+// GetWebhookDeliveriesResult is the response from snap/client on a GetWebhookDeliveries call.
+type GetWebhookDeliveriesResult struct {
+	*rbody.WebhookDeliveryList
+	Err error
+}
@@ -0,0 +1,211 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/gomit"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+var (
+	// EventStreamKeepAlive is how often a comment is written to an idle
+	// event stream so proxies do not close it
+	EventStreamKeepAlive = 30 * time.Second
+	// eventStreamBuffer is the number of events held for a slow client
+	// before further events are dropped
+	eventStreamBuffer = 256
+)
+
+// eventFilter selects the events a client streams.  An empty list matches
+// every event and a list with values matches any of them.
+type eventFilter struct {
+	namespaces []string
+	plugins    []string
+	tasks      []string
+}
+
+func newEventFilter(r *http.Request) *eventFilter {
+	q := r.URL.Query()
+	return &eventFilter{
+		namespaces: queryValues(q["namespace"]),
+		plugins:    queryValues(q["plugin"]),
+		tasks:      queryValues(q["task"]),
+	}
+}
+
+// queryValues splits comma separated values given for a query parameter
+func queryValues(vs []string) []string {
+	var values []string
+	for _, v := range vs {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+func (f *eventFilter) matches(e *rbody.StreamedEvent) bool {
+	if len(f.namespaces) > 0 {
+		found := false
+		for _, ns := range f.namespaces {
+			if e.InNamespace(ns) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.plugins) > 0 && !contains(f.plugins, e.PluginName) {
+		return false
+	}
+	if len(f.tasks) > 0 && !contains(f.tasks, e.TaskID) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sequencedEvent is an event with the sequence number the broker received it
+// with
+type sequencedEvent struct {
+	id    uint64
+	event *rbody.StreamedEvent
+}
+
+// eventBroker fans the events emitted by control and the scheduler out to
+// each client streaming GET /v1/events.  gomit calls its handlers in their own
+// goroutines so events may arrive out of order; each is numbered as it is
+// received and queued to the clients under the same lock, so the number, sent
+// as the id of the event, increases along every stream.
+type eventBroker struct {
+	sync.Mutex
+	seq         uint64
+	subscribers map[chan *sequencedEvent]*eventFilter
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: map[chan *sequencedEvent]*eventFilter{}}
+}
+
+func (b *eventBroker) subscribe(f *eventFilter) chan *sequencedEvent {
+	ch := make(chan *sequencedEvent, eventStreamBuffer)
+	b.Lock()
+	b.subscribers[ch] = f
+	b.Unlock()
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan *sequencedEvent) {
+	b.Lock()
+	delete(b.subscribers, ch)
+	b.Unlock()
+}
+
+func (b *eventBroker) publish(e *rbody.StreamedEvent) {
+	b.Lock()
+	defer b.Unlock()
+	b.seq++
+	se := &sequencedEvent{id: b.seq, event: e}
+	for ch, f := range b.subscribers {
+		if !f.matches(e) {
+			continue
+		}
+		select {
+		case ch <- se:
+		default:
+			// Never block the emitter on a client which is not keeping up
+			restLogger.WithFields(log.Fields{
+				"_block": "publish-event",
+				"event":  e.Namespace,
+			}).Warn("event stream is full, dropping event")
+		}
+	}
+}
+
+// HandleGomitEvent streams the events emitted by control and the scheduler to
+// clients of GET /v1/events.  Register the server as a handler with each of
+// them to enable the stream.
+func (s *Server) HandleGomitEvent(e gomit.Event) {
+	s.events.publish(rbody.NewStreamedEvent(e))
+}
+
+func (s *Server) getEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	logger := log.WithFields(log.Fields{
+		"_module": "api",
+		"_block":  "get-events",
+		"client":  r.RemoteAddr,
+	})
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		// This only works on ResponseWriters that support streaming
+		respond(500, rbody.FromError(ErrStreamingUnsupported), w)
+		return
+	}
+	ch := s.events.subscribe(newEventFilter(r))
+	defer s.events.unsubscribe(ch)
+
+	// Make this Server Sent Events compatible
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(200)
+	fmt.Fprint(w, ": stream opened\n\n")
+	flusher.Flush()
+
+	n := w.(http.CloseNotifier).CloseNotify()
+	keepAlive := time.NewTicker(EventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case se := <-ch:
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", se.id, se.event.Namespace, se.event.ToJSON())
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-n:
+			logger.Debug("client disconnecting")
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newEvent(b gomit.EventBody) gomit.Event {
+	return gomit.Event{Header: gomit.Header{Time: time.Now()}, Body: b}
+}
+
+// readSSE returns the field lines of the next event on the stream
+func readSSE(r *bufio.Reader) map[string]string {
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		So(err, ShouldBeNil)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		kv := strings.SplitN(line, ": ", 2)
+		fields[kv[0]] = kv[1]
+	}
+}
+
+func TestEvents(t *testing.T) {
+	Convey("NewStreamedEvent()", t, func() {
+		e := rbody.NewStreamedEvent(newEvent(&control_event.LoadPluginEvent{Name: "mock", Version: 2}))
+		So(e.Namespace, ShouldEqual, control_event.PluginLoaded)
+		So(e.PluginName, ShouldEqual, "mock")
+		So(e.PluginVersion, ShouldEqual, 2)
+
+		e = rbody.NewStreamedEvent(newEvent(&control_event.PluginSubscriptionEvent{PluginName: "file", PluginVersion: 3, TaskId: "1234"}))
+		So(e.PluginName, ShouldEqual, "file")
+		So(e.TaskID, ShouldEqual, "1234")
+
+		e = rbody.NewStreamedEvent(newEvent(&scheduler_event.MetricCollectionFailedEvent{TaskID: "1234", Errors: []error{errors.New("boom")}}))
+		So(e.TaskID, ShouldEqual, "1234")
+		So(e.ToJSON(), ShouldContainSubstring, `"Errors":["boom"]`)
+	})
+
+	Convey("eventFilter", t, func() {
+		e := &rbody.StreamedEvent{Namespace: control_event.PluginLoaded, PluginName: "mock"}
+		So((&eventFilter{}).matches(e), ShouldBeTrue)
+		So((&eventFilter{namespaces: []string{"Control"}}).matches(e), ShouldBeTrue)
+		So((&eventFilter{namespaces: []string{"Control."}}).matches(e), ShouldBeTrue)
+		So((&eventFilter{namespaces: []string{"Control.Plugin"}}).matches(e), ShouldBeFalse)
+		So((&eventFilter{namespaces: []string{"Scheduler", control_event.PluginLoaded}}).matches(e), ShouldBeTrue)
+		So((&eventFilter{plugins: []string{"file", "mock"}}).matches(e), ShouldBeTrue)
+		So((&eventFilter{plugins: []string{"file"}}).matches(e), ShouldBeFalse)
+		So((&eventFilter{namespaces: []string{"Control"}, tasks: []string{"1234"}}).matches(e), ShouldBeFalse)
+		So(queryValues([]string{"a,b", " c ", ""}), ShouldResemble, []string{"a", "b", "c"})
+	})
+
+	Convey("GET /v1/events", t, func() {
+		s, err := New(false, "", "")
+		So(err, ShouldBeNil)
+		So(s.Start("127.0.0.1:0"), ShouldBeNil)
+
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/v1/events?namespace=Control&plugin=mock", s.Port()))
+		So(err, ShouldBeNil)
+		defer resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, 200)
+		So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+		r := bufio.NewReader(resp.Body)
+		// the stream opens with a comment once the client is subscribed
+		line, err := r.ReadString('\n')
+		So(err, ShouldBeNil)
+		So(line, ShouldStartWith, ":")
+
+		s.HandleGomitEvent(newEvent(&scheduler_event.TaskStartedEvent{TaskID: "1234"}))
+		s.HandleGomitEvent(newEvent(&control_event.LoadPluginEvent{Name: "file", Version: 1}))
+		s.HandleGomitEvent(newEvent(&control_event.LoadPluginEvent{Name: "mock", Version: 1}))
+		s.HandleGomitEvent(newEvent(&control_event.UnloadPluginEvent{Name: "mock", Version: 1}))
+
+		// the ids number every event received, not only the ones streamed
+		fields := readSSE(r)
+		So(fields["id"], ShouldEqual, "3")
+		So(fields["event"], ShouldEqual, control_event.PluginLoaded)
+		e := &rbody.StreamedEvent{}
+		So(json.Unmarshal([]byte(fields["data"]), e), ShouldBeNil)
+		So(e.PluginName, ShouldEqual, "mock")
+
+		fields = readSSE(r)
+		So(fields["id"], ShouldEqual, "4")
+		So(fields["event"], ShouldEqual, control_event.PluginUnloaded)
+	})
+}
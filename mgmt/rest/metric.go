@@ -29,6 +29,7 @@ import (
 
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 )
 
 func (s *Server) getMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -52,6 +53,13 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 		return
 	}
 
+	// GET /v1/metrics/self cannot be routed on its own as it falls under the
+	// tree lookup URL
+	if namespace == "/self" {
+		s.getSelfMetrics(w, r, params)
+		return
+	}
+
 	ns := parseNamespace(namespace)
 
 	var (
@@ -128,6 +136,14 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 	respond(200, b, w)
 }
 
+// getSelfMetrics writes the metrics snapd keeps about itself in the
+// Prometheus text format
+func (s *Server) getSelfMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", telemetry.PrometheusContentType)
+	w.WriteHeader(200)
+	telemetry.WritePrometheus(w, telemetry.Default.Gather())
+}
+
 func respondWithMetrics(host string, mets []core.CatalogedMetric, w http.ResponseWriter) {
 	b := rbody.NewMetricsReturned()
 
@@ -22,6 +22,7 @@ package rest
 import (
 	"compress/gzip"
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,7 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 )
 
 const PluginAlreadyLoaded = "plugin is already loaded"
@@ -182,9 +184,52 @@ func (s *Server) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter
 		}
 		lp.LoadedPlugins = append(lp.LoadedPlugins, *catalogedPluginToLoaded(r.Host, pl))
 		respond(201, lp, w)
+	} else if mediaType == "application/json" {
+		s.loadRemotePlugin(w, r)
 	}
 }
 
+// loadRemotePlugin loads a plugin running as a service given by its URL
+func (s *Server) loadRemotePlugin(w http.ResponseWriter, r *http.Request) {
+	rr := &request.RemotePluginRequest{}
+	if err := json.NewDecoder(r.Body).Decode(rr); err != nil {
+		fields := map[string]interface{}{
+			"error": err.Error(),
+			"hint":  `The body of the request should be of the form '{"url": "tcp://...", "public_key": "-----BEGIN PUBLIC KEY-----...", "name": "...", "version": 1, "type": "collector"}'`,
+		}
+		restLogger.WithFields(fields).WithField("_block", "load-remote-plugin").Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	pt, err := core.ToPluginType(rr.Type)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	restLogger.Info("Loading remote plugin: ", rr.URL)
+	pl, serr := s.mm.LoadRemote(&core.RemotePlugin{
+		URL:       rr.URL,
+		PublicKey: []byte(rr.PublicKey),
+		Name:      rr.Name,
+		Version:   rr.Version,
+		Type:      pt,
+	})
+	if serr != nil {
+		restLogger.Error(serr)
+		rb := rbody.FromSnapError(serr)
+		ec := 500
+		if rb.ResponseBodyMessage() == PluginAlreadyLoaded {
+			ec = 409
+		}
+		respond(ec, rb, w)
+		return
+	}
+	lp := &rbody.PluginsLoaded{
+		LoadedPlugins: []rbody.LoadedPlugin{*catalogedPluginToLoaded(r.Host, pl)},
+	}
+	respond(201, lp, w)
+}
+
 func writeFile(filename string, b []byte) (string, error) {
 	// Create temporary directory
 	dir, err := ioutil.TempDir("", "")
@@ -293,7 +338,7 @@ func (s *Server) getPlugins(w http.ResponseWriter, r *http.Request, _ httprouter
 }
 
 func catalogedPluginToLoaded(host string, c core.CatalogedPlugin) *rbody.LoadedPlugin {
-	return &rbody.LoadedPlugin{
+	lp := &rbody.LoadedPlugin{
 		Name:            c.Name(),
 		Version:         c.Version(),
 		Type:            c.TypeName(),
@@ -302,6 +347,12 @@ func catalogedPluginToLoaded(host string, c core.CatalogedPlugin) *rbody.LoadedP
 		LoadedTimestamp: c.LoadedTimestamp().Unix(),
 		Href:            catalogedPluginURI(host, c),
 	}
+	if restarts := c.Restarts(); restarts.Count > 0 {
+		lp.Restarts = restarts.Count
+		lp.LastRestartTimestamp = restarts.LastRestart.Unix()
+		lp.LastRestartReason = restarts.LastReason
+	}
+	return lp
 }
 
 func (s *Server) getPluginsByType(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -388,10 +439,32 @@ func (s *Server) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.
 			LoadedTimestamp: plugin.LoadedTimestamp().Unix(),
 			Href:            catalogedPluginURI(r.Host, plugin),
 		}
+		if cs, err := s.mm.PluginCacheStats(plType, plName, int(plVersion)); err == nil {
+			pluginRet.Cache = cacheStatsToBody(cs)
+		}
 		respond(200, pluginRet, w)
 	}
 }
 
+func cacheStatsToBody(cs core.CacheStats) *rbody.PluginCacheStats {
+	c := &rbody.PluginCacheStats{
+		Hits:      cs.Hits,
+		Misses:    cs.Misses,
+		Entries:   cs.Entries,
+		Evictions: cs.Evictions,
+		Metrics:   make([]rbody.MetricCacheStats, len(cs.Metrics)),
+	}
+	for i, m := range cs.Metrics {
+		c.Metrics[i] = rbody.MetricCacheStats{
+			Namespace: m.Namespace,
+			Version:   m.Version,
+			Hits:      m.Hits,
+			Misses:    m.Misses,
+		}
+	}
+	return c
+}
+
 func catalogedPluginURI(host string, c core.CatalogedPlugin) string {
 	return fmt.Sprintf("%s://%s/v1/plugins/%s/%s/%d", protocolPrefix, host, c.TypeName(), c.Name(), c.Version())
 }
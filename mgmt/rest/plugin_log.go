@@ -0,0 +1,160 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// defaultPluginLogLines is the number of lines returned for a plugin when the
+// request does not give one
+const defaultPluginLogLines = 100
+
+// ErrInvalidLines is returned for a lines parameter which is not a number
+var ErrInvalidLines = errors.New("invalid lines")
+
+func newPluginLogLines(lines []core.PluginLogLine) []rbody.PluginLogLine {
+	rl := make([]rbody.PluginLogLine, len(lines))
+	for i, l := range lines {
+		rl[i] = newPluginLogLine(l)
+	}
+	return rl
+}
+
+func newPluginLogLine(l core.PluginLogLine) rbody.PluginLogLine {
+	return rbody.PluginLogLine{
+		Timestamp: l.Time,
+		Stream:    l.Stream,
+		Line:      l.Line,
+	}
+}
+
+func (s *Server) getPluginLogs(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	plName := p.ByName("name")
+	plType := p.ByName("type")
+	plVersion, iErr := strconv.ParseInt(p.ByName("version"), 10, 0)
+	f := map[string]interface{}{
+		"plugin-name":    plName,
+		"plugin-version": plVersion,
+		"plugin-type":    plType,
+	}
+
+	if iErr != nil {
+		se := serror.New(errors.New("invalid version"))
+		se.SetFields(f)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+
+	n := defaultPluginLogLines
+	if v := r.FormValue("lines"); v != "" {
+		var err error
+		n, err = strconv.Atoi(v)
+		if err != nil {
+			respond(400, rbody.FromSnapError(serror.New(ErrInvalidLines, f)), w)
+			return
+		}
+	}
+
+	follow, _ := strconv.ParseBool(r.FormValue("follow"))
+	if !follow {
+		lines, err := s.mm.PluginLogs(plType, plName, int(plVersion), n)
+		if err != nil {
+			respond(404, rbody.FromSnapError(serror.New(err, f)), w)
+			return
+		}
+		respond(200, &rbody.PluginLogs{
+			Name:    plName,
+			Version: int(plVersion),
+			Type:    plType,
+			Lines:   newPluginLogLines(lines),
+		}, w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		// This only works on ResponseWriters that support streaming
+		respond(500, rbody.FromError(ErrStreamingUnsupported), w)
+		return
+	}
+	lines, ch, stop, err := s.mm.FollowPluginLogs(plType, plName, int(plVersion), n)
+	if err != nil {
+		respond(404, rbody.FromSnapError(serror.New(err, f)), w)
+		return
+	}
+	defer stop()
+
+	logger := log.WithFields(log.Fields{
+		"_module": "api",
+		"_block":  "get-plugin-logs",
+		"client":  r.RemoteAddr,
+	})
+
+	// Make this Server Sent Events compatible
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(200)
+	fmt.Fprint(w, ": stream opened\n\n")
+	for _, l := range lines {
+		writePluginLogLine(w, l)
+	}
+	flusher.Flush()
+
+	closed := w.(http.CloseNotifier).CloseNotify()
+	keepAlive := time.NewTicker(EventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case l, ok := <-ch:
+			if !ok {
+				logger.Debug("plugin unloaded")
+				return
+			}
+			writePluginLogLine(w, l)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-closed:
+			logger.Debug("client disconnecting")
+			return
+		}
+	}
+}
+
+func writePluginLogLine(w http.ResponseWriter, l core.PluginLogLine) {
+	b, _ := json.Marshal(newPluginLogLine(l))
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", l.Stream, b)
+}
@@ -0,0 +1,204 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// Permission is an action on the REST API which a role may be granted
+type Permission string
+
+const (
+	// PermRead allows reading plugins, metrics, tasks, webhooks and tribe membership
+	PermRead Permission = "read"
+	// PermTasksControl allows starting, stopping and enabling tasks
+	PermTasksControl Permission = "tasks:control"
+	// PermTasksWrite allows creating, updating, testing and removing tasks
+	PermTasksWrite Permission = "tasks:write"
+	// PermPluginsWrite allows loading and unloading plugins and changing their config
+	PermPluginsWrite Permission = "plugins:write"
+	// PermTribeWrite allows changing tribe agreements
+	PermTribeWrite Permission = "tribe:write"
+	// PermWebhooksWrite allows adding and removing webhooks
+	PermWebhooksWrite Permission = "webhooks:write"
+)
+
+var (
+	// ErrForbidden - The error message returned to a client which lacks the permission for a route
+	ErrForbidden = errors.New("Permission denied")
+	// ErrRBACRequiresAuth - The error message for roles configured without authentication
+	ErrRBACRequiresAuth = errors.New("Role-based access control requires authentication to be enabled")
+
+	allPermissions = []Permission{PermRead, PermTasksControl, PermTasksWrite, PermPluginsWrite, PermTribeWrite, PermWebhooksWrite}
+
+	// builtinRoles are available without being declared in the config
+	builtinRoles = map[string][]Permission{
+		"reader":   {PermRead},
+		"operator": {PermRead, PermTasksControl},
+		"admin":    allPermissions,
+	}
+)
+
+// RBACConfig assigns roles to the principals authenticated by the REST API.
+// It is read from the "restapi" section of the snapd config file.
+type RBACConfig struct {
+	// Roles maps a role name to its permissions.  The roles reader, operator
+	// and admin are built in and may be redefined.
+	Roles map[string][]Permission `json:"roles"`
+	// Principals maps the name of an authenticated principal (user, token
+	// name or certificate common name) to its roles
+	Principals map[string][]string `json:"principals"`
+	// DefaultRoles are given to principals which are not listed
+	DefaultRoles []string `json:"default_roles"`
+}
+
+// rbac holds the permissions granted to each principal
+type rbac struct {
+	granted  map[string]map[Permission]bool
+	defaults map[Permission]bool
+}
+
+func newRBAC(cfg *RBACConfig) (*rbac, error) {
+	roles := map[string][]Permission{}
+	for name, perms := range builtinRoles {
+		roles[name] = perms
+	}
+	for name, perms := range cfg.Roles {
+		for _, p := range perms {
+			if !isPermission(p) {
+				return nil, fmt.Errorf("role %s has unknown permission %q", name, p)
+			}
+		}
+		roles[name] = perms
+	}
+	grant := func(principal string, names []string) (map[Permission]bool, error) {
+		g := map[Permission]bool{}
+		for _, n := range names {
+			perms, ok := roles[n]
+			if !ok {
+				return nil, fmt.Errorf("%s has unknown role %q", principal, n)
+			}
+			for _, p := range perms {
+				g[p] = true
+			}
+		}
+		return g, nil
+	}
+	r := &rbac{granted: map[string]map[Permission]bool{}}
+	var err error
+	if r.defaults, err = grant("default_roles", cfg.DefaultRoles); err != nil {
+		return nil, err
+	}
+	for principal, names := range cfg.Principals {
+		if r.granted[principal], err = grant("principal "+principal, names); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *rbac) allowed(principal string, p Permission) bool {
+	if g, ok := r.granted[principal]; ok {
+		return g[p]
+	}
+	return r.defaults[p]
+}
+
+// permissions returns the sorted permissions granted to principal
+func (r *rbac) permissions(principal string) []string {
+	g, ok := r.granted[principal]
+	if !ok {
+		g = r.defaults
+	}
+	perms := make([]string, 0, len(g))
+	for p := range g {
+		perms = append(perms, string(p))
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+func isPermission(p Permission) bool {
+	for _, a := range allPermissions {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableRBAC limits each route to the principals granted its permission.  It
+// must be called after EnableAuth and before Start.
+func (s *Server) EnableRBAC(cfg *RBACConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if s.auth == nil {
+		return ErrRBACRequiresAuth
+	}
+	r, err := newRBAC(cfg)
+	if err != nil {
+		return err
+	}
+	s.rbac = r
+	restLogger.WithFields(log.Fields{
+		"_block":     "enable-rbac",
+		"principals": len(cfg.Principals),
+	}).Info("REST API role-based access control enabled")
+	return nil
+}
+
+// authorize wraps the handler of a route so it is only called for principals
+// granted p.  It returns the handler unchanged when roles are not configured.
+func (s *Server) authorize(p Permission, h httprouter.Handle) httprouter.Handle {
+	if s.rbac == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		principal := Principal(r)
+		if !s.rbac.allowed(principal, p) {
+			restLogger.WithFields(log.Fields{
+				"_block":     "authorize",
+				"principal":  principal,
+				"permission": p,
+				"method":     r.Method,
+				"url":        r.URL.Path,
+			}).Warn("denied request")
+			respond(403, rbody.FromSnapError(serror.New(ErrForbidden, map[string]interface{}{
+				"principal":  principal,
+				"permission": string(p),
+				"granted":    strings.Join(s.rbac.permissions(principal), ","),
+			})), w)
+			return
+		}
+		h(w, r, ps)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/scheduler"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func startRBACAPI(cfg *RBACConfig) string {
+	s, err := New(false, "", "")
+	So(err, ShouldBeNil)
+	So(s.EnableAuth(&AuthConfig{Tokens: map[string]string{
+		"viewer": "viewer-token",
+		"ops":    "ops-token",
+		"root":   "root-token",
+		"guest":  "guest-token",
+	}}), ShouldBeNil)
+	So(s.EnableRBAC(cfg), ShouldBeNil)
+	c := control.New()
+	c.Start()
+	sch := scheduler.New()
+	sch.SetMetricManager(c)
+	sch.Start()
+	s.BindMetricManager(c)
+	s.BindTaskManager(sch)
+	s.BindConfigManager(c.Config)
+	So(s.Start("127.0.0.1:0"), ShouldBeNil)
+	return fmt.Sprintf("http://127.0.0.1:%d", s.Port())
+}
+
+func call(method, url, token string) (int, *rbody.APIResponse) {
+	req, err := http.NewRequest(method, url, strings.NewReader("{}"))
+	So(err, ShouldBeNil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	So(err, ShouldBeNil)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	So(err, ShouldBeNil)
+	ar := &rbody.APIResponse{}
+	So(json.Unmarshal(b, ar), ShouldBeNil)
+	return resp.StatusCode, ar
+}
+
+func TestRBAC(t *testing.T) {
+	Convey("newRBAC()", t, func() {
+		Convey("rejects unknown permissions", func() {
+			_, err := newRBAC(&RBACConfig{Roles: map[string][]Permission{"auditor": {"read", "delete"}}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `"delete"`)
+		})
+
+		Convey("rejects unknown roles", func() {
+			_, err := newRBAC(&RBACConfig{Principals: map[string][]string{"jane": {"root"}}})
+			So(err, ShouldNotBeNil)
+			_, err = newRBAC(&RBACConfig{DefaultRoles: []string{"root"}})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("grants the permissions of every role", func() {
+			r, err := newRBAC(&RBACConfig{
+				Roles:      map[string][]Permission{"tribe": {PermTribeWrite}},
+				Principals: map[string][]string{"jane": {"operator", "tribe"}},
+			})
+			So(err, ShouldBeNil)
+			So(r.permissions("jane"), ShouldResemble, []string{"read", "tasks:control", "tribe:write"})
+			So(r.allowed("jane", PermPluginsWrite), ShouldBeFalse)
+			So(r.permissions("john"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("EnableRBAC()", t, func() {
+		Convey("requires authentication", func() {
+			s, err := New(false, "", "")
+			So(err, ShouldBeNil)
+			So(s.EnableRBAC(&RBACConfig{}), ShouldEqual, ErrRBACRequiresAuth)
+			So(s.EnableRBAC(nil), ShouldBeNil)
+		})
+	})
+
+	Convey("Routes check the permissions of the principal", t, func() {
+		uri := startRBACAPI(&RBACConfig{
+			Principals: map[string][]string{
+				"viewer": {"reader"},
+				"ops":    {"operator"},
+				"root":   {"admin"},
+			},
+		})
+
+		Convey("readers may read", func() {
+			code, _ := call("GET", uri+"/v1/plugins", "viewer-token")
+			So(code, ShouldEqual, 200)
+			code, _ = call("GET", uri+"/v1/tasks", "viewer-token")
+			So(code, ShouldEqual, 200)
+		})
+
+		Convey("readers may not load plugins or control tasks", func() {
+			code, resp := call("POST", uri+"/v1/plugins", "viewer-token")
+			So(code, ShouldEqual, 403)
+			So(resp.Meta.Type, ShouldEqual, rbody.ErrorType)
+			e := resp.Body.(*rbody.Error)
+			So(e.ErrorMessage, ShouldEqual, ErrForbidden.Error())
+			So(e.Fields["principal"], ShouldEqual, "viewer")
+			So(e.Fields["permission"], ShouldEqual, string(PermPluginsWrite))
+			So(e.Fields["granted"], ShouldEqual, "read")
+
+			code, _ = call("PUT", uri+"/v1/tasks/1234/start", "viewer-token")
+			So(code, ShouldEqual, 403)
+		})
+
+		Convey("operators may control but not create tasks", func() {
+			code, _ := call("PUT", uri+"/v1/tasks/1234/start", "ops-token")
+			So(code, ShouldNotEqual, 403)
+			code, _ = call("POST", uri+"/v1/tasks", "ops-token")
+			So(code, ShouldEqual, 403)
+		})
+
+		Convey("admins may do anything", func() {
+			code, _ := call("DELETE", uri+"/v1/plugins/collector/mock/1", "root-token")
+			So(code, ShouldNotEqual, 403)
+			code, _ = call("POST", uri+"/v1/tasks", "root-token")
+			So(code, ShouldNotEqual, 403)
+		})
+
+		Convey("principals without roles may do nothing", func() {
+			code, _ := call("GET", uri+"/v1/plugins", "guest-token")
+			So(code, ShouldEqual, 403)
+		})
+	})
+
+	Convey("Default roles apply to principals which are not listed", t, func() {
+		uri := startRBACAPI(&RBACConfig{DefaultRoles: []string{"reader"}})
+		code, _ := call("GET", uri+"/v1/plugins", "guest-token")
+		So(code, ShouldEqual, 200)
+		code, _ = call("POST", uri+"/v1/plugins", "guest-token")
+		So(code, ShouldEqual, 403)
+	})
+}
@@ -79,6 +79,8 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &PluginsLoaded{})
 	case PluginUnloadedType:
 		return unmarshalAndHandleError(b, &PluginUnloaded{})
+	case PluginLogsType:
+		return unmarshalAndHandleError(b, &PluginLogs{})
 	case ScheduledTaskListReturnedType:
 		return unmarshalAndHandleError(b, &ScheduledTaskListReturned{})
 	case ScheduledTaskReturnedType:
@@ -95,6 +97,16 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &ScheduledTaskRemoved{})
 	case ScheduledTaskEnabledType:
 		return unmarshalAndHandleError(b, &ScheduledTaskEnabled{})
+	case ScheduledTaskUpdatedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskUpdated{})
+	case TaskValidatedType:
+		return unmarshalAndHandleError(b, &TaskValidated{})
+	case TaskTestedType:
+		return unmarshalAndHandleError(b, &TaskTested{})
+	case TaskSpoolReturnedType:
+		return unmarshalAndHandleError(b, &TaskSpoolReturned{})
+	case TaskSpoolPurgedType:
+		return unmarshalAndHandleError(b, &TaskSpoolPurged{})
 	case MetricReturnedType:
 		return unmarshalAndHandleError(b, &MetricReturned{})
 	case MetricsReturnedType:
@@ -123,6 +135,16 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &SetPluginConfigItem{*cdata.NewNode()})
 	case DeletePluginConfigItemType:
 		return unmarshalAndHandleError(b, &DeletePluginConfigItem{*cdata.NewNode()})
+	case WebhookListType:
+		return unmarshalAndHandleError(b, &WebhookList{})
+	case WebhookType:
+		return unmarshalAndHandleError(b, &Webhook{})
+	case AddWebhookType:
+		return unmarshalAndHandleError(b, &AddWebhook{})
+	case WebhookRemovedType:
+		return unmarshalAndHandleError(b, &WebhookRemoved{})
+	case WebhookDeliveryListType:
+		return unmarshalAndHandleError(b, &WebhookDeliveryList{})
 	case ErrorType:
 		return unmarshalAndHandleError(b, &Error{})
 	default:
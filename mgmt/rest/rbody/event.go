@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+// StreamedEvent is an event emitted by control or the scheduler as streamed
+// by GET /v1/events
+type StreamedEvent struct {
+	// Namespace of the event (e.g. Control.PluginLoaded)
+	Namespace     string    `json:"namespace"`
+	Timestamp     time.Time `json:"timestamp"`
+	PluginName    string    `json:"plugin_name,omitempty"`
+	PluginVersion int       `json:"plugin_version,omitempty"`
+	TaskID        string    `json:"task_id,omitempty"`
+	// The fields of the event itself
+	Event interface{} `json:"event"`
+}
+
+// InNamespace returns whether the event is in ns.  A namespace holds itself
+// and every namespace below it so Control holds Control.PluginLoaded.
+func (s *StreamedEvent) InNamespace(ns string) bool {
+	return s.Namespace == ns || strings.HasPrefix(s.Namespace, strings.TrimSuffix(ns, ".")+".")
+}
+
+func (s *StreamedEvent) ToJSON() string {
+	j, _ := json.Marshal(s)
+	return string(j)
+}
+
+// NewStreamedEvent returns the streamed form of an event emitted by control
+// or the scheduler
+func NewStreamedEvent(e gomit.Event) *StreamedEvent {
+	se := &StreamedEvent{
+		Namespace: e.Namespace(),
+		Timestamp: e.Header.Time,
+		Event:     e.Body,
+	}
+	switch v := e.Body.(type) {
+	case *control_event.LoadPluginEvent:
+		se.PluginName, se.PluginVersion = v.Name, v.Version
+	case *control_event.UnloadPluginEvent:
+		se.PluginName, se.PluginVersion = v.Name, v.Version
+	case *control_event.DeadAvailablePluginEvent:
+		se.PluginName, se.PluginVersion = v.Name, v.Version
+	case *control_event.SwapPluginsEvent:
+		se.PluginName, se.PluginVersion = v.LoadedPluginName, v.LoadedPluginVersion
+	case *control_event.HealthCheckFailedEvent:
+		se.PluginName, se.PluginVersion = v.Name, v.Version
+	case *control_event.PluginSubscriptionEvent:
+		se.PluginName, se.PluginVersion, se.TaskID = v.PluginName, v.PluginVersion, v.TaskId
+	case *control_event.PluginUnsubscriptionEvent:
+		se.PluginName, se.PluginVersion, se.TaskID = v.PluginName, v.PluginVersion, v.TaskId
+	case *control_event.PluginLimitReachedEvent:
+		se.PluginName, se.PluginVersion = v.Name, v.Version
+	case *control_event.MovePluginSubscriptionEvent:
+		se.PluginName, se.PluginVersion, se.TaskID = v.PluginName, v.NewVersion, v.TaskId
+	case *scheduler_event.TaskCreatedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskDeletedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskStartedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskStoppedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskDisabledEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskEndedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.TaskUpdatedEvent:
+		se.TaskID = v.TaskID
+	case *scheduler_event.MetricCollectedEvent:
+		se.TaskID = v.TaskID
+		// The metrics themselves are streamed by GET /v1/tasks/:id/watch
+		se.Event = struct {
+			TaskID      string
+			MetricCount int
+		}{v.TaskID, len(v.Metrics)}
+	case *scheduler_event.MetricCollectionFailedEvent:
+		se.TaskID = v.TaskID
+		errs := make([]string, len(v.Errors))
+		for i, err := range v.Errors {
+			errs[i] = err.Error()
+		}
+		se.Event = struct {
+			TaskID string
+			Errors []string
+		}{v.TaskID, errs}
+	}
+	return se
+}
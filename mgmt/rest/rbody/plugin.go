@@ -22,6 +22,7 @@ package rbody
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 const (
@@ -29,6 +30,7 @@ const (
 	PluginUnloadedType = "plugin_unloaded"
 	PluginListType     = "plugin_list_returned"
 	PluginReturnedType = "plugin_returned"
+	PluginLogsType     = "plugin_logs_returned"
 )
 
 // Successful response to the loading of a plugins
@@ -88,6 +90,29 @@ func (p *PluginReturned) ResponseBodyType() string {
 	return PluginReturnedType
 }
 
+// PluginLogs is the output of the running instances of a plugin
+type PluginLogs struct {
+	Name    string          `json:"name"`
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Lines   []PluginLogLine `json:"lines"`
+}
+
+func (p *PluginLogs) ResponseBodyMessage() string {
+	return fmt.Sprintf("Plugin logs returned (%sv%d)", p.Name, p.Version)
+}
+
+func (p *PluginLogs) ResponseBodyType() string {
+	return PluginLogsType
+}
+
+// PluginLogLine is a line a running plugin wrote to its stdout or stderr
+type PluginLogLine struct {
+	Timestamp time.Time `json:"timestamp"`
+	Stream    string    `json:"stream"`
+	Line      string    `json:"line"`
+}
+
 type LoadedPlugin struct {
 	Name            string `json:"name"`
 	Version         int    `json:"version"`
@@ -95,7 +120,30 @@ type LoadedPlugin struct {
 	Signed          bool   `json:"signed"`
 	Status          string `json:"status"`
 	LoadedTimestamp int64  `json:"loaded_timestamp"`
-	Href            string `json:"href"`
+	// Restarts of instances of the plugin which failed their health checks
+	Restarts             int    `json:"restarts"`
+	LastRestartTimestamp int64  `json:"last_restart_timestamp,omitempty"`
+	LastRestartReason    string `json:"last_restart_reason,omitempty"`
+	Href                 string `json:"href"`
+	// Statistics of the metric cache, only returned for a single plugin
+	Cache *PluginCacheStats `json:"cache,omitempty"`
+}
+
+// PluginCacheStats are the hits and misses of the metric cache of a plugin
+type PluginCacheStats struct {
+	Hits      uint64             `json:"hits"`
+	Misses    uint64             `json:"misses"`
+	Entries   int                `json:"entries"`
+	Evictions uint64             `json:"evictions"`
+	Metrics   []MetricCacheStats `json:"metrics"`
+}
+
+// MetricCacheStats are the hits and misses of the metric cache for one metric
+type MetricCacheStats struct {
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
 }
 
 type AvailablePlugin struct {
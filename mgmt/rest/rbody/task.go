@@ -40,6 +40,11 @@ const (
 	ScheduledTaskRemovedType       = "scheduled_task_removed"
 	ScheduledTaskWatchingEndedType = "schedule_task_watch_ended"
 	ScheduledTaskEnabledType       = "scheduled_task_enabled"
+	ScheduledTaskUpdatedType       = "scheduled_task_updated"
+	TaskSpoolReturnedType          = "task_spool_returned"
+	TaskValidatedType              = "task_validated"
+	TaskTestedType                 = "task_tested"
+	TaskSpoolPurgedType            = "task_spool_purged"
 
 	// Event types for task watcher streaming
 	TaskWatchStreamOpen   = "stream-open"
@@ -105,6 +110,7 @@ func AddSchedulerTaskFromTask(t core.Task) *AddScheduledTask {
 		HitCount:           int(t.HitCount()),
 		MissCount:          int(t.MissedCount()),
 		FailedCount:        int(t.FailedCount()),
+		TimedOutCount:      int(t.TimedOutCount()),
 		LastFailureMessage: t.LastFailureMessage(),
 		State:              t.State().String(),
 		Workflow:           t.WMap(),
@@ -127,6 +133,7 @@ type ScheduledTask struct {
 	HitCount           int               `json:"hit_count,omitempty"`
 	MissCount          int               `json:"miss_count,omitempty"`
 	FailedCount        int               `json:"failed_count,omitempty"`
+	TimedOutCount      int               `json:"timed_out_count,omitempty"`
 	LastFailureMessage string            `json:"last_failure_message,omitempty"`
 	State              string            `json:"task_state"`
 	Href               string            `json:"href"`
@@ -154,6 +161,7 @@ func SchedulerTaskFromTask(t core.Task) *ScheduledTask {
 		HitCount:           int(t.HitCount()),
 		MissCount:          int(t.MissedCount()),
 		FailedCount:        int(t.FailedCount()),
+		TimedOutCount:      int(t.TimedOutCount()),
 		LastFailureMessage: t.LastFailureMessage(),
 		State:              t.State().String(),
 	}
@@ -214,6 +222,159 @@ func (s *ScheduledTaskEnabled) ResponseBodyType() string {
 	return ScheduledTaskEnabledType
 }
 
+type ScheduledTaskUpdated struct {
+	AddScheduledTask
+}
+
+func (s *ScheduledTaskUpdated) ResponseBodyMessage() string {
+	return fmt.Sprintf("Scheduled task (%s) updated", s.AddScheduledTask.ID)
+}
+
+func (s *ScheduledTaskUpdated) ResponseBodyType() string {
+	return ScheduledTaskUpdatedType
+}
+
+// TaskValidated is the result of checking a task without creating it
+type TaskValidated struct {
+	Valid  bool                  `json:"valid"`
+	Errors []TaskValidationError `json:"errors"`
+}
+
+// TaskValidationError is a problem with a task and, when known, the path to
+// it in the request (e.g. collect.process[0].publish[1])
+type TaskValidationError struct {
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (t *TaskValidated) ResponseBodyMessage() string {
+	if t.Valid {
+		return "Task is valid"
+	}
+	return fmt.Sprintf("Task is not valid (%d errors)", len(t.Errors))
+}
+
+func (t *TaskValidated) ResponseBodyType() string {
+	return TaskValidatedType
+}
+
+// TaskTested holds what each node of a workflow output when it was run once
+// without creating a task
+type TaskTested struct {
+	Nodes []TaskTestNode `json:"nodes"`
+}
+
+// TaskTestNode is the output of a node of a tested workflow.  For a publisher
+// the metrics are the ones it would have been sent.
+type TaskTestNode struct {
+	Path          string          `json:"path"`
+	Type          string          `json:"type"`
+	PluginName    string          `json:"plugin_name,omitempty"`
+	PluginVersion int             `json:"plugin_version,omitempty"`
+	ContentType   string          `json:"content_type"`
+	Metrics       StreamedMetrics `json:"metrics"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+func (t *TaskTested) ResponseBodyMessage() string {
+	for _, n := range t.Nodes {
+		if len(n.Errors) > 0 {
+			return "Task test failed"
+		}
+	}
+	return "Task test succeeded"
+}
+
+func (t *TaskTested) ResponseBodyType() string {
+	return TaskTestedType
+}
+
+func TaskTestedFromResult(r *core.TaskTestResult) *TaskTested {
+	t := &TaskTested{Nodes: make([]TaskTestNode, len(r.Nodes))}
+	for i, n := range r.Nodes {
+		tn := TaskTestNode{
+			Path:          n.Path,
+			Type:          n.Type,
+			PluginName:    n.PluginName,
+			PluginVersion: n.PluginVersion,
+			ContentType:   n.ContentType,
+			Metrics:       make(StreamedMetrics, len(n.Metrics)),
+		}
+		for j, m := range n.Metrics {
+			tn.Metrics[j] = StreamedMetric{
+				Namespace: core.JoinNamespace(m.Namespace()),
+				Data:      m.Data(),
+				Source:    m.Source(),
+				Timestamp: m.Timestamp(),
+			}
+		}
+		for _, e := range n.Errors {
+			tn.Errors = append(tn.Errors, e.Error())
+		}
+		t.Nodes[i] = tn
+	}
+	return t
+}
+
+// TaskSpoolReturned lists the batches a task could not publish
+type TaskSpoolReturned struct {
+	ID      string         `json:"id"`
+	Batches []SpooledBatch `json:"batches"`
+}
+
+// SpooledBatch describes a batch in the publish spool without its content
+type SpooledBatch struct {
+	ID                string `json:"id"`
+	PluginName        string `json:"plugin_name"`
+	PluginVersion     int    `json:"plugin_version"`
+	ContentType       string `json:"content_type"`
+	Size              int    `json:"size"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+	Attempts          int    `json:"attempts"`
+	LastError         string `json:"last_error,omitempty"`
+}
+
+func (s *TaskSpoolReturned) ResponseBodyMessage() string {
+	return fmt.Sprintf("Publish spool of task (%s) returned", s.ID)
+}
+
+func (s *TaskSpoolReturned) ResponseBodyType() string {
+	return TaskSpoolReturnedType
+}
+
+func TaskSpoolFromBatches(id string, batches []*core.SpooledBatch) *TaskSpoolReturned {
+	t := &TaskSpoolReturned{
+		ID:      id,
+		Batches: make([]SpooledBatch, len(batches)),
+	}
+	for i, b := range batches {
+		t.Batches[i] = SpooledBatch{
+			ID:                b.ID,
+			PluginName:        b.PluginName,
+			PluginVersion:     b.PluginVersion,
+			ContentType:       b.ContentType,
+			Size:              len(b.Content),
+			CreationTimestamp: b.CreationTime.Unix(),
+			Attempts:          b.Attempts,
+			LastError:         b.LastError,
+		}
+	}
+	return t
+}
+
+type TaskSpoolPurged struct {
+	ID     string `json:"id"`
+	Purged int    `json:"purged"`
+}
+
+func (s *TaskSpoolPurged) ResponseBodyMessage() string {
+	return fmt.Sprintf("Publish spool of task (%s) purged", s.ID)
+}
+
+func (s *TaskSpoolPurged) ResponseBodyType() string {
+	return TaskSpoolPurgedType
+}
+
 func assertSchedule(s schedule.Schedule, t *AddScheduledTask) {
 	switch v := s.(type) {
 	case *schedule.SimpleSchedule:
@@ -222,6 +383,15 @@ func assertSchedule(s schedule.Schedule, t *AddScheduledTask) {
 			Interval: v.Interval.String(),
 		}
 		return
+	case *schedule.CronSchedule:
+		t.Schedule = &request.Schedule{
+			Type:     "cron",
+			Interval: v.Entry,
+		}
+		if v.Location != nil {
+			t.Schedule.TimeZone = v.Location.String()
+		}
+		return
 	}
 	t.Schedule = &request.Schedule{}
 }
@@ -0,0 +1,116 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	WebhookListType         = "webhook_list_returned"
+	WebhookType             = "webhook_returned"
+	AddWebhookType          = "webhook_added"
+	WebhookRemovedType      = "webhook_removed"
+	WebhookDeliveryListType = "webhook_deliveries_returned"
+)
+
+// Webhook is a target registered for the events emitted by snapd.  Its secret
+// is never returned.
+type Webhook struct {
+	ID                string   `json:"id"`
+	URL               string   `json:"url"`
+	Namespaces        []string `json:"namespaces"`
+	CreationTimestamp int64    `json:"creation_timestamp"`
+	Href              string   `json:"href"`
+}
+
+func (w *Webhook) CreationTime() time.Time {
+	return time.Unix(w.CreationTimestamp, 0)
+}
+
+func (w *Webhook) ResponseBodyMessage() string {
+	return "Webhook returned"
+}
+
+func (w *Webhook) ResponseBodyType() string {
+	return WebhookType
+}
+
+type AddWebhook Webhook
+
+func (a *AddWebhook) ResponseBodyMessage() string {
+	return fmt.Sprintf("Webhook added (%s)", a.ID)
+}
+
+func (a *AddWebhook) ResponseBodyType() string {
+	return AddWebhookType
+}
+
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+func (w *WebhookList) ResponseBodyMessage() string {
+	return "Webhooks returned"
+}
+
+func (w *WebhookList) ResponseBodyType() string {
+	return WebhookListType
+}
+
+type WebhookRemoved struct {
+	ID string `json:"id"`
+}
+
+func (w *WebhookRemoved) ResponseBodyMessage() string {
+	return fmt.Sprintf("Webhook removed (%s)", w.ID)
+}
+
+func (w *WebhookRemoved) ResponseBodyType() string {
+	return WebhookRemovedType
+}
+
+// WebhookDelivery is the delivery of one event to a webhook
+type WebhookDelivery struct {
+	ID                   string `json:"id"`
+	Event                string `json:"event"`
+	EventTimestamp       int64  `json:"event_timestamp"`
+	Attempts             int    `json:"attempts"`
+	StatusCode           int    `json:"status_code,omitempty"`
+	Error                string `json:"error,omitempty"`
+	Delivered            bool   `json:"delivered"`
+	LastAttemptTimestamp int64  `json:"last_attempt_timestamp,omitempty"`
+}
+
+// WebhookDeliveryList holds the most recent deliveries to a webhook, oldest
+// first
+type WebhookDeliveryList struct {
+	ID         string            `json:"id"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+func (w *WebhookDeliveryList) ResponseBodyMessage() string {
+	return fmt.Sprintf("Deliveries returned for webhook (%s)", w.ID)
+}
+
+func (w *WebhookDeliveryList) ResponseBodyType() string {
+	return WebhookDeliveryListType
+}
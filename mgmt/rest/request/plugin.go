@@ -0,0 +1,31 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package request
+
+// RemotePluginRequest loads a plugin running as a service which snapd
+// connects to at URL.  PublicKey is the PEM encoded RSA public key of the
+// plugin, which is required.
+type RemotePluginRequest struct {
+	URL       string `json:"url"`
+	PublicKey string `json:"public_key"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+	Type      string `json:"type"`
+}
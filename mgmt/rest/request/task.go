@@ -31,9 +31,19 @@ type TaskCreationRequest struct {
 	Start    bool              `json:"start"`
 }
 
+// TaskUpdateRequest holds the changes to an existing task.  Fields left
+// empty are not changed.
+type TaskUpdateRequest struct {
+	Name     string            `json:"name,omitempty"`
+	Deadline string            `json:"deadline,omitempty"`
+	Workflow *wmap.WorkflowMap `json:"workflow,omitempty"`
+	Schedule *Schedule         `json:"schedule,omitempty"`
+}
+
 type Schedule struct {
 	Type           string `json:"type,omitempty"`
 	Interval       string `json:"interval,omitempty"`
 	StartTimestamp *int64 `json:"start_timestamp,omitempty"`
 	StopTimestamp  *int64 `json:"stop_timestamp,omitempty"`
+	TimeZone       string `json:"timezone,omitempty"`
 }
@@ -0,0 +1,28 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package request
+
+// WebhookCreationRequest registers a webhook for the events in Namespaces, or
+// every event when there are none
+type WebhookCreationRequest struct {
+	URL        string   `json:"url"`
+	Namespaces []string `json:"namespaces"`
+	Secret     string   `json:"secret"`
+}
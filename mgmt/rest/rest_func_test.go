@@ -44,6 +44,7 @@ import (
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 	"github.com/intelsdi-x/snap/scheduler"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	. "github.com/smartystreets/goconvey/convey"
@@ -203,6 +204,54 @@ func stopTask(id string, port int) *rbody.APIResponse {
 	return getAPIResponse(resp)
 }
 
+func testTask(sample string, port int) *rbody.APIResponse {
+	jsonP, err := ioutil.ReadFile("./wmap_sample/" + sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wf, err := wmap.FromJson(jsonP)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/test", port)
+	b, err := json.Marshal(request.TaskCreationRequest{Workflow: wf})
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp, err := http.Post(uri, "application/json", bytes.NewReader(b))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
+func validateTask(sample, interval string, port int) *rbody.APIResponse {
+	jsonP, err := ioutil.ReadFile("./wmap_sample/" + sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wf, err := wmap.FromJson(jsonP)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/validate", port)
+	t := request.TaskCreationRequest{
+		Schedule: request.Schedule{Type: "simple", Interval: interval},
+		Workflow: wf,
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp, err := http.Post(uri, "application/json", bytes.NewReader(b))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
 func removeTask(id string, port int) *rbody.APIResponse {
 	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/%s", port, id)
 	client := &http.Client{}
@@ -272,6 +321,50 @@ func enableTask(id string, port int) *rbody.APIResponse {
 	return getAPIResponse(resp)
 }
 
+func updateTask(id string, tr request.TaskUpdateRequest, port int) *rbody.APIResponse {
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/%s", port, id)
+	j, err := json.Marshal(tr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{}
+	b := bytes.NewReader(j)
+	req, err := http.NewRequest("PUT", uri, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
+func getTaskSpool(id string, port int) *rbody.APIResponse {
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/%s/spool", port, id)
+	resp, err := http.Get(uri)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
+func purgeTaskSpool(id string, port int) *rbody.APIResponse {
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/%s/spool", port, id)
+	client := &http.Client{}
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
 func uploadPlugin(pluginPath string, port int) *rbody.APIResponse {
 	uri := fmt.Sprintf("http://localhost:%d/v1/plugins", port)
 
@@ -441,6 +534,12 @@ func startAPI(opts ...interface{}) *restAPIInstance {
 	c.Start()
 	s := scheduler.New()
 	s.SetMetricManager(c)
+	for _, opt := range opts {
+		switch t := opt.(type) {
+		case scheduler.PublishSpool:
+			s.SetPublishSpool(t)
+		}
+	}
 	s.Start()
 	r.BindMetricManager(c)
 	r.BindTaskManager(s)
@@ -604,5 +703,247 @@ func TestPluginRestCalls(t *testing.T) {
 				So(plr4.ErrorMessage, ShouldEqual, "Task must be disabled")
 			})
 		})
+
+		Convey("Update task - put - /v1/tasks/:id", func() {
+			Convey("Update a running task", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := createTask("1.json", "yeti", "1s", false, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.AddScheduledTask))
+				id := r1.Body.(*rbody.AddScheduledTask).ID
+
+				r2 := updateTask(id, request.TaskUpdateRequest{
+					Name:     "sasquatch",
+					Deadline: "2s",
+					Schedule: &request.Schedule{Type: "simple", Interval: "250ms"},
+				}, port)
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.ScheduledTaskUpdated))
+				plr2 := r2.Body.(*rbody.ScheduledTaskUpdated)
+				So(plr2.ID, ShouldEqual, id)
+				So(plr2.Name, ShouldEqual, "sasquatch")
+				So(plr2.Deadline, ShouldEqual, "2s")
+				So(plr2.Schedule.Interval, ShouldEqual, "250ms")
+				So(plr2.State, ShouldEqual, "Running")
+
+				r3 := getTask(id, port)
+				So(r3.Body, ShouldHaveSameTypeAs, new(rbody.ScheduledTaskReturned))
+				So(r3.Body.(*rbody.ScheduledTaskReturned).Name, ShouldEqual, "sasquatch")
+			})
+			Convey("Update a task that does not exist", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				r1 := updateTask("1234", request.TaskUpdateRequest{Name: "sasquatch"}, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+				So(r1.Meta.Code, ShouldEqual, 404)
+			})
+			Convey("Update a task with a bad schedule", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := createTask("1.json", "yeti", "1s", true, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.AddScheduledTask))
+				id := r1.Body.(*rbody.AddScheduledTask).ID
+
+				r2 := updateTask(id, request.TaskUpdateRequest{
+					Schedule: &request.Schedule{Type: "simple", Interval: "0s"},
+				}, port)
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+				So(r2.Meta.Code, ShouldEqual, 400)
+			})
+		})
+
+		Convey("Validate task - POST - /v1/tasks/validate", func() {
+			Convey("a valid task", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := validateTask("1.json", "1s", port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.TaskValidated))
+				plr1 := r1.Body.(*rbody.TaskValidated)
+				So(plr1.Valid, ShouldBeTrue)
+				So(plr1.Errors, ShouldBeEmpty)
+
+				// nothing was created
+				r2 := getTasks(port)
+				So(r2.Body.(*rbody.ScheduledTaskListReturned).ScheduledTasks, ShouldBeEmpty)
+			})
+			Convey("a task with a bad schedule and plugins that are not loaded", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				r1 := validateTask("1.json", "0s", port)
+				So(r1.Meta.Code, ShouldEqual, 200)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.TaskValidated))
+				plr1 := r1.Body.(*rbody.TaskValidated)
+				So(plr1.Valid, ShouldBeFalse)
+				So(len(plr1.Errors), ShouldBeGreaterThan, 1)
+				So(plr1.Errors[0].Path, ShouldEqual, "schedule")
+			})
+		})
+
+		Convey("Test task - POST - /v1/tasks/test", func() {
+			Convey("returns the output of each node", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := testTask("1.json", port)
+				So(r1.Meta.Code, ShouldEqual, 200)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.TaskTested))
+				plr1 := r1.Body.(*rbody.TaskTested)
+				So(plr1.Nodes, ShouldHaveLength, 2)
+				So(plr1.Nodes[0].Path, ShouldEqual, "collect")
+				So(plr1.Nodes[0].Errors, ShouldBeEmpty)
+				So(plr1.Nodes[0].Metrics, ShouldNotBeEmpty)
+				So(plr1.Nodes[0].Metrics[0].Namespace, ShouldEqual, "/intel/mock/foo")
+				So(plr1.Nodes[1].Path, ShouldEqual, "collect.publish[0]")
+				So(plr1.Nodes[1].PluginName, ShouldEqual, "file")
+				So(plr1.Nodes[1].Metrics, ShouldResemble, plr1.Nodes[0].Metrics)
+
+				// nothing was created
+				r2 := getTasks(port)
+				So(r2.Body.(*rbody.ScheduledTaskListReturned).ScheduledTasks, ShouldBeEmpty)
+			})
+			Convey("returns an error when the plugins are not loaded", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				r1 := testTask("1.json", port)
+				So(r1.Meta.Code, ShouldEqual, 500)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+			})
+		})
+
+		Convey("Task spool - get/delete - /v1/tasks/:id/spool", func() {
+			Convey("returns 404 when the spool is not enabled", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := createTask("1.json", "yeti", "1s", true, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.AddScheduledTask))
+				id := r1.Body.(*rbody.AddScheduledTask).ID
+
+				r2 := getTaskSpool(id, port)
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+				So(r2.Meta.Code, ShouldEqual, 404)
+			})
+			Convey("returns 404 for a task that does not exist", func(c C) {
+				r := startAPI()
+				port := r.port
+
+				r1 := purgeTaskSpool("1234", port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+				So(r1.Meta.Code, ShouldEqual, 404)
+			})
+			Convey("lists and purges the spooled batches", func(c C) {
+				dir, err := ioutil.TempDir("", "snap-publish-spool-")
+				So(err, ShouldBeNil)
+				defer os.RemoveAll(dir)
+				ps, err := scheduler.NewFilePublishSpool(dir)
+				So(err, ShouldBeNil)
+				r := startAPI(ps)
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := createTask("1.json", "yeti", "1s", true, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.AddScheduledTask))
+				id := r1.Body.(*rbody.AddScheduledTask).ID
+				So(ps.Add(&core.SpooledBatch{
+					TaskID:        id,
+					PluginName:    "file",
+					PluginVersion: 3,
+					ContentType:   "snap.gob",
+					Content:       []byte("batch"),
+					CreationTime:  time.Now(),
+					Attempts:      4,
+					LastError:     "connection refused",
+				}), ShouldBeNil)
+
+				r2 := getTaskSpool(id, port)
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.TaskSpoolReturned))
+				plr2 := r2.Body.(*rbody.TaskSpoolReturned)
+				So(plr2.ID, ShouldEqual, id)
+				So(plr2.Batches, ShouldHaveLength, 1)
+				So(plr2.Batches[0].PluginName, ShouldEqual, "file")
+				So(plr2.Batches[0].Size, ShouldEqual, 5)
+				So(plr2.Batches[0].Attempts, ShouldEqual, 4)
+				So(plr2.Batches[0].LastError, ShouldEqual, "connection refused")
+
+				r3 := purgeTaskSpool(id, port)
+				So(r3.Body, ShouldHaveSameTypeAs, new(rbody.TaskSpoolPurged))
+				So(r3.Body.(*rbody.TaskSpoolPurged).Purged, ShouldEqual, 1)
+
+				r4 := getTaskSpool(id, port)
+				So(r4.Body, ShouldHaveSameTypeAs, new(rbody.TaskSpoolReturned))
+				So(r4.Body.(*rbody.TaskSpoolReturned).Batches, ShouldBeEmpty)
+			})
+		})
+		Convey("Plugin logs - get - /v1/plugins/:type/:name/:version/logs", func() {
+			Convey("returns the output of a loaded plugin", func(c C) {
+				r := startAPI()
+				port := r.port
+				uploadPlugin(MOCK_PLUGIN_PATH1, port)
+
+				get := func(path string) *rbody.APIResponse {
+					resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/plugins/%s", port, path))
+					So(err, ShouldBeNil)
+					return getAPIResponse(resp)
+				}
+
+				r1 := get("collector/mock/1/logs?lines=10")
+				So(r1.Meta.Code, ShouldEqual, 200)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.PluginLogs))
+				pl := r1.Body.(*rbody.PluginLogs)
+				So(pl.Name, ShouldEqual, "mock")
+				So(pl.Version, ShouldEqual, 1)
+				So(pl.Type, ShouldEqual, "collector")
+				// the plugin has not run yet
+				So(pl.Lines, ShouldBeEmpty)
+
+				r2 := get("collector/mock/2/logs")
+				So(r2.Meta.Code, ShouldEqual, 404)
+				r3 := get("collector/mock/x/logs")
+				So(r3.Meta.Code, ShouldEqual, 400)
+				r4 := get("collector/mock/1/logs?lines=all")
+				So(r4.Meta.Code, ShouldEqual, 400)
+			})
+		})
+		Convey("Self metrics - get - /v1/metrics/self", func() {
+			Convey("returns snapd's own metrics in the Prometheus text format", func(c C) {
+				r := startAPI(control.EnableSelfCollector())
+				port := r.port
+
+				resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/metrics/self", port))
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, 200)
+				So(resp.Header.Get("Content-Type"), ShouldEqual, telemetry.PrometheusContentType)
+				body := string(readBody(resp))
+				So(body, ShouldContainSubstring, "# TYPE snap_scheduler_queue_depth gauge")
+				So(body, ShouldContainSubstring, `snap_scheduler_workers_count{pool="collect"}`)
+
+				// the metrics are in the catalog too
+				r2 := fetchMetrics(port, "/intel/snap/scheduler/*")
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.MetricsReturned))
+				So(len(*r2.Body.(*rbody.MetricsReturned)), ShouldBeGreaterThan, 0)
+			})
+		})
 	})
 }
@@ -20,6 +20,8 @@ limitations under the License.
 package rest
 
 import (
+	cryptotls "crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -39,6 +41,7 @@ import (
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/mgmt/webhook"
 	cschedule "github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
@@ -49,6 +52,8 @@ const (
 
 var (
 	ErrBadCert = errors.New("Invalid certificate given")
+	// ErrClientCertsRequireHTTPS - The error message for client certificate auth configured without HTTPS
+	ErrClientCertsRequireHTTPS = errors.New("Client certificate authentication requires HTTPS")
 
 	restLogger     = log.WithField("_module", "_mgmt-rest")
 	protocolPrefix = "http"
@@ -60,10 +65,14 @@ type managesMetrics interface {
 	GetMetricVersions([]string) ([]core.CatalogedMetric, error)
 	GetMetric([]string, int) (core.CatalogedMetric, error)
 	Load(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError)
+	LoadRemote(*core.RemotePlugin) (core.CatalogedPlugin, serror.SnapError)
 	Unload(core.Plugin) (core.CatalogedPlugin, serror.SnapError)
 	PluginCatalog() core.PluginCatalog
 	AvailablePlugins() []core.AvailablePlugin
 	GetAutodiscoverPaths() []string
+	PluginLogs(string, string, int, int) ([]core.PluginLogLine, error)
+	FollowPluginLogs(string, string, int, int) ([]core.PluginLogLine, <-chan core.PluginLogLine, func(), error)
+	PluginCacheStats(string, string, int) (core.CacheStats, error)
 }
 
 type managesTasks interface {
@@ -75,6 +84,11 @@ type managesTasks interface {
 	RemoveTask(string) error
 	WatchTask(string, core.TaskWatcherHandler) (core.TaskWatcherCloser, error)
 	EnableTask(string) (core.Task, error)
+	UpdateTask(string, cschedule.Schedule, *wmap.WorkflowMap, ...core.TaskOption) (core.Task, core.TaskErrors)
+	ValidateTask(cschedule.Schedule, *wmap.WorkflowMap) core.TaskErrors
+	TestTask(*wmap.WorkflowMap) (*core.TaskTestResult, core.TaskErrors)
+	GetTaskSpool(string) ([]*core.SpooledBatch, error)
+	PurgeTaskSpool(string) (int, error)
 }
 
 type managesTribe interface {
@@ -88,6 +102,14 @@ type managesTribe interface {
 	GetMember(name string) *agreement.Member
 }
 
+type managesWebhooks interface {
+	AddWebhook(url string, namespaces []string, secret string) (*webhook.Webhook, error)
+	GetWebhook(id string) (*webhook.Webhook, error)
+	GetWebhooks() []*webhook.Webhook
+	RemoveWebhook(id string) error
+	GetDeliveries(id string) ([]*webhook.Delivery, error)
+}
+
 type managesConfig interface {
 	GetPluginConfigDataNode(core.PluginType, string, int) cdata.ConfigDataNode
 	GetPluginConfigDataNodeAll() cdata.ConfigDataNode
@@ -98,20 +120,26 @@ type managesConfig interface {
 }
 
 type Server struct {
-	mm   managesMetrics
-	mt   managesTasks
-	tr   managesTribe
-	mc   managesConfig
-	n    *negroni.Negroni
-	r    *httprouter.Router
-	tls  *tls
-	addr net.Addr
-	err  chan error
+	mm     managesMetrics
+	mt     managesTasks
+	tr     managesTribe
+	mc     managesConfig
+	wh     managesWebhooks
+	n      *negroni.Negroni
+	r      *httprouter.Router
+	tls    *tls
+	auth   *authHandler
+	rbac   *rbac
+	events *eventBroker
+	cas    *x509.CertPool
+	addr   net.Addr
+	err    chan error
 }
 
 func New(https bool, cpath, kpath string) (*Server, error) {
 	s := &Server{
-		err: make(chan error),
+		err:    make(chan error),
+		events: newEventBroker(),
 	}
 
 	if https {
@@ -130,13 +158,54 @@ func New(https bool, cpath, kpath string) (*Server, error) {
 		negroni.NewRecovery(),
 	)
 	s.r = httprouter.New()
-	// Use negroni to handle routes
-	s.n.UseHandler(s.r)
 	return s, nil
 }
 
+// EnableAuth requires every request to authenticate with one of the methods
+// configured.  It must be called before Start.
+func (s *Server) EnableAuth(cfg *AuthConfig) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	var authenticators []Authenticator
+	if cfg.ClientCAFile != "" {
+		if s.tls == nil {
+			return ErrClientCertsRequireHTTPS
+		}
+		pool, err := loadCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		s.cas = pool
+		authenticators = append(authenticators, NewCertAuthenticator())
+	}
+	if len(cfg.Tokens) > 0 {
+		authenticators = append(authenticators, NewTokenAuthenticator(cfg.Tokens))
+	}
+	if cfg.PasswordFile != "" {
+		a, err := NewBasicAuthenticator(cfg.PasswordFile)
+		if err != nil {
+			return err
+		}
+		authenticators = append(authenticators, a)
+	}
+	s.auth = NewAuthHandler(authenticators...)
+	restLogger.WithFields(log.Fields{
+		"_block":       "enable-auth",
+		"basic":        cfg.PasswordFile != "",
+		"tokens":       len(cfg.Tokens),
+		"client-certs": cfg.ClientCAFile != "",
+	}).Info("REST API authentication enabled")
+	return nil
+}
+
 func (s *Server) Start(addrString string) error {
 	s.addRoutes()
+	if s.auth != nil {
+		s.n.Use(s.auth)
+	}
+	// Use negroni to handle routes
+	s.n.UseHandler(s.r)
 	return s.run(addrString)
 }
 
@@ -150,22 +219,30 @@ func (s *Server) Port() int {
 
 func (s *Server) run(addrString string) error {
 	restLogger.Info("Starting REST API on ", addrString)
+	ln, err := net.Listen("tcp", addrString)
+	if err != nil {
+		return err
+	}
+	s.addr = ln.Addr()
 	if s.tls != nil {
-		go s.serveTLS(addrString)
+		go s.serveTLS(ln)
 	} else {
-		ln, err := net.Listen("tcp", addrString)
-		if err != nil {
-			return err
-		}
-		s.addr = ln.Addr()
 		go s.serve(ln)
-
 	}
 	return nil
 }
 
-func (s *Server) serveTLS(addrString string) {
-	err := http.ListenAndServeTLS(addrString, s.tls.cert, s.tls.key, s.n)
+func (s *Server) serveTLS(ln net.Listener) {
+	srv := &http.Server{
+		Handler:   s.n,
+		TLSConfig: &cryptotls.Config{},
+	}
+	if s.cas != nil {
+		// Client certificates are optional so basic auth and tokens still work
+		srv.TLSConfig.ClientCAs = s.cas
+		srv.TLSConfig.ClientAuth = cryptotls.VerifyClientCertIfGiven
+	}
+	err := srv.ServeTLS(tcpKeepAliveListener{ln.(*net.TCPListener)}, s.tls.cert, s.tls.key)
 	if err != nil {
 		restLogger.Error(err)
 		s.err <- err
@@ -214,42 +291,64 @@ func (s *Server) BindConfigManager(c managesConfig) {
 	s.mc = c
 }
 
+func (s *Server) BindWebhookManager(w managesWebhooks) {
+	s.wh = w
+}
+
 func (s *Server) addRoutes() {
 	// plugin routes
-	s.r.GET("/v1/plugins", s.getPlugins)
-	s.r.GET("/v1/plugins/:type", s.getPluginsByType)
-	s.r.GET("/v1/plugins/:type/:name", s.getPluginsByName)
-	s.r.GET("/v1/plugins/:type/:name/:version", s.getPlugin)
-	s.r.POST("/v1/plugins", s.loadPlugin)
-	s.r.DELETE("/v1/plugins/:type/:name/:version", s.unloadPlugin)
-	s.r.GET("/v1/plugins/:type/:name/:version/config", s.getPluginConfigItem)
-	s.r.PUT("/v1/plugins/:type/:name/:version/config", s.setPluginConfigItem)
-	s.r.DELETE("/v1/plugins/:type/:name/:version/config", s.deletePluginConfigItem)
+	s.r.GET("/v1/plugins", s.authorize(PermRead, s.getPlugins))
+	s.r.GET("/v1/plugins/:type", s.authorize(PermRead, s.getPluginsByType))
+	s.r.GET("/v1/plugins/:type/:name", s.authorize(PermRead, s.getPluginsByName))
+	s.r.GET("/v1/plugins/:type/:name/:version", s.authorize(PermRead, s.getPlugin))
+	s.r.POST("/v1/plugins", s.authorize(PermPluginsWrite, s.loadPlugin))
+	s.r.DELETE("/v1/plugins/:type/:name/:version", s.authorize(PermPluginsWrite, s.unloadPlugin))
+	s.r.GET("/v1/plugins/:type/:name/:version/logs", s.authorize(PermRead, s.getPluginLogs))
+	s.r.GET("/v1/plugins/:type/:name/:version/config", s.authorize(PermRead, s.getPluginConfigItem))
+	s.r.PUT("/v1/plugins/:type/:name/:version/config", s.authorize(PermPluginsWrite, s.setPluginConfigItem))
+	s.r.DELETE("/v1/plugins/:type/:name/:version/config", s.authorize(PermPluginsWrite, s.deletePluginConfigItem))
 
 	// metric routes
-	s.r.GET("/v1/metrics", s.getMetrics)
-	s.r.GET("/v1/metrics/*namespace", s.getMetricsFromTree)
+	s.r.GET("/v1/metrics", s.authorize(PermRead, s.getMetrics))
+	s.r.GET("/v1/metrics/*namespace", s.authorize(PermRead, s.getMetricsFromTree))
 
 	// task routes
-	s.r.GET("/v1/tasks", s.getTasks)
-	s.r.GET("/v1/tasks/:id", s.getTask)
-	s.r.GET("/v1/tasks/:id/watch", s.watchTask)
-	s.r.POST("/v1/tasks", s.addTask)
-	s.r.PUT("/v1/tasks/:id/start", s.startTask)
-	s.r.PUT("/v1/tasks/:id/stop", s.stopTask)
-	s.r.DELETE("/v1/tasks/:id", s.removeTask)
-	s.r.PUT("/v1/tasks/:id/enable", s.enableTask)
+	s.r.GET("/v1/tasks", s.authorize(PermRead, s.getTasks))
+	s.r.GET("/v1/tasks/:id", s.authorize(PermRead, s.getTask))
+	s.r.GET("/v1/tasks/:id/watch", s.authorize(PermRead, s.watchTask))
+	s.r.POST("/v1/tasks", s.authorize(PermTasksWrite, s.addTask))
+	s.r.POST("/v1/tasks/validate", s.authorize(PermRead, s.validateTask))
+	s.r.POST("/v1/tasks/test", s.authorize(PermTasksWrite, s.testTask))
+	s.r.PUT("/v1/tasks/:id/start", s.authorize(PermTasksControl, s.startTask))
+	s.r.PUT("/v1/tasks/:id/stop", s.authorize(PermTasksControl, s.stopTask))
+	s.r.DELETE("/v1/tasks/:id", s.authorize(PermTasksWrite, s.removeTask))
+	s.r.PUT("/v1/tasks/:id/enable", s.authorize(PermTasksControl, s.enableTask))
+	s.r.PUT("/v1/tasks/:id", s.authorize(PermTasksWrite, s.updateTask))
+	s.r.GET("/v1/tasks/:id/spool", s.authorize(PermRead, s.getTaskSpool))
+	s.r.DELETE("/v1/tasks/:id/spool", s.authorize(PermTasksWrite, s.purgeTaskSpool))
+
+	// event routes
+	s.r.GET("/v1/events", s.authorize(PermRead, s.getEvents))
+
+	// webhook routes
+	if s.wh != nil {
+		s.r.GET("/v1/webhooks", s.authorize(PermRead, s.getWebhooks))
+		s.r.POST("/v1/webhooks", s.authorize(PermWebhooksWrite, s.addWebhook))
+		s.r.GET("/v1/webhooks/:id", s.authorize(PermRead, s.getWebhook))
+		s.r.DELETE("/v1/webhooks/:id", s.authorize(PermWebhooksWrite, s.removeWebhook))
+		s.r.GET("/v1/webhooks/:id/deliveries", s.authorize(PermRead, s.getWebhookDeliveries))
+	}
 
 	// tribe routes
 	if s.tr != nil {
-		s.r.GET("/v1/tribe/agreements", s.getAgreements)
-		s.r.POST("/v1/tribe/agreements", s.addAgreement)
-		s.r.GET("/v1/tribe/agreements/:name", s.getAgreement)
-		s.r.DELETE("/v1/tribe/agreements/:name", s.deleteAgreement)
-		s.r.PUT("/v1/tribe/agreements/:name/join", s.joinAgreement)
-		s.r.DELETE("/v1/tribe/agreements/:name/leave", s.leaveAgreement)
-		s.r.GET("/v1/tribe/members", s.getMembers)
-		s.r.GET("/v1/tribe/member/:name", s.getMember)
+		s.r.GET("/v1/tribe/agreements", s.authorize(PermRead, s.getAgreements))
+		s.r.POST("/v1/tribe/agreements", s.authorize(PermTribeWrite, s.addAgreement))
+		s.r.GET("/v1/tribe/agreements/:name", s.authorize(PermRead, s.getAgreement))
+		s.r.DELETE("/v1/tribe/agreements/:name", s.authorize(PermTribeWrite, s.deleteAgreement))
+		s.r.PUT("/v1/tribe/agreements/:name/join", s.authorize(PermTribeWrite, s.joinAgreement))
+		s.r.DELETE("/v1/tribe/agreements/:name/leave", s.authorize(PermTribeWrite, s.leaveAgreement))
+		s.r.GET("/v1/tribe/members", s.authorize(PermRead, s.getMembers))
+		s.r.GET("/v1/tribe/member/:name", s.authorize(PermRead, s.getMember))
 	}
 }
 
@@ -45,6 +45,7 @@ var (
 	ErrStreamingUnsupported    = errors.New("Streaming unsupported")
 	ErrTaskNotFound            = errors.New("Task not found")
 	ErrTaskDisabledNotRunnable = errors.New("Task is disabled. Cannot be started")
+	ErrPublishSpoolDisabled    = errors.New("Publish spool is not enabled")
 )
 
 type configItem struct {
@@ -64,6 +65,7 @@ type task struct {
 	HitCount           uint                    `json:"hit_count,omitempty"`
 	MissCount          uint                    `json:"miss_count,omitempty"`
 	FailedCount        uint                    `json:"failed_count,omitempty"`
+	TimedOutCount      uint                    `json:"timed_out_count,omitempty"`
 	LastFailureMessage string                  `json:"last_failure_message,omitempty"`
 	State              string                  `json:"task_state"`
 }
@@ -112,6 +114,55 @@ func (s *Server) addTask(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 	respond(201, taskB, w)
 }
 
+// validateTask checks a task creation request, including its workflow map,
+// plugins and metrics, without creating the task
+func (s *Server) validateTask(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tr, err := marshalTask(r.Body)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+
+	tv := &rbody.TaskValidated{Errors: []rbody.TaskValidationError{}}
+	sch, err := makeSchedule(tr.Schedule)
+	if err != nil {
+		tv.Errors = append(tv.Errors, rbody.TaskValidationError{Path: "schedule", Message: err.Error()})
+	}
+	if tr.Deadline != "" {
+		if _, err := time.ParseDuration(tr.Deadline); err != nil {
+			tv.Errors = append(tv.Errors, rbody.TaskValidationError{Path: "deadline", Message: err.Error()})
+		}
+	}
+	for _, e := range s.mt.ValidateTask(sch, tr.Workflow).Errors() {
+		ve := rbody.TaskValidationError{Message: e.Error()}
+		if p, ok := e.Fields()["path"]; ok {
+			ve.Path = fmt.Sprint(p)
+			ve.Message = strings.TrimPrefix(ve.Message, ve.Path+": ")
+		}
+		tv.Errors = append(tv.Errors, ve)
+	}
+	tv.Valid = len(tv.Errors) == 0
+	respond(200, tv, w)
+}
+
+// testTask runs the workflow of a task creation request once, without
+// creating the task or publishing anything, and returns the output of each
+// node.  The schedule of the request is ignored.
+func (s *Server) testTask(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	tr, err := marshalTask(r.Body)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+
+	res, errs := s.mt.TestTask(tr.Workflow)
+	if errs != nil && len(errs.Errors()) != 0 {
+		respond(500, rbody.FromSnapErrors(errs.Errors()), w)
+		return
+	}
+	respond(200, rbody.TaskTestedFromResult(res), w)
+}
+
 func (s *Server) getTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	sts := s.mt.GetTasks()
 
@@ -280,6 +331,38 @@ func (s *Server) removeTask(w http.ResponseWriter, r *http.Request, p httprouter
 	respond(200, &rbody.ScheduledTaskRemoved{ID: id}, w)
 }
 
+// getTaskSpool returns the batches which could not be published for a task
+func (s *Server) getTaskSpool(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	batches, err := s.mt.GetTaskSpool(id)
+	if err != nil {
+		if strings.Contains(err.Error(), ErrTaskNotFound.Error()) ||
+			strings.Contains(err.Error(), ErrPublishSpoolDisabled.Error()) {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	respond(200, rbody.TaskSpoolFromBatches(id, batches), w)
+}
+
+// purgeTaskSpool drops the batches which could not be published for a task
+func (s *Server) purgeTaskSpool(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	n, err := s.mt.PurgeTaskSpool(id)
+	if err != nil {
+		if strings.Contains(err.Error(), ErrTaskNotFound.Error()) ||
+			strings.Contains(err.Error(), ErrPublishSpoolDisabled.Error()) {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	respond(200, &rbody.TaskSpoolPurged{ID: id, Purged: n}, w)
+}
+
 //enableTask changes the task state from Disabled to Stopped
 func (s *Server) enableTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
@@ -297,6 +380,54 @@ func (s *Server) enableTask(w http.ResponseWriter, r *http.Request, p httprouter
 	respond(200, task, w)
 }
 
+// updateTask changes the schedule, workflow, name or deadline of an existing
+// task in place
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	var tr request.TaskUpdateRequest
+	errCode, err := marshalBody(&tr, r.Body)
+	if errCode != 0 && err != nil {
+		respond(errCode, rbody.FromError(err), w)
+		return
+	}
+
+	var sch cschedule.Schedule
+	if tr.Schedule != nil {
+		sch, err = makeSchedule(*tr.Schedule)
+		if err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+	}
+
+	var opts []core.TaskOption
+	if tr.Deadline != "" {
+		dl, err := time.ParseDuration(tr.Deadline)
+		if err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+		opts = append(opts, core.TaskDeadlineDuration(dl))
+	}
+	if tr.Name != "" {
+		opts = append(opts, core.SetTaskName(tr.Name))
+	}
+
+	tsk, errs := s.mt.UpdateTask(id, sch, tr.Workflow, opts...)
+	if errs != nil && len(errs.Errors()) != 0 {
+		if strings.Contains(errs.Errors()[0].Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromSnapErrors(errs.Errors()), w)
+			return
+		}
+		respond(500, rbody.FromSnapErrors(errs.Errors()), w)
+		return
+	}
+	task := &rbody.ScheduledTaskUpdated{}
+	task.AddScheduledTask = *rbody.AddSchedulerTaskFromTask(tsk)
+	task.Href = taskURI(r.Host, tsk)
+	respond(200, task, w)
+}
+
 func marshalTask(body io.ReadCloser) (*request.TaskCreationRequest, error) {
 	var tr request.TaskCreationRequest
 	errCode, err := marshalBody(&tr, body)
@@ -346,6 +477,22 @@ func makeSchedule(s request.Schedule) (cschedule.Schedule, error) {
 			return nil, err
 		}
 		return sch, nil
+	case "cron":
+		var loc *time.Location
+		if s.TimeZone != "" {
+			var err error
+			loc, err = time.LoadLocation(s.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sch := cschedule.NewCronSchedule(s.Interval, loc)
+
+		err := sch.Validate()
+		if err != nil {
+			return nil, err
+		}
+		return sch, nil
 	default:
 		return nil, errors.New("unknown schedule type " + s.Type)
 	}
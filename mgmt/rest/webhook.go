@@ -0,0 +1,127 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/webhook"
+)
+
+var webhookLogger = restLogger.WithFields(log.Fields{
+	"_module": "rest-webhook",
+})
+
+func (s *Server) getWebhooks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	hooks := s.wh.GetWebhooks()
+	res := &rbody.WebhookList{Webhooks: make([]rbody.Webhook, len(hooks))}
+	for i, h := range hooks {
+		res.Webhooks[i] = *webhookToRbody(r.Host, h)
+	}
+	respond(200, res, w)
+}
+
+func (s *Server) addWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	wr := &request.WebhookCreationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(wr); err != nil {
+		fields := map[string]interface{}{
+			"error": err.Error(),
+			"hint":  `The body of the request should be of the form '{"url": "https://...", "namespaces": ["Control"], "secret": "..."}'`,
+		}
+		webhookLogger.WithFields(fields).WithField("_block", "add-webhook").Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	h, err := s.wh.AddWebhook(wr.URL, wr.Namespaces, wr.Secret)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	respond(201, (*rbody.AddWebhook)(webhookToRbody(r.Host, h)), w)
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	h, err := s.wh.GetWebhook(p.ByName("id"))
+	if err != nil {
+		respondWebhookError(err, w)
+		return
+	}
+	respond(200, webhookToRbody(r.Host, h), w)
+}
+
+func (s *Server) removeWebhook(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	if err := s.wh.RemoveWebhook(id); err != nil {
+		respondWebhookError(err, w)
+		return
+	}
+	respond(200, &rbody.WebhookRemoved{ID: id}, w)
+}
+
+func (s *Server) getWebhookDeliveries(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	ds, err := s.wh.GetDeliveries(id)
+	if err != nil {
+		respondWebhookError(err, w)
+		return
+	}
+	res := &rbody.WebhookDeliveryList{ID: id, Deliveries: make([]rbody.WebhookDelivery, len(ds))}
+	for i, d := range ds {
+		res.Deliveries[i] = rbody.WebhookDelivery{
+			ID:             d.ID,
+			Event:          d.Event,
+			EventTimestamp: d.EventTime.Unix(),
+			Attempts:       d.Attempts,
+			StatusCode:     d.StatusCode,
+			Error:          d.Error,
+			Delivered:      d.Delivered,
+		}
+		if !d.LastAttempt.IsZero() {
+			res.Deliveries[i].LastAttemptTimestamp = d.LastAttempt.Unix()
+		}
+	}
+	respond(200, res, w)
+}
+
+func respondWebhookError(err error, w http.ResponseWriter) {
+	if err == webhook.ErrWebhookNotFound {
+		respond(404, rbody.FromError(err), w)
+		return
+	}
+	respond(500, rbody.FromError(err), w)
+}
+
+func webhookToRbody(host string, h *webhook.Webhook) *rbody.Webhook {
+	return &rbody.Webhook{
+		ID:                h.ID,
+		URL:               h.URL,
+		Namespaces:        h.Namespaces,
+		CreationTimestamp: h.CreationTime.Unix(),
+		Href:              fmt.Sprintf("%s://%s/v1/webhooks/%s", protocolPrefix, host, h.ID),
+	}
+}
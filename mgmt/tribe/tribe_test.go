@@ -59,6 +59,7 @@ func (t *mockTask) SetName(string)                            { return }
 func (t *mockTask) SetID(string)                              { return }
 func (t *mockTask) MissedCount() uint                         { return 0 }
 func (t *mockTask) FailedCount() uint                         { return 0 }
+func (t *mockTask) TimedOutCount() uint                       { return 0 }
 func (t *mockTask) LastFailureMessage() string                { return "" }
 func (t *mockTask) LastRunTime() *time.Time                   { return nil }
 func (t *mockTask) CreationTime() *time.Time                  { return nil }
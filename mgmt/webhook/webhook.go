@@ -0,0 +1,429 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook delivers the events emitted by control and the scheduler to
+// HTTP endpoints registered through the REST API.  Webhooks are kept in memory
+// and do not survive a restart of snapd.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/gomit"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+const (
+	// SignatureHeader holds sha256= followed by the hex encoded HMAC-SHA256
+	// of the request body keyed with the secret of the webhook
+	SignatureHeader = "X-Snap-Signature"
+	// EventHeader holds the namespace of the event delivered
+	EventHeader = "X-Snap-Event"
+	// DeliveryHeader holds the id of the delivery which is the same for
+	// every attempt
+	DeliveryHeader = "X-Snap-Delivery"
+)
+
+var (
+	// ErrWebhookNotFound - The error message for a webhook id which is not registered
+	ErrWebhookNotFound = errors.New("Webhook not found")
+	// ErrInvalidURL - The error message for a target which is not an absolute http or https URL
+	ErrInvalidURL = errors.New("Webhook URL must be an absolute http or https URL")
+	// ErrMissingSecret - The error message for a webhook without a signing secret
+	ErrMissingSecret = errors.New("Webhook secret is required")
+
+	webhookLogger = log.WithFields(log.Fields{
+		"_module": "webhook",
+	})
+)
+
+// Config holds the delivery settings of a Manager
+type Config struct {
+	// MaxAttempts is how many times a delivery is attempted before it is
+	// given up on
+	MaxAttempts int
+	// Backoff is the wait before the first retry.  It doubles for each
+	// further retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout limits each attempt
+	Timeout time.Duration
+	// LogSize is the number of deliveries kept for each webhook
+	LogSize int
+	// QueueSize is the number of deliveries which may wait for each webhook
+	// before further events are dropped
+	QueueSize int
+}
+
+// DefaultConfig returns the default delivery settings
+func DefaultConfig() *Config {
+	return &Config{
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		Timeout:     10 * time.Second,
+		LogSize:     100,
+		QueueSize:   100,
+	}
+}
+
+// Webhook is a registered target for events
+type Webhook struct {
+	ID string
+	// URL receives a POST for each event
+	URL string
+	// Namespaces filter the events delivered.  An event is delivered if it
+	// is in any of them or, when there are none, always.
+	Namespaces   []string
+	CreationTime time.Time
+}
+
+// Delivery records the delivery of one event to a webhook
+type Delivery struct {
+	ID        string
+	Event     string
+	EventTime time.Time
+	Attempts  int
+	// StatusCode of the last attempt, zero if no response was received
+	StatusCode  int
+	Error       string
+	Delivered   bool
+	LastAttempt time.Time
+}
+
+type hook struct {
+	Webhook
+	secret []byte
+	queue  chan *delivery
+	done   chan struct{}
+
+	sync.Mutex
+	deliveries []*Delivery
+}
+
+type delivery struct {
+	*Delivery
+	payload []byte
+}
+
+// Manager keeps the registered webhooks and delivers the events it handles to
+// them.  It is registered as an event handler with control and the scheduler.
+type Manager struct {
+	sync.Mutex
+	config *Config
+	hooks  map[string]*hook
+	client *http.Client
+}
+
+// New returns a Manager which delivers events using cfg
+func New(cfg *Config) *Manager {
+	return &Manager{
+		config: cfg,
+		hooks:  map[string]*hook{},
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (m *Manager) Name() string {
+	return "webhook"
+}
+
+func (m *Manager) Start() error {
+	webhookLogger.WithField("_block", "start").Info("webhook manager started")
+	return nil
+}
+
+// Stop removes every webhook abandoning their waiting deliveries
+func (m *Manager) Stop() {
+	m.Lock()
+	defer m.Unlock()
+	for id, h := range m.hooks {
+		close(h.done)
+		delete(m.hooks, id)
+	}
+}
+
+// AddWebhook registers a webhook which receives the events in namespaces
+// signed with secret
+func (m *Manager) AddWebhook(target string, namespaces []string, secret string) (*Webhook, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+	h := &hook{
+		Webhook: Webhook{
+			ID:           uuid.New(),
+			URL:          target,
+			Namespaces:   namespaces,
+			CreationTime: time.Now(),
+		},
+		secret: []byte(secret),
+		queue:  make(chan *delivery, m.config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	m.Lock()
+	m.hooks[h.ID] = h
+	m.Unlock()
+	go m.worker(h)
+	webhookLogger.WithFields(log.Fields{
+		"_block":     "add-webhook",
+		"webhook-id": h.ID,
+		"url":        target,
+		"namespaces": namespaces,
+	}).Info("webhook added")
+	w := h.Webhook
+	return &w, nil
+}
+
+// RemoveWebhook unregisters a webhook abandoning its waiting deliveries
+func (m *Manager) RemoveWebhook(id string) error {
+	m.Lock()
+	defer m.Unlock()
+	h, ok := m.hooks[id]
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	close(h.done)
+	delete(m.hooks, id)
+	webhookLogger.WithFields(log.Fields{
+		"_block":     "remove-webhook",
+		"webhook-id": id,
+	}).Info("webhook removed")
+	return nil
+}
+
+// GetWebhook returns the webhook registered with id
+func (m *Manager) GetWebhook(id string) (*Webhook, error) {
+	m.Lock()
+	defer m.Unlock()
+	h, ok := m.hooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	w := h.Webhook
+	return &w, nil
+}
+
+// GetWebhooks returns every registered webhook in the order they were added
+func (m *Manager) GetWebhooks() []*Webhook {
+	m.Lock()
+	defer m.Unlock()
+	hooks := make([]*Webhook, 0, len(m.hooks))
+	for _, h := range m.hooks {
+		w := h.Webhook
+		hooks = append(hooks, &w)
+	}
+	sort.Sort(byCreationTime(hooks))
+	return hooks
+}
+
+// GetDeliveries returns the most recent deliveries to a webhook, oldest first
+func (m *Manager) GetDeliveries(id string) ([]*Delivery, error) {
+	m.Lock()
+	h, ok := m.hooks[id]
+	m.Unlock()
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	h.Lock()
+	defer h.Unlock()
+	deliveries := make([]*Delivery, len(h.deliveries))
+	for i, d := range h.deliveries {
+		c := *d
+		deliveries[i] = &c
+	}
+	return deliveries, nil
+}
+
+// HandleGomitEvent queues the event for each webhook whose namespaces it is in
+func (m *Manager) HandleGomitEvent(e gomit.Event) {
+	se := rbody.NewStreamedEvent(e)
+	payload := []byte(se.ToJSON())
+	m.Lock()
+	defer m.Unlock()
+	for _, h := range m.hooks {
+		if !h.wants(se) {
+			continue
+		}
+		d := &delivery{
+			Delivery: &Delivery{
+				ID:        uuid.New(),
+				Event:     se.Namespace,
+				EventTime: se.Timestamp,
+			},
+			payload: payload,
+		}
+		h.record(d.Delivery, m.config.LogSize)
+		select {
+		case h.queue <- d:
+		default:
+			h.update(func() { d.Error = "delivery queue is full" })
+			webhookLogger.WithFields(log.Fields{
+				"_block":     "handle-gomit-event",
+				"webhook-id": h.ID,
+				"event":      se.Namespace,
+			}).Warn("dropping event for webhook")
+		}
+	}
+}
+
+func (h *hook) wants(e *rbody.StreamedEvent) bool {
+	if len(h.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range h.Namespaces {
+		if e.InNamespace(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a delivery to the log of the webhook dropping the oldest once it
+// holds size deliveries
+func (h *hook) record(d *Delivery, size int) {
+	h.Lock()
+	defer h.Unlock()
+	h.deliveries = append(h.deliveries, d)
+	if len(h.deliveries) > size {
+		h.deliveries = h.deliveries[len(h.deliveries)-size:]
+	}
+}
+
+// update calls f holding the lock which guards the deliveries in the log
+func (h *hook) update(f func()) {
+	h.Lock()
+	defer h.Unlock()
+	f()
+}
+
+// worker delivers the events queued for a webhook one at a time, in order,
+// until the webhook is removed
+func (m *Manager) worker(h *hook) {
+	for {
+		select {
+		case d := <-h.queue:
+			m.deliver(h, d)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (m *Manager) deliver(h *hook, d *delivery) {
+	logger := webhookLogger.WithFields(log.Fields{
+		"_block":      "deliver",
+		"webhook-id":  h.ID,
+		"delivery-id": d.ID,
+		"event":       d.Event,
+	})
+	backoff := m.config.Backoff
+	for {
+		code, err := m.post(h, d)
+		var attempts int
+		h.update(func() {
+			d.Attempts++
+			d.LastAttempt = time.Now()
+			d.StatusCode = code
+			d.Error = ""
+			if err != nil {
+				d.Error = err.Error()
+			} else {
+				d.Delivered = true
+			}
+			attempts = d.Attempts
+		})
+		if err == nil {
+			logger.WithField("attempts", attempts).Debug("event delivered")
+			return
+		}
+		if attempts >= m.config.MaxAttempts {
+			logger.WithFields(log.Fields{
+				"attempts": attempts,
+				"_error":   err.Error(),
+			}).Error("giving up on delivering event")
+			return
+		}
+		logger.WithFields(log.Fields{
+			"attempts": attempts,
+			"backoff":  backoff.String(),
+			"_error":   err.Error(),
+		}).Warn("delivering event failed, retrying")
+		select {
+		case <-time.After(backoff):
+		case <-h.done:
+			return
+		}
+		backoff *= 2
+		if backoff > m.config.MaxBackoff {
+			backoff = m.config.MaxBackoff
+		}
+	}
+}
+
+// post makes one attempt at a delivery returning the status code received
+func (m *Manager) post(h *hook, d *delivery) (int, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(h.secret, d.payload))
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload keyed with secret, as
+// sent in the X-Snap-Signature header
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type byCreationTime []*Webhook
+
+func (b byCreationTime) Len() int           { return len(b) }
+func (b byCreationTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCreationTime) Less(i, j int) bool { return b[i].CreationTime.Before(b[j].CreationTime) }
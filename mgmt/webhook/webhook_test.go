@@ -0,0 +1,210 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver records the requests made to it answering the first fail of them
+// with a 500
+type receiver struct {
+	sync.Mutex
+	fail     int
+	requests []received
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, _ := ioutil.ReadAll(req.Body)
+	r.Lock()
+	defer r.Unlock()
+	r.requests = append(r.requests, received{req.Header, b})
+	if len(r.requests) <= r.fail {
+		w.WriteHeader(500)
+	}
+}
+
+func (r *receiver) count() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.requests)
+}
+
+func testEvent(b gomit.EventBody) gomit.Event {
+	return gomit.Event{Header: gomit.Header{Time: time.Now()}, Body: b}
+}
+
+func testConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.MaxAttempts = 3
+	cfg.Backoff = 10 * time.Millisecond
+	cfg.MaxBackoff = 20 * time.Millisecond
+	cfg.LogSize = 2
+	return cfg
+}
+
+func waitFor(f func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if f() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWebhooks(t *testing.T) {
+	Convey("Webhook manager", t, func() {
+		m := New(testConfig())
+		So(m.Start(), ShouldBeNil)
+		defer m.Stop()
+		rcv := &receiver{}
+		ts := httptest.NewServer(rcv)
+		defer ts.Close()
+
+		Convey("validates webhooks", func() {
+			_, err := m.AddWebhook("ftp://example.com", nil, "s3cret")
+			So(err, ShouldEqual, ErrInvalidURL)
+			_, err = m.AddWebhook("/hooks", nil, "s3cret")
+			So(err, ShouldEqual, ErrInvalidURL)
+			_, err = m.AddWebhook(ts.URL, nil, "")
+			So(err, ShouldEqual, ErrMissingSecret)
+			So(m.GetWebhooks(), ShouldBeEmpty)
+		})
+
+		Convey("adds, gets and removes webhooks", func() {
+			w1, err := m.AddWebhook(ts.URL, []string{"Control"}, "s3cret")
+			So(err, ShouldBeNil)
+			w2, err := m.AddWebhook(ts.URL+"/other", nil, "s3cret")
+			So(err, ShouldBeNil)
+			w, err := m.GetWebhook(w1.ID)
+			So(err, ShouldBeNil)
+			So(w.URL, ShouldEqual, ts.URL)
+			So(w.Namespaces, ShouldResemble, []string{"Control"})
+			hooks := m.GetWebhooks()
+			So(hooks, ShouldHaveLength, 2)
+			So(hooks[0].ID, ShouldEqual, w1.ID)
+			So(hooks[1].ID, ShouldEqual, w2.ID)
+			So(m.RemoveWebhook(w1.ID), ShouldBeNil)
+			So(m.RemoveWebhook(w1.ID), ShouldEqual, ErrWebhookNotFound)
+			_, err = m.GetWebhook(w1.ID)
+			So(err, ShouldEqual, ErrWebhookNotFound)
+			_, err = m.GetDeliveries(w1.ID)
+			So(err, ShouldEqual, ErrWebhookNotFound)
+		})
+
+		Convey("delivers signed events", func() {
+			w, err := m.AddWebhook(ts.URL, nil, "s3cret")
+			So(err, ShouldBeNil)
+			m.HandleGomitEvent(testEvent(&control_event.LoadPluginEvent{Name: "mock", Version: 1}))
+			So(waitFor(func() bool { return rcv.count() == 1 }), ShouldBeTrue)
+			r := rcv.requests[0]
+			So(r.header.Get(EventHeader), ShouldEqual, control_event.PluginLoaded)
+			So(r.header.Get(SignatureHeader), ShouldEqual, "sha256="+Sign([]byte("s3cret"), r.body))
+			se := &rbody.StreamedEvent{}
+			So(json.Unmarshal(r.body, se), ShouldBeNil)
+			So(se.PluginName, ShouldEqual, "mock")
+
+			So(waitFor(func() bool {
+				ds, _ := m.GetDeliveries(w.ID)
+				return len(ds) == 1 && ds[0].Delivered
+			}), ShouldBeTrue)
+			ds, err := m.GetDeliveries(w.ID)
+			So(err, ShouldBeNil)
+			So(ds[0].ID, ShouldEqual, r.header.Get(DeliveryHeader))
+			So(ds[0].Attempts, ShouldEqual, 1)
+			So(ds[0].StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("filters events by namespace", func() {
+			_, err := m.AddWebhook(ts.URL, []string{"Scheduler.TaskStarted", "Control.PluginLoaded"}, "s3cret")
+			So(err, ShouldBeNil)
+			m.HandleGomitEvent(testEvent(&control_event.UnloadPluginEvent{Name: "mock", Version: 1}))
+			m.HandleGomitEvent(testEvent(&scheduler_event.TaskStartedEvent{TaskID: "1"}))
+			So(waitFor(func() bool { return rcv.count() == 1 }), ShouldBeTrue)
+			time.Sleep(50 * time.Millisecond)
+			So(rcv.count(), ShouldEqual, 1)
+			So(rcv.requests[0].header.Get(EventHeader), ShouldEqual, scheduler_event.TaskStarted)
+		})
+
+		Convey("retries failed deliveries", func() {
+			rcv.fail = 1
+			w, err := m.AddWebhook(ts.URL, nil, "s3cret")
+			So(err, ShouldBeNil)
+			m.HandleGomitEvent(testEvent(&scheduler_event.TaskStartedEvent{TaskID: "1"}))
+			So(waitFor(func() bool {
+				ds, _ := m.GetDeliveries(w.ID)
+				return len(ds) == 1 && ds[0].Delivered
+			}), ShouldBeTrue)
+			So(rcv.count(), ShouldEqual, 2)
+			ds, _ := m.GetDeliveries(w.ID)
+			So(ds[0].Attempts, ShouldEqual, 2)
+			So(ds[0].Error, ShouldBeEmpty)
+			// every attempt is the same delivery
+			So(rcv.requests[0].header.Get(DeliveryHeader), ShouldEqual, rcv.requests[1].header.Get(DeliveryHeader))
+		})
+
+		Convey("gives up after the maximum attempts", func() {
+			rcv.fail = 10
+			w, err := m.AddWebhook(ts.URL, nil, "s3cret")
+			So(err, ShouldBeNil)
+			m.HandleGomitEvent(testEvent(&scheduler_event.TaskStartedEvent{TaskID: "1"}))
+			So(waitFor(func() bool {
+				ds, _ := m.GetDeliveries(w.ID)
+				return len(ds) == 1 && ds[0].Attempts == 3
+			}), ShouldBeTrue)
+			time.Sleep(50 * time.Millisecond)
+			So(rcv.count(), ShouldEqual, 3)
+			ds, _ := m.GetDeliveries(w.ID)
+			So(ds[0].Delivered, ShouldBeFalse)
+			So(ds[0].StatusCode, ShouldEqual, 500)
+			So(ds[0].Error, ShouldContainSubstring, "500")
+		})
+
+		Convey("keeps only the most recent deliveries", func() {
+			w, err := m.AddWebhook(ts.URL, nil, "s3cret")
+			So(err, ShouldBeNil)
+			for _, id := range []string{"1", "2", "3"} {
+				m.HandleGomitEvent(testEvent(&scheduler_event.TaskStartedEvent{TaskID: id}))
+			}
+			So(waitFor(func() bool { return rcv.count() == 3 }), ShouldBeTrue)
+			ds, _ := m.GetDeliveries(w.ID)
+			So(ds, ShouldHaveLength, 2)
+		})
+	})
+}
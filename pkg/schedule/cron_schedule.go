@@ -0,0 +1,347 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// cronDescriptors maps the predefined cron entries to their expanded form
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dowNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// CronSchedule is a schedule that fires at the wall clock times matched
+// by a cron entry.  Both the standard 5 field entry (minute, hour, day of
+// month, month, day of week) and a 6 field entry with leading seconds are
+// accepted.
+type CronSchedule struct {
+	Entry    string
+	Location *time.Location
+	state    ScheduleState
+	spec     *cronSpec
+}
+
+// NewCronSchedule returns an instance of CronSchedule given a cron entry and
+// the location its times are evaluated in.  A nil location uses local time.
+func NewCronSchedule(entry string, loc *time.Location) *CronSchedule {
+	return &CronSchedule{
+		Entry:    entry,
+		Location: loc,
+	}
+}
+
+// GetState returns ScheduleState of CronSchedule
+func (c *CronSchedule) GetState() ScheduleState {
+	return c.state
+}
+
+// Validate parses the cron entry and returns an error if it is malformed or
+// can never fire
+func (c *CronSchedule) Validate() error {
+	if strings.TrimSpace(c.Entry) == "" {
+		return ErrMissingCronEntry
+	}
+	spec, err := parseCronEntry(c.Entry)
+	if err != nil {
+		return err
+	}
+	if spec.next(time.Now().In(c.location())).IsZero() {
+		return ErrCronEntryNeverFires
+	}
+	c.spec = spec
+	return nil
+}
+
+// Wait blocks until the next time matched by the cron entry.  Any matching
+// times which passed between last and the call to Wait are reported as
+// missed.
+func (c *CronSchedule) Wait(last time.Time) Response {
+	return wait(c, last)
+}
+
+// Next returns the next time matched by the cron entry and the response to
+// fire with then.  The time the schedule fired at is the cron time rather
+// than when the wait ended so that waits measured from it are whole seconds.
+func (c *CronSchedule) Next(last time.Time) (time.Time, Response) {
+	if c.spec == nil {
+		if err := c.Validate(); err != nil {
+			c.state = Error
+			now := time.Now()
+			return now, &CronScheduleResponse{
+				state:    c.GetState(),
+				err:      err,
+				lastTime: now,
+			}
+		}
+	}
+
+	now := time.Now().In(c.location())
+	var m uint
+	if (last != time.Time{}) {
+		for t := c.spec.next(last.In(c.location())); !t.IsZero() && !t.After(now); t = c.spec.next(t) {
+			m++
+		}
+	}
+
+	next := c.spec.next(now)
+	if next.IsZero() {
+		c.state = Ended
+		return now, &CronScheduleResponse{
+			state:    c.GetState(),
+			missed:   m,
+			lastTime: now,
+		}
+	}
+	logger.WithFields(log.Fields{
+		"_block":         "cron-wait",
+		"entry":          c.Entry,
+		"next":           next,
+		"missed":         m,
+		"sleep-duration": next.Sub(now),
+	}).Debug("waiting for next cron time")
+	return next, &CronScheduleResponse{
+		state:    c.GetState(),
+		missed:   m,
+		lastTime: next,
+	}
+}
+
+func (c *CronSchedule) location() *time.Location {
+	if c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
+
+// CronScheduleResponse is the response from CronSchedule
+// conforming to ScheduleResponse interface
+type CronScheduleResponse struct {
+	state    ScheduleState
+	err      error
+	missed   uint
+	lastTime time.Time
+}
+
+// State returns the state of the Schedule
+func (c *CronScheduleResponse) State() ScheduleState {
+	return c.state
+}
+
+// Error returns last error
+func (c *CronScheduleResponse) Error() error {
+	return c.err
+}
+
+// Missed returns any missed intervals
+func (c *CronScheduleResponse) Missed() uint {
+	return c.missed
+}
+
+// LastTime returns the last cron schedule response time
+func (c *CronScheduleResponse) LastTime() time.Time {
+	return c.lastTime
+}
+
+// cronSpec holds a bit set of the matching values for each field of a parsed
+// cron entry
+type cronSpec struct {
+	second, minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted day field. When both
+	// day fields are restricted a day matching either one fires.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{"second", 0, 59, nil}
+	minuteField = cronField{"minute", 0, 59, nil}
+	hourField   = cronField{"hour", 0, 23, nil}
+	domField    = cronField{"day of month", 1, 31, nil}
+	monthField  = cronField{"month", 1, 12, monthNames}
+	dowField    = cronField{"day of week", 0, 7, dowNames}
+)
+
+func parseCronEntry(entry string) (*cronSpec, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, "@") {
+		e, ok := cronDescriptors[strings.ToLower(entry)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown cron descriptor %s", entry)
+		}
+		entry = e
+	}
+
+	fields := strings.Fields(entry)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("Cron entry must have 5 or 6 fields, found %d in \"%s\"", len(fields), entry)
+	}
+
+	spec := &cronSpec{
+		domStar: fields[3] == "*" || fields[3] == "?",
+		dowStar: fields[5] == "*" || fields[5] == "?",
+	}
+	var err error
+	if spec.second, err = secondField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if spec.minute, err = minuteField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if spec.hour, err = hourField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if spec.dom, err = domField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if spec.month, err = monthField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if spec.dow, err = dowField.parse(fields[5]); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	return spec, nil
+}
+
+// parse returns the bit set of values matched by a single cron field which
+// is a comma separated list of '*', values or ranges with an optional step.
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		lo, hi, step := f.min, f.max, 1
+		rng := item
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("Invalid step in cron %s field \"%s\"", f.name, item)
+			}
+			rng = item[:i]
+		}
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			parts := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = f.value(parts[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(parts[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("Invalid range in cron %s field \"%s\"", f.name, item)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// A single value with a step runs to the end of the field
+			if step == 1 {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("Invalid value \"%s\" in cron %s field (expected %d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first time matched by the spec strictly after t or the
+// zero time if nothing matches within the next five years.
+func (s *cronSpec) next(t time.Time) time.Time {
+	loc := t.Location()
+	// Start at the next whole second
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second + time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(-time.Duration(t.Second())*time.Second + time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCronSchedule(t *testing.T) {
+	Convey("Cron Schedule", t, func() {
+		Convey("valid entries", func() {
+			for _, e := range []string{
+				"* * * * *",
+				"0 * * * *",
+				"*/5 * * * * *",
+				"0 30 9-17/2 * * mon-fri",
+				"0 0 1,15 jan,jul *",
+				"0 0 * * 7",
+				"@hourly",
+				"@daily",
+			} {
+				s := NewCronSchedule(e, nil)
+				So(s.Validate(), ShouldBeNil)
+			}
+		})
+
+		Convey("invalid entries", func() {
+			s := NewCronSchedule("", nil)
+			So(s.Validate(), ShouldEqual, ErrMissingCronEntry)
+
+			for _, e := range []string{
+				"* * * *",
+				"* * * * * * *",
+				"60 * * * *",
+				"* 24 * * *",
+				"* * 0 * *",
+				"* * * 13 *",
+				"* * * * 8",
+				"5-1 * * * *",
+				"*/0 * * * *",
+				"foo * * * *",
+				"@fortnightly",
+			} {
+				s := NewCronSchedule(e, nil)
+				So(s.Validate(), ShouldNotBeNil)
+			}
+		})
+
+		Convey("entry that never fires", func() {
+			s := NewCronSchedule("0 0 30 feb *", nil)
+			So(s.Validate(), ShouldEqual, ErrCronEntryNeverFires)
+		})
+
+		Convey("computes the next time", func() {
+			start := time.Date(2015, time.December, 31, 23, 59, 30, 500, time.UTC)
+
+			spec, err := parseCronEntry("0 * * * *")
+			So(err, ShouldBeNil)
+			So(spec.next(start), ShouldResemble, time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+			spec, err = parseCronEntry("*/15 * * * * *")
+			So(err, ShouldBeNil)
+			So(spec.next(start), ShouldResemble, time.Date(2015, time.December, 31, 23, 59, 45, 0, time.UTC))
+
+			spec, err = parseCronEntry("0 9 * * mon")
+			So(err, ShouldBeNil)
+			So(spec.next(start), ShouldResemble, time.Date(2016, time.January, 4, 9, 0, 0, 0, time.UTC))
+
+			spec, err = parseCronEntry("0 0 29 feb *")
+			So(err, ShouldBeNil)
+			So(spec.next(start), ShouldResemble, time.Date(2016, time.February, 29, 0, 0, 0, 0, time.UTC))
+		})
+
+		Convey("day of month or day of week when both are restricted", func() {
+			start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+			spec, err := parseCronEntry("0 0 15 * mon")
+			So(err, ShouldBeNil)
+			// Monday the 4th comes before the 15th
+			So(spec.next(start), ShouldResemble, time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC))
+		})
+
+		Convey("evaluates times in its location", func() {
+			loc, err := time.LoadLocation("America/New_York")
+			So(err, ShouldBeNil)
+			s := NewCronSchedule("0 9 * * *", loc)
+			So(s.Validate(), ShouldBeNil)
+			start := time.Date(2016, time.January, 1, 12, 0, 0, 0, time.UTC)
+			n := s.spec.next(start.In(loc))
+			So(n.UTC(), ShouldResemble, time.Date(2016, time.January, 1, 14, 0, 0, 0, time.UTC))
+		})
+
+		Convey("test Wait()", func() {
+			s := NewCronSchedule("* * * * * *", nil)
+			So(s.Validate(), ShouldBeNil)
+
+			r := s.Wait(time.Time{})
+			So(r.State(), ShouldEqual, Active)
+			So(r.Error(), ShouldBeNil)
+			So(r.Missed(), ShouldEqual, 0)
+			// fires on the whole second
+			So(r.LastTime().Nanosecond(), ShouldBeLessThan, int(50*time.Millisecond))
+
+			last := r.LastTime()
+			time.Sleep(time.Millisecond * 2500)
+			r = s.Wait(last)
+			So(r.State(), ShouldEqual, Active)
+			So(r.Missed(), ShouldEqual, 2)
+			So(r.LastTime().Sub(last), ShouldBeBetweenOrEqual, 3*time.Second, 3*time.Second+50*time.Millisecond)
+		})
+
+		Convey("test Next()", func() {
+			s := NewCronSchedule("0 0 * * * *", time.UTC)
+			So(s.Validate(), ShouldBeNil)
+			last := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+			at, r := s.Next(last)
+			// returns the next cron time without waiting for it
+			So(at, ShouldResemble, time.Now().UTC().Truncate(time.Hour).Add(time.Hour))
+			So(r.LastTime(), ShouldResemble, at)
+			So(r.State(), ShouldEqual, Active)
+			So(r.Missed(), ShouldEqual, 2)
+		})
+
+		Convey("invalid schedule errors on Wait()", func() {
+			s := NewCronSchedule("bad", nil)
+			r := s.Wait(time.Time{})
+			So(r.State(), ShouldEqual, Error)
+			So(r.Error(), ShouldNotBeNil)
+		})
+	})
+}
@@ -14,6 +14,10 @@ var (
 	ErrInvalidStopTime = errors.New("Stop time is in the past")
 	// ErrStopBeforeStart - Error message for the stop time cannot occur before start time
 	ErrStopBeforeStart = errors.New("Stop time cannot occur before start time")
+	// ErrMissingCronEntry - Error message for the cron entry is empty
+	ErrMissingCronEntry = errors.New("Cron entry is missing")
+	// ErrCronEntryNeverFires - Error message for the cron entry never matches a point in time
+	ErrCronEntryNeverFires = errors.New("Cron entry will never fire")
 )
 
 // ScheduleState int type
@@ -36,6 +40,9 @@ type Schedule interface {
 	Validate() error
 	// Blocks until time to fire and returns a schedule.Response
 	Wait(time.Time) Response
+	// Returns the time to fire at and the schedule.Response to fire with
+	// without blocking
+	Next(time.Time) (time.Time, Response)
 }
 
 // Response interface defines the behavior of schedule response
@@ -50,21 +57,28 @@ type Response interface {
 	LastTime() time.Time
 }
 
-func waitOnInterval(last time.Time, i time.Duration) (uint, time.Time) {
+// wait blocks until the time the schedule fires at and returns its response
+func wait(s Schedule, last time.Time) Response {
+	at, r := s.Next(last)
+	time.Sleep(at.Sub(time.Now()))
+	return r
+}
+
+// nextOnInterval returns the intervals missed between last and now and the
+// time the next interval fires at.  Without a last time the first interval
+// starts now.
+func nextOnInterval(now, last time.Time, i time.Duration) (uint, time.Time) {
 	if (last == time.Time{}) {
-		time.Sleep(i)
-		return uint(0), time.Now()
+		return uint(0), now.Add(i)
 	}
 	// Get the difference in time.Duration since last in nanoseconds (int64)
-	timeDiff := time.Since(last).Nanoseconds()
+	timeDiff := now.Sub(last).Nanoseconds()
 	// cache our schedule interval in nanseconds
 	nanoInterval := i.Nanoseconds()
 	// use modulo operation to obtain the remainder of time over last interval
 	remainder := timeDiff % nanoInterval
 	// substract remainder from
 	missed := (timeDiff - remainder) / nanoInterval // timeDiff.Nanoseconds() % s.Interval.Nanoseconds()
-	waitDuration := nanoInterval - remainder
-	// Wait until predicted interval fires
-	time.Sleep(time.Duration(waitDuration))
-	return uint(missed), time.Now()
+	// the next interval is a whole number of intervals from last
+	return uint(missed), last.Add(time.Duration(missed+1) * i)
 }
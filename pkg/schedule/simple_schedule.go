@@ -33,8 +33,14 @@ func (s *SimpleSchedule) Validate() error {
 
 // Wait returns the SimpleSchedule state, misses and the last schedule ran
 func (s *SimpleSchedule) Wait(last time.Time) Response {
-	m, t := waitOnInterval(last, s.Interval)
-	return &SimpleScheduleResponse{state: s.GetState(), missed: m, lastTime: t}
+	return wait(s, last)
+}
+
+// Next returns the time the next interval fires at and the response to fire
+// with
+func (s *SimpleSchedule) Next(last time.Time) (time.Time, Response) {
+	m, t := nextOnInterval(time.Now(), last, s.Interval)
+	return t, &SimpleScheduleResponse{state: s.GetState(), missed: m, lastTime: t}
 }
 
 // SimpleScheduleResponse a response from SimpleSchedule conforming to ScheduleResponse interface
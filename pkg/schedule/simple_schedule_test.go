@@ -33,6 +33,16 @@ func TestSimpleSchedule(t *testing.T) {
 			So(afterMS, ShouldBeLessThan, shouldWait+10)
 		})
 
+		Convey("test Next()", func() {
+			s := NewSimpleSchedule(time.Hour)
+			last := time.Now().Add(-150 * time.Minute)
+			at, r := s.Next(last)
+			// returns the next interval without waiting for it
+			So(at, ShouldResemble, last.Add(3*time.Hour))
+			So(r.LastTime(), ShouldResemble, at)
+			So(r.Missed(), ShouldEqual, 2)
+		})
+
 		Convey("invalid schedule", func() {
 			s := NewSimpleSchedule(0)
 			err := s.Validate()
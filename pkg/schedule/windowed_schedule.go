@@ -51,16 +51,23 @@ func (w *WindowedSchedule) Validate() error {
 // Wait waits the window interval and return.
 // Otherwise, it exits with a completed state
 func (w *WindowedSchedule) Wait(last time.Time) Response {
+	return wait(w, last)
+}
+
+// Next returns the time the next interval within the window fires at and the
+// response to fire with.  Past the window it returns now with a completed
+// state.
+func (w *WindowedSchedule) Next(last time.Time) (time.Time, Response) {
+	now := time.Now()
 	// Do we even have a specific start time?
 	if w.StartTime != nil {
 		// Wait till it is time to start if before the window start
-		if time.Now().Before(*w.StartTime) {
-			wait := w.StartTime.Sub(time.Now())
+		if now.Before(*w.StartTime) {
 			logger.WithFields(log.Fields{
 				"_block":         "windowed-wait",
-				"sleep-duration": wait,
+				"sleep-duration": w.StartTime.Sub(now),
 			}).Debug("Waiting for window to start")
-			time.Sleep(wait)
+			now = *w.StartTime
 		}
 		if (last == time.Time{}) {
 			logger.WithFields(log.Fields{
@@ -73,44 +80,29 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 			logger.WithFields(log.Fields{
 				"_block": "windowed-wait",
 			}).Debug("Last was unset using start time")
-			last = time.Now()
+			last = now
 		}
 	}
 
 	// If within the window we wait our interval and return
 	// otherwise we exit with a compleled state.
 	var m uint
+	at := now
 	// Do we even have a stop time?
-	if w.StopTime != nil {
-		if time.Now().Before(*w.StopTime) {
-			logger.WithFields(log.Fields{
-				"_block":           "windowed-wait",
-				"time-before-stop": w.StopTime.Sub(time.Now()),
-			}).Debug("Within window, calling interval")
-			logger.WithFields(log.Fields{
-				"_block":   "windowed-wait",
-				"last":     last,
-				"interval": w.Interval,
-			}).Debug("waiting for interval")
-			m, _ = waitOnInterval(last, w.Interval)
-		} else {
-			w.state = Ended
-			m = 0
-		}
+	if w.StopTime != nil && !now.Before(*w.StopTime) {
+		w.state = Ended
 	} else {
 		logger.WithFields(log.Fields{
 			"_block":   "windowed-wait",
 			"last":     last,
 			"interval": w.Interval,
 		}).Debug("waiting for interval")
-		// This has no end like a simple schedule
-		m, _ = waitOnInterval(last, w.Interval)
-
+		m, at = nextOnInterval(now, last, w.Interval)
 	}
-	return &WindowedScheduleResponse{
+	return at, &WindowedScheduleResponse{
 		state:    w.GetState(),
 		missed:   m,
-		lastTime: time.Now(),
+		lastTime: at,
 	}
 }
 
@@ -0,0 +1,90 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text format
+const PrometheusContentType = "text/plain; version=0.0.4"
+
+var invalidNameChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// PrometheusName returns the name of the metric in the Prometheus text format.
+// The static elements of the namespace, without its leading "intel", are
+// joined with underscores so /intel/snap/scheduler/queue/*/depth becomes
+// snap_scheduler_queue_depth.
+func PrometheusName(d *Desc) string {
+	dynamic := map[int]bool{}
+	for _, l := range d.Labels {
+		dynamic[l.Index] = true
+	}
+	var parts []string
+	for i, e := range d.Namespace {
+		if dynamic[i] || (i == 0 && e == "intel") {
+			continue
+		}
+		parts = append(parts, invalidNameChars.ReplaceAllString(e, "_"))
+	}
+	return strings.Join(parts, "_")
+}
+
+// WritePrometheus writes the samples, as returned by Registry.Gather, in the
+// Prometheus text format.  The dynamic elements of each namespace become
+// labels.
+func WritePrometheus(w io.Writer, samples []Sample) error {
+	bw := bufio.NewWriter(w)
+	var last *Desc
+	for _, s := range samples {
+		name := PrometheusName(s.Desc)
+		if s.Desc != last {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, escapeHelp(s.Desc.Description))
+			fmt.Fprintf(bw, "# TYPE %s %s\n", name, s.Desc.Kind)
+			last = s.Desc
+		}
+		bw.WriteString(name)
+		if len(s.Desc.Labels) > 0 {
+			bw.WriteString("{")
+			for i, l := range s.Desc.Labels {
+				if i > 0 {
+					bw.WriteString(",")
+				}
+				fmt.Fprintf(bw, "%s=\"%s\"", invalidNameChars.ReplaceAllString(l.Name, "_"), escapeLabelValue(s.LabelValues[i]))
+			}
+			bw.WriteString("}")
+		}
+		fmt.Fprintf(bw, " %s\n", strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+	return bw.Flush()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,319 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package telemetry holds the metrics snapd keeps about itself.  They are
+// collected through the built-in /intel/snap collector and exposed in the
+// Prometheus text format by the REST API.
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is how the value of a metric changes
+type Kind int
+
+const (
+	// CounterKind metrics only ever increase
+	CounterKind Kind = iota
+	// GaugeKind metrics go up and down
+	GaugeKind
+)
+
+func (k Kind) String() string {
+	if k == CounterKind {
+		return "counter"
+	}
+	return "gauge"
+}
+
+// Label names a dynamic element of a namespace
+type Label struct {
+	Index int
+	Name  string
+}
+
+// Desc describes a metric.  The dynamic elements of its namespace, given by
+// Labels, are "*" in Namespace and are filled in by the values of each sample.
+type Desc struct {
+	Namespace   []string
+	Labels      []Label
+	Kind        Kind
+	Description string
+}
+
+func (d *Desc) key() string {
+	return "/" + strings.Join(d.Namespace, "/")
+}
+
+// Sample is the value of a metric for one set of label values
+type Sample struct {
+	Desc        *Desc
+	LabelValues []string
+	Value       float64
+}
+
+// Namespace returns the namespace of the metric with its dynamic elements
+// filled in
+func (s *Sample) Namespace() []string {
+	ns := make([]string, len(s.Desc.Namespace))
+	copy(ns, s.Desc.Namespace)
+	for i, l := range s.Desc.Labels {
+		ns[l.Index] = s.LabelValues[i]
+	}
+	return ns
+}
+
+type collector interface {
+	describe() *Desc
+	collect(func(Sample))
+}
+
+// Registry holds metrics by namespace
+type Registry struct {
+	sync.Mutex
+	metrics map[string]collector
+}
+
+// Default is the registry snapd keeps its own metrics in
+var Default = NewRegistry()
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]collector{}}
+}
+
+// Counter returns the counter described by d, registering it the first time
+func (r *Registry) Counter(d Desc) *Counter {
+	d.Kind = CounterKind
+	return &Counter{r.register(&d, func(d *Desc) collector { return newValues(d) }).(*values)}
+}
+
+// Gauge returns the gauge described by d, registering it the first time
+func (r *Registry) Gauge(d Desc) *Gauge {
+	d.Kind = GaugeKind
+	return &Gauge{r.register(&d, func(d *Desc) collector { return newValues(d) }).(*values)}
+}
+
+// Timer returns a timer which counts observations in the counter
+// <namespace>/count and sums their duration in the counter <namespace>/seconds
+func (r *Registry) Timer(d Desc) *Timer {
+	count, seconds := d, d
+	count.Namespace = append(append([]string{}, d.Namespace...), "count")
+	count.Description = d.Description + " (count)"
+	seconds.Namespace = append(append([]string{}, d.Namespace...), "seconds")
+	seconds.Description = d.Description + " (total seconds)"
+	return &Timer{r.Counter(count), r.Counter(seconds)}
+}
+
+// Func registers f to report the values of the metric described by d when it
+// is gathered.  It replaces any function registered before for d.
+func (r *Registry) Func(d Desc, f func(emit func(v float64, labelValues ...string))) {
+	r.Lock()
+	defer r.Unlock()
+	r.metrics[d.key()] = &funcCollector{desc: &d, f: f}
+}
+
+func (r *Registry) register(d *Desc, create func(*Desc) collector) collector {
+	r.Lock()
+	defer r.Unlock()
+	if c, ok := r.metrics[d.key()]; ok {
+		if v, ok := c.(*values); ok && v.desc.Kind == d.Kind {
+			return v
+		}
+		panic(fmt.Sprintf("telemetry: %s is already registered as another kind of metric", d.key()))
+	}
+	c := create(d)
+	r.metrics[d.key()] = c
+	return c
+}
+
+// Describe returns the description of every metric ordered by namespace
+func (r *Registry) Describe() []*Desc {
+	r.Lock()
+	defer r.Unlock()
+	descs := make([]*Desc, 0, len(r.metrics))
+	for _, k := range r.keys() {
+		descs = append(descs, r.metrics[k].describe())
+	}
+	return descs
+}
+
+// Gather returns the current value of every metric ordered by namespace
+func (r *Registry) Gather() []Sample {
+	r.Lock()
+	cs := make([]collector, 0, len(r.metrics))
+	for _, k := range r.keys() {
+		cs = append(cs, r.metrics[k])
+	}
+	r.Unlock()
+	var samples []Sample
+	for _, c := range cs {
+		var ss []Sample
+		c.collect(func(s Sample) { ss = append(ss, s) })
+		sort.Sort(byLabelValues(ss))
+		samples = append(samples, ss...)
+	}
+	return samples
+}
+
+func (r *Registry) keys() []string {
+	keys := make([]string, 0, len(r.metrics))
+	for k := range r.metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// values holds the value of a metric for each set of label values
+type values struct {
+	sync.Mutex
+	desc   *Desc
+	values map[string]*Sample
+}
+
+func newValues(d *Desc) *values {
+	return &values{desc: d, values: map[string]*Sample{}}
+}
+
+func (v *values) describe() *Desc {
+	return v.desc
+}
+
+func (v *values) collect(emit func(Sample)) {
+	v.Lock()
+	defer v.Unlock()
+	for _, s := range v.values {
+		emit(*s)
+	}
+}
+
+func (v *values) get(labelValues []string) *Sample {
+	if len(labelValues) != len(v.desc.Labels) {
+		panic(fmt.Sprintf("telemetry: %s takes %d label values, got %d", v.desc.key(), len(v.desc.Labels), len(labelValues)))
+	}
+	k := strings.Join(labelValues, "\xff")
+	s, ok := v.values[k]
+	if !ok {
+		s = &Sample{Desc: v.desc, LabelValues: append([]string{}, labelValues...)}
+		v.values[k] = s
+	}
+	return s
+}
+
+func (v *values) add(d float64, labelValues []string) {
+	v.Lock()
+	defer v.Unlock()
+	v.get(labelValues).Value += d
+}
+
+func (v *values) set(d float64, labelValues []string) {
+	v.Lock()
+	defer v.Unlock()
+	v.get(labelValues).Value = d
+}
+
+func (v *values) delete(labelValues []string) {
+	v.Lock()
+	defer v.Unlock()
+	delete(v.values, strings.Join(labelValues, "\xff"))
+}
+
+// Counter is a metric which only ever increases
+type Counter struct {
+	v *values
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.v.add(1, labelValues)
+}
+
+// Add adds d, which must not be negative, to the counter
+func (c *Counter) Add(d float64, labelValues ...string) {
+	if d < 0 {
+		panic("telemetry: counters cannot decrease")
+	}
+	c.v.add(d, labelValues)
+}
+
+// Gauge is a metric which goes up and down
+type Gauge struct {
+	v *values
+}
+
+// Set sets the gauge to d
+func (g *Gauge) Set(d float64, labelValues ...string) {
+	g.v.set(d, labelValues)
+}
+
+// Add adds d, which may be negative, to the gauge
+func (g *Gauge) Add(d float64, labelValues ...string) {
+	g.v.add(d, labelValues)
+}
+
+// Delete drops the value for the label values
+func (g *Gauge) Delete(labelValues ...string) {
+	g.v.delete(labelValues)
+}
+
+// Timer counts how often something happens and how long it takes
+type Timer struct {
+	count   *Counter
+	seconds *Counter
+}
+
+// Observe records something which took d
+func (t *Timer) Observe(d time.Duration, labelValues ...string) {
+	t.count.Inc(labelValues...)
+	t.seconds.Add(d.Seconds(), labelValues...)
+}
+
+// Since records something which started at start
+func (t *Timer) Since(start time.Time, labelValues ...string) {
+	t.Observe(time.Since(start), labelValues...)
+}
+
+type funcCollector struct {
+	desc *Desc
+	f    func(emit func(v float64, labelValues ...string))
+}
+
+func (f *funcCollector) describe() *Desc {
+	return f.desc
+}
+
+func (f *funcCollector) collect(emit func(Sample)) {
+	f.f(func(v float64, labelValues ...string) {
+		emit(Sample{Desc: f.desc, LabelValues: labelValues, Value: v})
+	})
+}
+
+type byLabelValues []Sample
+
+func (b byLabelValues) Len() int      { return len(b) }
+func (b byLabelValues) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byLabelValues) Less(i, j int) bool {
+	return strings.Join(b[i].LabelValues, "\xff") < strings.Join(b[j].LabelValues, "\xff")
+}
@@ -0,0 +1,123 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetry
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("Registry", t, func() {
+		r := NewRegistry()
+		calls := r.Counter(Desc{
+			Namespace:   []string{"intel", "snap", "plugins", "*", "calls"},
+			Labels:      []Label{{Index: 3, Name: "plugin"}},
+			Description: "calls made to a plugin",
+		})
+		depth := r.Gauge(Desc{
+			Namespace:   []string{"intel", "snap", "queue", "depth"},
+			Description: "jobs waiting",
+		})
+
+		Convey("keeps values for each set of label values", func() {
+			calls.Inc("mock")
+			calls.Add(2, "mock")
+			calls.Inc("file")
+			depth.Set(3)
+			depth.Add(-1)
+
+			samples := r.Gather()
+			So(samples, ShouldHaveLength, 3)
+			So(samples[0].Namespace(), ShouldResemble, []string{"intel", "snap", "plugins", "file", "calls"})
+			So(samples[0].Value, ShouldEqual, 1)
+			So(samples[1].Namespace(), ShouldResemble, []string{"intel", "snap", "plugins", "mock", "calls"})
+			So(samples[1].Value, ShouldEqual, 3)
+			So(samples[2].Namespace(), ShouldResemble, []string{"intel", "snap", "queue", "depth"})
+			So(samples[2].Value, ShouldEqual, 2)
+
+			depth.Delete()
+			So(r.Gather(), ShouldHaveLength, 2)
+		})
+
+		Convey("returns the registered metric when asked again", func() {
+			again := r.Counter(Desc{Namespace: []string{"intel", "snap", "plugins", "*", "calls"}, Labels: []Label{{Index: 3, Name: "plugin"}}})
+			again.Inc("mock")
+			calls.Inc("mock")
+			So(r.Gather()[0].Value, ShouldEqual, 2)
+			So(func() { r.Gauge(Desc{Namespace: []string{"intel", "snap", "plugins", "*", "calls"}}) }, ShouldPanic)
+		})
+
+		Convey("refuses the wrong number of label values", func() {
+			So(func() { calls.Inc() }, ShouldPanic)
+			So(func() { calls.Add(-1, "mock") }, ShouldPanic)
+		})
+
+		Convey("records timers as a count and a sum", func() {
+			tm := r.Timer(Desc{Namespace: []string{"intel", "snap", "jobs", "latency"}, Description: "job latency"})
+			tm.Observe(time.Second)
+			tm.Observe(500 * time.Millisecond)
+			descs := r.Describe()
+			So(descs, ShouldHaveLength, 4)
+			So(descs[0].Namespace, ShouldResemble, []string{"intel", "snap", "jobs", "latency", "count"})
+			So(descs[1].Namespace, ShouldResemble, []string{"intel", "snap", "jobs", "latency", "seconds"})
+			samples := r.Gather()
+			So(samples[0].Value, ShouldEqual, 2)
+			So(samples[1].Value, ShouldEqual, 1.5)
+		})
+
+		Convey("calls functions when gathering", func() {
+			d := Desc{Namespace: []string{"intel", "snap", "pools", "*", "size"}, Labels: []Label{{Index: 3, Name: "pool"}}, Kind: GaugeKind}
+			r.Func(d, func(emit func(float64, ...string)) {
+				emit(1, "b")
+				emit(2, "a")
+			})
+			samples := r.Gather()
+			So(samples, ShouldHaveLength, 2)
+			So(samples[0].LabelValues, ShouldResemble, []string{"a"})
+			So(samples[1].LabelValues, ShouldResemble, []string{"b"})
+
+			// registering again replaces the function
+			r.Func(d, func(emit func(float64, ...string)) { emit(5, "c") })
+			samples = r.Gather()
+			So(samples, ShouldHaveLength, 1)
+			So(samples[0].Value, ShouldEqual, 5)
+		})
+
+		Convey("writes the Prometheus text format", func() {
+			calls.Inc("mock")
+			calls.Inc(`my "plugin"`)
+			depth.Set(4)
+			var buf bytes.Buffer
+			So(WritePrometheus(&buf, r.Gather()), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `# HELP snap_plugins_calls calls made to a plugin
+# TYPE snap_plugins_calls counter
+snap_plugins_calls{plugin="mock"} 1
+snap_plugins_calls{plugin="my \"plugin\""} 1
+# HELP snap_queue_depth jobs waiting
+# TYPE snap_queue_depth gauge
+snap_queue_depth 4
+`)
+		})
+	})
+}
@@ -0,0 +1,353 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+const (
+	name       = "prometheus"
+	version    = 1
+	pluginType = plugin.PublisherPluginType
+
+	defaultListen    = "127.0.0.1:9106"
+	defaultStaleness = "5m"
+)
+
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	// ErrInvalidStaleness - The error message for a staleness which is not a positive duration
+	ErrInvalidStaleness = errors.New("staleness must be a positive duration")
+)
+
+type prometheusPublisher struct {
+	sync.Mutex
+	exporters map[string]*exporter
+}
+
+// NewPrometheusPublisher returns a publisher which serves the latest value of
+// each metric it receives in the Prometheus text exposition format.
+func NewPrometheusPublisher() *prometheusPublisher {
+	return &prometheusPublisher{exporters: map[string]*exporter{}}
+}
+
+func (p *prometheusPublisher) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error {
+	logger := log.WithFields(log.Fields{
+		"_module": "publisher-prometheus",
+		"_block":  "publish",
+	})
+	metrics, err := plugin.UnmarshallPluginMetricTypes(contentType, content)
+	if err != nil {
+		return err
+	}
+	listen := defaultListen
+	if v, ok := config["listen"].(ctypes.ConfigValueStr); ok && v.Value != "" {
+		listen = v.Value
+	}
+	staleness, err := parseStaleness(config)
+	if err != nil {
+		return err
+	}
+	e, err := p.exporter(listen)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"listen": listen,
+			"_error": err.Error(),
+		}).Error("unable to serve metrics")
+		return err
+	}
+	expires := time.Now().Add(staleness)
+	for _, m := range metrics {
+		if err := e.update(m, expires); err != nil {
+			logger.WithFields(log.Fields{
+				"namespace": "/" + strings.Join(m.Namespace(), "/"),
+				"_error":    err.Error(),
+			}).Warn("skipping metric")
+		}
+	}
+	return nil
+}
+
+// exporter returns the exporter serving on listen, starting it if this is
+// the first time the address has been seen.
+func (p *prometheusPublisher) exporter(listen string) (*exporter, error) {
+	p.Lock()
+	defer p.Unlock()
+	if e, ok := p.exporters[listen]; ok {
+		return e, nil
+	}
+	e := newExporter()
+	if err := e.serve(listen); err != nil {
+		return nil, err
+	}
+	p.exporters[listen] = e
+	return e, nil
+}
+
+func parseStaleness(config map[string]ctypes.ConfigValue) (time.Duration, error) {
+	s := defaultStaleness
+	if v, ok := config["staleness"].(ctypes.ConfigValueStr); ok && v.Value != "" {
+		s = v.Value
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidStaleness
+	}
+	return d, nil
+}
+
+func Meta() *plugin.PluginMeta {
+	return plugin.NewPluginMeta(
+		name,
+		version,
+		pluginType,
+		[]string{plugin.SnapGOBContentType, plugin.SnapJSONContentType},
+		[]string{plugin.SnapGOBContentType},
+	)
+}
+
+func (p *prometheusPublisher) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	cp := cpolicy.New()
+	config := cpolicy.NewPolicyNode()
+
+	r1, err := cpolicy.NewStringRule("listen", false, defaultListen)
+	handleErr(err)
+	r1.Description = "Address the /metrics endpoint listens on"
+	config.Add(r1)
+
+	r2, err := cpolicy.NewStringRule("staleness", false, defaultStaleness)
+	handleErr(err)
+	r2.Description = "How long a value is served after it was last published (e.g. 90s, 5m)"
+	config.Add(r2)
+
+	cp.Add([]string{""}, config)
+	return cp, nil
+}
+
+// sample is the latest value of a single series
+type sample struct {
+	name    string
+	labels  map[string]string
+	value   float64
+	expires time.Time
+}
+
+// exporter keeps the latest sample of each series and serves them on /metrics
+type exporter struct {
+	sync.Mutex
+	samples map[string]*sample
+}
+
+func newExporter() *exporter {
+	return &exporter{samples: map[string]*sample{}}
+}
+
+func (e *exporter) serve(listen string) error {
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	go http.Serve(l, mux)
+	return nil
+}
+
+func (e *exporter) update(m plugin.PluginMetricType, expires time.Time) error {
+	v, err := toFloat(m.Data())
+	if err != nil {
+		return err
+	}
+	s := newSample(m)
+	s.value = v
+	s.expires = expires
+	e.Lock()
+	e.samples[s.key()] = s
+	e.Unlock()
+	return nil
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(e.render(time.Now()))
+}
+
+// render writes every sample which has not expired by now in the text
+// exposition format, dropping those which have.
+func (e *exporter) render(now time.Time) []byte {
+	e.Lock()
+	samples := make([]*sample, 0, len(e.samples))
+	for k, s := range e.samples {
+		if now.After(s.expires) {
+			delete(e.samples, k)
+			continue
+		}
+		samples = append(samples, s)
+	}
+	e.Unlock()
+	sort.Sort(byKey(samples))
+
+	var buf bytes.Buffer
+	last := ""
+	for _, s := range samples {
+		if s.name != last {
+			fmt.Fprintf(&buf, "# TYPE %s gauge\n", s.name)
+			last = s.name
+		}
+		fmt.Fprintf(&buf, "%s %s\n", s.key(), strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+	return buf.Bytes()
+}
+
+// newSample maps a metric to a series.  Namespace elements make up the name
+// apart from dynamic elements, which become labels along with the tags and
+// the source of the metric.
+func newSample(m plugin.PluginMetricType) *sample {
+	ns := m.Namespace()
+	labels := map[string]string{}
+	if m.Source() != "" {
+		labels["source"] = m.Source()
+	}
+	dynamic := map[int]bool{}
+	for _, l := range m.Labels() {
+		if l.Index >= 0 && l.Index < len(ns) {
+			labels[labelName(l.Name)] = ns[l.Index]
+			dynamic[l.Index] = true
+		}
+	}
+	for k, v := range m.Tags() {
+		labels[labelName(k)] = v
+	}
+	parts := make([]string, 0, len(ns))
+	for i, n := range ns {
+		if !dynamic[i] {
+			parts = append(parts, n)
+		}
+	}
+	return &sample{name: metricName(parts), labels: labels}
+}
+
+// key returns the series as it appears in the exposition, e.g.
+// intel_disk_reads{disk="sda",source="host1"}
+func (s *sample) key() string {
+	if len(s.labels) == 0 {
+		return s.name
+	}
+	names := make([]string, 0, len(s.labels))
+	for k := range s.labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, k := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, k, labelValueEscaper.Replace(s.labels[k]))
+	}
+	return s.name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+type byKey []*sample
+
+func (b byKey) Len() int      { return len(b) }
+func (b byKey) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byKey) Less(i, j int) bool {
+	if b[i].name != b[j].name {
+		return b[i].name < b[j].name
+	}
+	return b[i].key() < b[j].key()
+}
+
+func metricName(ns []string) string {
+	n := invalidNameChars.ReplaceAllString(strings.Join(ns, "_"), "_")
+	if n == "" || (n[0] >= '0' && n[0] <= '9') {
+		n = "_" + n
+	}
+	return n
+}
+
+func labelName(s string) string {
+	n := invalidLabelChars.ReplaceAllString(s, "_")
+	if n == "" || (n[0] >= '0' && n[0] <= '9') {
+		n = "_" + n
+	}
+	return n
+}
+
+func toFloat(data interface{}) (float64, error) {
+	switch v := data.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unsupported data type %T", data)
+}
+
+func handleErr(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
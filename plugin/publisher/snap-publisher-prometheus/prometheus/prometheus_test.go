@@ -0,0 +1,135 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func freeAddr() string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestPrometheusPublish(t *testing.T) {
+	Convey("Publish()", t, func() {
+		now := time.Now()
+		metrics := []plugin.PluginMetricType{
+			*plugin.NewPluginMetricType([]string{"intel", "mock", "foo"}, now, "host1", nil, nil, 99),
+			*plugin.NewPluginMetricType([]string{"intel", "disk", "sda", "reads"}, now, "", map[string]string{"rack": "r1"},
+				[]core.Label{{Index: 2, Name: "disk"}}, 1.5),
+			*plugin.NewPluginMetricType([]string{"intel", "mock", "bar"}, now, "", nil, nil, "not a number"),
+		}
+		var buf bytes.Buffer
+		So(gob.NewEncoder(&buf).Encode(metrics), ShouldBeNil)
+
+		addr := freeAddr()
+		config := map[string]ctypes.ConfigValue{
+			"listen":    ctypes.ConfigValueStr{Value: addr},
+			"staleness": ctypes.ConfigValueStr{Value: "1m"},
+		}
+		p := NewPrometheusPublisher()
+
+		Convey("rejects an unknown content type", func() {
+			So(p.Publish("", buf.Bytes(), config), ShouldNotBeNil)
+		})
+
+		Convey("rejects an invalid staleness", func() {
+			config["staleness"] = ctypes.ConfigValueStr{Value: "-1s"}
+			So(p.Publish(plugin.SnapGOBContentType, buf.Bytes(), config), ShouldEqual, ErrInvalidStaleness)
+		})
+
+		Convey("serves the latest values on /metrics", func() {
+			So(p.Publish(plugin.SnapGOBContentType, buf.Bytes(), config), ShouldBeNil)
+			resp, err := http.Get("http://" + addr + "/metrics")
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			b, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "# TYPE intel_disk_reads gauge\n"+
+				"intel_disk_reads{disk=\"sda\",rack=\"r1\"} 1.5\n"+
+				"# TYPE intel_mock_foo gauge\n"+
+				"intel_mock_foo{source=\"host1\"} 99\n")
+
+			Convey("and replaces them when published again", func() {
+				metrics = metrics[:1]
+				metrics[0].AddData(100)
+				buf.Reset()
+				So(gob.NewEncoder(&buf).Encode(metrics), ShouldBeNil)
+				So(p.Publish(plugin.SnapGOBContentType, buf.Bytes(), config), ShouldBeNil)
+				So(string(p.exporters[addr].render(time.Now())), ShouldContainSubstring, "intel_mock_foo{source=\"host1\"} 100\n")
+			})
+		})
+
+		Convey("accepts JSON", func() {
+			b, _, err := plugin.MarshalPluginMetricTypes(plugin.SnapJSONContentType, metrics[:1])
+			So(err, ShouldBeNil)
+			So(p.Publish(plugin.SnapJSONContentType, b, config), ShouldBeNil)
+			So(p.exporters[addr].samples, ShouldHaveLength, 1)
+		})
+
+		Convey("fails when the address cannot be listened on", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer l.Close()
+			config["listen"] = ctypes.ConfigValueStr{Value: l.Addr().String()}
+			So(p.Publish(plugin.SnapGOBContentType, buf.Bytes(), config), ShouldNotBeNil)
+			So(p.exporters, ShouldBeEmpty)
+		})
+	})
+
+	Convey("render()", t, func() {
+		e := newExporter()
+		now := time.Now()
+		m := plugin.NewPluginMetricType([]string{"intel", "9lives", "a-b"}, now, "", map[string]string{"quote": `a"b`}, nil, true)
+		So(e.update(*m, now.Add(time.Second)), ShouldBeNil)
+
+		Convey("sanitizes names and escapes label values", func() {
+			So(string(e.render(now)), ShouldEqual, "# TYPE intel_9lives_a_b gauge\nintel_9lives_a_b{quote=\"a\\\"b\"} 1\n")
+		})
+
+		Convey("drops values past the staleness window", func() {
+			So(e.render(now.Add(2*time.Second)), ShouldBeEmpty)
+			So(e.samples, ShouldBeEmpty)
+		})
+	})
+
+	Convey("Meta()", t, func() {
+		meta := Meta()
+		So(meta.Name, ShouldEqual, name)
+		So(meta.AcceptedContentTypes, ShouldContain, plugin.SnapJSONContentType)
+	})
+}
@@ -22,7 +22,10 @@ package scheduler
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"sync"
 	"time"
 
@@ -33,6 +36,7 @@ import (
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/intelsdi-x/snap/pkg/promise"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
 const (
@@ -45,6 +49,15 @@ const (
 	defaultDeadline = time.Duration(5 * time.Second)
 )
 
+var (
+	// ErrInvalidRetryAttempts - The error message for a retry policy with negative attempts
+	ErrInvalidRetryAttempts = errors.New("Retry attempts cannot be negative")
+	// ErrUnsupportedMetricType - The error message for a collected metric which cannot be sent to a plugin
+	ErrUnsupportedMetricType = errors.New("Unsupported metric type")
+	// ErrUnsupportedParentJobType - The error message for a job whose parent job has no output
+	ErrUnsupportedParentJobType = errors.New("Unsupported parent job type")
+)
+
 // Represents a queued job, together with a synchronization
 // barrier to signal job completion (successful or otherwise).
 //
@@ -92,6 +105,18 @@ type job interface {
 
 type jobType int
 
+func (j jobType) String() string {
+	switch j {
+	case collectJobType:
+		return "collect"
+	case publishJobType:
+		return "publish"
+	case processJobType:
+		return "process"
+	}
+	return "unknown"
+}
+
 type coreJob struct {
 	sync.Mutex
 	taskID    string
@@ -129,8 +154,14 @@ func (c *coreJob) AddErrors(errs ...error) {
 	c.errors = append(c.errors, errs...)
 }
 
+// Errors returns a copy of the errors of the job, which an abandoned job may
+// still be adding to
 func (c *coreJob) Errors() []error {
-	return c.errors
+	c.Lock()
+	defer c.Unlock()
+	errs := make([]error, len(c.errors))
+	copy(errs, c.errors)
+	return errs
 }
 
 func (c *coreJob) TaskID() string {
@@ -232,6 +263,8 @@ type processJob struct {
 	config        map[string]ctypes.ConfigValue
 	contentType   string
 	content       []byte
+	// the content type of content as returned by the processor
+	returnedContentType string
 }
 
 func newProcessJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, processor processesMetrics, taskID string) job {
@@ -258,41 +291,29 @@ func (p *processJob) Run() {
 		"plugin-config":  p.config,
 	}).Debug("starting processor job")
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
+	content, err := inboundContent(p.parentJob, p.contentType)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-job",
+			"block":           "run",
+			"job-type":        "processor",
+			"content-type":    p.contentType,
+			"plugin-name":     p.pluginName,
+			"plugin-version":  p.pluginVersion,
+			"plugin-config":   p.config,
+			"parent-job-type": p.parentJob.Type(),
+			"error":           err.Error(),
+		}).Error("error converting content for processor job")
+		p.AddErrors(err)
+		return
+	}
+	p.process(content)
+}
 
-	switch p.parentJob.Type() {
-	case collectJobType:
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			metrics := make([]plugin.PluginMetricType, len(p.parentJob.(*collectorJob).metrics))
-			for i, m := range p.parentJob.(*collectorJob).metrics {
-				switch mt := m.(type) {
-				case plugin.PluginMetricType:
-					metrics[i] = mt
-				default:
-					panic("unsupported type")
-				}
-			}
-			enc.Encode(metrics)
-			_, content, errs := p.processor.ProcessMetrics(p.contentType, buf.Bytes(), p.pluginName, p.pluginVersion, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "processor",
-						"content-type":   p.contentType,
-						"plugin-name":    p.pluginName,
-						"plugin-version": p.pluginVersion,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with processor job")
-				}
-				p.AddErrors(errs...)
-			}
-			p.content = content
-		default:
+func (p *processJob) process(content []byte) {
+	contentType, content, errs := p.processor.ProcessMetrics(p.contentType, content, p.pluginName, p.pluginVersion, p.config, p.Deadline(), p.taskID)
+	if errs != nil {
+		for _, e := range errs {
 			log.WithFields(log.Fields{
 				"_module":        "scheduler-job",
 				"block":          "run",
@@ -301,22 +322,18 @@ func (p *processJob) Run() {
 				"plugin-name":    p.pluginName,
 				"plugin-version": p.pluginVersion,
 				"plugin-config":  p.config,
-			}).Fatal("unsupported content type")
-			panic(fmt.Sprintf("unsupported content type. {plugin name: %s version: %v content-type: '%v'}", p.pluginName, p.pluginVersion, p.contentType))
+				"error":          e.Error(),
+			}).Error("error with processor job")
 		}
-	default:
-		log.WithFields(log.Fields{
-			"_module":         "scheduler-job",
-			"block":           "run",
-			"job-type":        "processor",
-			"content-type":    p.contentType,
-			"plugin-name":     p.pluginName,
-			"plugin-version":  p.pluginVersion,
-			"plugin-config":   p.config,
-			"parent-job-type": p.parentJob.Type(),
-		}).Fatal("unsupported parent job type")
-		panic("unsupported parent job type")
+		p.AddErrors(errs...)
+	}
+	// a processor which does not say what it returned is taken to have
+	// returned the content type it was sent
+	if contentType == "" {
+		contentType = p.contentType
 	}
+	p.returnedContentType = contentType
+	p.content = content
 }
 
 type publisherJob struct {
@@ -327,9 +344,17 @@ type publisherJob struct {
 	pluginVersion int
 	config        map[string]ctypes.ConfigValue
 	contentType   string
+	retry         *retryPolicy
+	spool         PublishSpool
+	node          string
+	// attempt is the number of the attempt at the publish the job makes
+	// and retryIn how long to wait before the next one when the job failed
+	// and its retry policy allows another
+	attempt int
+	retryIn time.Duration
 }
 
-func newPublishJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, publisher publishesMetrics, taskID string) job {
+func newPublishJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, publisher publishesMetrics, taskID string, retry *retryPolicy, spool PublishSpool) job {
 	return &publisherJob{
 		parentJob:     parentJob,
 		publisher:     publisher,
@@ -338,7 +363,54 @@ func newPublishJob(parentJob job, pluginName string, pluginVersion int, contentT
 		coreJob:       newCoreJob(publishJobType, parentJob.Deadline(), taskID),
 		config:        config,
 		contentType:   contentType,
+		retry:         retry,
+		spool:         spool,
+		node:          publishNodeID(pluginName, pluginVersion, config),
+		attempt:       1,
+	}
+}
+
+// retryJob returns the job making the next attempt at the publish of p.  It
+// keeps the start time of p, which is when the batch was first published.
+func (p *publisherJob) retryJob() *publisherJob {
+	r := *p
+	r.coreJob = newCoreJob(publishJobType, p.Deadline(), p.taskID)
+	r.starttime = p.StartTime()
+	r.attempt = p.attempt + 1
+	r.retryIn = 0
+	return &r
+}
+
+// workPublishJob works the publish job j with wm and, while the retry policy
+// of the job allows, queues its next attempt once the backoff elapses.  The
+// backoff is waited for here rather than in the job so a publish worker is not
+// held between attempts.
+func workPublishJob(wm managesWork, j job) []error {
+	for {
+		errs := wm.Work(j).Promise().Await()
+		pj, ok := j.(*publisherJob)
+		if !ok || pj.retryIn == 0 {
+			return errs
+		}
+		timer := time.NewTimer(pj.retryIn)
+		<-timer.C
+		j = pj.retryJob()
+	}
+}
+
+// publishNodeID identifies a publish node by its plugin and a hash of its
+// config so a spooled batch is only replayed to the sink it was meant for
+func publishNodeID(pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%q=%T:%v;", k, config[k], config[k])
+	}
+	return fmt.Sprintf("%s:%d:%x", pluginName, pluginVersion, h.Sum64())
 }
 
 func (p *publisherJob) Run() {
@@ -351,72 +423,9 @@ func (p *publisherJob) Run() {
 		"plugin-version": p.pluginVersion,
 		"plugin-config":  p.config,
 	}).Debug("starting publisher job")
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
 
-	switch p.parentJob.Type() {
-	case collectJobType:
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			metrics := make([]plugin.PluginMetricType, len(p.parentJob.(*collectorJob).metrics))
-			for i, m := range p.parentJob.(*collectorJob).metrics {
-				switch mt := m.(type) {
-				case plugin.PluginMetricType:
-					metrics[i] = mt
-				default:
-					panic("unsupported type")
-				}
-			}
-			enc.Encode(metrics)
-			errs := p.publisher.PublishMetrics(p.contentType, buf.Bytes(), p.pluginName, p.pluginVersion, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "publisher",
-						"content-type":   p.contentType,
-						"plugin-name":    p.pluginName,
-						"plugin-version": p.pluginVersion,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with publisher job")
-				}
-				p.AddErrors(errs...)
-			}
-		default:
-			log.WithFields(log.Fields{
-				"_module":        "scheduler-job",
-				"block":          "run",
-				"job-type":       "publisher",
-				"content-type":   p.contentType,
-				"plugin-name":    p.pluginName,
-				"plugin-version": p.pluginVersion,
-				"plugin-config":  p.config,
-			}).Fatal("unsupported content type")
-			panic(fmt.Sprintf("unsupported content type. {plugin name: %s version: %v content-type: '%v'}", p.pluginName, p.pluginVersion, p.contentType))
-		}
-	case processJobType:
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			errs := p.publisher.PublishMetrics(p.contentType, p.parentJob.(*processJob).content, p.pluginName, p.pluginVersion, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "publisher",
-						"content-type":   p.contentType,
-						"plugin-name":    p.pluginName,
-						"plugin-version": p.pluginVersion,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with publisher job")
-				}
-				p.AddErrors(errs...)
-			}
-		}
-	default:
+	content, err := inboundContent(p.parentJob, p.contentType)
+	if err != nil {
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-job",
 			"block":           "run",
@@ -426,7 +435,210 @@ func (p *publisherJob) Run() {
 			"plugin-version":  p.pluginVersion,
 			"plugin-config":   p.config,
 			"parent-job-type": p.parentJob.Type(),
-		}).Fatal("unsupported parent job type")
-		panic("unsupported job type")
+			"error":           err.Error(),
+		}).Error("error converting content for publisher job")
+		p.AddErrors(err)
+		return
+	}
+	p.publish(content)
+}
+
+// publish publishes the content.  When it fails and the retry policy of the
+// job allows another attempt, retryIn is set for workPublishJob to queue it.
+// Content which fails the last attempt is spooled.  After a successful
+// publish the batches spooled for this publisher are replayed.
+func (p *publisherJob) publish(content []byte) {
+	logger := log.WithFields(log.Fields{
+		"_module":        "scheduler-job",
+		"block":          "publish",
+		"job-type":       "publisher",
+		"content-type":   p.contentType,
+		"plugin-name":    p.pluginName,
+		"plugin-version": p.pluginVersion,
+		"plugin-config":  p.config,
+	})
+	errs := p.publisher.PublishMetrics(p.contentType, content, p.pluginName, p.pluginVersion, p.config, p.Deadline(), p.taskID)
+	if len(errs) == 0 {
+		p.replay()
+		return
+	}
+	// a retry must finish before the deadline of the job
+	if p.retry != nil && p.attempt <= p.retry.attempts {
+		wait := p.retry.wait(p.attempt)
+		if !time.Now().Add(wait).After(p.Deadline()) {
+			logger.WithFields(log.Fields{
+				"attempt": p.attempt,
+				"wait":    wait,
+				"error":   errs[len(errs)-1].Error(),
+			}).Warn("retrying publisher job")
+			p.retryIn = wait
+			return
+		}
+	}
+	for _, e := range errs {
+		logger.WithFields(log.Fields{
+			"error": e.Error(),
+		}).Error("error with publisher job")
+	}
+	p.AddErrors(errs...)
+
+	if p.spool == nil || p.retry == nil {
+		return
+	}
+	b := &core.SpooledBatch{
+		TaskID:        p.taskID,
+		PluginName:    p.pluginName,
+		PluginVersion: p.pluginVersion,
+		Node:          p.node,
+		ContentType:   p.contentType,
+		Content:       content,
+		CreationTime:  p.StartTime(),
+		Attempts:      p.attempt,
+		LastError:     errs[len(errs)-1].Error(),
+	}
+	if len(p.config) > 0 {
+		b.Config = cdata.FromTable(p.config)
+	}
+	if err := p.spool.Add(b); err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("unable to spool failed batch")
+		return
+	}
+	logger.WithFields(log.Fields{
+		"batch-id": b.ID,
+	}).Info("failed batch spooled")
+}
+
+// replay publishes the batches spooled for the publish node of this job,
+// oldest first, until one fails or the deadline of the job is reached.  Each
+// batch is claimed first so sibling nodes never replay it twice.  Batches
+// older than the max age of the retry policy are dropped.
+func (p *publisherJob) replay() {
+	if p.spool == nil || p.retry == nil {
+		return
+	}
+	logger := log.WithFields(log.Fields{
+		"_module":        "scheduler-job",
+		"block":          "replay",
+		"job-type":       "publisher",
+		"plugin-name":    p.pluginName,
+		"plugin-version": p.pluginVersion,
+		"node":           p.node,
+	})
+	batches, err := p.spool.List(p.taskID)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("unable to list spooled batches")
+		return
+	}
+	for _, b := range batches {
+		if b.Node != p.node {
+			continue
+		}
+		if p.retry.maxAge > 0 && time.Since(b.CreationTime) > p.retry.maxAge {
+			logger.WithFields(log.Fields{
+				"batch-id": b.ID,
+			}).Warn("dropping expired spooled batch")
+			p.spool.Remove(b.TaskID, b.ID)
+			continue
+		}
+		if time.Now().After(p.Deadline()) {
+			return
+		}
+		if ok, err := p.spool.Claim(b.TaskID, b.ID); !ok {
+			if err != nil {
+				logger.WithFields(log.Fields{
+					"batch-id": b.ID,
+					"error":    err.Error(),
+				}).Error("unable to claim spooled batch")
+			}
+			continue
+		}
+		if errs := p.publisher.PublishMetrics(b.ContentType, b.Content, p.pluginName, p.pluginVersion, p.config, p.Deadline(), p.taskID); len(errs) != 0 {
+			logger.WithFields(log.Fields{
+				"batch-id": b.ID,
+				"error":    errs[len(errs)-1].Error(),
+			}).Warn("unable to replay spooled batch")
+			p.spool.Release(b.TaskID, b.ID)
+			return
+		}
+		logger.WithFields(log.Fields{
+			"batch-id": b.ID,
+		}).Info("spooled batch replayed")
+		p.spool.Remove(b.TaskID, b.ID)
+	}
+}
+
+// retryPolicy is the parsed form of a wmap.RetryPolicy
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+	maxAge   time.Duration
+}
+
+func newRetryPolicy(r *wmap.RetryPolicy) (*retryPolicy, error) {
+	if r == nil {
+		return nil, nil
+	}
+	if r.Attempts < 0 {
+		return nil, ErrInvalidRetryAttempts
+	}
+	rp := &retryPolicy{attempts: r.Attempts}
+	var err error
+	if r.Backoff != "" {
+		if rp.backoff, err = time.ParseDuration(r.Backoff); err != nil {
+			return nil, err
+		}
+	}
+	if r.MaxAge != "" {
+		if rp.maxAge, err = time.ParseDuration(r.MaxAge); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+// wait returns how long to wait before the given retry.  The backoff doubles
+// with each retry.
+func (r *retryPolicy) wait(retry int) time.Duration {
+	return r.backoff * time.Duration(1<<uint(retry-1))
+}
+
+// inboundContent returns the output of a parent job as the content type the
+// node of a child job is sent.  The metrics of a collector job are encoded as
+// snap.gob and the content a processor returned is used as is.  Either one is
+// converted with plugin.SwapPluginMetricContentType when the node was bound
+// to another content type.
+func inboundContent(parentJob job, contentType string) ([]byte, error) {
+	var (
+		from    string
+		content []byte
+	)
+	switch pj := parentJob.(type) {
+	case *collectorJob:
+		metrics := make([]plugin.PluginMetricType, len(pj.metrics))
+		for i, m := range pj.metrics {
+			mt, ok := m.(plugin.PluginMetricType)
+			if !ok {
+				return nil, fmt.Errorf("%v: %T", ErrUnsupportedMetricType, m)
+			}
+			metrics[i] = mt
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(metrics); err != nil {
+			return nil, err
+		}
+		from, content = plugin.SnapGOBContentType, buf.Bytes()
+	case *processJob:
+		from, content = pj.returnedContentType, pj.content
+	default:
+		return nil, fmt.Errorf("%v: %v", ErrUnsupportedParentJobType, parentJob.Type())
+	}
+	if from == contentType {
+		return content, nil
 	}
+	content, _, err := plugin.SwapPluginMetricContentType(from, contentType, content)
+	return content, err
 }
@@ -21,11 +21,17 @@ package scheduler
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
 
 	log "github.com/Sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
@@ -112,3 +118,263 @@ func TestQueuedJob(t *testing.T) {
 		})
 	})
 }
+
+// mockPublisher fails the first failures calls to PublishMetrics and records
+// the content of every successful call
+type mockPublisher struct {
+	sync.Mutex
+	failures  int
+	calls     int
+	published [][]byte
+}
+
+func (m *mockPublisher) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error {
+	m.Lock()
+	defer m.Unlock()
+	m.calls++
+	if m.calls <= m.failures {
+		return []error{errors.New("publish failed")}
+	}
+	m.published = append(m.published, content)
+	return nil
+}
+
+func newTestProcessJob(content string) job {
+	return &processJob{
+		coreJob:             newCoreJob(processJobType, time.Now().Add(defaultDeadline), "taskid"),
+		content:             []byte(content),
+		returnedContentType: plugin.SnapGOBContentType,
+	}
+}
+
+func TestPublisherJob(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("newRetryPolicy()", t, func() {
+		Convey("returns nil without a policy", func() {
+			rp, err := newRetryPolicy(nil)
+			So(err, ShouldBeNil)
+			So(rp, ShouldBeNil)
+		})
+		Convey("parses the durations", func() {
+			rp, err := newRetryPolicy(&wmap.RetryPolicy{Attempts: 3, Backoff: "1s", MaxAge: "1h"})
+			So(err, ShouldBeNil)
+			So(rp.attempts, ShouldEqual, 3)
+			So(rp.wait(1), ShouldEqual, time.Second)
+			So(rp.wait(3), ShouldEqual, 4*time.Second)
+			So(rp.maxAge, ShouldEqual, time.Hour)
+		})
+		Convey("rejects a bad policy", func() {
+			_, err := newRetryPolicy(&wmap.RetryPolicy{Attempts: -1})
+			So(err, ShouldEqual, ErrInvalidRetryAttempts)
+			_, err = newRetryPolicy(&wmap.RetryPolicy{Backoff: "soon"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Run()", t, func() {
+		dir, err := ioutil.TempDir("", "snap-publish-spool-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		spool, err := NewFilePublishSpool(dir)
+		So(err, ShouldBeNil)
+		rp := &retryPolicy{attempts: 2, backoff: time.Millisecond}
+
+		Convey("publishes without a retry policy", func() {
+			mp := &mockPublisher{failures: 1}
+			pj := newPublishJob(newTestProcessJob("a"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", nil, spool)
+			pj.Run()
+			So(mp.calls, ShouldEqual, 1)
+			So(pj.Errors(), ShouldHaveLength, 1)
+			batches, _ := spool.List("taskid")
+			So(batches, ShouldBeEmpty)
+		})
+		Convey("retries a failed publish", func() {
+			mp := &mockPublisher{failures: 2}
+			wm := &runningWorkManager{}
+			pj := newPublishJob(newTestProcessJob("a"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+			errs := workPublishJob(wm, pj)
+			So(mp.calls, ShouldEqual, 3)
+			So(wm.jobs, ShouldEqual, 3)
+			So(errs, ShouldBeEmpty)
+			So(mp.published, ShouldResemble, [][]byte{[]byte("a")})
+		})
+		Convey("leaves the retry to the caller", func() {
+			mp := &mockPublisher{failures: 1}
+			pj := newPublishJob(newTestProcessJob("a"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+			pj.Run()
+			So(mp.calls, ShouldEqual, 1)
+			So(pj.Errors(), ShouldBeEmpty)
+			So(pj.(*publisherJob).retryIn, ShouldEqual, time.Millisecond)
+			next := pj.(*publisherJob).retryJob()
+			So(next.attempt, ShouldEqual, 2)
+			So(next.retryIn, ShouldEqual, 0)
+			So(next.StartTime(), ShouldResemble, pj.StartTime())
+		})
+		Convey("spools a batch which fails every retry", func() {
+			mp := &mockPublisher{failures: 3}
+			pj := newPublishJob(newTestProcessJob("a"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+			errs := workPublishJob(&runningWorkManager{}, pj)
+			So(mp.calls, ShouldEqual, 3)
+			So(errs, ShouldHaveLength, 1)
+			batches, err := spool.List("taskid")
+			So(err, ShouldBeNil)
+			So(batches, ShouldHaveLength, 1)
+			So(batches[0].Content, ShouldResemble, []byte("a"))
+			So(batches[0].PluginName, ShouldEqual, "pub")
+			So(batches[0].Node, ShouldEqual, publishNodeID("pub", 1, nil))
+			So(batches[0].Attempts, ShouldEqual, 3)
+			So(batches[0].LastError, ShouldEqual, "publish failed")
+
+			Convey("and replays it after the next successful publish", func() {
+				mp.calls = 0
+				mp.failures = 0
+				pj := newPublishJob(newTestProcessJob("b"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+				pj.Run()
+				So(pj.Errors(), ShouldBeEmpty)
+				So(mp.published, ShouldResemble, [][]byte{[]byte("b"), []byte("a")})
+				batches, _ := spool.List("taskid")
+				So(batches, ShouldBeEmpty)
+			})
+			Convey("but not for another publisher", func() {
+				mp.failures = 0
+				pj := newPublishJob(newTestProcessJob("b"), "other", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+				pj.Run()
+				batches, _ := spool.List("taskid")
+				So(batches, ShouldHaveLength, 1)
+			})
+			Convey("but not for a node of the plugin with another config", func() {
+				mp.failures = 0
+				cfg := map[string]ctypes.ConfigValue{"file": ctypes.ConfigValueStr{Value: "/tmp/other"}}
+				pj := newPublishJob(newTestProcessJob("b"), "pub", 1, plugin.SnapGOBContentType, cfg, mp, "taskid", rp, spool)
+				pj.Run()
+				So(mp.published, ShouldResemble, [][]byte{[]byte("b")})
+				batches, _ := spool.List("taskid")
+				So(batches, ShouldHaveLength, 1)
+			})
+			Convey("only once by sibling nodes replaying together", func() {
+				mp.calls = 0
+				mp.failures = 0
+				var wg sync.WaitGroup
+				for _, c := range []string{"b", "c"} {
+					pj := newPublishJob(newTestProcessJob(c), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						pj.Run()
+					}()
+				}
+				wg.Wait()
+				So(mp.calls, ShouldEqual, 3)
+				batches, _ := spool.List("taskid")
+				So(batches, ShouldBeEmpty)
+			})
+			Convey("and drops it once it expires", func() {
+				mp.calls = 0
+				mp.failures = 0
+				batches[0].CreationTime = time.Now().Add(-time.Hour)
+				So(spool.Add(batches[0]), ShouldBeNil)
+				rp := &retryPolicy{attempts: 2, backoff: time.Millisecond, maxAge: time.Minute}
+				pj := newPublishJob(newTestProcessJob("b"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+				pj.Run()
+				So(mp.published, ShouldResemble, [][]byte{[]byte("b")})
+				batches, _ := spool.List("taskid")
+				So(batches, ShouldBeEmpty)
+			})
+		})
+		Convey("does not retry past the deadline", func() {
+			mp := &mockPublisher{failures: 3}
+			rp := &retryPolicy{attempts: 2, backoff: time.Minute}
+			pj := newPublishJob(newTestProcessJob("a"), "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", rp, spool)
+			errs := workPublishJob(&runningWorkManager{}, pj)
+			So(mp.calls, ShouldEqual, 1)
+			So(errs, ShouldHaveLength, 1)
+			batches, _ := spool.List("taskid")
+			So(batches, ShouldHaveLength, 1)
+			So(batches[0].Attempts, ShouldEqual, 1)
+		})
+	})
+}
+
+// runningWorkManager runs each job it is given before returning it
+type runningWorkManager struct {
+	jobs int
+}
+
+func (m *runningWorkManager) Work(j job) queuedJob {
+	m.jobs++
+	j.Run()
+	qj := newQueuedJob(j)
+	qj.Promise().Complete(j.Errors())
+	return qj
+}
+
+type mockProcessor struct {
+	contentType string
+	returned    string
+}
+
+func (m *mockProcessor) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error) {
+	m.contentType = contentType
+	return m.returned, content, nil
+}
+
+func TestJobContentTypes(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Jobs convert content between content types", t, func() {
+		cj := &collectorJob{
+			coreJob: newCoreJob(collectJobType, time.Now().Add(defaultDeadline), "taskid"),
+			metrics: []core.Metric{
+				*plugin.NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "host", nil, nil, 1),
+			},
+		}
+
+		Convey("from a collector to a snap.json publisher", func() {
+			mp := &mockPublisher{}
+			pj := newPublishJob(cj, "pub", 1, plugin.SnapJSONContentType, nil, mp, "taskid", nil, nil)
+			pj.Run()
+			So(pj.Errors(), ShouldBeEmpty)
+			So(mp.published, ShouldHaveLength, 1)
+			mts, err := plugin.UnmarshallPluginMetricTypes(plugin.SnapJSONContentType, mp.published[0])
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, []string{"foo", "bar"})
+		})
+		Convey("from a processor to a snap.json processor", func() {
+			content, err := inboundContent(cj, plugin.SnapGOBContentType)
+			So(err, ShouldBeNil)
+			mpr := &mockProcessor{}
+			prj := newProcessJob(newTestProcessJob(string(content)), "pr", 1, plugin.SnapJSONContentType, nil, mpr, "taskid")
+			prj.Run()
+			So(prj.Errors(), ShouldBeEmpty)
+			So(mpr.contentType, ShouldEqual, plugin.SnapJSONContentType)
+			mts, err := plugin.UnmarshallPluginMetricTypes(plugin.SnapJSONContentType, prj.(*processJob).content)
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			// a processor which does not say what it returned is taken to
+			// have returned what it was sent
+			So(prj.(*processJob).returnedContentType, ShouldEqual, plugin.SnapJSONContentType)
+		})
+		Convey("and fail without panicking when they cannot", func() {
+			parent := newTestProcessJob("a")
+			parent.(*processJob).returnedContentType = "foo.bar"
+			mp := &mockPublisher{}
+			pj := newPublishJob(parent, "pub", 1, plugin.SnapGOBContentType, nil, mp, "taskid", nil, nil)
+			So(pj.Run, ShouldNotPanic)
+			So(pj.Errors(), ShouldHaveLength, 1)
+			So(mp.calls, ShouldEqual, 0)
+
+			mpr := &mockProcessor{}
+			prj := newProcessJob(parent, "pr", 1, plugin.SnapJSONContentType, nil, mpr, "taskid")
+			So(prj.Run, ShouldNotPanic)
+			So(prj.Errors(), ShouldHaveLength, 1)
+		})
+		Convey("but pass on a content type which needs no conversion", func() {
+			parent := newTestProcessJob("a")
+			parent.(*processJob).returnedContentType = "foo.bar"
+			mp := &mockPublisher{}
+			pj := newPublishJob(parent, "pub", 1, "foo.bar", nil, mp, "taskid", nil, nil)
+			pj.Run()
+			So(pj.Errors(), ShouldBeEmpty)
+			So(mp.published, ShouldResemble, [][]byte{[]byte("a")})
+		})
+	})
+}
@@ -0,0 +1,238 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// PublishSpool holds the batches of metrics which could not be published
+// so they can be replayed when the publisher recovers.
+type PublishSpool interface {
+	// Add puts a batch in the spool.  The batch is given an id if it has none.
+	Add(*core.SpooledBatch) error
+	// List returns the batches of a task, oldest first
+	List(taskID string) ([]*core.SpooledBatch, error)
+	// Remove deletes a single batch of a task
+	Remove(taskID, id string) error
+	// Claim takes a batch of a task for replay so no other publisher
+	// replays it.  False is returned if the batch is claimed or gone.
+	Claim(taskID, id string) (bool, error)
+	// Release puts a claimed batch back in the spool
+	Release(taskID, id string) error
+	// Purge deletes every batch of a task and returns how many were deleted
+	Purge(taskID string) (int, error)
+}
+
+// claimedPrefix is added to the file of a batch while it is replayed
+const claimedPrefix = ".claimed-"
+
+// filePublishSpool keeps each batch as a JSON document in a directory per
+// task.
+type filePublishSpool struct {
+	sync.Mutex
+	path string
+}
+
+// NewFilePublishSpool returns a PublishSpool which keeps batches in the
+// directory at path.  The directory is created if it does not exist.
+// Batches claimed when snapd last stopped are put back in the spool.
+func NewFilePublishSpool(path string) (PublishSpool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	f := &filePublishSpool{path: path}
+	if err := f.releaseClaims(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *filePublishSpool) batchPath(taskID, id string) string {
+	return filepath.Join(f.path, taskID, id+".json")
+}
+
+// claimedPath is hidden so claimed batches are not listed
+func (f *filePublishSpool) claimedPath(taskID, id string) string {
+	return filepath.Join(f.path, taskID, claimedPrefix+id+".json")
+}
+
+func (f *filePublishSpool) releaseClaims() error {
+	tasks, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		if !t.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(f.path, t.Name()))
+		if err != nil {
+			return err
+		}
+		for _, fi := range files {
+			if !strings.HasPrefix(fi.Name(), claimedPrefix) {
+				continue
+			}
+			id := strings.TrimSuffix(strings.TrimPrefix(fi.Name(), claimedPrefix), ".json")
+			if err := os.Rename(f.claimedPath(t.Name(), id), f.batchPath(t.Name(), id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *filePublishSpool) Add(b *core.SpooledBatch) error {
+	if b.ID == "" {
+		b.ID = uuid.New()
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	f.Lock()
+	defer f.Unlock()
+	dir := filepath.Join(f.path, b.TaskID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves a partial batch
+	tmp, err := ioutil.TempFile(dir, ".batch-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.batchPath(b.TaskID, b.ID))
+}
+
+func (f *filePublishSpool) List(taskID string) ([]*core.SpooledBatch, error) {
+	f.Lock()
+	defer f.Unlock()
+	files, err := ioutil.ReadDir(filepath.Join(f.path, taskID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var batches []*core.SpooledBatch
+	for _, fi := range files {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(f.path, taskID, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b := &core.SpooledBatch{}
+		if err := json.Unmarshal(data, b); err != nil {
+			schedulerLogger.WithFields(log.Fields{
+				"_block":  "list-spooled-batches",
+				"task-id": taskID,
+				"file":    fi.Name(),
+				"_error":  err.Error(),
+			}).Error("unable to read spooled batch")
+			continue
+		}
+		batches = append(batches, b)
+	}
+	sort.Sort(byCreationTime(batches))
+	return batches, nil
+}
+
+func (f *filePublishSpool) Remove(taskID, id string) error {
+	f.Lock()
+	defer f.Unlock()
+	for _, path := range []string{f.batchPath(taskID, id), f.claimedPath(taskID, id)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *filePublishSpool) Claim(taskID, id string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	err := os.Rename(f.batchPath(taskID, id), f.claimedPath(taskID, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (f *filePublishSpool) Release(taskID, id string) error {
+	f.Lock()
+	defer f.Unlock()
+	err := os.Rename(f.claimedPath(taskID, id), f.batchPath(taskID, id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *filePublishSpool) Purge(taskID string) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	dir := filepath.Join(f.path, taskID)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var n int
+	for _, fi := range files {
+		if filepath.Ext(fi.Name()) == ".json" && !strings.HasPrefix(fi.Name(), ".") {
+			n++
+		}
+	}
+	return n, os.RemoveAll(dir)
+}
+
+type byCreationTime []*core.SpooledBatch
+
+func (b byCreationTime) Len() int           { return len(b) }
+func (b byCreationTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCreationTime) Less(i, j int) bool { return b[i].CreationTime.Before(b[j].CreationTime) }
@@ -0,0 +1,122 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestFilePublishSpool(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("File publish spool", t, func() {
+		dir, err := ioutil.TempDir("", "snap-publish-spool-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ps, err := NewFilePublishSpool(dir)
+		So(err, ShouldBeNil)
+
+		Convey("lists nothing for an unknown task", func() {
+			batches, err := ps.List("nope")
+			So(err, ShouldBeNil)
+			So(batches, ShouldBeEmpty)
+		})
+		Convey("adds, lists and removes batches", func() {
+			now := time.Now()
+			b1 := &core.SpooledBatch{TaskID: "t1", PluginName: "file", Content: []byte("1"), CreationTime: now}
+			b2 := &core.SpooledBatch{TaskID: "t1", PluginName: "file", Content: []byte("2"), CreationTime: now.Add(-time.Minute)}
+			So(ps.Add(b1), ShouldBeNil)
+			So(ps.Add(b2), ShouldBeNil)
+			So(b1.ID, ShouldNotBeEmpty)
+			So(ps.Add(&core.SpooledBatch{TaskID: "t2"}), ShouldBeNil)
+
+			batches, err := ps.List("t1")
+			So(err, ShouldBeNil)
+			So(batches, ShouldHaveLength, 2)
+			// oldest first
+			So(batches[0].Content, ShouldResemble, []byte("2"))
+			So(batches[1].ID, ShouldEqual, b1.ID)
+
+			So(ps.Remove("t1", b2.ID), ShouldBeNil)
+			So(ps.Remove("t1", b2.ID), ShouldBeNil)
+			batches, _ = ps.List("t1")
+			So(batches, ShouldHaveLength, 1)
+
+			Convey("and purges the batches of a task", func() {
+				n, err := ps.Purge("t1")
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 1)
+				batches, _ := ps.List("t1")
+				So(batches, ShouldBeEmpty)
+				batches, _ = ps.List("t2")
+				So(batches, ShouldHaveLength, 1)
+			})
+		})
+		Convey("claims and releases batches", func() {
+			b := &core.SpooledBatch{TaskID: "t1"}
+			So(ps.Add(b), ShouldBeNil)
+			ok, err := ps.Claim("t1", b.ID)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			// a claimed batch is neither listed nor claimed again
+			batches, _ := ps.List("t1")
+			So(batches, ShouldBeEmpty)
+			ok, err = ps.Claim("t1", b.ID)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+
+			So(ps.Release("t1", b.ID), ShouldBeNil)
+			batches, _ = ps.List("t1")
+			So(batches, ShouldHaveLength, 1)
+
+			Convey("and puts back the batches claimed when it was last used", func() {
+				ok, _ := ps.Claim("t1", b.ID)
+				So(ok, ShouldBeTrue)
+				ps, err := NewFilePublishSpool(dir)
+				So(err, ShouldBeNil)
+				batches, _ := ps.List("t1")
+				So(batches, ShouldHaveLength, 1)
+			})
+			Convey("and removes a claimed batch", func() {
+				ok, _ := ps.Claim("t1", b.ID)
+				So(ok, ShouldBeTrue)
+				So(ps.Remove("t1", b.ID), ShouldBeNil)
+				So(ps.Release("t1", b.ID), ShouldBeNil)
+				batches, _ := ps.List("t1")
+				So(batches, ShouldBeEmpty)
+			})
+		})
+		Convey("skips a corrupt batch", func() {
+			So(ps.Add(&core.SpooledBatch{TaskID: "t1"}), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(dir, "t1", "bad.json"), []byte("{"), 0600), ShouldBeNil)
+			batches, err := ps.List("t1")
+			So(err, ShouldBeNil)
+			So(batches, ShouldHaveLength, 1)
+		})
+	})
+}
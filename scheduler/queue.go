@@ -156,6 +156,13 @@ func (q *queue) handle() {
 	}
 }
 
+// depth returns the number of jobs waiting in the queue
+func (q *queue) depth() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.length()
+}
+
 func (q *queue) length() int {
 	return len(q.items)
 }
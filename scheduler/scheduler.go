@@ -50,6 +50,8 @@ var (
 	ErrTaskAlreadyStopped = errors.New("Task is already stopped.")
 	// ErrTaskDisabledNotRunnable - The error message for task is disabled and cannot be started
 	ErrTaskDisabledNotRunnable = errors.New("Task is disabled. Cannot be started.")
+	// ErrPublishSpoolDisabled - The error message for the publish spool is not set
+	ErrPublishSpoolDisabled = errors.New("Publish spool is not enabled.")
 )
 
 type schedulerState int
@@ -67,6 +69,7 @@ type managesMetrics interface {
 	processesMetrics
 	managesPluginContentTypes
 	ValidateDeps([]core.Metric, []core.SubscribedPlugin) []serror.SnapError
+	GatherCollectors([]core.Metric) ([]core.Plugin, []serror.SnapError)
 	SubscribeDeps(string, []core.Metric, []core.Plugin) []serror.SnapError
 	UnsubscribeDeps(string, []core.Metric, []core.Plugin) []serror.SnapError
 }
@@ -81,11 +84,11 @@ type collectsMetrics interface {
 }
 
 type publishesMetrics interface {
-	PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error
+	PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error
 }
 
 type processesMetrics interface {
-	ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error)
+	ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error)
 }
 
 type scheduler struct {
@@ -96,6 +99,8 @@ type scheduler struct {
 	logger          *log.Entry
 	eventManager    *gomit.EventController
 	taskWatcherColl *taskWatcherCollection
+	taskStore       TaskStore
+	publishSpool    PublishSpool
 }
 
 type managesWork interface {
@@ -178,15 +183,12 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 	// Generate a workflow from the workflow map
 	wf, err := wmapToWorkflow(wfMap)
 	if err != nil {
-		te.errs = append(te.errs, serror.New(err))
+		te.errs = append(te.errs, workflowErrors(err)...)
 		f := buildErrorsLog(te.Errors(), logger)
 		f.Error(ErrSchedulerNotStarted.Error())
 		return nil, te
 	}
 
-	// Bind plugin content type selections in workflow
-	err = wf.BindPluginContentTypes(s.metricManager)
-
 	// validate plugins and metrics
 	mts, plugins := s.gatherMetricsAndPlugins(wf)
 	errs := s.metricManager.ValidateDeps(mts, plugins)
@@ -195,8 +197,17 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 		return nil, te
 	}
 
+	// Bind plugin content type selections in workflow
+	if err := wf.BindPluginContentTypes(s.metricManager); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("workflow content types not valid")
+		return nil, te
+	}
+
 	// Create the task object
 	task := newTask(sch, wf, s.workManager, s.metricManager, s.eventManager, opts...)
+	task.spool = s.publishSpool
 
 	// Add task to taskCollection
 	if err := s.tasks.add(task); err != nil {
@@ -210,6 +221,7 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 		"task-id":    task.ID(),
 		"task-state": task.State(),
 	}).Info("task created")
+	s.persistTask(task)
 
 	event := &scheduler_event.TaskCreatedEvent{
 		TaskID:        task.id,
@@ -233,6 +245,37 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 	return task, te
 }
 
+// ValidateTask checks a schedule and workflow map the way CreateTask does
+// without creating a task.  A nil schedule is not checked.
+func (s *scheduler) ValidateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap) core.TaskErrors {
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+	if s.state != schedulerStarted {
+		te.errs = append(te.errs, serror.New(ErrSchedulerNotStarted))
+		return te
+	}
+	if sch != nil {
+		if err := sch.Validate(); err != nil {
+			te.errs = append(te.errs, serror.New(err))
+		}
+	}
+	wf, err := wmapToWorkflow(wfMap)
+	if err != nil {
+		te.errs = append(te.errs, workflowErrors(err)...)
+		return te
+	}
+	mts, plugins := s.gatherMetricsAndPlugins(wf)
+	if errs := s.metricManager.ValidateDeps(mts, plugins); len(errs) > 0 {
+		te.errs = append(te.errs, errs...)
+		return te
+	}
+	if err := wf.BindPluginContentTypes(s.metricManager); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+	}
+	return te
+}
+
 // RemoveTask given a tasks id.  The task must be stopped.
 // Can return errors ErrTaskNotFound and ErrTaskNotStopped.
 func (s *scheduler) RemoveTask(id string) error {
@@ -255,12 +298,187 @@ func (s *scheduler) removeTask(id, source string) error {
 		}).Error(ErrTaskNotFound)
 		return err
 	}
+	if err := s.tasks.remove(t); err != nil {
+		return err
+	}
 	event := &scheduler_event.TaskDeletedEvent{
 		TaskID: t.id,
 		Source: source,
 	}
 	defer s.eventManager.Emit(event)
-	return s.tasks.remove(t)
+	s.unpersistTask(t.id)
+	if s.publishSpool != nil {
+		if _, err := s.publishSpool.Purge(t.id); err != nil {
+			logger.WithFields(log.Fields{
+				"task-id": t.id,
+				"_error":  err.Error(),
+			}).Error("unable to purge the publish spool of the task")
+		}
+	}
+	return nil
+}
+
+// UpdateTask replaces the schedule and/or workflow of an existing task and
+// applies the given options (e.g. name or deadline) while keeping its id,
+// counters and watchers.  A nil schedule or workflow map leaves the current
+// one in place.  For a running task only the plugins that changed are
+// subscribed or unsubscribed and the swap happens between fires.
+func (s *scheduler) UpdateTask(id string, sch schedule.Schedule, wfMap *wmap.WorkflowMap, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+	return s.updateTask(id, sch, wfMap, "user", opts...)
+}
+
+func (s *scheduler) updateTask(id string, sch schedule.Schedule, wfMap *wmap.WorkflowMap, source string, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+	logger := s.logger.WithFields(log.Fields{
+		"_block":  "update-task",
+		"source":  source,
+		"task-id": id,
+	})
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+
+	t, err := s.getTask(id)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error(ErrTaskNotFound)
+		return nil, te
+	}
+
+	if sch != nil {
+		if err := sch.Validate(); err != nil {
+			te.errs = append(te.errs, serror.New(err))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("schedule passed not valid")
+			return nil, te
+		}
+	}
+
+	var wf *schedulerWorkflow
+	if wfMap != nil {
+		wf, err = wmapToWorkflow(wfMap)
+		if err != nil {
+			te.errs = append(te.errs, workflowErrors(err)...)
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("workflow passed not valid")
+			return nil, te
+		}
+		mts, plugins := s.gatherMetricsAndPlugins(wf)
+		if errs := s.metricManager.ValidateDeps(mts, plugins); len(errs) > 0 {
+			te.errs = append(te.errs, errs...)
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("workflow dependencies not valid")
+			return nil, te
+		}
+		if err := wf.BindPluginContentTypes(s.metricManager); err != nil {
+			te.errs = append(te.errs, serror.New(err))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("workflow content types not valid")
+			return nil, te
+		}
+	}
+
+	// Holding the task lock keeps the task from firing while its
+	// subscriptions and workflow are swapped.
+	t.Lock()
+	if wf != nil && t.state == core.TaskSpinning {
+		if errs := s.updateDeps(t.id, t.workflow, wf); len(errs) > 0 {
+			t.Unlock()
+			te.errs = append(te.errs, errs...)
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error("error updating task subscriptions")
+			return nil, te
+		}
+	}
+	t.update(sch, wf, opts...)
+	t.Unlock()
+
+	logger.WithFields(log.Fields{
+		"task-state": t.State(),
+	}).Info("task updated")
+	s.persistTask(t)
+
+	event := &scheduler_event.TaskUpdatedEvent{
+		TaskID: t.id,
+		Source: source,
+	}
+	defer s.eventManager.Emit(event)
+	return t, te
+}
+
+// updateDeps subscribes the plugins the new workflow adds and unsubscribes
+// the ones it no longer uses, leaving the subscriptions to the plugins both
+// workflows use untouched.  On failure the subscriptions of the old workflow
+// are restored.
+func (s *scheduler) updateDeps(taskID string, oldWf, newWf *schedulerWorkflow) []serror.SnapError {
+	oldPlugins, errs := s.workflowPlugins(oldWf)
+	if len(errs) > 0 {
+		return errs
+	}
+	newPlugins, errs := s.workflowPlugins(newWf)
+	if len(errs) > 0 {
+		return errs
+	}
+	added, removed := diffPlugins(oldPlugins, newPlugins)
+
+	if len(removed) > 0 {
+		if errs := s.metricManager.UnsubscribeDeps(taskID, nil, removed); len(errs) > 0 {
+			return errs
+		}
+	}
+	if len(added) > 0 {
+		if errs := s.metricManager.SubscribeDeps(taskID, nil, added); len(errs) > 0 {
+			rollback := s.metricManager.UnsubscribeDeps(taskID, nil, added)
+			if len(removed) > 0 {
+				rollback = append(rollback, s.metricManager.SubscribeDeps(taskID, nil, removed)...)
+			}
+			for _, e := range rollback {
+				errs = append(errs, serror.New(fmt.Errorf("restoring the subscriptions of the task: %v", e), e.Fields()))
+			}
+			return errs
+		}
+	}
+	return nil
+}
+
+// workflowPlugins returns the collectors of the metrics of the workflow and
+// its processors and publishers, as they are subscribed to
+func (s *scheduler) workflowPlugins(wf *schedulerWorkflow) ([]core.Plugin, []serror.SnapError) {
+	mts, plugins := s.gatherMetricsAndPlugins(wf)
+	collectors, errs := s.metricManager.GatherCollectors(mts)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return append(collectors, returnCorePlugin(plugins)...), nil
+}
+
+// diffPlugins returns the plugins in b which are not in a and the plugins in
+// a which are not in b
+func diffPlugins(a, b []core.Plugin) (added, removed []core.Plugin) {
+	key := func(p core.Plugin) string {
+		return fmt.Sprintf("%s:%s:%d", p.TypeName(), p.Name(), p.Version())
+	}
+	inA := make(map[string]bool)
+	for _, p := range a {
+		inA[key(p)] = true
+	}
+	inB := make(map[string]bool)
+	for _, p := range b {
+		if inB[key(p)] {
+			continue
+		}
+		inB[key(p)] = true
+		if !inA[key(p)] {
+			added = append(added, p)
+		}
+	}
+	for _, p := range a {
+		if !inB[key(p)] {
+			inB[key(p)] = true
+			removed = append(removed, p)
+		}
+	}
+	return added, removed
 }
 
 // GetTasks returns a copy of the tasks in a map where the task id is the key
@@ -330,6 +548,18 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 		}
 	}
 
+	// The content types of a restored task could not be bound if its plugins
+	// were not loaded yet so they are bound again with the plugins loaded now
+	if err := t.workflow.BindPluginContentTypes(s.metricManager); err != nil {
+		logger.WithFields(log.Fields{
+			"task-id": t.ID(),
+			"_error":  err.Error(),
+		}).Error("task failed to start due to content types")
+		return []serror.SnapError{
+			serror.New(err),
+		}
+	}
+
 	mts, plugins := s.gatherMetricsAndPlugins(t.workflow)
 	cps := returnCorePlugin(plugins)
 	serrs := s.metricManager.SubscribeDeps(t.ID(), mts, cps)
@@ -354,6 +584,7 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 		"task-id":    t.ID(),
 		"task-state": t.State(),
 	}).Info("task started")
+	s.persistTask(t)
 	return nil
 }
 
@@ -409,6 +640,7 @@ func (s *scheduler) stopTask(id, source string) []serror.SnapError {
 		"task-id":    t.ID(),
 		"task-state": t.State(),
 	}).Info("task stopped")
+	s.persistTask(t)
 	return nil
 }
 
@@ -438,10 +670,14 @@ func (s *scheduler) EnableTask(id string) (core.Task, error) {
 		"task-id":    t.ID(),
 		"task-state": t.State(),
 	}).Info("task enabled")
+	s.persistTask(t)
 	return t, nil
 }
 
 // Start starts the scheduler
+// Start starts the scheduler.  The tasks in the task store are not restored
+// by Start as the plugins they depend on are usually loaded after the
+// scheduler is started; RestoreTasks must be called once they are.
 func (s *scheduler) Start() error {
 	if s.metricManager == nil {
 		s.logger.WithFields(log.Fields{
@@ -477,6 +713,56 @@ func (s *scheduler) SetMetricManager(mm managesMetrics) {
 	}).Debug("metric manager linked")
 }
 
+// SetTaskStore sets the store tasks are persisted in. Tasks in the store are
+// recreated by RestoreTasks.
+func (s *scheduler) SetTaskStore(ts TaskStore) {
+	s.taskStore = ts
+	s.logger.WithFields(log.Fields{
+		"_block": "set-task-store",
+	}).Debug("task store linked")
+}
+
+// SetPublishSpool sets the spool batches which could not be published are kept
+// in until they are replayed.
+func (s *scheduler) SetPublishSpool(ps PublishSpool) {
+	s.publishSpool = ps
+	s.logger.WithFields(log.Fields{
+		"_block": "set-publish-spool",
+	}).Debug("publish spool linked")
+}
+
+// GetTaskSpool returns the batches spooled for a task, oldest first.
+func (s *scheduler) GetTaskSpool(id string) ([]*core.SpooledBatch, error) {
+	if _, err := s.getTask(id); err != nil {
+		return nil, err
+	}
+	if s.publishSpool == nil {
+		return nil, ErrPublishSpoolDisabled
+	}
+	return s.publishSpool.List(id)
+}
+
+// PurgeTaskSpool drops the batches spooled for a task and returns how many
+// were dropped.
+func (s *scheduler) PurgeTaskSpool(id string) (int, error) {
+	if _, err := s.getTask(id); err != nil {
+		return 0, err
+	}
+	if s.publishSpool == nil {
+		return 0, ErrPublishSpoolDisabled
+	}
+	n, err := s.publishSpool.Purge(id)
+	if err != nil {
+		return n, err
+	}
+	s.logger.WithFields(log.Fields{
+		"_block":  "purge-task-spool",
+		"task-id": id,
+		"purged":  n,
+	}).Info("publish spool purged")
+	return n, nil
+}
+
 //
 func (s *scheduler) WatchTask(id string, tw core.TaskWatcherHandler) (core.TaskWatcherCloser, error) {
 	task, err := s.getTask(id)
@@ -541,7 +827,19 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 		mts, plugins := s.gatherMetricsAndPlugins(task.workflow)
 		cps := returnCorePlugin(plugins)
 		s.metricManager.UnsubscribeDeps(task.ID(), mts, cps)
+		s.persistTask(task)
 		s.taskWatcherColl.handleTaskDisabled(v.TaskID, v.Why)
+	case *scheduler_event.TaskEndedEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"task-id":         v.TaskID,
+		}).Debug("event received")
+		// The schedule of the task ended so it is not restarted with snapd
+		if task, err := s.getTask(v.TaskID); err == nil {
+			s.persistTask(task)
+		}
 	default:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -551,6 +849,143 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 	}
 }
 
+// persistTask saves the task to the task store if one is set
+func (s *scheduler) persistTask(t *task) {
+	if s.taskStore == nil {
+		return
+	}
+	r, err := newTaskRecord(t)
+	if err == nil {
+		err = s.taskStore.Save(r)
+	}
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			"_block":  "persist-task",
+			"_error":  err.Error(),
+			"task-id": t.id,
+		}).Error("error persisting task")
+	}
+}
+
+// unpersistTask removes the task from the task store if one is set
+func (s *scheduler) unpersistTask(id string) {
+	if s.taskStore == nil {
+		return
+	}
+	if err := s.taskStore.Remove(id); err != nil {
+		s.logger.WithFields(log.Fields{
+			"_block":  "unpersist-task",
+			"_error":  err.Error(),
+			"task-id": id,
+		}).Error("error removing persisted task")
+	}
+}
+
+// RestoreTasks recreates the tasks in the task store with their original ids
+// and starts the ones which were running.  It must be called after Start and
+// after the plugins the tasks depend on are loaded.
+func (s *scheduler) RestoreTasks() {
+	if s.taskStore == nil || s.state != schedulerStarted {
+		return
+	}
+	logger := s.logger.WithFields(log.Fields{
+		"_block": "restore-tasks",
+	})
+	records, err := s.taskStore.Load()
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"_error": err.Error(),
+		}).Error("error loading persisted tasks")
+		return
+	}
+	for _, r := range records {
+		t, err := s.restoreTask(r)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"_error":  err.Error(),
+				"task-id": r.ID,
+			}).Error("error restoring task")
+			continue
+		}
+		logger.WithFields(log.Fields{
+			"task-id":    t.ID(),
+			"task-name":  t.GetName(),
+			"task-state": r.State,
+		}).Info("task restored")
+		if r.State == core.TaskSpinning.String() {
+			if errs := s.startTask(t.ID(), "user"); errs != nil {
+				buildErrorsLog(errs, logger).WithFields(log.Fields{
+					"task-id": t.ID(),
+				}).Warn("restored task could not be started")
+			}
+		}
+	}
+}
+
+func (s *scheduler) restoreTask(r *TaskRecord) (*task, error) {
+	if r.Schedule == nil {
+		return nil, ErrMissingTaskRecordSchedule
+	}
+	sch, err := r.Schedule.Schedule()
+	if err != nil {
+		return nil, err
+	}
+	wf, err := wmapToWorkflow(r.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	// The plugins of a restored task may not be loaded yet so an error
+	// binding its content types is not fatal.  They are bound again when the
+	// task is started.
+	if err := wf.BindPluginContentTypes(s.metricManager); err != nil {
+		s.logger.WithFields(log.Fields{
+			"_block":  "restore-task",
+			"_error":  err.Error(),
+			"task-id": r.ID,
+		}).Warn("content types of restored task could not be bound")
+	}
+
+	opts := []core.TaskOption{
+		core.SetTaskID(r.ID),
+		core.SetTaskName(r.Name),
+		core.OptionStopOnFailure(r.StopOnFailure),
+	}
+	if r.Deadline != "" {
+		dl, err := time.ParseDuration(r.Deadline)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.TaskDeadlineDuration(dl))
+	}
+	t := newTask(sch, wf, s.workManager, s.metricManager, s.eventManager, opts...)
+	t.creationTime = r.CreationTime
+	t.spool = s.publishSpool
+	switch r.State {
+	case core.TaskDisabled.String():
+		t.state = core.TaskDisabled
+	case core.TaskEnded.String():
+		t.state = core.TaskEnded
+	}
+	if err := s.tasks.add(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// workflowErrors converts the error from building a workflow into snap
+// errors with one for each problem found validating the workflow map.
+func workflowErrors(err error) []serror.SnapError {
+	verrs, ok := err.(wmap.ValidationErrors)
+	if !ok {
+		return []serror.SnapError{serror.New(err)}
+	}
+	errs := make([]serror.SnapError, len(verrs))
+	for i, e := range verrs {
+		errs[i] = serror.New(e, map[string]interface{}{"path": e.Path})
+	}
+	return errs
+}
+
 func (s *scheduler) getTask(id string) (*task, error) {
 	task := s.tasks.Get(id)
 	if task == nil {
@@ -22,12 +22,17 @@ package scheduler
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -83,11 +88,11 @@ func (m *mockMetricManager) CollectMetrics([]core.Metric, time.Time, string) ([]
 	return nil, nil
 }
 
-func (m *mockMetricManager) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+func (m *mockMetricManager) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error {
 	return nil
 }
 
-func (m *mockMetricManager) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+func (m *mockMetricManager) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error) {
 	return "", nil, nil
 }
 
@@ -109,6 +114,65 @@ func (m *mockMetricManager) UnsubscribeDeps(taskID string, mts []core.Metric, pr
 	return nil
 }
 
+// GatherCollectors has each metric collected by a collector named after the
+// first element of its namespace
+func (m *mockMetricManager) GatherCollectors(mts []core.Metric) ([]core.Plugin, []serror.SnapError) {
+	var collectors []core.Plugin
+	for _, mt := range mts {
+		collectors = append(collectors, mockPlugin{typ: "collector", name: mt.Namespace()[0], version: mt.Version()})
+	}
+	return collectors, nil
+}
+
+type mockPlugin struct {
+	typ     string
+	name    string
+	version int
+}
+
+func (p mockPlugin) TypeName() string { return p.typ }
+func (p mockPlugin) Name() string     { return p.name }
+func (p mockPlugin) Version() int     { return p.version }
+
+// subscribingMetricManager records the plugins subscribed and unsubscribed
+type subscribingMetricManager struct {
+	*mockMetricManager
+	subscribed    []string
+	unsubscribed  []string
+	failSubscribe bool
+	// failUnsubscribe is the key of a plugin which fails to be unsubscribed
+	failUnsubscribe string
+}
+
+func pluginKeys(prs []core.Plugin) []string {
+	keys := []string{}
+	for _, p := range prs {
+		keys = append(keys, fmt.Sprintf("%s:%s:%d", p.TypeName(), p.Name(), p.Version()))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *subscribingMetricManager) SubscribeDeps(taskID string, mts []core.Metric, prs []core.Plugin) []serror.SnapError {
+	if m.failSubscribe {
+		m.failSubscribe = false
+		return []serror.SnapError{serror.New(errors.New("subscription error"))}
+	}
+	m.subscribed = append(m.subscribed, pluginKeys(prs)...)
+	return nil
+}
+
+func (m *subscribingMetricManager) UnsubscribeDeps(taskID string, mts []core.Metric, prs []core.Plugin) []serror.SnapError {
+	keys := pluginKeys(prs)
+	for _, k := range keys {
+		if k == m.failUnsubscribe {
+			return []serror.SnapError{serror.New(errors.New("unsubscription error"))}
+		}
+	}
+	m.unsubscribed = append(m.unsubscribed, keys...)
+	return nil
+}
+
 type mockMetricManagerError struct {
 	errs []error
 }
@@ -223,6 +287,17 @@ func TestScheduler(t *testing.T) {
 
 		})
 
+		Convey("returns an error when a content type cannot be converted", func() {
+			c.setReturnedContentType("machine", core.ProcessorPluginType, 1, []string{"foo.bar"})
+			defer c.setReturnedContentType("machine", core.ProcessorPluginType, 1, []string{"snap.gob"})
+			n := len(s.GetTasks())
+			_, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+			So(err.Errors(), ShouldHaveLength, 1)
+			So(err.Errors()[0].Error(), ShouldContainSubstring, "does not accept")
+			So(s.GetTasks(), ShouldHaveLength, n)
+			So(s.ValidateTask(schedule.NewSimpleSchedule(time.Second*1), w).Errors(), ShouldHaveLength, 1)
+		})
+
 		Convey("returns an error when scheduler started and MetricManager is not set", func() {
 			s1 := New()
 			err := s1.Start()
@@ -315,6 +390,110 @@ func TestScheduler(t *testing.T) {
 			So(err1, ShouldBeNil)
 			So(etsk.State(), ShouldEqual, core.TaskStopped)
 		})
+		Convey("Update a stopped task", func() {
+			tsk, _ := s.CreateTask(schedule.NewSimpleSchedule(time.Millisecond*100), w, false)
+			So(tsk, ShouldNotBeNil)
+			tsk.(*task).hitCount = 5
+
+			w2 := wmap.NewWorkflowMap()
+			w2.CollectNode.AddMetric("/foo/qux", 1)
+			utsk, errs := s.UpdateTask(tsk.ID(), schedule.NewSimpleSchedule(time.Second), w2, core.SetTaskName("updated"), core.TaskDeadlineDuration(time.Second))
+			So(errs.Errors(), ShouldBeEmpty)
+			So(utsk.ID(), ShouldEqual, tsk.ID())
+			So(utsk.GetName(), ShouldEqual, "updated")
+			So(utsk.HitCount(), ShouldEqual, 5)
+			So(utsk.(*task).deadlineDuration, ShouldEqual, time.Second)
+			So(utsk.(*task).schedule.(*schedule.SimpleSchedule).Interval, ShouldEqual, time.Second)
+			So(utsk.(*task).workflow.metrics[0].Namespace(), ShouldResemble, []string{"foo", "qux"})
+			So(utsk.State(), ShouldEqual, core.TaskStopped)
+
+			Convey("keeps the workflow when only the schedule changes", func() {
+				utsk, errs := s.UpdateTask(tsk.ID(), schedule.NewSimpleSchedule(time.Minute), nil)
+				So(errs.Errors(), ShouldBeEmpty)
+				So(utsk.(*task).workflow.metrics[0].Namespace(), ShouldResemble, []string{"foo", "qux"})
+				So(utsk.GetName(), ShouldEqual, "updated")
+			})
+		})
+		Convey("Update returns errors", func() {
+			tsk, _ := s.CreateTask(schedule.NewSimpleSchedule(time.Millisecond*100), w, false)
+			So(tsk, ShouldNotBeNil)
+
+			Convey("for a task that doesn't exist", func() {
+				_, errs := s.UpdateTask("1234", schedule.NewSimpleSchedule(time.Second), nil)
+				So(errs.Errors()[0].Error(), ShouldStartWith, ErrTaskNotFound.Error())
+			})
+			Convey("for an invalid schedule", func() {
+				_, errs := s.UpdateTask(tsk.ID(), schedule.NewSimpleSchedule(0), nil)
+				So(errs.Errors()[0].Error(), ShouldEqual, schedule.ErrInvalidInterval.Error())
+			})
+			Convey("when the new workflow does not validate", func() {
+				c.failValidatingMetrics = true
+				_, errs := s.UpdateTask(tsk.ID(), nil, w)
+				c.failValidatingMetrics = false
+				So(errs.Errors()[0].Error(), ShouldEqual, "metric validation error")
+				// the task is unchanged
+				So(tsk.(*task).workflow.metrics, ShouldHaveLength, 2)
+			})
+		})
+		Convey("ValidateTask", func() {
+			n := len(s.GetTasks())
+			So(s.ValidateTask(schedule.NewSimpleSchedule(time.Second), w).Errors(), ShouldBeEmpty)
+			So(s.GetTasks(), ShouldHaveLength, n)
+
+			Convey("returns every problem with the workflow map", func() {
+				w.CollectNode.Add(wmap.NewProcessNode("machine", 1))
+				w.CollectNode.AddConfigItem("/foo/Bar", "user", "root")
+				errs := s.ValidateTask(schedule.NewSimpleSchedule(0), w).Errors()
+				So(errs, ShouldHaveLength, 3)
+				So(errs[0].Error(), ShouldEqual, schedule.ErrInvalidInterval.Error())
+				So(errs[1].Fields()["path"], ShouldEqual, "collect.config[/foo/Bar]")
+				So(errs[2].Error(), ShouldEqual, "collect.process[1]: processor output never reaches a publisher")
+
+				_, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false)
+				So(te.Errors(), ShouldHaveLength, 2)
+			})
+			Convey("returns errors when metrics do not validate", func() {
+				c.failValidatingMetrics = true
+				errs := s.ValidateTask(nil, w).Errors()
+				c.failValidatingMetrics = false
+				So(errs, ShouldNotBeEmpty)
+				So(errs[0].Error(), ShouldEqual, "metric validation error")
+			})
+		})
+		Convey("Task spool", func() {
+			tsk, _ := s.CreateTask(schedule.NewSimpleSchedule(time.Millisecond*100), w, false)
+			So(tsk, ShouldNotBeNil)
+
+			Convey("returns an error when the spool is not set", func() {
+				_, err := s.GetTaskSpool(tsk.ID())
+				So(err, ShouldEqual, ErrPublishSpoolDisabled)
+				_, err = s.PurgeTaskSpool(tsk.ID())
+				So(err, ShouldEqual, ErrPublishSpoolDisabled)
+			})
+			Convey("returns an error for a task that doesn't exist", func() {
+				_, err := s.GetTaskSpool("1234")
+				So(err.Error(), ShouldStartWith, ErrTaskNotFound.Error())
+			})
+			Convey("lists and purges the batches of a task", func() {
+				dir, err := ioutil.TempDir("", "snap-publish-spool-")
+				So(err, ShouldBeNil)
+				defer os.RemoveAll(dir)
+				ps, err := NewFilePublishSpool(dir)
+				So(err, ShouldBeNil)
+				s.SetPublishSpool(ps)
+				So(ps.Add(&core.SpooledBatch{TaskID: tsk.ID()}), ShouldBeNil)
+
+				batches, err := s.GetTaskSpool(tsk.ID())
+				So(err, ShouldBeNil)
+				So(batches, ShouldHaveLength, 1)
+				n, err := s.PurgeTaskSpool(tsk.ID())
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, 1)
+				batches, err = s.GetTaskSpool(tsk.ID())
+				So(err, ShouldBeNil)
+				So(batches, ShouldBeEmpty)
+			})
+		})
 		Convey("Start disabled task", func() {
 			tsk, _ := s.CreateTask(schedule.NewSimpleSchedule(time.Millisecond*100), w, false)
 			So(tsk, ShouldNotBeNil)
@@ -337,6 +516,70 @@ func TestScheduler(t *testing.T) {
 			So(scheduler.state, ShouldEqual, schedulerStopped)
 		})
 	})
+	Convey("diffPlugins()", t, func() {
+		a := []core.Plugin{
+			mockPlugin{typ: "collector", name: "foo", version: 1},
+			mockPlugin{typ: "collector", name: "foo", version: 1},
+			mockPlugin{typ: "publisher", name: "file", version: -1},
+		}
+		b := []core.Plugin{
+			mockPlugin{typ: "collector", name: "foo", version: 1},
+			mockPlugin{typ: "publisher", name: "file", version: 2},
+			mockPlugin{typ: "publisher", name: "file", version: 2},
+		}
+		added, removed := diffPlugins(a, b)
+		So(pluginKeys(added), ShouldResemble, []string{"publisher:file:2"})
+		So(pluginKeys(removed), ShouldResemble, []string{"publisher:file:-1"})
+	})
+	Convey("Updating the workflow of a running task", t, func() {
+		c := &subscribingMetricManager{mockMetricManager: new(mockMetricManager)}
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{plugin.SnapGOBContentType})
+		c.setAcceptedContentType("rmq", core.PublisherPluginType, -1, []string{plugin.SnapGOBContentType})
+		s := New()
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+		defer s.Stop()
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		w.CollectNode.AddMetric("/qux/quux", 1)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+		tsk, errs := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), w, true)
+		So(errs.Errors(), ShouldBeEmpty)
+		c.subscribed, c.unsubscribed = nil, nil
+
+		w2 := wmap.NewWorkflowMap()
+		w2.CollectNode.AddMetric("/foo/bar", 1)
+		w2.CollectNode.AddMetric("/foo/baz", 1)
+		w2.CollectNode.Add(wmap.NewPublishNode("rmq", -1))
+
+		Convey("subscribes only the plugins it adds and unsubscribes only the ones it removes", func() {
+			_, errs := s.UpdateTask(tsk.ID(), nil, w2)
+			So(errs.Errors(), ShouldBeEmpty)
+			So(c.subscribed, ShouldResemble, []string{"publisher:rmq:-1"})
+			So(c.unsubscribed, ShouldResemble, []string{"collector:qux:1", "publisher:file:-1"})
+		})
+		Convey("restores the subscriptions of the old workflow when it fails", func() {
+			c.failSubscribe = true
+			_, errs := s.UpdateTask(tsk.ID(), nil, w2)
+			So(errs.Errors(), ShouldHaveLength, 1)
+			So(c.unsubscribed, ShouldResemble, []string{"collector:qux:1", "publisher:file:-1", "publisher:rmq:-1"})
+			So(c.subscribed, ShouldResemble, []string{"collector:qux:1", "publisher:file:-1"})
+			var namespaces []string
+			for _, m := range tsk.(*task).workflow.metrics {
+				namespaces = append(namespaces, strings.Join(m.Namespace(), "/"))
+			}
+			So(namespaces, ShouldHaveLength, 2)
+			So(namespaces, ShouldContain, "qux/quux")
+		})
+		Convey("reports the errors of restoring the subscriptions", func() {
+			c.failSubscribe = true
+			c.failUnsubscribe = "publisher:rmq:-1"
+			_, errs := s.UpdateTask(tsk.ID(), nil, w2)
+			So(errs.Errors(), ShouldHaveLength, 2)
+			So(errs.Errors()[0].Error(), ShouldEqual, "subscription error")
+			So(errs.Errors()[1].Error(), ShouldEqual, "restoring the subscriptions of the task: unsubscription error")
+		})
+	})
 	Convey("SetMetricManager()", t, func() {
 		Convey("Should set metricManager for scheduler", func() {
 			scheduler := New()
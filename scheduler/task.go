@@ -62,8 +62,8 @@ type task struct {
 
 	id                 string
 	name               string
-	schResponseChan    chan schedule.Response
 	killChan           chan struct{}
+	scheduleChan       chan struct{}
 	schedule           schedule.Schedule
 	workflow           *schedulerWorkflow
 	state              core.TaskState
@@ -75,10 +75,12 @@ type task struct {
 	hitCount           uint
 	missedIntervals    uint
 	failedRuns         uint
+	timedOutRuns       uint
 	lastFailureMessage string
 	lastFailureTime    time.Time
 	stopOnFailure      uint
 	eventEmitter       gomit.Emitter
+	spool              PublishSpool
 }
 
 //NewTask creates a Task
@@ -94,7 +96,7 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 	task := &task{
 		id:               taskID,
 		name:             name,
-		schResponseChan:  make(chan schedule.Response),
+		scheduleChan:     make(chan struct{}, 1),
 		schedule:         s,
 		state:            core.TaskStopped,
 		creationTime:     time.Now(),
@@ -173,6 +175,12 @@ func (t *task) FailedCount() uint {
 	return t.failedRuns
 }
 
+// TimedOutCount returns the number of runs in which a job timed out.  These
+// runs are also counted as failed.
+func (t *task) TimedOutCount() uint {
+	return t.timedOutRuns
+}
+
 // LastFailureMessage returns the last error from a task run
 func (t *task) LastFailureMessage() string {
 	return t.lastFailureMessage
@@ -209,6 +217,11 @@ func (t *task) Spin() {
 	if t.state == core.TaskStopped {
 		t.state = core.TaskSpinning
 		t.killChan = make(chan struct{})
+		// drop any schedule update made while the task was stopped
+		select {
+		case <-t.scheduleChan:
+		default:
+		}
 		// spin in a goroutine
 		go t.spin()
 	}
@@ -253,22 +266,53 @@ func (t *task) Schedule() schedule.Schedule {
 	return t.schedule
 }
 
+// update swaps the schedule and/or workflow of the task and applies the
+// options.  A nil schedule or workflow leaves the current one in place.  The
+// caller must hold the task lock, which is also held while firing, so the
+// swap always happens between fires.
+func (t *task) update(s schedule.Schedule, wf *schedulerWorkflow, opts ...core.TaskOption) {
+	if wf != nil {
+		wf.eventEmitter = t.eventEmitter
+		t.workflow = wf
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if s != nil {
+		t.schedule = s
+		// wake the spin loop so it waits on the new schedule
+		select {
+		case t.scheduleChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func (t *task) spin() {
 	var consecutiveFailures uint
 	for {
 		schedulerLogger.Debug("task spin loop")
-		// Start go routine to wait on schedule
-		go t.waitForSchedule()
+		t.Lock()
+		at, sr := t.schedule.Next(t.lastFireTime)
+		t.Unlock()
+		timer := time.NewTimer(at.Sub(time.Now()))
 		// wait here on
-		//  schResponseChan - response from schedule
+		//  timer - the schedule fires
+		//  scheduleChan - signals the schedule has been replaced
 		//  killChan - signals task needs to be stopped
 		select {
-		case sr := <-t.schResponseChan:
+		case <-t.scheduleChan:
+			// abandon the wait on the old schedule
+			timer.Stop()
+			continue
+		case <-timer.C:
 			switch sr.State() {
 			// If response show this schedule is stil active we fire
 			case schedule.Active:
 				t.missedIntervals += sr.Missed()
-				t.lastFireTime = time.Now()
+				// the time the schedule fired at, which for a cron schedule
+				// is the cron time rather than when the wait ended
+				t.lastFireTime = sr.LastTime()
 				t.hitCount++
 				t.fire()
 				if t.lastFailureTime == t.lastFireTime {
@@ -309,6 +353,10 @@ func (t *task) spin() {
 				t.Lock()
 				t.state = core.TaskEnded
 				t.Unlock()
+				// Send task ended event
+				event := new(scheduler_event.TaskEndedEvent)
+				event.TaskID = t.id
+				defer t.eventEmitter.Emit(event)
 				return //spin
 
 			// Schedule has errored
@@ -322,6 +370,7 @@ func (t *task) spin() {
 			}
 		case <-t.killChan:
 			// Only here can it truly be stopped
+			timer.Stop()
 			t.Lock()
 			t.state = core.TaskStopped
 			t.lastFireTime = time.Time{}
@@ -340,14 +389,6 @@ func (t *task) fire() {
 	t.state = core.TaskSpinning
 }
 
-func (t *task) waitForSchedule() {
-	select {
-	case <-t.killChan:
-		return
-	case t.schResponseChan <- t.schedule.Wait(t.lastFireTime):
-	}
-}
-
 type taskCollection struct {
 	*sync.Mutex
 
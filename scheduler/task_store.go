@@ -0,0 +1,241 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+var (
+	// ErrUnsupportedScheduleType - The error message for a schedule which cannot be persisted
+	ErrUnsupportedScheduleType = errors.New("Schedule type cannot be persisted")
+	// ErrMissingTaskRecordSchedule - The error message for a persisted task without a schedule
+	ErrMissingTaskRecordSchedule = errors.New("Task record is missing its schedule")
+)
+
+// TaskStore persists tasks so they survive a restart of the scheduler.
+type TaskStore interface {
+	// Save creates or replaces the record of a task
+	Save(*TaskRecord) error
+	// Remove deletes the record of a task given its id
+	Remove(id string) error
+	// Load returns all of the task records in the store
+	Load() ([]*TaskRecord, error)
+}
+
+// TaskRecord is the persisted form of a task
+type TaskRecord struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Deadline      string            `json:"deadline"`
+	StopOnFailure uint              `json:"stop_on_failure"`
+	CreationTime  time.Time         `json:"creation_timestamp"`
+	Schedule      *ScheduleRecord   `json:"schedule"`
+	Workflow      *wmap.WorkflowMap `json:"workflow"`
+	State         string            `json:"task_state"`
+}
+
+// ScheduleRecord is the persisted form of a schedule.Schedule
+type ScheduleRecord struct {
+	Type      string     `json:"type"`
+	Interval  string     `json:"interval,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	StopTime  *time.Time `json:"stop_time,omitempty"`
+	TimeZone  string     `json:"timezone,omitempty"`
+}
+
+func newTaskRecord(t *task) (*TaskRecord, error) {
+	sr, err := newScheduleRecord(t.schedule)
+	if err != nil {
+		return nil, err
+	}
+	state := t.State()
+	switch state {
+	case core.TaskSpinning, core.TaskFiring:
+		state = core.TaskSpinning
+	case core.TaskStopping:
+		state = core.TaskStopped
+	}
+	return &TaskRecord{
+		ID:            t.id,
+		Name:          t.name,
+		Deadline:      t.deadlineDuration.String(),
+		StopOnFailure: t.stopOnFailure,
+		CreationTime:  t.creationTime,
+		Schedule:      sr,
+		Workflow:      t.workflow.workflowMap,
+		State:         state.String(),
+	}, nil
+}
+
+func newScheduleRecord(s schedule.Schedule) (*ScheduleRecord, error) {
+	switch v := s.(type) {
+	case *schedule.SimpleSchedule:
+		return &ScheduleRecord{
+			Type:     "simple",
+			Interval: v.Interval.String(),
+		}, nil
+	case *schedule.WindowedSchedule:
+		return &ScheduleRecord{
+			Type:      "windowed",
+			Interval:  v.Interval.String(),
+			StartTime: v.StartTime,
+			StopTime:  v.StopTime,
+		}, nil
+	case *schedule.CronSchedule:
+		sr := &ScheduleRecord{
+			Type:     "cron",
+			Interval: v.Entry,
+		}
+		if v.Location != nil {
+			sr.TimeZone = v.Location.String()
+		}
+		return sr, nil
+	}
+	return nil, ErrUnsupportedScheduleType
+}
+
+// Schedule returns the schedule.Schedule described by the record
+func (s *ScheduleRecord) Schedule() (schedule.Schedule, error) {
+	switch s.Type {
+	case "simple":
+		d, err := time.ParseDuration(s.Interval)
+		if err != nil {
+			return nil, err
+		}
+		return schedule.NewSimpleSchedule(d), nil
+	case "windowed":
+		d, err := time.ParseDuration(s.Interval)
+		if err != nil {
+			return nil, err
+		}
+		return schedule.NewWindowedSchedule(d, s.StartTime, s.StopTime), nil
+	case "cron":
+		var loc *time.Location
+		if s.TimeZone != "" {
+			var err error
+			loc, err = time.LoadLocation(s.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return schedule.NewCronSchedule(s.Interval, loc), nil
+	}
+	return nil, fmt.Errorf("%v: %s", ErrUnsupportedScheduleType, s.Type)
+}
+
+// fileTaskStore keeps each task record as a JSON document, named after the
+// task id, in a single directory.
+type fileTaskStore struct {
+	sync.Mutex
+	path string
+}
+
+// NewFileTaskStore returns a TaskStore which persists tasks in the directory
+// at path. The directory is created if it does not exist.
+func NewFileTaskStore(path string) (TaskStore, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	return &fileTaskStore{path: path}, nil
+}
+
+func (f *fileTaskStore) Save(r *TaskRecord) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	f.Lock()
+	defer f.Unlock()
+	// Write to a temporary file first so a crash never leaves a partial record
+	tmp, err := ioutil.TempFile(f.path, ".task-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.recordPath(r.ID))
+}
+
+func (f *fileTaskStore) Remove(id string) error {
+	f.Lock()
+	defer f.Unlock()
+	err := os.Remove(f.recordPath(id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *fileTaskStore) Load() ([]*TaskRecord, error) {
+	f.Lock()
+	defer f.Unlock()
+	files, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var records []*TaskRecord
+	for _, fi := range files {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(f.path, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		r := &TaskRecord{}
+		if err := json.Unmarshal(b, r); err != nil {
+			// Skip a corrupt record rather than losing every other task
+			schedulerLogger.WithFields(log.Fields{
+				"_block": "load-task-records",
+				"file":   fi.Name(),
+				"_error": err.Error(),
+			}).Error("unable to read task record")
+			continue
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func (f *fileTaskStore) recordPath(id string) string {
+	return filepath.Join(f.path, id+".json")
+}
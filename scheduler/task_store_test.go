@@ -0,0 +1,244 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestScheduleRecord(t *testing.T) {
+	Convey("ScheduleRecord", t, func() {
+		Convey("round trips a simple schedule", func() {
+			r, err := newScheduleRecord(schedule.NewSimpleSchedule(time.Second * 5))
+			So(err, ShouldBeNil)
+			So(r.Type, ShouldEqual, "simple")
+			s, err := r.Schedule()
+			So(err, ShouldBeNil)
+			So(s.(*schedule.SimpleSchedule).Interval, ShouldEqual, time.Second*5)
+		})
+		Convey("round trips a windowed schedule", func() {
+			start := time.Now().Add(time.Minute)
+			stop := start.Add(time.Hour)
+			r, err := newScheduleRecord(schedule.NewWindowedSchedule(time.Second, &start, &stop))
+			So(err, ShouldBeNil)
+			So(r.Type, ShouldEqual, "windowed")
+			s, err := r.Schedule()
+			So(err, ShouldBeNil)
+			ws := s.(*schedule.WindowedSchedule)
+			So(ws.Interval, ShouldEqual, time.Second)
+			So(ws.StartTime.Equal(start), ShouldBeTrue)
+			So(ws.StopTime.Equal(stop), ShouldBeTrue)
+		})
+		Convey("round trips a cron schedule", func() {
+			loc, err := time.LoadLocation("America/New_York")
+			So(err, ShouldBeNil)
+			r, err := newScheduleRecord(schedule.NewCronSchedule("0 9 * * *", loc))
+			So(err, ShouldBeNil)
+			So(r.Type, ShouldEqual, "cron")
+			So(r.TimeZone, ShouldEqual, "America/New_York")
+			s, err := r.Schedule()
+			So(err, ShouldBeNil)
+			cs := s.(*schedule.CronSchedule)
+			So(cs.Entry, ShouldEqual, "0 9 * * *")
+			So(cs.Location.String(), ShouldEqual, "America/New_York")
+		})
+		Convey("returns an error for an unknown type", func() {
+			r := &ScheduleRecord{Type: "foo"}
+			_, err := r.Schedule()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestFileTaskStore(t *testing.T) {
+	Convey("fileTaskStore", t, func() {
+		dir, err := ioutil.TempDir("", "snap-task-store")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		ts, err := NewFileTaskStore(filepath.Join(dir, "tasks"))
+		So(err, ShouldBeNil)
+		records, err := ts.Load()
+		So(err, ShouldBeNil)
+		So(records, ShouldBeEmpty)
+
+		r := &TaskRecord{
+			ID:       "1234",
+			Name:     "foo",
+			Deadline: "5s",
+			Schedule: &ScheduleRecord{Type: "simple", Interval: "1s"},
+			Workflow: wmap.NewWorkflowMap(),
+			State:    core.TaskStopped.String(),
+		}
+		So(ts.Save(r), ShouldBeNil)
+
+		Convey("loads saved records", func() {
+			records, err := ts.Load()
+			So(err, ShouldBeNil)
+			So(len(records), ShouldEqual, 1)
+			So(records[0].ID, ShouldEqual, "1234")
+			So(records[0].Name, ShouldEqual, "foo")
+			So(records[0].Schedule.Interval, ShouldEqual, "1s")
+		})
+		Convey("replaces a record with the same id", func() {
+			r.State = core.TaskSpinning.String()
+			So(ts.Save(r), ShouldBeNil)
+			records, err := ts.Load()
+			So(err, ShouldBeNil)
+			So(len(records), ShouldEqual, 1)
+			So(records[0].State, ShouldEqual, "Running")
+		})
+		Convey("removes records", func() {
+			So(ts.Remove("1234"), ShouldBeNil)
+			records, err := ts.Load()
+			So(err, ShouldBeNil)
+			So(records, ShouldBeEmpty)
+			// removing a missing record is not an error
+			So(ts.Remove("1234"), ShouldBeNil)
+		})
+		Convey("skips corrupt records", func() {
+			err := ioutil.WriteFile(filepath.Join(dir, "tasks", "bad.json"), []byte("{"), 0600)
+			So(err, ShouldBeNil)
+			records, err := ts.Load()
+			So(err, ShouldBeNil)
+			So(len(records), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestSchedulerRestoreTasks(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Scheduler with a task store", t, func() {
+		dir, err := ioutil.TempDir("", "snap-task-store")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ts, err := NewFileTaskStore(dir)
+		So(err, ShouldBeNil)
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+
+		s := New()
+		s.SetMetricManager(new(mockMetricManager))
+		s.SetTaskStore(ts)
+		So(s.Start(), ShouldBeNil)
+
+		t1, errs := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false, core.SetTaskName("persisted"))
+		So(errs.Errors(), ShouldBeEmpty)
+		t2, errs := s.CreateTask(schedule.NewCronSchedule("@hourly", time.UTC), w, false)
+		So(errs.Errors(), ShouldBeEmpty)
+		t2.(*task).state = core.TaskDisabled
+		s.persistTask(t2.(*task))
+
+		records, err := ts.Load()
+		So(err, ShouldBeNil)
+		So(len(records), ShouldEqual, 2)
+
+		Convey("restores tasks with their ids, names and states", func() {
+			s2 := New()
+			s2.SetMetricManager(new(mockMetricManager))
+			s2.SetTaskStore(ts)
+			So(s2.Start(), ShouldBeNil)
+			s2.RestoreTasks()
+
+			r1, err := s2.GetTask(t1.ID())
+			So(err, ShouldBeNil)
+			So(r1.GetName(), ShouldEqual, "persisted")
+			So(r1.State(), ShouldEqual, core.TaskStopped)
+			So(r1.CreationTime().Equal(*t1.CreationTime()), ShouldBeTrue)
+
+			r2, err := s2.GetTask(t2.ID())
+			So(err, ShouldBeNil)
+			So(r2.State(), ShouldEqual, core.TaskDisabled)
+			So(r2.(*task).schedule.(*schedule.CronSchedule).Entry, ShouldEqual, "@hourly")
+		})
+		Convey("forgets removed tasks", func() {
+			So(s.RemoveTask(t1.ID()), ShouldBeNil)
+			records, err := ts.Load()
+			So(err, ShouldBeNil)
+			So(len(records), ShouldEqual, 1)
+			So(records[0].ID, ShouldEqual, t2.ID())
+		})
+		Convey("binds the content types of a restored task when it is started", func() {
+			pw := wmap.NewWorkflowMap()
+			pw.CollectNode.AddMetric("/foo/bar", 1)
+			pw.CollectNode.Add(wmap.NewPublishNode("file", 1))
+			mm := new(mockMetricManager)
+			mm.setAcceptedContentType("file", core.PublisherPluginType, 1, []string{"snap.gob"})
+			s.SetMetricManager(mm)
+			t3, errs := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), pw, false)
+			So(errs.Errors(), ShouldBeEmpty)
+
+			// the publisher is not loaded when the task is restored
+			mm2 := new(mockMetricManager)
+			s2 := New()
+			s2.SetMetricManager(&testTaskMetricManager{mockMetricManager: mm2, subscribed: map[string]int{}})
+			s2.SetTaskStore(ts)
+			So(s2.Start(), ShouldBeNil)
+			s2.RestoreTasks()
+			r3, err := s2.GetTask(t3.ID())
+			So(err, ShouldBeNil)
+			So(r3.(*task).workflow.publishNodes[0].InboundContentType, ShouldBeEmpty)
+			So(s2.StartTask(t3.ID()), ShouldNotBeEmpty)
+
+			mm2.setAcceptedContentType("file", core.PublisherPluginType, 1, []string{"snap.gob"})
+			So(s2.StartTask(t3.ID()), ShouldBeEmpty)
+			So(r3.(*task).workflow.publishNodes[0].InboundContentType, ShouldEqual, "snap.gob")
+			s2.StopTask(t3.ID())
+		})
+		Convey("persists a task whose schedule ended", func() {
+			s.SetMetricManager(&testTaskMetricManager{mockMetricManager: new(mockMetricManager), subscribed: map[string]int{}})
+			start := time.Now()
+			stop := start.Add(time.Millisecond * 50)
+			t3, errs := s.CreateTask(schedule.NewWindowedSchedule(time.Millisecond*10, &start, &stop), w, true)
+			So(errs.Errors(), ShouldBeEmpty)
+			var state string
+			for i := 0; i < 50 && state != core.TaskEnded.String(); i++ {
+				time.Sleep(time.Millisecond * 10)
+				records, err := ts.Load()
+				So(err, ShouldBeNil)
+				for _, r := range records {
+					if r.ID == t3.ID() {
+						state = r.State
+					}
+				}
+			}
+			So(state, ShouldEqual, core.TaskEnded.String())
+		})
+		Convey("does not restore before the scheduler is started", func() {
+			s2 := New()
+			s2.SetMetricManager(new(mockMetricManager))
+			s2.SetTaskStore(ts)
+			s2.RestoreTasks()
+			So(s2.GetTasks(), ShouldBeEmpty)
+		})
+	})
+}
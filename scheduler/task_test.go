@@ -110,6 +110,17 @@ func TestTask(t *testing.T) {
 			})
 		})
 
+		Convey("counts a run whose collector times out", func() {
+			sc := &slowCollectorMetricManager{mockMetricManager: c, delay: time.Millisecond * 500}
+			task := newTask(schedule.NewSimpleSchedule(time.Hour), wf, newWorkManager(), sc, emitter, core.TaskDeadlineDuration(time.Millisecond*20))
+			start := time.Now()
+			task.workflow.Start(task)
+			So(time.Since(start), ShouldBeLessThan, time.Millisecond*400)
+			So(task.TimedOutCount(), ShouldEqual, 1)
+			So(task.FailedCount(), ShouldEqual, 1)
+			So(task.LastFailureMessage(), ShouldEqual, ErrJobTimedOut.Error())
+		})
+
 		Convey("task fires", func() {
 			sch := schedule.NewSimpleSchedule(time.Nanosecond * 100)
 			task := newTask(sch, wf, newWorkManager(), c, emitter)
@@ -120,6 +131,33 @@ func TestTask(t *testing.T) {
 			task.Stop()
 		})
 
+		Convey("reports the cron time a cron schedule fired at", func() {
+			task := newTask(schedule.NewCronSchedule("* * * * * *", time.UTC), wf, newWorkManager(), c, emitter)
+			task.Spin()
+			time.Sleep(time.Millisecond * 1100)
+			task.Lock()
+			hits, last := task.hitCount, task.lastFireTime
+			task.Unlock()
+			task.Stop()
+			So(hits, ShouldBeGreaterThan, 0)
+			So(last.Nanosecond(), ShouldEqual, 0)
+		})
+
+		Convey("update swaps the schedule of a spinning task", func() {
+			sch := schedule.NewSimpleSchedule(time.Hour)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			task.Spin()
+			time.Sleep(time.Millisecond * 10)
+			So(task.hitCount, ShouldEqual, 0)
+			task.Lock()
+			task.update(schedule.NewSimpleSchedule(time.Millisecond*10), nil, core.SetTaskName("updated"))
+			task.Unlock()
+			time.Sleep(time.Millisecond * 100)
+			So(task.hitCount, ShouldBeGreaterThan, 0)
+			So(task.GetName(), ShouldEqual, "updated")
+			task.Stop()
+		})
+
 		Convey("Enable a running task", func() {
 			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
 			task := newTask(sch, wf, newWorkManager(), c, emitter)
@@ -188,3 +226,14 @@ func TestTask(t *testing.T) {
 
 	})
 }
+
+// slowCollectorMetricManager takes delay to collect metrics
+type slowCollectorMetricManager struct {
+	*mockMetricManager
+	delay time.Duration
+}
+
+func (m *slowCollectorMetricManager) CollectMetrics(mts []core.Metric, deadline time.Time, taskID string) ([]core.Metric, []error) {
+	time.Sleep(m.delay)
+	return nil, nil
+}
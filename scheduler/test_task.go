@@ -0,0 +1,147 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// TestTask runs the workflow of a workflow map once without creating a task.
+// Metrics are collected and processed with the same jobs a task uses but
+// nothing is published.  The result holds the metrics output by the collect
+// and process nodes and the metrics each publish node would have been sent.
+func (s *scheduler) TestTask(wfMap *wmap.WorkflowMap) (*core.TaskTestResult, core.TaskErrors) {
+	logger := s.logger.WithFields(log.Fields{
+		"_block": "test-task",
+	})
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+	if s.state != schedulerStarted {
+		te.errs = append(te.errs, serror.New(ErrSchedulerNotStarted))
+		return nil, te
+	}
+	wf, err := wmapToWorkflow(wfMap)
+	if err != nil {
+		te.errs = append(te.errs, workflowErrors(err)...)
+		return nil, te
+	}
+	mts, plugins := s.gatherMetricsAndPlugins(wf)
+	if errs := s.metricManager.ValidateDeps(mts, plugins); len(errs) > 0 {
+		te.errs = append(te.errs, errs...)
+		return nil, te
+	}
+	if err := wf.BindPluginContentTypes(s.metricManager); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		return nil, te
+	}
+
+	// The collectors and processors are subscribed under an id of their own
+	// so their plugins are running for the test.  Publishers are not used.
+	id := "test-" + uuid.New()
+	var cps []core.Plugin
+	for _, p := range plugins {
+		if p.TypeName() != "publisher" {
+			cps = append(cps, p)
+		}
+	}
+	if errs := s.metricManager.SubscribeDeps(id, mts, cps); len(errs) > 0 {
+		s.metricManager.UnsubscribeDeps(id, mts, cps)
+		te.errs = append(te.errs, errs...)
+		return nil, te
+	}
+	defer s.metricManager.UnsubscribeDeps(id, mts, cps)
+
+	logger.WithFields(log.Fields{
+		"test-id": id,
+	}).Info("testing task")
+	r := &core.TaskTestResult{}
+	j := newCollectorJob(wf.metrics, defaultDeadline, s.metricManager, wf.configTree, id)
+	errs := s.workManager.Work(j).Promise().Await()
+	n := &core.TaskTestNode{
+		Path:        "collect",
+		Type:        "collector",
+		ContentType: plugin.SnapGOBContentType,
+		Errors:      errs,
+	}
+	r.Nodes = append(r.Nodes, n)
+	// A job which timed out is abandoned while it may still be running so
+	// what it output is only read when it succeeded
+	if len(errs) == 0 {
+		n.Metrics = j.(*collectorJob).metrics
+		s.testJobs("collect", wf.processNodes, wf.publishNodes, id, j, n.Metrics, r)
+	}
+	return r, te
+}
+
+// testJobs works the process nodes under the parent job and records what
+// they output and what the publish nodes would be sent, which is the metrics
+// output by the parent job
+func (s *scheduler) testJobs(path string, prs []*processNode, pus []*publishNode, id string, pj job, metrics []core.Metric, r *core.TaskTestResult) {
+	for i, pr := range prs {
+		j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), s.metricManager, id)
+		errs := s.workManager.Work(j).Promise().Await()
+		n := &core.TaskTestNode{
+			Path:          fmt.Sprintf("%s.process[%d]", path, i),
+			Type:          pr.TypeName(),
+			PluginName:    pr.Name(),
+			PluginVersion: pr.Version(),
+			Errors:        errs,
+		}
+		r.Nodes = append(r.Nodes, n)
+		if len(errs) != 0 {
+			continue
+		}
+		n.ContentType = j.(*processJob).returnedContentType
+		mts, err := plugin.UnmarshallPluginMetricTypes(n.ContentType, j.(*processJob).content)
+		if err != nil {
+			n.Errors = append(n.Errors, err)
+			continue
+		}
+		n.Metrics = pluginMetrics(mts)
+		s.testJobs(n.Path, pr.ProcessNodes, pr.PublishNodes, id, j, n.Metrics, r)
+	}
+	for i, pu := range pus {
+		r.Nodes = append(r.Nodes, &core.TaskTestNode{
+			Path:          fmt.Sprintf("%s.publish[%d]", path, i),
+			Type:          pu.TypeName(),
+			PluginName:    pu.Name(),
+			PluginVersion: pu.Version(),
+			ContentType:   pu.InboundContentType,
+			Metrics:       metrics,
+		})
+	}
+}
+
+func pluginMetrics(mts []plugin.PluginMetricType) []core.Metric {
+	metrics := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		metrics[i] = m
+	}
+	return metrics
+}
@@ -0,0 +1,186 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// testTaskMetricManager collects a metric for every requested one and has
+// processors double the data of the metrics
+type testTaskMetricManager struct {
+	*mockMetricManager
+	subscribed   map[string]int
+	published    int
+	failCollect  bool
+	failProcess  bool
+	unsubscribed int
+}
+
+func (m *testTaskMetricManager) SubscribeDeps(taskID string, mts []core.Metric, prs []core.Plugin) []serror.SnapError {
+	for _, p := range prs {
+		m.subscribed[p.TypeName()]++
+	}
+	return nil
+}
+
+func (m *testTaskMetricManager) UnsubscribeDeps(taskID string, mts []core.Metric, prs []core.Plugin) []serror.SnapError {
+	m.unsubscribed++
+	return nil
+}
+
+func (m *testTaskMetricManager) CollectMetrics(mts []core.Metric, deadline time.Time, taskID string) ([]core.Metric, []error) {
+	if m.failCollect {
+		return nil, []error{errors.New("collect failed")}
+	}
+	metrics := make([]core.Metric, len(mts))
+	for i, mt := range mts {
+		metrics[i] = *plugin.NewPluginMetricType(mt.Namespace(), time.Now(), "host", nil, nil, 1)
+	}
+	return metrics, nil
+}
+
+func (m *testTaskMetricManager) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) (string, []byte, []error) {
+	if m.failProcess {
+		return "", nil, []error{errors.New("process failed")}
+	}
+	mts, err := plugin.UnmarshallPluginMetricTypes(contentType, content)
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	for i := range mts {
+		switch d := mts[i].Data_.(type) {
+		case int:
+			mts[i].Data_ = d * 2
+		case float64:
+			mts[i].Data_ = d * 2
+		}
+	}
+	b, ct, err := plugin.MarshalPluginMetricTypes(plugin.SnapJSONContentType, mts)
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	return ct, b, nil
+}
+
+func (m *testTaskMetricManager) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, deadline time.Time, taskID string) []error {
+	m.published++
+	return nil
+}
+
+func TestTestTask(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("TestTask()", t, func() {
+		c := &testTaskMetricManager{
+			mockMetricManager: new(mockMetricManager),
+			subscribed:        make(map[string]int),
+		}
+		c.setAcceptedContentType("double", core.ProcessorPluginType, 1, []string{"snap.gob", "snap.json"})
+		c.setReturnedContentType("double", core.ProcessorPluginType, 1, []string{"snap.json"})
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{"snap.gob"})
+		s := New()
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+		defer s.Stop()
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		pr1 := wmap.NewProcessNode("double", 1)
+		pr2 := wmap.NewProcessNode("double", 1)
+		pr2.AddConfigItem("again", true)
+		pr2.Add(wmap.NewPublishNode("file", -1))
+		pr1.Add(pr2)
+		pr1.Add(wmap.NewPublishNode("file", -1))
+		w.CollectNode.Add(pr1)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+
+		Convey("returns what each node output or would be sent", func() {
+			r, te := s.TestTask(w)
+			So(te.Errors(), ShouldBeEmpty)
+			paths := make([]string, len(r.Nodes))
+			data := make([]interface{}, len(r.Nodes))
+			for i, n := range r.Nodes {
+				So(n.Errors, ShouldBeEmpty)
+				So(n.Metrics, ShouldHaveLength, 1)
+				paths[i] = n.Path
+				data[i] = n.Metrics[0].Data()
+			}
+			So(paths, ShouldResemble, []string{
+				"collect",
+				"collect.process[0]",
+				"collect.process[0].process[0]",
+				"collect.process[0].process[0].publish[0]",
+				"collect.process[0].publish[0]",
+				"collect.publish[0]",
+			})
+			// the JSON from the processors decodes numbers to float64
+			So(data, ShouldResemble, []interface{}{1, float64(2), float64(4), float64(4), float64(2), 1})
+			So(r.Nodes[1].ContentType, ShouldEqual, plugin.SnapJSONContentType)
+			So(r.Nodes[2].Type, ShouldEqual, "processor")
+			So(r.Nodes[5].Type, ShouldEqual, "publisher")
+
+			// nothing is published, scheduled or left subscribed
+			So(c.published, ShouldEqual, 0)
+			So(c.subscribed["publisher"], ShouldEqual, 0)
+			So(c.subscribed["processor"], ShouldEqual, 2)
+			So(c.unsubscribed, ShouldEqual, 1)
+			So(s.GetTasks(), ShouldBeEmpty)
+		})
+		Convey("returns the errors of a node and skips the nodes below it", func() {
+			c.failProcess = true
+			r, te := s.TestTask(w)
+			So(te.Errors(), ShouldBeEmpty)
+			So(r.Nodes, ShouldHaveLength, 3)
+			So(r.Nodes[1].Errors[0].Error(), ShouldEqual, "process failed")
+			So(r.Nodes[1].Metrics, ShouldBeEmpty)
+			So(r.Nodes[2].Path, ShouldEqual, "collect.publish[0]")
+
+			c.failCollect = true
+			r, _ = s.TestTask(w)
+			So(r.Nodes, ShouldHaveLength, 1)
+			So(r.Nodes[0].Errors, ShouldNotBeEmpty)
+			// the output of a failed job is not read
+			So(r.Nodes[0].Metrics, ShouldBeEmpty)
+		})
+		Convey("returns an error for an invalid workflow", func() {
+			_, te := s.TestTask(wmap.NewWorkflowMap())
+			So(te.Errors(), ShouldHaveLength, 1)
+			c.failValidatingMetrics = true
+			_, te = s.TestTask(w)
+			So(te.Errors()[0].Error(), ShouldEqual, "metric validation error")
+		})
+	})
+	Convey("TestTask() on a stopped scheduler", t, func() {
+		s := New()
+		_, te := s.TestTask(wmap.NewWorkflowMap())
+		So(te.Errors()[0].Error(), ShouldEqual, ErrSchedulerNotStarted.Error())
+	})
+}
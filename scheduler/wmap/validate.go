@@ -0,0 +1,282 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var metricNamespaceRegexp = regexp.MustCompile(`^(/[^/\s]+)+$`)
+
+// ValidationError is a problem found in a workflow map along with the path to
+// where it is in the document (e.g. collect.process[0].publish[1])
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (v *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidationErrors holds every problem found by Validate
+type ValidationErrors []*ValidationError
+
+func (v ValidationErrors) Error() string {
+	s := make([]string, len(v))
+	for i, e := range v {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (v *ValidationErrors) add(path, format string, a ...interface{}) {
+	*v = append(*v, &ValidationError{Path: path, Message: fmt.Sprintf(format, a...)})
+}
+
+// Validate checks the workflow map and returns every problem found or nil if
+// there are none.  It reports keys in the document which are not part of a
+// workflow map, malformed namespaces, config values of an unsupported type,
+// processors whose output never reaches a publisher and sibling nodes which
+// duplicate each other.
+func (w *WorkflowMap) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if w == nil || w.CollectNode == nil {
+		errs.add("collect", "collect node is required")
+		return errs
+	}
+	for _, k := range w.unknownKeys {
+		errs.add(k, "unknown key")
+	}
+	c := w.CollectNode
+	if len(c.Metrics) == 0 {
+		errs.add("collect.metrics", "at least one metric is required")
+	}
+	for _, ns := range sortedKeys(c.Metrics) {
+		if !isValidMetricNamespace(ns) {
+			errs.add(fmt.Sprintf("collect.metrics[%s]", ns), "malformed namespace")
+		}
+	}
+	nss := make([]string, 0, len(c.Config))
+	for ns := range c.Config {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	for _, ns := range nss {
+		path := fmt.Sprintf("collect.config[%s]", ns)
+		if !isValidNamespaceString(ns) {
+			errs.add(path, "malformed namespace")
+		}
+		validateConfig(path, c.Config[ns], &errs)
+	}
+	validateChildren("collect", c.ProcessNodes, c.PublishNodes, &errs)
+	return errs
+}
+
+func validateChildren(path string, prs []ProcessWorkflowMapNode, pus []PublishWorkflowMapNode, errs *ValidationErrors) {
+	for i, pr := range prs {
+		p := fmt.Sprintf("%s.process[%d]", path, i)
+		validatePlugin(p, pr.Name, pr.Config, errs)
+		for j := 0; j < i; j++ {
+			if pr.Name == prs[j].Name && pr.Version == prs[j].Version && reflect.DeepEqual(pr.Config, prs[j].Config) {
+				errs.add(p, "duplicate of %s.process[%d]", path, j)
+				break
+			}
+		}
+		if len(pr.ProcessNodes) == 0 && len(pr.PublishNodes) == 0 {
+			errs.add(p, "processor output never reaches a publisher")
+		}
+		validateChildren(p, pr.ProcessNodes, pr.PublishNodes, errs)
+	}
+	for i, pu := range pus {
+		p := fmt.Sprintf("%s.publish[%d]", path, i)
+		validatePlugin(p, pu.Name, pu.Config, errs)
+		for j := 0; j < i; j++ {
+			if pu.Name == pus[j].Name && pu.Version == pus[j].Version && reflect.DeepEqual(pu.Config, pus[j].Config) {
+				errs.add(p, "duplicate of %s.publish[%d]", path, j)
+				break
+			}
+		}
+		if pu.Retry != nil {
+			validateRetry(p+".retry", pu.Retry, errs)
+		}
+	}
+}
+
+func validatePlugin(path, name string, config map[string]interface{}, errs *ValidationErrors) {
+	if name == "" {
+		errs.add(path+".plugin_name", "plugin name is required")
+	}
+	validateConfig(path+".config", config, errs)
+}
+
+func validateConfig(path string, config map[string]interface{}, errs *ValidationErrors) {
+	for _, k := range sortedKeys(config) {
+		switch config[k].(type) {
+		case string, int, float64, bool:
+		default:
+			errs.add(fmt.Sprintf("%s.%s", path, k), "unsupported config value type %T", config[k])
+		}
+	}
+}
+
+func validateRetry(path string, r *RetryPolicy, errs *ValidationErrors) {
+	if r.Attempts < 0 {
+		errs.add(path+".attempts", "attempts cannot be negative")
+	}
+	if r.Backoff != "" {
+		if _, err := time.ParseDuration(r.Backoff); err != nil {
+			errs.add(path+".backoff", "invalid duration %q", r.Backoff)
+		}
+	}
+	if r.MaxAge != "" {
+		if _, err := time.ParseDuration(r.MaxAge); err != nil {
+			errs.add(path+".max_age", "invalid duration %q", r.MaxAge)
+		}
+	}
+}
+
+func isValidMetricNamespace(ns string) bool {
+	return metricNamespaceRegexp.MatchString(ns)
+}
+
+func sortedKeys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The keys allowed in each node of a workflow map document
+var (
+	workflowMapKeys = []string{"collect"}
+	collectKeys     = []string{"metrics", "config", "process", "publish"}
+	metricKeys      = []string{"version"}
+	processKeys     = []string{"plugin_name", "plugin_version", "config", "parallel", "process", "publish"}
+	publishKeys     = []string{"plugin_name", "plugin_version", "config", "parallel", "retry"}
+	retryKeys       = []string{"attempts", "backoff", "max_age"}
+)
+
+// UnmarshalJSON decodes a workflow map and records any keys in the document
+// which are not part of a workflow map so Validate can report them.
+func (w *WorkflowMap) UnmarshalJSON(b []byte) error {
+	type plain WorkflowMap
+	if err := json.Unmarshal(b, (*plain)(w)); err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	w.unknownKeys = findUnknownKeys(doc)
+	return nil
+}
+
+// UnmarshalYAML decodes a workflow map and records any keys in the document
+// which are not part of a workflow map so Validate can report them.
+func (w *WorkflowMap) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain WorkflowMap
+	if err := unmarshal((*plain)(w)); err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	w.unknownKeys = findUnknownKeys(doc)
+	return nil
+}
+
+func findUnknownKeys(doc interface{}) []string {
+	var unknown []string
+	m := checkKeys("", doc, workflowMapKeys, &unknown)
+	c := checkKeys("collect", m["collect"], collectKeys, &unknown)
+	if metrics, ok := asMap(c["metrics"]); ok {
+		for _, ns := range sortedKeys(metrics) {
+			checkKeys(fmt.Sprintf("collect.metrics[%s]", ns), metrics[ns], metricKeys, &unknown)
+		}
+	}
+	findUnknownChildKeys("collect", c, &unknown)
+	return unknown
+}
+
+func findUnknownChildKeys(path string, node map[string]interface{}, unknown *[]string) {
+	prs, _ := node["process"].([]interface{})
+	for i, pr := range prs {
+		p := fmt.Sprintf("%s.process[%d]", path, i)
+		findUnknownChildKeys(p, checkKeys(p, pr, processKeys, unknown), unknown)
+	}
+	pus, _ := node["publish"].([]interface{})
+	for i, pu := range pus {
+		p := fmt.Sprintf("%s.publish[%d]", path, i)
+		n := checkKeys(p, pu, publishKeys, unknown)
+		checkKeys(p+".retry", n["retry"], retryKeys, unknown)
+	}
+}
+
+// checkKeys appends the path of every key of node which is not in allowed to
+// unknown and returns node as a map with string keys.
+func checkKeys(path string, node interface{}, allowed []string, unknown *[]string) map[string]interface{} {
+	m, ok := asMap(node)
+	if !ok {
+		return nil
+	}
+	for _, k := range sortedKeys(m) {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if path == "" {
+				*unknown = append(*unknown, k)
+			} else {
+				*unknown = append(*unknown, path+"."+k)
+			}
+		}
+	}
+	return m
+}
+
+// asMap returns a map decoded from JSON or YAML as a map with string keys
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		sm := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			sm[fmt.Sprint(k)] = val
+		}
+		return sm, true
+	}
+	return nil, false
+}
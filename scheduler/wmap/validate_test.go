@@ -0,0 +1,197 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wmap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func errorPaths(errs ValidationErrors) []string {
+	paths := make([]string, len(errs))
+	for i, e := range errs {
+		paths[i] = e.Path
+	}
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Validate()", t, func() {
+		Convey("accepts a valid workflow map", func() {
+			So(Sample().Validate(), ShouldBeEmpty)
+			w, err := FromYaml(SampleWorkflowMapYaml())
+			So(err, ShouldBeNil)
+			So(w.Validate(), ShouldBeEmpty)
+		})
+
+		Convey("requires a collect node with metrics", func() {
+			var w *WorkflowMap
+			So(errorPaths(w.Validate()), ShouldResemble, []string{"collect"})
+			So(errorPaths((&WorkflowMap{}).Validate()), ShouldResemble, []string{"collect"})
+			So(errorPaths(NewWorkflowMap().Validate()), ShouldResemble, []string{"collect.metrics"})
+		})
+
+		Convey("reports unknown keys", func() {
+			j := `{
+  "collect": {
+    "metrics": {"/foo/bar": {"version": 1, "ver": 2}},
+    "process": [
+      {
+        "plugin_name": "passthru",
+        "publish": [{"plugin_name": "file", "retry": {"tries": 3}}],
+        "confg": {}
+      }
+    ],
+    "publsh": []
+  },
+  "schedule": {}
+}`
+			w, err := FromJson(j)
+			So(err, ShouldBeNil)
+			So(errorPaths(w.Validate()), ShouldResemble, []string{
+				"schedule",
+				"collect.publsh",
+				"collect.metrics[/foo/bar].ver",
+				"collect.process[0].confg",
+				"collect.process[0].publish[0].retry.tries",
+			})
+
+			y := `
+collect:
+  metrics:
+    /foo/bar: {}
+  publish:
+    - plugin_name: file
+      plugin_verison: 2
+`
+			w, err = FromYaml(y)
+			So(err, ShouldBeNil)
+			errs := w.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldEqual, "collect.publish[0].plugin_verison: unknown key")
+
+			Convey("when nested in another document", func() {
+				var tr struct {
+					Workflow *WorkflowMap `json:"workflow"`
+				}
+				err := json.Unmarshal([]byte(`{"workflow": `+j+`}`), &tr)
+				So(err, ShouldBeNil)
+				So(tr.Workflow.Validate(), ShouldHaveLength, 5)
+			})
+		})
+
+		Convey("reports malformed namespaces", func() {
+			w := NewWorkflowMap()
+			w.CollectNode.Metrics["foo/bar"] = metricInfo{}
+			w.CollectNode.Metrics["/foo//bar"] = metricInfo{}
+			w.CollectNode.Metrics["/foo/*"] = metricInfo{}
+			w.CollectNode.AddConfigItem("/Foo/bar", "user", "root")
+			So(errorPaths(w.Validate()), ShouldResemble, []string{
+				"collect.metrics[/foo//bar]",
+				"collect.metrics[foo/bar]",
+				"collect.config[/Foo/bar]",
+			})
+		})
+
+		Convey("reports unsupported config value types", func() {
+			y := `
+collect:
+  metrics:
+    /foo/bar: {}
+  config:
+    /foo/bar:
+      hosts: [a, b]
+  publish:
+    - plugin_name: file
+      config:
+        file: /tmp/out
+        opts:
+          mode: 644
+`
+			w, err := FromYaml(y)
+			So(err, ShouldBeNil)
+			errs := w.Validate()
+			So(errorPaths(errs), ShouldResemble, []string{
+				"collect.config[/foo/bar].hosts",
+				"collect.publish[0].config.opts",
+			})
+			So(errs[0].Message, ShouldEqual, "unsupported config value type []interface {}")
+		})
+
+		Convey("reports processors that never reach a publisher", func() {
+			w, err := FromJson(mustRead("./sample/1.json"))
+			So(err, ShouldBeNil)
+			errs := w.Validate()
+			So(errorPaths(errs), ShouldResemble, []string{"collect.process[0].process[0]"})
+			So(errs[0].Message, ShouldEqual, "processor output never reaches a publisher")
+		})
+
+		Convey("reports duplicate sibling nodes", func() {
+			w := Sample()
+			pu := NewPublishNode("rabbitmq", 5)
+			pu.AddConfigItem("user", "root")
+			w.CollectNode.Add(pu)
+			pu = NewPublishNode("rabbitmq", 5)
+			pu.AddConfigItem("user", "guest")
+			w.CollectNode.Add(pu)
+			errs := w.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldEqual, "collect.publish[1]: duplicate of collect.publish[0]")
+		})
+
+		Convey("reports missing plugin names and bad retry policies", func() {
+			w := Sample()
+			pu := NewPublishNode("", 1)
+			pu.Retry = &RetryPolicy{Attempts: -1, Backoff: "1x", MaxAge: "1h"}
+			w.CollectNode.Add(pu)
+			So(errorPaths(w.Validate()), ShouldResemble, []string{
+				"collect.publish[1].plugin_name",
+				"collect.publish[1].retry.attempts",
+				"collect.publish[1].retry.backoff",
+			})
+		})
+
+		Convey("reports every problem", func() {
+			errs := ValidationErrors{
+				{Path: "collect.metrics", Message: "at least one metric is required"},
+				{Path: "collect.publish[0]", Message: "duplicate of collect.publish[1]"},
+			}
+			So(errs.Error(), ShouldEqual, "collect.metrics: at least one metric is required; collect.publish[0]: duplicate of collect.publish[1]")
+		})
+	})
+
+	Convey("AddMetric()", t, func() {
+		w := NewWorkflowMap()
+		So(w.CollectNode.AddMetric("/foo/bar", 1), ShouldBeNil)
+		So(w.CollectNode.AddMetric("foo/bar", 1), ShouldNotBeNil)
+		So(w.CollectNode.Metrics, ShouldHaveLength, 1)
+	})
+}
+
+func mustRead(path string) []byte {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
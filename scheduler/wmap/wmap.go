@@ -130,6 +130,8 @@ func Sample() *WorkflowMap {
 // A map of a desired workflow that is used to create a scheduleWorkflow
 type WorkflowMap struct {
 	CollectNode *CollectWorkflowMapNode `json:"collect"yaml:"collect"`
+	// keys found when decoding the map which are not part of a workflow map
+	unknownKeys []string
 }
 
 func NewWorkflowMap() *WorkflowMap {
@@ -204,8 +206,9 @@ func (c *CollectWorkflowMapNode) Add(node interface{}) error {
 }
 
 func (c *CollectWorkflowMapNode) AddMetric(ns string, v int) error {
-	// TODO regex validation here that this matches /one/two/three format
-	// c.MetricsNamespaces = append(c.MetricsNamespaces, ns)
+	if !isValidMetricNamespace(ns) {
+		return fmt.Errorf("Invalid namespace: %v", ns)
+	}
 	c.Metrics[ns] = metricInfo{Version_: v}
 	return nil
 }
@@ -224,6 +227,9 @@ type ProcessWorkflowMapNode struct {
 	PublishNodes []PublishWorkflowMapNode `json:"publish,omitempty"yaml:"publish"`
 	// TODO processor config
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
+	// Parallel set to false works this node in order with its siblings
+	// instead of concurrently with them.  Defaults to true.
+	Parallel *bool `json:"parallel,omitempty" yaml:"parallel"`
 }
 
 func NewProcessNode(name string, version int) *ProcessWorkflowMapNode {
@@ -253,6 +259,16 @@ func (p *ProcessWorkflowMapNode) AddConfigItem(key string, value interface{}) {
 	p.Config[key] = value
 }
 
+// IsParallel returns whether the node is worked concurrently with its siblings
+func (p *ProcessWorkflowMapNode) IsParallel() bool {
+	return p.Parallel == nil || *p.Parallel
+}
+
+// SetParallel sets whether the node is worked concurrently with its siblings
+func (p *ProcessWorkflowMapNode) SetParallel(v bool) {
+	p.Parallel = &v
+}
+
 func (p *ProcessWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error) {
 	if p.Config == nil {
 		return cdata.NewNode(), nil
@@ -265,6 +281,24 @@ type PublishWorkflowMapNode struct {
 	Version int    `json:"plugin_version"yaml:"plugin_version"`
 	// TODO publisher config
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
+	// Parallel set to false works this node in order with its siblings
+	// instead of concurrently with them.  Defaults to true.
+	Parallel *bool `json:"parallel,omitempty" yaml:"parallel"`
+	// Retry describes how a failed publish is retried and spooled
+	Retry *RetryPolicy `json:"retry,omitempty" yaml:"retry"`
+}
+
+// RetryPolicy describes how a failed publish is retried.  A batch which
+// still fails is spooled and replayed once the publisher recovers.
+type RetryPolicy struct {
+	// Attempts is the number of retries after the first attempt
+	Attempts int `json:"attempts" yaml:"attempts"`
+	// Backoff is the wait before the first retry which doubles after each
+	// retry (e.g. "500ms")
+	Backoff string `json:"backoff,omitempty" yaml:"backoff"`
+	// MaxAge is how long a spooled batch is kept for replay (e.g. "1h").
+	// Empty keeps it until it is replayed or purged.
+	MaxAge string `json:"max_age,omitempty" yaml:"max_age"`
 }
 
 func NewPublishNode(name string, version int) *PublishWorkflowMapNode {
@@ -282,6 +316,16 @@ func (p *PublishWorkflowMapNode) AddConfigItem(key string, value interface{}) {
 	p.Config[key] = value
 }
 
+// IsParallel returns whether the node is worked concurrently with its siblings
+func (p *PublishWorkflowMapNode) IsParallel() bool {
+	return p.Parallel == nil || *p.Parallel
+}
+
+// SetParallel sets whether the node is worked concurrently with its siblings
+func (p *PublishWorkflowMapNode) SetParallel(v bool) {
+	p.Parallel = &v
+}
+
 func (p *PublishWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error) {
 	if p.Config == nil {
 		return cdata.NewNode(), nil
@@ -20,6 +20,7 @@ limitations under the License.
 package wmap
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -167,6 +168,56 @@ func TestWorkflow(t *testing.T) {
 			fmt.Println(wmap)
 		})
 
+		Convey("parallel option", func() {
+			y := []byte(`
+collect:
+  metrics:
+    /foo/bar: {}
+  publish:
+    - plugin_name: a
+    - plugin_name: b
+      parallel: false
+    - plugin_name: c
+      parallel: true
+`)
+			wmap, err := FromYaml(y)
+			So(err, ShouldBeNil)
+			So(wmap.CollectNode.PublishNodes[0].IsParallel(), ShouldBeTrue)
+			So(wmap.CollectNode.PublishNodes[1].IsParallel(), ShouldBeFalse)
+			So(wmap.CollectNode.PublishNodes[2].IsParallel(), ShouldBeTrue)
+
+			pr := NewProcessNode("passthru", 1)
+			So(pr.IsParallel(), ShouldBeTrue)
+			pr.SetParallel(false)
+			So(pr.IsParallel(), ShouldBeFalse)
+			j, err := json.Marshal(pr)
+			So(err, ShouldBeNil)
+			So(string(j), ShouldContainSubstring, `"parallel":false`)
+		})
+
+		Convey("retry policy", func() {
+			y := []byte(`
+collect:
+  metrics:
+    /foo/bar: {}
+  publish:
+    - plugin_name: a
+    - plugin_name: b
+      retry:
+        attempts: 3
+        backoff: 2s
+        max_age: 1h
+`)
+			wmap, err := FromYaml(y)
+			So(err, ShouldBeNil)
+			So(wmap.CollectNode.PublishNodes[0].Retry, ShouldBeNil)
+			So(wmap.CollectNode.PublishNodes[1].Retry, ShouldResemble, &RetryPolicy{Attempts: 3, Backoff: "2s", MaxAge: "1h"})
+
+			j, err := json.Marshal(wmap.CollectNode.PublishNodes[1])
+			So(err, ShouldBeNil)
+			So(string(j), ShouldContainSubstring, `"retry":{"attempts":3,"backoff":"2s","max_age":"1h"}`)
+		})
+
 		Convey("Converts strings to bytes or keeps byte type", func() {
 			p, err := inStringBytes("test")
 			So(p, ShouldResemble, []byte("test"))
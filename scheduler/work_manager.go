@@ -19,7 +19,11 @@ limitations under the License.
 
 package scheduler
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/intelsdi-x/snap/pkg/telemetry"
+)
 
 /*
 
@@ -180,9 +184,72 @@ func newWorkManager(opts ...workManagerOption) *workManager {
 		wm.processWkrs[i] = newWorker(wm.processchan)
 		go wm.processWkrs[i].start()
 	}
+	wm.registerTelemetry()
 	return wm
 }
 
+// registerTelemetry reports the depth of the queues and the use of the worker
+// pools, replacing any work manager which reported them before.
+func (w *workManager) registerTelemetry() {
+	telemetry.Default.Func(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "scheduler", "queue", "*", "depth"},
+		Labels:      []telemetry.Label{{Index: 4, Name: "queue"}},
+		Kind:        telemetry.GaugeKind,
+		Description: "Jobs waiting in the queue",
+	}, func(emit func(float64, ...string)) {
+		emit(float64(w.collectq.depth()), collectJobType.String())
+		emit(float64(w.processq.depth()), processJobType.String())
+		emit(float64(w.publishq.depth()), publishJobType.String())
+	})
+	workers := func(emit func(count, busy int, pool string)) {
+		w.mutex.Lock()
+		pools := map[string][]*worker{
+			collectJobType.String(): w.collectWkrs,
+			processJobType.String(): w.processWkrs,
+			publishJobType.String(): w.publishWkrs,
+		}
+		w.mutex.Unlock()
+		for pool, wkrs := range pools {
+			busy := 0
+			for _, wkr := range wkrs {
+				if wkr.isBusy() {
+					busy++
+				}
+			}
+			emit(len(wkrs), busy, pool)
+		}
+	}
+	poolLabels := []telemetry.Label{{Index: 4, Name: "pool"}}
+	telemetry.Default.Func(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "scheduler", "workers", "*", "count"},
+		Labels:      poolLabels,
+		Kind:        telemetry.GaugeKind,
+		Description: "Workers in the pool",
+	}, func(emit func(float64, ...string)) {
+		workers(func(count, _ int, pool string) { emit(float64(count), pool) })
+	})
+	telemetry.Default.Func(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "scheduler", "workers", "*", "busy"},
+		Labels:      poolLabels,
+		Kind:        telemetry.GaugeKind,
+		Description: "Workers in the pool running a job",
+	}, func(emit func(float64, ...string)) {
+		workers(func(_, busy int, pool string) { emit(float64(busy), pool) })
+	})
+	telemetry.Default.Func(telemetry.Desc{
+		Namespace:   []string{"intel", "snap", "scheduler", "workers", "*", "utilization"},
+		Labels:      poolLabels,
+		Kind:        telemetry.GaugeKind,
+		Description: "Fraction of the workers in the pool running a job",
+	}, func(emit func(float64, ...string)) {
+		workers(func(count, busy int, pool string) {
+			if count > 0 {
+				emit(float64(busy)/float64(count), pool)
+			}
+		})
+	})
+}
+
 // Start workManager's loop just handles queuing errors.
 func (w *workManager) Start() {
 
@@ -235,6 +302,8 @@ func (w *workManager) Work(j job) queuedJob {
 // AddCollectWorker adds a new worker to
 // the collector worker pool
 func (w *workManager) AddCollectWorker() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	nw := newWorker(w.collectchan)
 	go nw.start()
 	w.collectWkrs = append(w.collectWkrs, nw)
@@ -244,6 +313,8 @@ func (w *workManager) AddCollectWorker() {
 // AddPublishWorker adds a new worker to
 // the publisher worker pool
 func (w *workManager) AddPublishWorker() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	nw := newWorker(w.publishchan)
 	go nw.start()
 	w.publishWkrs = append(w.publishWkrs, nw)
@@ -253,6 +324,8 @@ func (w *workManager) AddPublishWorker() {
 // AddProcessWorker adds a new worker to
 // the processor worker pool
 func (w *workManager) AddProcessWorker() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	nw := newWorker(w.processchan)
 	go nw.start()
 	w.processWkrs = append(w.processWkrs, nw)
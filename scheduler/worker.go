@@ -21,17 +21,33 @@ package scheduler
 
 import (
 	"errors"
+	"sync/atomic"
+	"time"
 
 	"github.com/intelsdi-x/snap/pkg/chrono"
+	"github.com/intelsdi-x/snap/pkg/telemetry"
 	"github.com/pborman/uuid"
 )
 
 var workerKillChan = make(chan struct{})
 
+var jobLatency = telemetry.Default.Timer(telemetry.Desc{
+	Namespace:   []string{"intel", "snap", "scheduler", "jobs", "*", "latency"},
+	Labels:      []telemetry.Label{{Index: 4, Name: "type"}},
+	Description: "Time from jobs being created until they complete",
+})
+
+var (
+	// ErrJobTimedOut - The error message for a job which did not finish before its deadline
+	ErrJobTimedOut = errors.New("Job timed out before finishing")
+)
+
 type worker struct {
 	id       string
 	rcv      <-chan queuedJob
 	kamikaze chan struct{}
+	// busy is 1 while the worker has a job
+	busy int32
 }
 
 func newWorker(rChan <-chan queuedJob) *worker {
@@ -47,9 +63,10 @@ func (w *worker) start() {
 	for {
 		select {
 		case q := <-w.rcv:
+			atomic.StoreInt32(&w.busy, 1)
 			// assert that deadline is not exceeded
 			if chrono.Chrono.Now().Before(q.Job().Deadline()) {
-				q.Job().Run()
+				w.run(q.Job())
 			} else {
 				// the deadline was exceeded and this job will not run
 				q.Job().AddErrors(errors.New("Worker refused to run overdue job."))
@@ -57,6 +74,8 @@ func (w *worker) start() {
 
 			// mark the job complete
 			q.Promise().Complete(q.Job().Errors())
+			jobLatency.Since(q.Job().StartTime(), q.Job().Type().String())
+			atomic.StoreInt32(&w.busy, 0)
 
 		// the single kill-channel -- used when resizing worker pools
 		case <-w.kamikaze:
@@ -68,3 +87,30 @@ func (w *worker) start() {
 		}
 	}
 }
+
+func (w *worker) isBusy() bool {
+	return atomic.LoadInt32(&w.busy) == 1
+}
+
+// run works the job until it finishes or its deadline passes.  A job which
+// is still running at its deadline is abandoned, so the worker is free for
+// the next job, and left to finish on its own.  The calls it makes to plugins
+// give up at the same deadline.  A job which failed after its deadline
+// passed is also timed out.
+func (w *worker) run(j job) {
+	done := make(chan struct{})
+	go func() {
+		j.Run()
+		close(done)
+	}()
+	timer := time.NewTimer(j.Deadline().Sub(chrono.Chrono.Now()))
+	defer timer.Stop()
+	select {
+	case <-done:
+		if len(j.Errors()) != 0 && !chrono.Chrono.Now().Before(j.Deadline()) {
+			j.AddErrors(ErrJobTimedOut)
+		}
+	case <-timer.C:
+		j.AddErrors(ErrJobTimedOut)
+	}
+}
@@ -61,6 +61,28 @@ func TestWorker(t *testing.T) {
 		So(errors, ShouldNotBeEmpty)
 		So(mj.worked, ShouldBeFalse)
 	})
+	Convey("abandons a job still running at its deadline", t, func() {
+		workerKillChan = make(chan struct{})
+		rcv := make(chan queuedJob)
+		w := newWorker(rcv)
+		go w.start()
+		mj := newMultiSyncMockJob(1)
+		mj.deadline = time.Now().Add(50 * time.Millisecond)
+		qj := newQueuedJob(mj)
+		rcv <- qj
+		errors := qj.Promise().Await()
+		So(errors, ShouldResemble, []error{ErrJobTimedOut})
+		So(mj.worked, ShouldBeFalse)
+
+		// the worker is free for the next job
+		mj2 := newMockJob()
+		rcv <- newQueuedJob(mj2)
+		mj2.Await()
+		So(mj2.worked, ShouldBeTrue)
+
+		mj.RendezVous()
+		mj.Await()
+	})
 	Convey("stops the worker if kamikaze chan is closed", t, func() {
 		workerKillChan = make(chan struct{})
 		rcv := make(chan queuedJob)
@@ -22,6 +22,7 @@ package scheduler
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/intelsdi-x/gomit"
 
@@ -54,16 +55,14 @@ var (
 
 // WmapToWorkflow attempts to convert a wmap.WorkflowMap to a schedulerWorkflow instance.
 func wmapToWorkflow(wfMap *wmap.WorkflowMap) (*schedulerWorkflow, error) {
+	if errs := wfMap.Validate(); len(errs) != 0 {
+		return nil, errs
+	}
 	wf := &schedulerWorkflow{}
 	err := convertCollectionNode(wfMap.CollectNode, wf)
 	if err != nil {
 		return nil, err
 	}
-	// ***
-	// TODO validate workflow makes sense here
-	// - flows that don't end in publishers?
-	// - duplicate child nodes anywhere?
-	//***
 	// Retain a copy of the original workflow map
 	wf.workflowMap = wfMap
 	return wf, nil
@@ -133,6 +132,7 @@ func convertProcessNode(pr []wmap.ProcessWorkflowMapNode) ([]*processNode, error
 			name:         p.Name,
 			version:      p.Version,
 			config:       cdn,
+			parallel:     p.IsParallel(),
 			ProcessNodes: prC,
 			PublishNodes: puC,
 		}
@@ -153,10 +153,16 @@ func convertPublishNode(pu []wmap.PublishWorkflowMapNode) ([]*publishNode, error
 		if p.Version < 1 {
 			p.Version = -1
 		}
+		retry, err := newRetryPolicy(p.Retry)
+		if err != nil {
+			return nil, err
+		}
 		puNodes[i] = &publishNode{
-			name:    p.Name,
-			version: p.Version,
-			config:  cdn,
+			name:     p.Name,
+			version:  p.Version,
+			config:   cdn,
+			parallel: p.IsParallel(),
+			retry:    retry,
 		}
 	}
 	return puNodes, nil
@@ -179,6 +185,7 @@ type processNode struct {
 	name               string
 	version            int
 	config             *cdata.ConfigDataNode
+	parallel           bool
 	ProcessNodes       []*processNode
 	PublishNodes       []*publishNode
 	InboundContentType string
@@ -204,6 +211,8 @@ type publishNode struct {
 	name               string
 	version            int
 	config             *cdata.ConfigDataNode
+	parallel           bool
+	retry              *retryPolicy
 	InboundContentType string
 }
 
@@ -225,10 +234,11 @@ func (p *publishNode) TypeName() string {
 
 type wfContentTypes map[string]map[string][]string
 
-// BindPluginContentTypes
+// BindPluginContentTypes selects the content type each node of the workflow
+// is sent.  An error is returned if a node does not accept a content type
+// which the node before it returns or which snap can convert it to.
 func (s *schedulerWorkflow) BindPluginContentTypes(mm managesPluginContentTypes) error {
-	bindPluginContentTypes(s.publishNodes, s.processNodes, mm, []string{plugin.SnapGOBContentType})
-	return nil
+	return bindPluginContentTypes(s.publishNodes, s.processNodes, mm, []string{plugin.SnapGOBContentType})
 }
 
 func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPluginContentTypes, lct []string) error {
@@ -237,63 +247,75 @@ func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPl
 		if err != nil {
 			return err
 		}
-
-		for _, ac := range act {
-			for _, lc := range lct {
-				// if the return contenet type from the previous node matches
-				// the accept content type for this node set it as the
-				// inbound content type
-				if ac == lc {
-					pr.InboundContentType = ac
-				}
-			}
-		}
-		// if the inbound content type isn't set yet snap may be able to do
-		// the conversion
+		pr.InboundContentType = selectContentType(act, lct)
 		if pr.InboundContentType == "" {
-			for _, ac := range act {
-				switch ac {
-				case plugin.SnapGOBContentType:
-					pr.InboundContentType = plugin.SnapGOBContentType
-				case plugin.SnapJSONContentType:
-					pr.InboundContentType = plugin.SnapJSONContentType
-				case plugin.SnapAllContentType:
-					pr.InboundContentType = plugin.SnapGOBContentType
-				}
-			}
-			// else we return an error
-			if pr.InboundContentType == "" {
-				return fmt.Errorf("Invalid workflow.  Plugin '%s' does not accept the snap content types or the types '%v' returned from the previous node.", pr.Name(), lct)
-			}
+			return fmt.Errorf("Invalid workflow.  Plugin '%s' does not accept the snap content types or the types '%v' returned from the previous node.", pr.Name(), lct)
 		}
 		//continue the walk down the nodes
-		bindPluginContentTypes(pr.PublishNodes, pr.ProcessNodes, mm, rct)
+		if err := bindPluginContentTypes(pr.PublishNodes, pr.ProcessNodes, mm, rct); err != nil {
+			return err
+		}
 	}
 	for _, pu := range pus {
 		act, _, err := mm.GetPluginContentTypes(pu.Name(), core.PublisherPluginType, pu.Version())
 		if err != nil {
 			return err
 		}
-		// if the inbound content type isn't set yet snap may be able to do
-		// the conversion
+		pu.InboundContentType = selectContentType(act, lct)
 		if pu.InboundContentType == "" {
-			for _, ac := range act {
-				switch ac {
-				case plugin.SnapGOBContentType:
-					pu.InboundContentType = plugin.SnapGOBContentType
-				case plugin.SnapJSONContentType:
-					pu.InboundContentType = plugin.SnapJSONContentType
-				case plugin.SnapAllContentType:
-					pu.InboundContentType = plugin.SnapGOBContentType
-				}
+			return fmt.Errorf("Invalid workflow.  Plugin '%s' does not accept the snap content types or the types '%v' returned from the previous node.", pu.Name(), lct)
+		}
+	}
+	return nil
+}
+
+// selectContentType returns the content type, out of the accepted content
+// types of a node, which every content type returned by the previous node
+// can be sent as.  A content type returned by the previous node is preferred
+// since it needs no conversion.  An empty string is returned if there is none.
+func selectContentType(act, lct []string) string {
+	var selected string
+	for _, ac := range act {
+		// snap.* accepts any of the snap content types, which is sent as
+		// snap.gob
+		if ac == plugin.SnapAllContentType {
+			ac = plugin.SnapGOBContentType
+		}
+		ok := true
+		for _, lc := range lct {
+			if !isContentTypeConvertible(lc, ac) {
+				ok = false
+				break
 			}
-			// else we return an error
-			if pu.InboundContentType == "" {
-				return fmt.Errorf("Invalid workflow.  Plugin '%s' does not accept the snap content types or the types '%v' returned from the previous node.", pu.Name(), lct)
+		}
+		if !ok {
+			continue
+		}
+		for _, lc := range lct {
+			if ac == lc {
+				return ac
 			}
 		}
+		if selected == "" {
+			selected = ac
+		}
 	}
-	return nil
+	return selected
+}
+
+// isContentTypeConvertible returns whether content of one content type can be
+// sent to a node as another.  Snap converts between its own content types,
+// any other content type can only be sent as is.
+func isContentTypeConvertible(from, to string) bool {
+	return from == to || isSnapContentType(from) && isSnapContentType(to)
+}
+
+func isSnapContentType(ct string) bool {
+	switch ct {
+	case plugin.SnapAllContentType, plugin.SnapGOBContentType, plugin.SnapJSONContentType:
+		return true
+	}
+	return false
 }
 
 // Start starts a workflow
@@ -307,8 +329,11 @@ func (s *schedulerWorkflow) Start(t *task) {
 
 	if len(errors) != 0 {
 		t.failedRuns++
+		if timedOut(errors) {
+			t.timedOutRuns++
+		}
 		t.lastFailureTime = t.lastFireTime
-		t.lastFailureMessage = j.Errors()[len(j.Errors())-1].Error()
+		t.lastFailureMessage = errors[len(errors)-1].Error()
 		event := new(scheduler_event.MetricCollectionFailedEvent)
 		event.TaskID = t.id
 		event.Errors = errors
@@ -323,7 +348,24 @@ func (s *schedulerWorkflow) Start(t *task) {
 	defer s.eventEmitter.Emit(event)
 
 	// walk through the tree and dispatch work
-	s.workJobs(s.processNodes, s.publishNodes, t, j)
+	if errors := s.workJobs(s.processNodes, s.publishNodes, t, j); len(errors) != 0 {
+		t.failedRuns++
+		if timedOut(errors) {
+			t.timedOutRuns++
+		}
+		t.lastFailureTime = t.lastFireTime
+		t.lastFailureMessage = errors[len(errors)-1].Error()
+	}
+}
+
+// timedOut returns whether any of the errors is from a job which timed out
+func timedOut(errs []error) bool {
+	for _, e := range errs {
+		if e == ErrJobTimedOut {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *schedulerWorkflow) State() WorkflowState {
@@ -334,27 +376,54 @@ func (s *schedulerWorkflow) StateString() string {
 	return WorkflowStateLookup[s.state]
 }
 
-func (s *schedulerWorkflow) workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
-	for _, pr := range prs {
-		j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), t.metricsManager, t.id)
-		errors := t.manager.Work(j).Promise().Await()
-		if len(errors) != 0 {
-			t.failedRuns++
-			t.lastFailureTime = t.lastFireTime
-			t.lastFailureMessage = errors[len(errors)-1].Error()
+// workJobs dispatches the jobs for the given process and publish nodes and
+// blocks until they and the nodes below them are done.  Sibling nodes are
+// worked concurrently.  A node which is not parallel waits for the siblings
+// before it and is done before the siblings after it are dispatched.  The
+// errors from every branch are returned.
+func (s *schedulerWorkflow) workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) []error {
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		errors []error
+	)
+	dispatch := func(parallel bool, work func() []error) {
+		run := func() {
+			if errs := work(); len(errs) != 0 {
+				mutex.Lock()
+				errors = append(errors, errs...)
+				mutex.Unlock()
+			}
+		}
+		if !parallel {
+			wg.Wait()
+			run()
 			return
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
 
-		s.workJobs(pr.ProcessNodes, pr.PublishNodes, t, j)
+	for _, pr := range prs {
+		pr := pr
+		dispatch(pr.parallel, func() []error {
+			j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), t.metricsManager, t.id)
+			if errs := t.manager.Work(j).Promise().Await(); len(errs) != 0 {
+				return errs
+			}
+			return s.workJobs(pr.ProcessNodes, pr.PublishNodes, t, j)
+		})
 	}
 	for _, pu := range pus {
-		j := newPublishJob(pj, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), t.metricsManager, t.id)
-		errors := t.manager.Work(j).Promise().Await()
-		if len(errors) != 0 {
-			t.failedRuns++
-			t.lastFailureTime = t.lastFireTime
-			t.lastFailureMessage = errors[len(errors)-1].Error()
-			return
-		}
+		pu := pu
+		dispatch(pu.parallel, func() []error {
+			j := newPublishJob(pj, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), t.metricsManager, t.id, pu.retry, t.spool)
+			return workPublishJob(t.manager, j)
+		})
 	}
+	wg.Wait()
+	return errors
 }
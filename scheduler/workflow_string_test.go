@@ -32,7 +32,7 @@ func TestWorkflowString(t *testing.T) {
 	log.SetLevel(log.FatalLevel)
 	Convey("String", t, func() {
 		w := wmap.NewWorkflowMap()
-		w.CollectNode.AddMetric("fall", 1)
+		w.CollectNode.AddMetric("/fall", 1)
 		pr1 := wmap.NewProcessNode("winter", 1)
 		pr2 := wmap.NewProcessNode("summer", 1)
 		pu1 := wmap.NewPublishNode("spring", 1)
@@ -41,6 +41,7 @@ func TestWorkflowString(t *testing.T) {
 		pr1.AddConfigItem("leaves", 1)
 		pr2.AddConfigItem("flowers", 2)
 		pu2.AddConfigItem("grass", 3)
+		pr2.Add(wmap.NewPublishNode("solstice", 1))
 		w.CollectNode.Add(pr1)
 		w.CollectNode.Add(pu1)
 		w.CollectNode.ProcessNodes[0].Add(pr2)
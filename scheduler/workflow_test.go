@@ -20,12 +20,15 @@ limitations under the License.
 package scheduler
 
 import (
+	"errors"
 	"os"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/pkg/schedule"
@@ -115,3 +118,143 @@ func TestCollectPublishWorkflow(t *testing.T) {
 		})
 	})
 }
+
+// mockWorkManager completes each job after the delay given for its plugin
+// and records the order the jobs completed in
+type mockWorkManager struct {
+	sync.Mutex
+	delay map[string]time.Duration
+	fail  map[string]bool
+	done  []string
+}
+
+func (m *mockWorkManager) Work(j job) queuedJob {
+	var name string
+	switch v := j.(type) {
+	case *processJob:
+		name = v.pluginName
+	case *publisherJob:
+		name = v.pluginName
+	}
+	time.Sleep(m.delay[name])
+	m.Lock()
+	m.done = append(m.done, name)
+	m.Unlock()
+	qj := newQueuedJob(j)
+	if m.fail[name] {
+		qj.Promise().Complete([]error{errors.New(name + " failed")})
+	} else {
+		qj.Promise().Complete([]error{})
+	}
+	return qj
+}
+
+func TestWorkJobs(t *testing.T) {
+	Convey("workJobs", t, func() {
+		m := &mockWorkManager{
+			delay: map[string]time.Duration{
+				"a": time.Millisecond * 100,
+				"b": time.Millisecond * 100,
+				"c": time.Millisecond * 100,
+			},
+			fail: map[string]bool{},
+		}
+		tsk := &task{id: "1234", manager: m}
+		pj := &collectorJob{coreJob: &coreJob{}}
+		pub := func(name string, parallel bool) *publishNode {
+			return &publishNode{name: name, version: 1, config: cdata.NewNode(), parallel: parallel}
+		}
+		wf := &schedulerWorkflow{}
+
+		Convey("works sibling nodes concurrently", func() {
+			start := time.Now()
+			errs := wf.workJobs(nil, []*publishNode{pub("a", true), pub("b", true), pub("c", true)}, tsk, pj)
+			So(errs, ShouldBeEmpty)
+			So(time.Since(start), ShouldBeLessThan, time.Millisecond*250)
+			So(m.done, ShouldHaveLength, 3)
+		})
+		Convey("works the children of a process node after it", func() {
+			m.delay["a"] = time.Millisecond * 50
+			pr := &processNode{name: "a", version: 1, config: cdata.NewNode(), parallel: true}
+			pr.PublishNodes = []*publishNode{pub("c", true)}
+			errs := wf.workJobs([]*processNode{pr}, []*publishNode{pub("b", true)}, tsk, pj)
+			So(errs, ShouldBeEmpty)
+			So(m.done, ShouldResemble, []string{"a", "b", "c"})
+		})
+		Convey("keeps the order of a node which is not parallel", func() {
+			m.delay["a"] = time.Millisecond * 50
+			m.delay["b"] = time.Millisecond * 10
+			m.delay["c"] = 0
+			errs := wf.workJobs(nil, []*publishNode{pub("a", true), pub("b", false), pub("c", true)}, tsk, pj)
+			So(errs, ShouldBeEmpty)
+			So(m.done, ShouldResemble, []string{"a", "b", "c"})
+		})
+		Convey("gathers the errors from every branch", func() {
+			m.fail["a"] = true
+			m.fail["c"] = true
+			pr := &processNode{name: "a", version: 1, config: cdata.NewNode(), parallel: true}
+			pr.PublishNodes = []*publishNode{pub("b", true)}
+			errs := wf.workJobs([]*processNode{pr}, []*publishNode{pub("c", true)}, tsk, pj)
+			So(errs, ShouldHaveLength, 2)
+			// a failed so its child is not worked
+			So(m.done, ShouldNotContain, "b")
+		})
+	})
+}
+
+func TestBindPluginContentTypes(t *testing.T) {
+	Convey("BindPluginContentTypes()", t, func() {
+		c := &mockMetricManager{}
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		pr := wmap.NewProcessNode("pr", 1)
+		pr.Add(wmap.NewPublishNode("pub", 1))
+		w.CollectNode.Add(pr)
+		w.CollectNode.Add(wmap.NewPublishNode("pub", 1))
+
+		Convey("converts between the snap content types", func() {
+			c.setAcceptedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapJSONContentType})
+			c.setReturnedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapJSONContentType})
+			c.setAcceptedContentType("pub", core.PublisherPluginType, 1, []string{plugin.SnapAllContentType})
+			wf, err := wmapToWorkflow(w)
+			So(err, ShouldBeNil)
+			So(wf.BindPluginContentTypes(c), ShouldBeNil)
+			So(wf.processNodes[0].InboundContentType, ShouldEqual, plugin.SnapJSONContentType)
+			So(wf.processNodes[0].PublishNodes[0].InboundContentType, ShouldEqual, plugin.SnapGOBContentType)
+			So(wf.publishNodes[0].InboundContentType, ShouldEqual, plugin.SnapGOBContentType)
+		})
+		Convey("prefers the content type returned by the previous node", func() {
+			c.setAcceptedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapGOBContentType})
+			c.setReturnedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapJSONContentType})
+			c.setAcceptedContentType("pub", core.PublisherPluginType, 1, []string{plugin.SnapGOBContentType, plugin.SnapJSONContentType})
+			wf, err := wmapToWorkflow(w)
+			So(err, ShouldBeNil)
+			So(wf.BindPluginContentTypes(c), ShouldBeNil)
+			So(wf.processNodes[0].PublishNodes[0].InboundContentType, ShouldEqual, plugin.SnapJSONContentType)
+			So(wf.publishNodes[0].InboundContentType, ShouldEqual, plugin.SnapGOBContentType)
+		})
+		Convey("passes a plugin specific content type to a node which accepts it", func() {
+			c.setAcceptedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapGOBContentType})
+			c.setReturnedContentType("pr", core.ProcessorPluginType, 1, []string{"influx.line"})
+			c.setAcceptedContentType("pub", core.PublisherPluginType, 1, []string{plugin.SnapGOBContentType, "influx.line"})
+			wf, err := wmapToWorkflow(w)
+			So(err, ShouldBeNil)
+			So(wf.BindPluginContentTypes(c), ShouldBeNil)
+			So(wf.processNodes[0].PublishNodes[0].InboundContentType, ShouldEqual, "influx.line")
+		})
+		Convey("rejects a content type which cannot be converted", func() {
+			c.setAcceptedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapGOBContentType})
+			c.setReturnedContentType("pr", core.ProcessorPluginType, 1, []string{plugin.SnapGOBContentType, "influx.line"})
+			c.setAcceptedContentType("pub", core.PublisherPluginType, 1, []string{plugin.SnapGOBContentType})
+			wf, err := wmapToWorkflow(w)
+			So(err, ShouldBeNil)
+			So(wf.BindPluginContentTypes(c), ShouldNotBeNil)
+		})
+		Convey("rejects a node which accepts no content type it can be sent", func() {
+			c.setAcceptedContentType("pr", core.ProcessorPluginType, 1, []string{"influx.line"})
+			wf, err := wmapToWorkflow(w)
+			So(err, ShouldBeNil)
+			So(wf.BindPluginContentTypes(c), ShouldNotBeNil)
+		})
+	})
+}
@@ -41,10 +41,6 @@ echo "Getting GoConvey if not found"
 go get github.com/smartystreets/goconvey
 echo "Getting goimports if not found"
 go get golang.org/x/tools/cmd/goimports
-echo "Getting govet if not found"
-go get golang.org/x/tools/cmd/vet
-echo "Getting cover if not found"
-go get golang.org/x/tools/cmd/cover
 
 # Automatic checks
 echo "gofmt"
@@ -36,11 +36,13 @@ import (
 	"github.com/codegangsta/cli"
 
 	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest"
 	"github.com/intelsdi-x/snap/mgmt/tribe"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/mgmt/webhook"
 	"github.com/intelsdi-x/snap/scheduler"
 )
 
@@ -83,12 +85,35 @@ var (
 		Usage:  "Auto discover paths separated by colons.",
 		EnvVar: "SNAP_AUTOLOAD_PATH",
 	}
+	flAutodiscoverWatch = cli.StringFlag{
+		Name:   "auto-discover-watch",
+		Usage:  "How often to rescan the auto discover paths and load new plugins, e.g. 5s. Empty disables watching.",
+		EnvVar: "SNAP_AUTOLOAD_WATCH",
+	}
+	flAutodiscoverSwap = cli.BoolFlag{
+		Name:  "auto-discover-swap",
+		Usage: "Swap a watched plugin for its file when the file is replaced",
+	}
+	flAutodiscoverUnload = cli.BoolFlag{
+		Name:  "auto-discover-unload",
+		Usage: "Unload a watched plugin when its file is removed",
+	}
+	flPluginSocketDir = cli.StringFlag{
+		Name:   "plugin-socket-dir",
+		Usage:  "A private directory for plugins to listen on Unix sockets in instead of TCP ports",
+		EnvVar: "SNAP_PLUGIN_SOCKET_DIR",
+	}
 	flPluginTrust = cli.IntFlag{
 		Name:   "plugin-trust, t",
 		Usage:  "0-2 (Disabled, Enabled, Warning)",
 		EnvVar: "SNAP_TRUST_LEVEL",
 		Value:  1,
 	}
+	flAllowRemotePlugins = cli.BoolFlag{
+		Name:   "allow-remote-plugins",
+		Usage:  "Load plugins running as a service, whose signature cannot be checked, while plugin trust is enabled",
+		EnvVar: "SNAP_ALLOW_REMOTE_PLUGINS",
+	}
 	flkeyringPaths = cli.StringFlag{
 		Name:   "keyring-files, k",
 		Usage:  "Keyring files for signing verification separated by colons",
@@ -100,6 +125,12 @@ var (
 		EnvVar: "SNAP_CACHE_EXPIRATION",
 		Value:  "500ms",
 	}
+	flCacheSize = cli.IntFlag{
+		Name:   "cache-size",
+		Usage:  "The maximum number of entries in the metric cache of a plugin, 0 for no limit",
+		EnvVar: "SNAP_CACHE_SIZE",
+		Value:  10000,
+	}
 	flConfig = cli.StringFlag{
 		Name:  "config",
 		Usage: "A path to a config file",
@@ -116,6 +147,25 @@ var (
 		Name:  "rest-key",
 		Usage: "A path to a key file to use for HTTPS deployment of snap's REST API",
 	}
+	flRestPasswordFile = cli.StringFlag{
+		Name:  "rest-password-file",
+		Usage: "A path to a file of user:bcrypt-hash lines which enables HTTP basic auth for snap's REST API",
+	}
+	flRestClientCA = cli.StringFlag{
+		Name:  "rest-client-ca",
+		Usage: "A path to the CA certificates used to verify client certificates when snap's REST API is running HTTPS",
+	}
+	flTaskStorePath = cli.StringFlag{
+		Name:   "task-store-path",
+		Usage:  "A path to a directory where tasks are persisted across restarts. Empty path disables persistence.",
+		Value:  filepath.Join(defaultDataDir, "tasks"),
+		EnvVar: "SNAP_TASK_STORE_PATH",
+	}
+	flPublishSpoolPath = cli.StringFlag{
+		Name:   "publish-spool-path",
+		Usage:  "A path to a directory where batches which could not be published are spooled for replay. Empty path disables spooling.",
+		EnvVar: "SNAP_PUBLISH_SPOOL_PATH",
+	}
 
 	gitversion string
 )
@@ -123,6 +173,9 @@ var (
 const (
 	defaultQueueSize uint = 25
 	defaultPoolSize  uint = 4
+
+	// defaultDataDir is where snapd keeps the state it persists by default
+	defaultDataDir = "/var/lib/snap"
 )
 
 type coreModule interface {
@@ -145,6 +198,12 @@ type managesTribe interface {
 var coreModules []coreModule
 
 func main() {
+	// snapd is run as the limits shim of a plugin with resource limits to
+	// set its rlimits before it executes the plugin
+	if len(os.Args) > 1 && os.Args[1] == plugin.LimitsShimArg {
+		plugin.RunLimitsShim(os.Args[2:])
+	}
+
 	// Add a check to see if gitversion is blank from the build process
 	if gitversion == "" {
 		gitversion = "unknown"
@@ -161,14 +220,24 @@ func main() {
 		flLogPath,
 		flMaxProcs,
 		flPluginVersion,
+		flAutodiscoverWatch,
+		flAutodiscoverSwap,
+		flAutodiscoverUnload,
 		flNumberOfPLs,
 		flCache,
+		flCacheSize,
+		flPluginSocketDir,
 		flPluginTrust,
+		flAllowRemotePlugins,
 		flkeyringPaths,
 		flRestCert,
 		flConfig,
 		flRestHttps,
 		flRestKey,
+		flRestPasswordFile,
+		flRestClientCA,
+		flTaskStorePath,
+		flPublishSpoolPath,
 	}
 	app.Flags = append(app.Flags, tribe.Flags...)
 
@@ -216,6 +285,7 @@ func action(ctx *cli.Context) {
 	disableAPI := ctx.Bool("disable-api")
 	apiPort := ctx.Int("api-port")
 	autodiscoverPath := ctx.String("auto-discover")
+	autodiscoverWatch := ctx.String("auto-discover-watch")
 	maxRunning := ctx.Int("max-running-plugins")
 	pluginTrust := ctx.Int("plugin-trust")
 	keyringPaths := ctx.String("keyring-files")
@@ -229,10 +299,24 @@ func action(ctx *cli.Context) {
 	if err != nil {
 		log.Fatal(fmt.Sprintf("invalid cache-expiration format: %s", cachestr))
 	}
+	var watchInterval time.Duration
+	if autodiscoverWatch != "" {
+		watchInterval, err = time.ParseDuration(autodiscoverWatch)
+		if err != nil || watchInterval <= 0 {
+			log.Fatal(fmt.Sprintf("invalid auto-discover-watch format: %s", autodiscoverWatch))
+		}
+	}
 	config := ctx.String("config")
 	restHttps := ctx.Bool("rest-https")
 	restKey := ctx.String("rest-key")
 	restCert := ctx.String("rest-cert")
+	restAuth := &rest.AuthConfig{
+		PasswordFile: ctx.String("rest-password-file"),
+		ClientCAFile: ctx.String("rest-client-ca"),
+	}
+	var restRBAC *rest.RBACConfig
+	taskStorePath := ctx.String("task-store-path")
+	publishSpoolPath := ctx.String("publish-spool-path")
 
 	log.Info("Starting snapd (version: ", gitversion, ")")
 
@@ -242,9 +326,30 @@ func action(ctx *cli.Context) {
 	// Validate log level and trust level settings for snapd
 	validateLevelSettings(logLevel, pluginTrust)
 
+	if exe, err := os.Executable(); err == nil {
+		plugin.LimitsShim = []string{exe, plugin.LimitsShimArg}
+	} else {
+		log.WithFields(log.Fields{
+			"block":   "main",
+			"_module": "snapd",
+			"error":   err.Error(),
+		}).Warning("plugins cannot be given open files limits")
+	}
+
 	controlOpts := []control.PluginControlOpt{
 		control.MaxRunningPlugins(maxRunning),
 		control.CacheExpiration(cache),
+		control.CacheSize(ctx.Int("cache-size")),
+		control.EnableSelfCollector(),
+	}
+	if logPath := ctx.String("log-path"); logPath != "" {
+		controlOpts = append(controlOpts, control.PluginLogPath(filepath.Join(logPath, "plugins")))
+	}
+	if socketDir := ctx.String("plugin-socket-dir"); socketDir != "" {
+		controlOpts = append(controlOpts, control.UnixSockets(socketDir))
+	}
+	if ctx.Bool("allow-remote-plugins") {
+		controlOpts = append(controlOpts, control.AllowRemotePlugins())
 	}
 
 	if config != "" {
@@ -268,6 +373,33 @@ func action(ctx *cli.Context) {
 			}).Fatal("invalid config")
 		}
 		controlOpts = append(controlOpts, control.OptSetConfig(cfg))
+
+		var restCfg struct {
+			RestAPI struct {
+				Auth *rest.AuthConfig `json:"auth"`
+				RBAC *rest.RBACConfig `json:"rbac"`
+			} `json:"restapi"`
+		}
+		err = json.Unmarshal(b, &restCfg)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+				"error":   err.Error(),
+				"path":    config,
+			}).Fatal("invalid config")
+		}
+		// Flags take precedence over the config file
+		if a := restCfg.RestAPI.Auth; a != nil {
+			if restAuth.PasswordFile == "" {
+				restAuth.PasswordFile = a.PasswordFile
+			}
+			if restAuth.ClientCAFile == "" {
+				restAuth.ClientCAFile = a.ClientCAFile
+			}
+			restAuth.Tokens = a.Tokens
+		}
+		restRBAC = restCfg.RestAPI.RBAC
 	}
 
 	c := control.New(
@@ -286,6 +418,43 @@ func action(ctx *cli.Context) {
 		scheduler.ProcessWkrSizeOption(defaultPoolSize),
 	)
 	s.SetMetricManager(c)
+	if taskStorePath != "" {
+		ts, err := scheduler.NewFileTaskStore(taskStorePath)
+		switch {
+		case err != nil && !ctx.IsSet("task-store-path"):
+			// snapd may not be allowed to write to the default
+			// path, e.g. when run by a user, which is not fatal
+			log.WithFields(log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+				"error":   err.Error(),
+				"path":    taskStorePath,
+			}).Warning("unable to open the default task store, task persistence is disabled")
+		case err != nil:
+			log.WithFields(log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+				"error":   err.Error(),
+				"path":    taskStorePath,
+			}).Fatal("unable to open task store")
+		default:
+			s.SetTaskStore(ts)
+			log.Info("task persistence is enabled")
+		}
+	}
+	if publishSpoolPath != "" {
+		ps, err := scheduler.NewFilePublishSpool(publishSpoolPath)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+				"error":   err.Error(),
+				"path":    publishSpoolPath,
+			}).Fatal("unable to open publish spool")
+		}
+		s.SetPublishSpool(ps)
+		log.Info("publish spooling is enabled")
+	}
 	coreModules = append(coreModules, s)
 
 	var tr managesTribe
@@ -304,6 +473,15 @@ func action(ctx *cli.Context) {
 		tr = t
 	}
 
+	// Webhooks are managed through the REST API
+	var wh *webhook.Manager
+	if !disableAPI {
+		wh = webhook.New(webhook.DefaultConfig())
+		c.RegisterEventHandler("webhook", wh)
+		s.RegisterEventHandler("webhook", wh)
+		coreModules = append(coreModules, wh)
+	}
+
 	// Set interrupt handling so we can die gracefully.
 	startInterruptHandling(coreModules...)
 
@@ -481,10 +659,22 @@ func action(ctx *cli.Context) {
 				}
 			}
 		}
+		if watchInterval > 0 {
+			c.WatchAutodiscoverPaths(
+				control.WatchIntervalOption(watchInterval),
+				control.WatchSwapOption(ctx.Bool("auto-discover-swap")),
+				control.WatchUnloadOption(ctx.Bool("auto-discover-unload")),
+			)
+		}
 	} else {
 		log.Info("auto discover path is disabled")
 	}
 
+	// Restore persisted tasks now that their plugins are loaded.  The
+	// scheduler is started with the other modules, before the plugins are
+	// loaded, so restoring is not part of its Start.
+	s.RestoreTasks()
+
 	//API
 	if !disableAPI {
 		r, err := rest.New(restHttps, restCert, restKey)
@@ -492,9 +682,20 @@ func action(ctx *cli.Context) {
 			log.Fatal(err)
 			return
 		}
+		if err := r.EnableAuth(restAuth); err != nil {
+			log.Fatal(err)
+			return
+		}
+		if err := r.EnableRBAC(restRBAC); err != nil {
+			log.Fatal(err)
+			return
+		}
 		r.BindMetricManager(c)
 		r.BindConfigManager(c.Config)
 		r.BindTaskManager(s)
+		c.RegisterEventHandler("rest", r)
+		s.RegisterEventHandler("rest", r)
+		r.BindWebhookManager(wh)
 		if tr != nil {
 			r.BindTribeManager(tr)
 		}